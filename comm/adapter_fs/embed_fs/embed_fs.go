@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"path"
 	"strings"
+	"sync"
 
 	"github.com/go-xlite/wbx/comm"
 	webFs "github.com/go-xlite/wbx/comm/web_fs"
@@ -16,6 +17,7 @@ type EmbedFS struct {
 	*webFs.WebFs
 	fs          *embed.FS
 	EmbedPrefix string
+	etags       sync.Map // path -> content hash ETag (embedded content never changes)
 }
 
 // NewEmbedFS creates a new embedded filesystem provider
@@ -85,7 +87,26 @@ func (e *EmbedFS) Stat(path string) (comm.FileInfo, error) {
 	if err != nil {
 		return comm.FileInfo{}, err
 	}
-	return webFs.ConvertFileInfo(info), nil
+	fileInfo := webFs.ConvertFileInfo(info)
+	if !info.IsDir() {
+		fileInfo.ETag = e.contentETag(fullPath)
+	}
+	return fileInfo, nil
+}
+
+// contentETag returns the cached content hash ETag of an embedded file,
+// hashing it on first use since embedded files carry no modification time
+func (e *EmbedFS) contentETag(fullPath string) string {
+	if etag, ok := e.etags.Load(fullPath); ok {
+		return etag.(string)
+	}
+	data, err := fs.ReadFile(e.fs, fullPath)
+	if err != nil {
+		return ""
+	}
+	etag := comm.ETagForBytes(data)
+	e.etags.Store(fullPath, etag)
+	return etag
 }
 
 // ListDir returns a list of files and directories from the embedded filesystem
@@ -0,0 +1,292 @@
+package memfs
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-xlite/wbx/comm"
+	webFs "github.com/go-xlite/wbx/comm/web_fs"
+)
+
+// MemFs provides filesystem operations over an in-memory tree, for tests and
+// programmatically generated content. Build it fluently:
+//
+//	fs := memfs.NewMemFs().
+//		AddFile("index.html", []byte("<h1>hi</h1>")).
+//		AddDir("assets")
+type MemFs struct {
+	*webFs.WebFs
+	files map[string]*memFile
+	dirs  map[string]time.Time
+	mu    sync.RWMutex
+}
+
+var (
+	_ comm.IFsAdapter      = (*MemFs)(nil)
+	_ comm.IFsStreamWriter = (*MemFs)(nil)
+)
+
+type memFile struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+	etag    string
+}
+
+// NewMemFs creates an empty, writable in-memory filesystem
+func NewMemFs() *MemFs {
+	return &MemFs{
+		WebFs: webFs.NewWebFs(),
+		files: make(map[string]*memFile),
+		dirs:  map[string]time.Time{"": time.Now()},
+	}
+}
+
+// AddFile adds (or replaces) a file, creating parent directories
+func (m *MemFs) AddFile(filePath string, data []byte) *MemFs {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.putFile(m.makePath(filePath), data, 0644)
+	return m
+}
+
+// AddFileString adds a file with string content
+func (m *MemFs) AddFileString(filePath string, content string) *MemFs {
+	return m.AddFile(filePath, []byte(content))
+}
+
+// AddDir adds an (empty) directory, creating parents
+func (m *MemFs) AddDir(dirPath string) *MemFs {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.putDirs(m.makePath(dirPath))
+	return m
+}
+
+// SetModTime sets the modification time of an existing file or directory
+func (m *MemFs) SetModTime(filePath string, modTime time.Time) *MemFs {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := m.makePath(filePath)
+	if f, ok := m.files[key]; ok {
+		f.modTime = modTime
+	} else if _, ok := m.dirs[key]; ok {
+		m.dirs[key] = modTime
+	}
+	return m
+}
+
+// ReadFile returns a copy of the file contents
+func (m *MemFs) ReadFile(filePath string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, ok := m.files[m.makePath(filePath)]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: filePath, Err: fs.ErrNotExist}
+	}
+	return bytes.Clone(f.data), nil
+}
+
+// WriteFile stores data, creating parent directories
+func (m *MemFs) WriteFile(filePath string, data []byte, perm fs.FileMode) error {
+	if m.IsReadOnly() {
+		return &fs.PathError{Op: "write", Path: filePath, Err: fs.ErrPermission}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := m.makePath(filePath)
+	if _, isDir := m.dirs[key]; isDir {
+		return &fs.PathError{Op: "write", Path: filePath, Err: fs.ErrInvalid}
+	}
+	m.putFile(key, bytes.Clone(data), perm)
+	return nil
+}
+
+// Create returns a writer whose data is stored at filePath when closed
+func (m *MemFs) Create(filePath string, perm fs.FileMode) (comm.IFsFileWriter, error) {
+	if m.IsReadOnly() {
+		return nil, &fs.PathError{Op: "create", Path: filePath, Err: fs.ErrPermission}
+	}
+	return &memFileWriter{fs: m, path: filePath, perm: perm}, nil
+}
+
+// CreateNew is Create failing with fs.ErrExist when filePath exists. The name
+// is reserved with an empty file until the writer is closed or aborted.
+func (m *MemFs) CreateNew(filePath string, perm fs.FileMode) (comm.IFsFileWriter, error) {
+	if m.IsReadOnly() {
+		return nil, &fs.PathError{Op: "create", Path: filePath, Err: fs.ErrPermission}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := m.makePath(filePath)
+	_, isFile := m.files[key]
+	_, isDir := m.dirs[key]
+	if isFile || isDir {
+		return nil, &fs.PathError{Op: "create", Path: filePath, Err: fs.ErrExist}
+	}
+	m.putFile(key, nil, perm)
+	return &memFileWriter{fs: m, path: filePath, perm: perm, reserved: true}, nil
+}
+
+// Remove deletes a file
+func (m *MemFs) Remove(filePath string) error {
+	if m.IsReadOnly() {
+		return &fs.PathError{Op: "remove", Path: filePath, Err: fs.ErrPermission}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := m.makePath(filePath)
+	if _, ok := m.files[key]; !ok {
+		return &fs.PathError{Op: "remove", Path: filePath, Err: fs.ErrNotExist}
+	}
+	delete(m.files, key)
+	return nil
+}
+
+// memFileWriter buffers writes and commits them through WriteFile on Close
+type memFileWriter struct {
+	bytes.Buffer
+	fs       *MemFs
+	path     string
+	perm     fs.FileMode
+	reserved bool // path holds a placeholder created by CreateNew
+}
+
+func (fw *memFileWriter) Close() error {
+	return fw.fs.WriteFile(fw.path, fw.Bytes(), fw.perm)
+}
+
+// Abort drops the buffered data and the placeholder of CreateNew
+func (fw *memFileWriter) Abort() error {
+	fw.Reset()
+	if fw.reserved {
+		fw.fs.mu.Lock()
+		delete(fw.fs.files, fw.fs.makePath(fw.path))
+		fw.fs.mu.Unlock()
+	}
+	return nil
+}
+
+// Open opens a file for reading
+func (m *MemFs) Open(filePath string) (io.ReadCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, ok := m.files[m.makePath(filePath)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: filePath, Err: fs.ErrNotExist}
+	}
+	// Writes replace the slice, so readers keep a consistent snapshot
+	return io.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+// Exists checks if a file or directory exists
+func (m *MemFs) Exists(filePath string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key := m.makePath(filePath)
+	_, isFile := m.files[key]
+	_, isDir := m.dirs[key]
+	return isFile || isDir
+}
+
+// Stat returns file information, including a content hash ETag for files
+func (m *MemFs) Stat(filePath string) (comm.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key := m.makePath(filePath)
+	if info, ok := m.info(key); ok {
+		return info, nil
+	}
+	return comm.FileInfo{}, &fs.PathError{Op: "stat", Path: filePath, Err: fs.ErrNotExist}
+}
+
+// ListDir returns the direct children of a directory, sorted by name
+func (m *MemFs) ListDir(dirPath string) ([]comm.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	dir := m.makePath(dirPath)
+	if _, ok := m.dirs[dir]; !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: dirPath, Err: fs.ErrNotExist}
+	}
+
+	var result []comm.FileInfo
+	for key := range m.files {
+		if parentOf(key) == dir {
+			info, _ := m.info(key)
+			result = append(result, info)
+		}
+	}
+	for key := range m.dirs {
+		if key != "" && parentOf(key) == dir {
+			info, _ := m.info(key)
+			result = append(result, info)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// IsDir checks if the path is a directory
+func (m *MemFs) IsDir(dirPath string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.dirs[m.makePath(dirPath)]
+	return ok
+}
+
+// info builds the FileInfo for a normalized key (lock held)
+func (m *MemFs) info(key string) (comm.FileInfo, bool) {
+	if f, ok := m.files[key]; ok {
+		info := webFs.NewFileInfo(path.Base(key), int64(len(f.data)), f.mode, f.modTime, false)
+		info.ETag = f.etag
+		return info, true
+	}
+	if modTime, ok := m.dirs[key]; ok {
+		return webFs.NewFileInfo(path.Base("/"+key), 0, fs.ModeDir|0755, modTime, true), true
+	}
+	return comm.FileInfo{}, false
+}
+
+// putFile stores a file and its parent directories (lock held)
+func (m *MemFs) putFile(key string, data []byte, perm fs.FileMode) {
+	m.putDirs(parentOf(key))
+	m.files[key] = &memFile{
+		data:    data,
+		mode:    perm,
+		modTime: time.Now(),
+		etag:    comm.ETagForBytes(data),
+	}
+}
+
+// putDirs records dir and all its parents (lock held)
+func (m *MemFs) putDirs(dir string) {
+	for {
+		if _, ok := m.dirs[dir]; !ok {
+			m.dirs[dir] = time.Now()
+		}
+		if dir == "" {
+			return
+		}
+		dir = parentOf(dir)
+	}
+}
+
+// makePath normalizes a path relative to the base path into a map key
+func (m *MemFs) makePath(filePath string) string {
+	return strings.TrimPrefix(path.Clean("/"+m.GetBasePath()+"/"+filePath), "/")
+}
+
+// parentOf returns the parent key of key ("" for top-level entries)
+func parentOf(key string) string {
+	parent := path.Dir(key)
+	if parent == "." || parent == "/" {
+		return ""
+	}
+	return parent
+}
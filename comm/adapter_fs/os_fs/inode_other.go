@@ -0,0 +1,10 @@
+//go:build !unix
+
+package osfs
+
+import "io/fs"
+
+// inodeOf returns 0 on platforms without inode numbers
+func inodeOf(info fs.FileInfo) uint64 {
+	return 0
+}
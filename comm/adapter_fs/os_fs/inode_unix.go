@@ -0,0 +1,16 @@
+//go:build unix
+
+package osfs
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// inodeOf returns the inode number of info, or 0 if unavailable
+func inodeOf(info fs.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
@@ -1,6 +1,7 @@
 package osfs
 
 import (
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
@@ -55,6 +56,97 @@ func (o *OsFs) WriteFile(path string, data []byte, perm fs.FileMode) error {
 	return os.WriteFile(fullPath, data, perm)
 }
 
+// Create opens path for streamed writing. Data goes to a temporary file in the
+// same directory which is renamed into place on Close, so readers never see a
+// partial file.
+func (o *OsFs) Create(path string, perm fs.FileMode) (comm.IFsFileWriter, error) {
+	return o.create(path, perm, false)
+}
+
+// CreateNew is Create failing with fs.ErrExist when path exists. The name is
+// reserved with an empty file (created with O_EXCL) until the writer is closed
+// or aborted.
+func (o *OsFs) CreateNew(path string, perm fs.FileMode) (comm.IFsFileWriter, error) {
+	return o.create(path, perm, true)
+}
+
+func (o *OsFs) create(path string, perm fs.FileMode, exclusive bool) (comm.IFsFileWriter, error) {
+	if o.IsReadOnly() {
+		return nil, &fs.PathError{Op: "create", Path: path, Err: fs.ErrPermission}
+	}
+
+	fullPath := o.makePath(path)
+	dir := filepath.Dir(fullPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	if exclusive {
+		placeholder, err := os.OpenFile(fullPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if err != nil {
+			return nil, err
+		}
+		placeholder.Close()
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(fullPath)+".part-*")
+	if err == nil {
+		if err = tmp.Chmod(perm); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		if exclusive {
+			os.Remove(fullPath)
+		}
+		return nil, err
+	}
+	return &osFileWriter{File: tmp, target: fullPath, reserved: exclusive}, nil
+}
+
+// Remove deletes a file
+func (o *OsFs) Remove(path string) error {
+	if o.IsReadOnly() {
+		return &fs.PathError{Op: "remove", Path: path, Err: fs.ErrPermission}
+	}
+	return os.Remove(o.makePath(path))
+}
+
+// osFileWriter renames its temporary file to target when closed
+type osFileWriter struct {
+	*os.File
+	target   string
+	reserved bool // target is a placeholder created by CreateNew
+}
+
+func (fw *osFileWriter) Close() error {
+	if err := fw.File.Close(); err != nil {
+		fw.discard()
+		return err
+	}
+	if err := os.Rename(fw.Name(), fw.target); err != nil {
+		fw.discard()
+		return err
+	}
+	return nil
+}
+
+// Abort removes the temporary file without publishing it
+func (fw *osFileWriter) Abort() error {
+	err := fw.File.Close()
+	fw.discard()
+	return err
+}
+
+// discard removes the temporary file and the placeholder of CreateNew
+func (fw *osFileWriter) discard() {
+	os.Remove(fw.Name())
+	if fw.reserved {
+		os.Remove(fw.target)
+	}
+}
+
 // Open opens a file for reading
 func (o *OsFs) Open(path string) (io.ReadCloser, error) {
 	fullPath := o.makePath(path)
@@ -75,7 +167,12 @@ func (o *OsFs) Stat(path string) (comm.FileInfo, error) {
 	if err != nil {
 		return comm.FileInfo{}, err
 	}
-	return webFs.ConvertFileInfo(info), nil
+	fileInfo := webFs.ConvertFileInfo(info)
+	if !info.IsDir() {
+		// inode + mtime + size changes whenever the file is replaced or rewritten
+		fileInfo.ETag = fmt.Sprintf(`"%x-%x-%x"`, inodeOf(info), info.ModTime().UnixNano(), info.Size())
+	}
+	return fileInfo, nil
 }
 
 // ListDir returns a list of files and directories
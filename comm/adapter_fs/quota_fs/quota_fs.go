@@ -0,0 +1,363 @@
+package quotafs
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-xlite/wbx/comm"
+)
+
+var (
+	// ErrQuotaExceeded is returned when a write would push a prefix or user over its quota
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrFileTooLarge is returned when a single file is larger than MaxFileSize
+	ErrFileTooLarge = errors.New("file too large")
+)
+
+// QuotaUsage reports the bytes used against a quota (Limit 0 = unlimited)
+type QuotaUsage struct {
+	Key   string `json:"key"`
+	Used  int64  `json:"used"`
+	Limit int64  `json:"limit"`
+}
+
+// QuotaFs wraps a writable IFsAdapter and enforces byte quotas per path prefix
+// and per user on WriteFile and on streamed writes (Create, CreateNew); Remove
+// gives the bytes back. All other operations pass through unchanged.
+type QuotaFs struct {
+	comm.IFsAdapter
+	MaxFileSize int64 // Largest single file accepted (0 = unlimited)
+
+	prefixLimits map[string]int64
+	prefixUsage  map[string]int64
+	userLimits   map[string]int64
+	userUsage    map[string]int64
+	owners       map[string]string // path -> user that wrote it
+	mu           sync.Mutex
+}
+
+// NewQuotaFs wraps inner with quota accounting
+func NewQuotaFs(inner comm.IFsAdapter) *QuotaFs {
+	return &QuotaFs{
+		IFsAdapter:   inner,
+		prefixLimits: make(map[string]int64),
+		prefixUsage:  make(map[string]int64),
+		userLimits:   make(map[string]int64),
+		userUsage:    make(map[string]int64),
+		owners:       make(map[string]string),
+	}
+}
+
+// SetMaxFileSize sets the largest single file accepted
+func (q *QuotaFs) SetMaxFileSize(size int64) *QuotaFs {
+	q.MaxFileSize = size
+	return q
+}
+
+// SetPrefixQuota limits the total bytes stored under prefix. Current usage is
+// computed by walking the existing files under prefix.
+func (q *QuotaFs) SetPrefixQuota(prefix string, limit int64) *QuotaFs {
+	prefix = cleanPath(prefix)
+	used := q.walkSize(prefix)
+
+	q.mu.Lock()
+	q.prefixLimits[prefix] = limit
+	q.prefixUsage[prefix] = used
+	q.mu.Unlock()
+	return q
+}
+
+// SetUserQuota limits the total bytes written by user through WriteFileAs.
+// User usage is tracked in memory from writes made through this adapter.
+func (q *QuotaFs) SetUserQuota(user string, limit int64) *QuotaFs {
+	q.mu.Lock()
+	q.userLimits[user] = limit
+	q.mu.Unlock()
+	return q
+}
+
+// WriteFile writes data subject to the prefix quotas
+func (q *QuotaFs) WriteFile(filePath string, data []byte, perm fs.FileMode) error {
+	return q.WriteFileAs("", filePath, data, perm)
+}
+
+// WriteFileAs writes data on behalf of user, subject to the prefix quotas and the
+// user's quota. Replacing a file only counts the size difference.
+func (q *QuotaFs) WriteFileAs(user string, filePath string, data []byte, perm fs.FileMode) error {
+	filePath = cleanPath(filePath)
+	size := int64(len(data))
+	if q.MaxFileSize > 0 && size > q.MaxFileSize {
+		return &fs.PathError{Op: "write", Path: filePath, Err: ErrFileTooLarge}
+	}
+
+	// Hold the lock across the write so concurrent writers cannot both squeeze under a limit
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	c := q.priceLocked(user, filePath, size)
+	if err := q.checkLocked(c); err != nil {
+		return err
+	}
+	if err := q.IFsAdapter.WriteFile(filePath, data, perm); err != nil {
+		return err
+	}
+	q.commitLocked(c)
+	return nil
+}
+
+// Create opens filePath for streaming subject to the prefix quotas, which are
+// checked as data is written and again when the writer is closed. The wrapped
+// adapter must implement comm.IFsStreamWriter.
+func (q *QuotaFs) Create(filePath string, perm fs.FileMode) (comm.IFsFileWriter, error) {
+	return q.create(filePath, perm, false)
+}
+
+// CreateNew is Create failing with fs.ErrExist when filePath already exists
+func (q *QuotaFs) CreateNew(filePath string, perm fs.FileMode) (comm.IFsFileWriter, error) {
+	return q.create(filePath, perm, true)
+}
+
+func (q *QuotaFs) create(filePath string, perm fs.FileMode, exclusive bool) (comm.IFsFileWriter, error) {
+	sw, ok := q.IFsAdapter.(comm.IFsStreamWriter)
+	if !ok {
+		return nil, &fs.PathError{Op: "create", Path: filePath, Err: errors.ErrUnsupported}
+	}
+	filePath = cleanPath(filePath)
+	var out comm.IFsFileWriter
+	var err error
+	if exclusive {
+		out, err = sw.CreateNew(filePath, perm)
+	} else {
+		out, err = sw.Create(filePath, perm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	q.mu.Lock()
+	c := q.priceLocked("", filePath, 0)
+	q.mu.Unlock()
+	return &quotaWriter{q: q, out: out, charge: c}, nil
+}
+
+// Remove deletes filePath and returns its bytes to the quotas
+func (q *QuotaFs) Remove(filePath string) error {
+	sw, ok := q.IFsAdapter.(comm.IFsStreamWriter)
+	if !ok {
+		return &fs.PathError{Op: "remove", Path: filePath, Err: errors.ErrUnsupported}
+	}
+	filePath = cleanPath(filePath)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	c := q.priceLocked("", filePath, 0)
+	if err := sw.Remove(filePath); err != nil {
+		return err
+	}
+	q.commitLocked(c)
+	return nil
+}
+
+// charge is a write of size bytes to path on behalf of user, priced against
+// the file it replaces
+type charge struct {
+	path     string
+	user     string
+	size     int64
+	oldSize  int64
+	oldOwner string
+	owned    bool
+}
+
+// userDelta is what the write adds to the user's usage: all of it, unless
+// the user already owned the replaced file
+func (c *charge) userDelta() int64 {
+	if c.owned && c.oldOwner == c.user {
+		return c.size - c.oldSize
+	}
+	return c.size
+}
+
+// priceLocked prices a write against the file currently stored (lock held)
+func (q *QuotaFs) priceLocked(user, filePath string, size int64) *charge {
+	c := &charge{path: filePath, user: user, size: size}
+	if info, err := q.IFsAdapter.Stat(filePath); err == nil && !info.IsDir {
+		c.oldSize = info.Size
+	}
+	c.oldOwner, c.owned = q.owners[filePath]
+	return c
+}
+
+// checkLocked fails with ErrQuotaExceeded when c does not fit (lock held)
+func (q *QuotaFs) checkLocked(c *charge) error {
+	delta := c.size - c.oldSize
+	for _, prefix := range q.matchingPrefixes(c.path) {
+		if limit := q.prefixLimits[prefix]; limit > 0 && q.prefixUsage[prefix]+delta > limit {
+			return &fs.PathError{Op: "write", Path: c.path, Err: ErrQuotaExceeded}
+		}
+	}
+	if c.user != "" {
+		if limit := q.userLimits[c.user]; limit > 0 && q.userUsage[c.user]+c.userDelta() > limit {
+			return &fs.PathError{Op: "write", Path: c.path, Err: ErrQuotaExceeded}
+		}
+	}
+	return nil
+}
+
+// commitLocked records a charge whose write succeeded (lock held)
+func (q *QuotaFs) commitLocked(c *charge) {
+	for _, prefix := range q.matchingPrefixes(c.path) {
+		q.prefixUsage[prefix] += c.size - c.oldSize
+	}
+	if c.owned && c.oldOwner != c.user {
+		q.userUsage[c.oldOwner] -= c.oldSize
+	}
+	if c.user != "" {
+		q.userUsage[c.user] += c.userDelta()
+		q.owners[c.path] = c.user
+	} else {
+		delete(q.owners, c.path)
+	}
+}
+
+// quotaWriter streams a file through QuotaFs and charges it on Close
+type quotaWriter struct {
+	q      *QuotaFs
+	out    comm.IFsFileWriter
+	charge *charge // priced when the writer was created, for early checks
+}
+
+func (w *quotaWriter) Write(p []byte) (int, error) {
+	q := w.q
+	size := w.charge.size + int64(len(p))
+	if q.MaxFileSize > 0 && size > q.MaxFileSize {
+		return 0, &fs.PathError{Op: "write", Path: w.charge.path, Err: ErrFileTooLarge}
+	}
+	q.mu.Lock()
+	early := *w.charge
+	early.size = size
+	err := q.checkLocked(&early)
+	q.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.out.Write(p)
+	w.charge.size += int64(n)
+	return n, err
+}
+
+// Close publishes the file if it still fits the quotas, re-priced against
+// whatever it replaces now
+func (w *quotaWriter) Close() error {
+	q := w.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	c := q.priceLocked("", w.charge.path, w.charge.size)
+	if err := q.checkLocked(c); err != nil {
+		w.out.Abort()
+		return err
+	}
+	if err := w.out.Close(); err != nil {
+		return err
+	}
+	q.commitLocked(c)
+	return nil
+}
+
+func (w *quotaWriter) Abort() error {
+	return w.out.Abort()
+}
+
+// PrefixUsage returns the usage of a configured prefix quota
+func (q *QuotaFs) PrefixUsage(prefix string) QuotaUsage {
+	prefix = cleanPath(prefix)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return QuotaUsage{Key: prefix, Used: q.prefixUsage[prefix], Limit: q.prefixLimits[prefix]}
+}
+
+// UserUsage returns the bytes written by user and their quota
+func (q *QuotaFs) UserUsage(user string) QuotaUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return QuotaUsage{Key: user, Used: q.userUsage[user], Limit: q.userLimits[user]}
+}
+
+// GetUsage returns the usage of all prefix and user quotas
+func (q *QuotaFs) GetUsage() map[string][]QuotaUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	prefixes := make([]QuotaUsage, 0, len(q.prefixLimits))
+	for prefix, limit := range q.prefixLimits {
+		prefixes = append(prefixes, QuotaUsage{Key: prefix, Used: q.prefixUsage[prefix], Limit: limit})
+	}
+	users := make([]QuotaUsage, 0, len(q.userUsage))
+	for user, used := range q.userUsage {
+		users = append(users, QuotaUsage{Key: user, Used: used, Limit: q.userLimits[user]})
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return prefixes[i].Key < prefixes[j].Key })
+	sort.Slice(users, func(i, j int) bool { return users[i].Key < users[j].Key })
+	return map[string][]QuotaUsage{"prefixes": prefixes, "users": users}
+}
+
+// HandleUsage returns a handler reporting GetUsage as JSON
+func (q *QuotaFs) HandleUsage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(q.GetUsage())
+	}
+}
+
+// StatusForError maps a write error to an HTTP status:
+// 413 for ErrFileTooLarge, 507 for ErrQuotaExceeded, 500 otherwise (200 for nil)
+func StatusForError(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, ErrFileTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrQuotaExceeded):
+		return http.StatusInsufficientStorage
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// matchingPrefixes returns the configured prefixes containing filePath (lock held)
+func (q *QuotaFs) matchingPrefixes(filePath string) []string {
+	var matches []string
+	for prefix := range q.prefixLimits {
+		if prefix == "" || filePath == prefix || strings.HasPrefix(filePath, prefix+"/") {
+			matches = append(matches, prefix)
+		}
+	}
+	return matches
+}
+
+// walkSize returns the total size of the files under dir
+func (q *QuotaFs) walkSize(dir string) int64 {
+	entries, err := q.IFsAdapter.ListDir(dir)
+	if err != nil {
+		return 0
+	}
+	var total int64
+	for _, entry := range entries {
+		if entry.IsDir {
+			total += q.walkSize(path.Join(dir, entry.Name))
+		} else {
+			total += entry.Size
+		}
+	}
+	return total
+}
+
+func cleanPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-xlite/wbx/comm"
+)
+
+// Event types emitted by wbx packages
+const (
+	TypeLogin         = "auth.login"
+	TypeLoginFailed   = "auth.login_failed"
+	TypeLogout        = "auth.logout"
+	TypeSessionRevoke = "session.revoke"
+	TypeProxyBlocked  = "proxy.blocked"
+	TypeAdminRequest  = "admin.request"
+)
+
+// Outcomes
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+// Event is one structured audit record
+type Event struct {
+	Time       time.Time      `json:"time"`
+	Type       string         `json:"type"`
+	Outcome    string         `json:"outcome,omitempty"`
+	Actor      string         `json:"actor,omitempty"` // User or subject performing the action
+	RemoteAddr string         `json:"remote_addr,omitempty"`
+	Method     string         `json:"method,omitempty"`
+	Path       string         `json:"path,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+}
+
+// AuditSink stores or forwards audit events
+type AuditSink interface {
+	Write(ev Event) error
+}
+
+// SinkFunc adapts a function to AuditSink
+type SinkFunc func(ev Event) error
+
+// Write calls f(ev)
+func (f SinkFunc) Write(ev Event) error {
+	return f(ev)
+}
+
+// MultiSink fans events out to several sinks, returning the first error
+func MultiSink(sinks ...AuditSink) AuditSink {
+	return SinkFunc(func(ev Event) error {
+		var first error
+		for _, sink := range sinks {
+			if err := sink.Write(ev); err != nil && first == nil {
+				first = err
+			}
+		}
+		return first
+	})
+}
+
+var (
+	sink   AuditSink
+	sinkMu sync.RWMutex
+)
+
+// SetSink installs the global audit sink (nil disables auditing)
+func SetSink(s AuditSink) {
+	sinkMu.Lock()
+	sink = s
+	sinkMu.Unlock()
+}
+
+// GetSink returns the global audit sink, or nil
+func GetSink() AuditSink {
+	sinkMu.RLock()
+	defer sinkMu.RUnlock()
+	return sink
+}
+
+// Enabled reports whether a sink is installed
+func Enabled() bool {
+	return GetSink() != nil
+}
+
+// Emit sends ev to the global sink, stamping Time if unset.
+// Sink errors are logged, never returned to the caller.
+func Emit(ev Event) {
+	s := GetSink()
+	if s == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	if err := s.Write(ev); err != nil {
+		fmt.Printf("Audit: failed to write %s event: %v\n", ev.Type, err)
+	}
+}
+
+// EmitRequest emits an event filled with the method, path and remote address of r
+func EmitRequest(r *http.Request, eventType, outcome, actor string, details map[string]any) {
+	if !Enabled() {
+		return
+	}
+	Emit(Event{
+		Type:       eventType,
+		Outcome:    outcome,
+		Actor:      actor,
+		RemoteAddr: comm.ClientIP(r),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Details:    details,
+	})
+}
+
+// Middleware emits an admin.request event for every mutating request
+// (anything but GET/HEAD/OPTIONS) passing through next. actor may be nil.
+func Middleware(actor func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &comm.CaptureWriter{ResponseWriter: w}
+			next.ServeHTTP(comm.WrapResponseWriter(w, cw), r)
+			status := cw.Status()
+			if !cw.WroteHeader() {
+				// Nothing or only 1xx headers written: net/http answers 200
+				status = http.StatusOK
+			}
+
+			name := ""
+			if actor != nil {
+				name = actor(r)
+			}
+			outcome := OutcomeSuccess
+			switch {
+			case status == http.StatusUnauthorized || status == http.StatusForbidden:
+				outcome = OutcomeDenied
+			case status >= http.StatusBadRequest:
+				outcome = OutcomeFailure
+			}
+			EmitRequest(r, TypeAdminRequest, outcome, name, map[string]any{"status": status})
+		})
+	}
+}
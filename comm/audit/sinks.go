@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// JSONLinesSink writes one JSON object per line to an io.Writer
+type JSONLinesSink struct {
+	w   io.Writer
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONLinesSink creates a JSON-lines sink writing to w
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{w: w, enc: json.NewEncoder(w)}
+}
+
+// NewJSONLinesFile opens (appending) path and returns a JSON-lines sink for it
+func NewJSONLinesFile(path string) (*JSONLinesSink, error) {
+	f, err := openAppend(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONLinesSink(f), nil
+}
+
+// Write encodes ev as a single line
+func (s *JSONLinesSink) Write(ev Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(ev)
+}
+
+// Close closes the underlying writer if it is an io.Closer
+func (s *JSONLinesSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// FileSink appends human-readable lines to a log file:
+//
+//	2024-01-02T15:04:05Z auth.login success actor=admin remote=10.0.0.1 POST /login
+//
+// Free-form values (actor, path, details) are written as Go-quoted strings
+// when they contain spaces, quotes, '=' or control characters, and detail
+// values that are not strings or numbers as quoted JSON, so a crafted
+// username or path cannot forge extra fields or lines.
+type FileSink struct {
+	f  *os.File
+	mu sync.Mutex
+}
+
+// NewFileSink opens (appending) path for audit lines
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := openAppend(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{f: f}, nil
+}
+
+// Write appends ev as one line
+func (s *FileSink) Write(ev Event) error {
+	var b strings.Builder
+	b.WriteString(ev.Time.UTC().Format(time.RFC3339))
+	b.WriteString(" " + quoteValue(ev.Type))
+	if ev.Outcome != "" {
+		b.WriteString(" " + quoteValue(ev.Outcome))
+	}
+	if ev.Actor != "" {
+		b.WriteString(" actor=" + quoteValue(ev.Actor))
+	}
+	if ev.RemoteAddr != "" {
+		b.WriteString(" remote=" + quoteValue(ev.RemoteAddr))
+	}
+	if ev.Method != "" {
+		b.WriteString(" " + quoteValue(ev.Method))
+	}
+	if ev.Path != "" {
+		b.WriteString(" " + quoteValue(ev.Path))
+	}
+	keys := make([]string, 0, len(ev.Details))
+	for key := range ev.Details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.WriteString(" " + quoteValue(key) + "=" + formatDetail(ev.Details[key]))
+	}
+	b.WriteByte('\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.f.WriteString(b.String())
+	return err
+}
+
+// Close closes the log file
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
+
+// quoteValue returns s as is when it is a plain token, Go-quoted otherwise
+func quoteValue(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '"' || r == '=' || r == '\\' || r == 0x7f || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+// formatDetail renders a detail value: strings quoted as needed, numbers and
+// booleans as is, anything else as (quoted) JSON
+func formatDetail(value any) string {
+	switch v := value.(type) {
+	case string:
+		return quoteValue(v)
+	case int, int64, int32, uint, uint64, uint32, float64, float32, bool:
+		return fmt.Sprint(v)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return quoteValue(fmt.Sprint(value))
+	}
+	return quoteValue(string(data))
+}
+
+func openAppend(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+}
@@ -0,0 +1,300 @@
+// Package bandwidth accounts response bytes per user across handlers and
+// optionally enforces transfer quotas, for multi-tenant deployments that bill
+// by traffic. One Meter is shared by the handlers that should count:
+//
+//	meter := bandwidth.NewMeter(userOf).SetDefaultQuota(bandwidth.Quota{Bytes: 10 << 30})
+//	media.SetBandwidth(meter)
+//	proxy.SetBandwidth(meter)
+//	cdn.SetBandwidth(meter)
+//	routes.HandlePathFn("/admin/bandwidth", meter.Handler())
+package bandwidth
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-xlite/wbx/comm"
+)
+
+// Action is what happens to requests of a user over quota
+type Action int
+
+const (
+	Block    Action = iota // Answer new requests with 429 until the usage is reset
+	Throttle               // Keep serving, paced to Quota.ThrottleRate
+)
+
+// Quota limits the transfer of a user until its usage is reset (e.g. at the
+// start of each billing period)
+type Quota struct {
+	Bytes        int64  // Response bytes allowed (0 = unlimited)
+	Action       Action // Block or Throttle once Bytes is used up
+	ThrottleRate int64  // Bytes per second for Throttle (0 = 64KB/s)
+}
+
+// DefaultThrottleRate paces throttled users when the quota sets no rate
+const DefaultThrottleRate = 64 << 10
+
+// Usage is the accounted transfer of one user
+type Usage struct {
+	User     string `json:"user"`
+	Bytes    int64  `json:"bytes"`
+	Requests int64  `json:"requests"`
+	Quota    int64  `json:"quota"` // 0 = unlimited
+	Exceeded bool   `json:"exceeded"`
+}
+
+// Meter accounts response bytes per user and enforces quotas
+type Meter struct {
+	// UserFunc identifies the user of a request, e.g. from the session;
+	// requests with an empty user are neither accounted nor limited
+	UserFunc func(r *http.Request) string
+
+	defaultQuota Quota
+	quotas       map[string]Quota
+	accounts     map[string]*account
+	mu           sync.RWMutex
+}
+
+type account struct {
+	bytes, requests atomic.Int64
+}
+
+// NewMeter creates a meter identifying users with userFunc
+func NewMeter(userFunc func(r *http.Request) string) *Meter {
+	return &Meter{
+		UserFunc: userFunc,
+		quotas:   make(map[string]Quota),
+		accounts: make(map[string]*account),
+	}
+}
+
+// SetDefaultQuota sets the quota of users without their own
+func (m *Meter) SetDefaultQuota(q Quota) *Meter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.defaultQuota = q
+	return m
+}
+
+// SetQuota sets the quota of user, overriding the default
+func (m *Meter) SetQuota(user string, q Quota) *Meter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quotas[user] = q
+	return m
+}
+
+// quotaFor returns the quota applying to user
+func (m *Meter) quotaFor(user string) Quota {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if q, ok := m.quotas[user]; ok {
+		return q
+	}
+	return m.defaultQuota
+}
+
+// account returns the counters of user, creating them on first use
+func (m *Meter) account(user string) *account {
+	m.mu.RLock()
+	acct, ok := m.accounts[user]
+	m.mu.RUnlock()
+	if ok {
+		return acct
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if acct, ok = m.accounts[user]; !ok {
+		acct = &account{}
+		m.accounts[user] = acct
+	}
+	return acct
+}
+
+// lookup returns the counters of user, or nil before its first request;
+// queries use it so they cannot create accounts
+func (m *Meter) lookup(user string) *account {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.accounts[user]
+}
+
+// Add reports n bytes transferred for user outside Middleware, e.g. frames
+// sent over a hijacked WebSocket connection
+func (m *Meter) Add(user string, n int64) {
+	if user == "" || n <= 0 {
+		return
+	}
+	m.account(user).bytes.Add(n)
+}
+
+// Exceeded reports whether user has used up its quota
+func (m *Meter) Exceeded(user string) bool {
+	q := m.quotaFor(user)
+	if q.Bytes <= 0 {
+		return false
+	}
+	acct := m.lookup(user)
+	return acct != nil && acct.bytes.Load() >= q.Bytes
+}
+
+// Usage returns the accounted transfer of user
+func (m *Meter) Usage(user string) Usage {
+	q := m.quotaFor(user)
+	u := Usage{User: user, Quota: q.Bytes}
+	if acct := m.lookup(user); acct != nil {
+		u.Bytes, u.Requests = acct.bytes.Load(), acct.requests.Load()
+	}
+	u.Exceeded = u.Quota > 0 && u.Bytes >= u.Quota
+	return u
+}
+
+// Snapshot returns the usage of all users, highest transfer first
+func (m *Meter) Snapshot() []Usage {
+	m.mu.RLock()
+	users := make([]string, 0, len(m.accounts))
+	for user := range m.accounts {
+		users = append(users, user)
+	}
+	m.mu.RUnlock()
+
+	list := make([]Usage, 0, len(users))
+	for _, user := range users {
+		list = append(list, m.Usage(user))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Bytes != list[j].Bytes {
+			return list[i].Bytes > list[j].Bytes
+		}
+		return list[i].User < list[j].User
+	})
+	return list
+}
+
+// Reset clears the usage of user, e.g. when a new billing period starts. The
+// counters are zeroed rather than dropped, so responses still being written
+// count toward the new period.
+func (m *Meter) Reset(user string) {
+	if acct := m.lookup(user); acct != nil {
+		acct.reset()
+	}
+}
+
+// ResetAll clears the usage of all users
+func (m *Meter) ResetAll() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, acct := range m.accounts {
+		acct.reset()
+	}
+}
+
+func (a *account) reset() {
+	a.bytes.Store(0)
+	a.requests.Store(0)
+}
+
+// Handler serves the usage of all users as JSON (?top=10 limits the list,
+// ?user=name returns a single user)
+func (m *Meter) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if user := r.URL.Query().Get("user"); user != "" {
+			json.NewEncoder(w).Encode(m.Usage(user))
+			return
+		}
+		list := m.Snapshot()
+		if n, err := strconv.Atoi(r.URL.Query().Get("top")); err == nil && n > 0 && n < len(list) {
+			list = list[:n]
+		}
+		json.NewEncoder(w).Encode(list)
+	}
+}
+
+// Middleware accounts the response bytes of next to the request's user. Users
+// over a Block quota get 429; the check happens when a request starts, so a
+// response already underway is completed. Bytes written after a hijack are
+// not seen; report them with Add.
+func (m *Meter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := ""
+		if m.UserFunc != nil {
+			user = m.UserFunc(r)
+		}
+		if user == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		acct := m.account(user)
+		acct.requests.Add(1)
+		q := m.quotaFor(user)
+		if q.Bytes > 0 && q.Action == Block && acct.bytes.Load() >= q.Bytes {
+			http.Error(w, "Bandwidth quota exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		mw := &meteredWriter{ResponseWriter: w, acct: acct, quota: q, ctx: r.Context()}
+		next.ServeHTTP(comm.WrapResponseWriter(w, mw), r)
+	})
+}
+
+// meteredWriter counts response bytes and paces throttled users
+type meteredWriter struct {
+	http.ResponseWriter
+	acct  *account
+	quota Quota
+	ctx   context.Context
+}
+
+func (mw *meteredWriter) Write(b []byte) (int, error) {
+	n, err := mw.ResponseWriter.Write(b)
+	mw.account(int64(n))
+	return n, err
+}
+
+// ReadFrom implements io.ReaderFrom, keeping sendfile while not throttled
+func (mw *meteredWriter) ReadFrom(src io.Reader) (int64, error) {
+	if rf, ok := mw.ResponseWriter.(io.ReaderFrom); ok && !mw.throttled() {
+		n, err := rf.ReadFrom(src)
+		mw.account(n)
+		return n, err
+	}
+	return io.Copy(struct{ io.Writer }{mw}, src)
+}
+
+// throttled reports whether writes are paced
+func (mw *meteredWriter) throttled() bool {
+	q := mw.quota
+	return q.Bytes > 0 && q.Action == Throttle && mw.acct.bytes.Load() >= q.Bytes
+}
+
+// account adds n bytes and, once over a Throttle quota, waits as long as
+// sending them at the throttle rate takes
+func (mw *meteredWriter) account(n int64) {
+	if n <= 0 {
+		return
+	}
+	mw.acct.bytes.Add(n)
+	if !mw.throttled() {
+		return
+	}
+	rate := mw.quota.ThrottleRate
+	if rate <= 0 {
+		rate = DefaultThrottleRate
+	}
+	timer := time.NewTimer(time.Duration(n) * time.Second / time.Duration(rate))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-mw.ctx.Done():
+	}
+}
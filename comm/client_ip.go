@@ -0,0 +1,222 @@
+package comm
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+)
+
+// CloudflareCIDRs are Cloudflare's published edge ranges (https://www.cloudflare.com/ips/)
+var CloudflareCIDRs = []string{
+	"173.245.48.0/20", "103.21.244.0/22", "103.22.200.0/22", "103.31.4.0/22",
+	"141.101.64.0/18", "108.162.192.0/18", "190.93.240.0/20", "188.114.96.0/20",
+	"197.234.240.0/22", "198.41.128.0/17", "162.158.0.0/15", "104.16.0.0/13",
+	"104.24.0.0/14", "172.64.0.0/13", "131.0.72.0/22",
+	"2400:cb00::/32", "2606:4700::/32", "2803:f800::/32", "2405:b500::/32",
+	"2405:8100::/32", "2a06:98c0::/29", "2c0f:f248::/32",
+}
+
+// PrivateCIDRs cover loopback and private networks, for proxies on the same host or LAN
+var PrivateCIDRs = []string{
+	"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "::1/128", "fc00::/7",
+}
+
+// DefaultClientIPHeaders are consulted in order when the peer is a trusted
+// proxy. CF-Connecting-IP is only honoured when the peer is also a Cloudflare
+// edge (see CloudflareTrust), as other proxies pass it through unchanged.
+var DefaultClientIPHeaders = []string{"CF-Connecting-IP", "X-Forwarded-For", "X-Real-IP"}
+
+// cloudflareHeader carries the client IP set by Cloudflare's edge
+const cloudflareHeader = "CF-Connecting-IP"
+
+var (
+	cloudflareTrust     *TrustedProxies
+	cloudflareTrustOnce sync.Once
+)
+
+// CloudflareTrust returns the shared Cloudflare edge range set. It decides
+// where CF-Connecting-IP is honoured from and is kept current by replacing
+// its ranges (weblite.RefreshCloudflareRanges).
+func CloudflareTrust() *TrustedProxies {
+	cloudflareTrustOnce.Do(func() {
+		cloudflareTrust, _ = NewTrustedProxies(CloudflareCIDRs...)
+		cloudflareTrust.Headers = []string{cloudflareHeader}
+	})
+	return cloudflareTrust
+}
+
+// TrustedProxies resolves the real client IP of requests arriving through
+// proxies/CDNs. Forwarding headers are only honoured when the direct peer
+// lies within a trusted range, so clients cannot spoof their address.
+type TrustedProxies struct {
+	Headers  []string // Headers carrying the client IP, in priority order
+	prefixes []netip.Prefix
+	mu       sync.RWMutex
+}
+
+// NewTrustedProxies creates a resolver trusting the given CIDRs (or bare IPs)
+func NewTrustedProxies(cidrs ...string) (*TrustedProxies, error) {
+	tp := &TrustedProxies{Headers: DefaultClientIPHeaders}
+	if err := tp.Add(cidrs...); err != nil {
+		return nil, err
+	}
+	return tp, nil
+}
+
+// Add trusts additional CIDRs (or bare IPs)
+func (tp *TrustedProxies) Add(cidrs ...string) error {
+	parsed := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := ParsePrefix(cidr)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, prefix)
+	}
+	tp.mu.Lock()
+	tp.prefixes = append(tp.prefixes, parsed...)
+	tp.mu.Unlock()
+	return nil
+}
+
+// Replace swaps the trusted ranges for cidrs (used when refreshing published ranges)
+func (tp *TrustedProxies) Replace(cidrs ...string) error {
+	parsed := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := ParsePrefix(cidr)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, prefix)
+	}
+	tp.mu.Lock()
+	tp.prefixes = parsed
+	tp.mu.Unlock()
+	return nil
+}
+
+// Trusts reports whether ip (string form) lies in a trusted range
+func (tp *TrustedProxies) Trusts(ip string) bool {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+	for _, prefix := range tp.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the client address of r. X-Forwarded-For is walked from the
+// right, skipping trusted hops, so appended entries cannot be forged.
+func (tp *TrustedProxies) ClientIP(r *http.Request) string {
+	peer := RemoteHost(r.RemoteAddr)
+	if !tp.Trusts(peer) {
+		return peer
+	}
+	for _, header := range tp.Headers {
+		value := r.Header.Get(header)
+		if value == "" {
+			continue
+		}
+		if strings.EqualFold(header, cloudflareHeader) && !CloudflareTrust().Trusts(peer) {
+			// Only Cloudflare sets it; from anyone else it is client-supplied
+			continue
+		}
+		if !strings.EqualFold(header, "X-Forwarded-For") {
+			if ip := strings.TrimSpace(value); validIP(ip) {
+				return ip
+			}
+			continue
+		}
+		hops := strings.Split(value, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(hops[i])
+			if !validIP(ip) {
+				break
+			}
+			if i == 0 || !tp.Trusts(ip) {
+				return ip
+			}
+		}
+	}
+	return peer
+}
+
+var (
+	trustedProxies   *TrustedProxies
+	trustedProxiesMu sync.RWMutex
+)
+
+// SetTrustedProxies installs the resolver used by ClientIP (nil trusts nobody)
+func SetTrustedProxies(tp *TrustedProxies) {
+	trustedProxiesMu.Lock()
+	trustedProxies = tp
+	trustedProxiesMu.Unlock()
+}
+
+// GetTrustedProxies returns the global resolver, or nil
+func GetTrustedProxies() *TrustedProxies {
+	trustedProxiesMu.RLock()
+	defer trustedProxiesMu.RUnlock()
+	return trustedProxies
+}
+
+// TrustCloudflare installs a global resolver trusting Cloudflare's edge ranges
+// plus any extra CIDRs
+func TrustCloudflare(extra ...string) *TrustedProxies {
+	tp, err := NewTrustedProxies(append(append([]string{}, CloudflareCIDRs...), extra...)...)
+	if err != nil {
+		// Presets always parse; extra ranges are the caller's responsibility
+		panic(err)
+	}
+	SetTrustedProxies(tp)
+	return tp
+}
+
+// ClientIP returns the client IP of r using the global trusted-proxy
+// configuration, falling back to the peer address of the connection
+func ClientIP(r *http.Request) string {
+	if tp := GetTrustedProxies(); tp != nil {
+		return tp.ClientIP(r)
+	}
+	return RemoteHost(r.RemoteAddr)
+}
+
+// RemoteHost strips the port from a host:port address
+func RemoteHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// ParsePrefix parses a CIDR range or a bare IP (as a single-address range)
+func ParsePrefix(cidr string) (netip.Prefix, error) {
+	cidr = strings.TrimSpace(cidr)
+	if !strings.Contains(cidr, "/") {
+		addr, err := netip.ParseAddr(cidr)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return prefix.Masked(), nil
+}
+
+func validIP(ip string) bool {
+	_, err := netip.ParseAddr(ip)
+	return err == nil
+}
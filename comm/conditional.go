@@ -0,0 +1,67 @@
+package comm
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// StatETag returns the ETag to use for a file described by info: the adapter's
+// hint when present, otherwise one derived from size and modification time.
+// Returns "" when neither is known (e.g. embedded files without a hint).
+func StatETag(info FileInfo) string {
+	if info.ETag != "" {
+		return info.ETag
+	}
+	if info.ModTime.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime.UnixNano(), info.Size)
+}
+
+// ServeNotModified answers a GET/HEAD request with 304 Not Modified when its
+// If-None-Match or If-Modified-Since header matches etag/modTime, so handlers can
+// Stat a file and skip reading it. Returns true when the response was written.
+func ServeNotModified(w http.ResponseWriter, r *http.Request, etag string, modTime time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	notModified := false
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		// If-None-Match takes precedence over If-Modified-Since (RFC 9110 13.2.2)
+		notModified = etag != "" && etagListMatches(inm, etag)
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modTime.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil {
+			notModified = !modTime.Truncate(time.Second).After(t)
+		}
+	}
+	if !notModified {
+		return false
+	}
+
+	header := w.Header()
+	if etag != "" {
+		header.Set("ETag", etag)
+	}
+	if !modTime.IsZero() {
+		header.Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagListMatches reports whether an If-None-Match list contains etag (weak comparison)
+func etagListMatches(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package comm
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DebugAll enables or disables debug output for every module at once
+const DebugAll = "*"
+
+// DefaultDebugRate is the number of debug lines a module may emit per second
+const DefaultDebugRate = 20
+
+// Logger receives debug output, *log.Logger satisfies it
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+// LoggerFunc adapts a function to Logger
+type LoggerFunc func(format string, args ...any)
+
+// Printf calls f
+func (f LoggerFunc) Printf(format string, args ...any) {
+	f(format, args...)
+}
+
+type debugModule struct {
+	window  time.Time
+	lines   int
+	dropped int
+}
+
+var debugState = struct {
+	mu      sync.Mutex
+	enabled map[string]bool
+	modules map[string]*debugModule
+	logger  Logger
+	rate    int
+}{
+	enabled: map[string]bool{},
+	modules: map[string]*debugModule{},
+	logger: LoggerFunc(func(format string, args ...any) {
+		fmt.Fprintf(os.Stdout, format+"\n", args...)
+	}),
+	rate: DefaultDebugRate,
+}
+
+// debugOn is set while any module has debugging enabled, so disabled Debugf
+// calls return without taking debugState.mu
+var debugOn atomic.Bool
+
+// SetDebug toggles debug output for module ("websock", "webstream", ...).
+// DebugAll applies to modules without an explicit setting.
+func SetDebug(module string, on bool) {
+	debugState.mu.Lock()
+	defer debugState.mu.Unlock()
+	debugState.enabled[module] = on
+	anyOn := false
+	for _, enabled := range debugState.enabled {
+		anyOn = anyOn || enabled
+	}
+	debugOn.Store(anyOn)
+}
+
+// SetDebugLogger replaces the logger debug lines are written to, nil restores stdout
+func SetDebugLogger(logger Logger) {
+	debugState.mu.Lock()
+	defer debugState.mu.Unlock()
+	if logger == nil {
+		logger = LoggerFunc(func(format string, args ...any) {
+			fmt.Fprintf(os.Stdout, format+"\n", args...)
+		})
+	}
+	debugState.logger = logger
+}
+
+// SetDebugRate limits each module to perSecond lines per second, 0 disables the limit
+func SetDebugRate(perSecond int) {
+	debugState.mu.Lock()
+	defer debugState.mu.Unlock()
+	debugState.rate = perSecond
+}
+
+// DebugEnabled reports whether debug output is on for module
+func DebugEnabled(module string) bool {
+	if !debugOn.Load() {
+		return false
+	}
+	debugState.mu.Lock()
+	defer debugState.mu.Unlock()
+	return debugEnabled(module)
+}
+
+func debugEnabled(module string) bool {
+	if on, ok := debugState.enabled[module]; ok {
+		return on
+	}
+	return debugState.enabled[DebugAll]
+}
+
+// Debugf writes a line prefixed with [module] when debugging is enabled for it.
+// Lines over the per-second rate are dropped and counted in the next window.
+func Debugf(module, format string, args ...any) {
+	if !debugOn.Load() {
+		return
+	}
+	debugState.mu.Lock()
+	if !debugEnabled(module) {
+		debugState.mu.Unlock()
+		return
+	}
+	m := debugState.modules[module]
+	if m == nil {
+		m = &debugModule{}
+		debugState.modules[module] = m
+	}
+	now := time.Now()
+	dropped := 0
+	if now.Sub(m.window) >= time.Second {
+		dropped = m.dropped
+		m.window, m.lines, m.dropped = now, 0, 0
+	}
+	if debugState.rate > 0 && m.lines >= debugState.rate {
+		m.dropped++
+		debugState.mu.Unlock()
+		return
+	}
+	m.lines++
+	logger := debugState.logger
+	debugState.mu.Unlock()
+
+	if dropped > 0 {
+		logger.Printf("[%s] %d debug lines suppressed", module, dropped)
+	}
+	logger.Printf("[%s] "+format, append([]any{module}, args...)...)
+}
@@ -0,0 +1,27 @@
+package comm
+
+import (
+	"math/rand"
+	"time"
+)
+
+// DrainReason is the close reason sent to streaming connections ended by a
+// drain before shutdown (WebCast.Drain, WebSock.Drain)
+const DrainReason = "draining"
+
+// Reconnect hint given to drained clients: DrainRetryBase plus up to
+// DrainRetryJitter so clients closed at the same moment do not return together.
+// Shared by WebCast and WebSock so both transports spread reconnects alike.
+var (
+	DrainRetryBase   = time.Second
+	DrainRetryJitter = 2 * time.Second
+)
+
+// DrainRetryHint returns a jittered reconnect delay for a drained client
+func DrainRetryHint() time.Duration {
+	hint := DrainRetryBase
+	if DrainRetryJitter > 0 {
+		hint += time.Duration(rand.Int63n(int64(DrainRetryJitter)))
+	}
+	return hint
+}
@@ -0,0 +1,104 @@
+package comm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+)
+
+// ErrorReporter receives server-side failures (panics, proxy errors, 5xx
+// responses) so deployments can forward them to Sentry-style services.
+// stack is nil when the error did not originate from a panic.
+type ErrorReporter interface {
+	Report(ctx context.Context, err error, stack []byte, req *http.Request)
+}
+
+// ErrorReporterFunc adapts a function to ErrorReporter
+type ErrorReporterFunc func(ctx context.Context, err error, stack []byte, req *http.Request)
+
+// Report calls f
+func (f ErrorReporterFunc) Report(ctx context.Context, err error, stack []byte, req *http.Request) {
+	f(ctx, err, stack, req)
+}
+
+// PanicError wraps a value recovered from a panic
+type PanicError struct {
+	Value any
+}
+
+func (pe *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", pe.Value)
+}
+
+// Unwrap returns the panic value if it is an error
+func (pe *PanicError) Unwrap() error {
+	if err, ok := pe.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// StatusError reports a handler that answered with a 5xx status
+type StatusError struct {
+	Status int
+}
+
+func (se *StatusError) Error() string {
+	return fmt.Sprintf("server responded %d %s", se.Status, http.StatusText(se.Status))
+}
+
+var (
+	errorReporter   ErrorReporter
+	errorReporterMu sync.RWMutex
+)
+
+// SetErrorReporter installs the global error reporter (nil disables reporting)
+func SetErrorReporter(reporter ErrorReporter) {
+	errorReporterMu.Lock()
+	errorReporter = reporter
+	errorReporterMu.Unlock()
+}
+
+// GetErrorReporter returns the global error reporter, or nil
+func GetErrorReporter() ErrorReporter {
+	errorReporterMu.RLock()
+	defer errorReporterMu.RUnlock()
+	return errorReporter
+}
+
+// ReportError sends err to the global reporter, if any. A panicking reporter
+// is contained so reporting can never take down a request.
+func ReportError(ctx context.Context, err error, stack []byte, req *http.Request) {
+	reporter := GetErrorReporter()
+	if reporter == nil || err == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if reported, ok := ctx.Value(reportedKey{}).(*bool); ok {
+		*reported = true
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			fmt.Printf("ErrorReporter panicked: %v\n", rec)
+		}
+	}()
+	reporter.Report(ctx, err, stack, req)
+}
+
+type reportedKey struct{}
+
+// WithReportTracking returns a context whose *bool is set once ReportError is
+// called with it, so outer layers can avoid reporting the same failure twice
+func WithReportTracking(ctx context.Context) (context.Context, *bool) {
+	reported := new(bool)
+	return context.WithValue(ctx, reportedKey{}, reported), reported
+}
+
+// ReportPanic reports a recovered panic value together with the current stack
+func ReportPanic(ctx context.Context, recovered any, req *http.Request) {
+	ReportError(ctx, &PanicError{Value: recovered}, debug.Stack(), req)
+}
@@ -0,0 +1,145 @@
+// Package events is a small in-process publish/subscribe bus. WebLite owns one
+// (WebLite.Events) and subsystems publish cross-cutting events to it, so an
+// application can hook them in one place instead of wiring callbacks per module.
+package events
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-xlite/wbx/comm"
+)
+
+// Topics published by wbx subsystems
+const (
+	SessionRevoked     = "session.revoked"        // Data: SessionRevokedData
+	ConfigReloaded     = "config.reloaded"        // Data: ConfigReloadedData
+	TargetUnhealthy    = "proxy.target.unhealthy" // Data: TargetData
+	TargetHealthy      = "proxy.target.healthy"   // Data: TargetData, after a TargetUnhealthy
+	ClientConnected    = "client.connected"       // Data: ClientData
+	ClientDisconnected = "client.disconnected"    // Data: ClientData
+)
+
+// Event is one published event
+type Event struct {
+	Topic  string
+	Source string // Publishing subsystem, e.g. "websock" or "weblite/demo"
+	Time   time.Time
+	Data   any
+}
+
+// SessionRevokedData is the payload of SessionRevoked
+type SessionRevokedData struct {
+	SessionKey string // comm.SessionKeyFor of the token, never the token itself
+	Closed     int    // Live WS/SSE connections closed
+}
+
+// ConfigReloadedData is the payload of ConfigReloaded
+type ConfigReloadedData struct {
+	Action string // "listener.added", "listener.removed", "listener.replaced" or "restart"
+	Err    error  // Set when the change partially failed
+}
+
+// TargetData is the payload of TargetUnhealthy and TargetHealthy
+type TargetData struct {
+	Target string // Upstream host
+	Err    error  // Failure that marked the target unhealthy
+}
+
+// ClientData is the payload of ClientConnected and ClientDisconnected
+type ClientData struct {
+	Transport  string // "ws" or "sse"
+	ClientID   string
+	SessionKey string // Auth session of the connection, if any
+}
+
+// Publisher is the side of the bus subsystems depend on
+type Publisher interface {
+	Publish(topic, source string, data any)
+}
+
+type subscription struct {
+	id      uint64
+	pattern string
+	handler func(Event)
+}
+
+// Bus delivers published events to the subscribers of their topic
+type Bus struct {
+	subs   []*subscription
+	nextID uint64
+	mu     sync.RWMutex
+}
+
+// NewBus creates an empty bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe calls handler for events whose topic matches pattern: an exact
+// topic, a prefix ending in ".*" ("client.*") or "*" for all events.
+// Handlers run synchronously on the publishing goroutine, so they must not
+// block; start a goroutine for slow work. The returned func unsubscribes.
+func (b *Bus) Subscribe(pattern string, handler func(Event)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	id := b.nextID
+	b.subs = append(b.subs, &subscription{id: id, pattern: pattern, handler: handler})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, sub := range b.subs {
+			if sub.id == id {
+				b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers an event to every matching subscriber. A panicking
+// subscriber is reported and does not affect the others or the publisher.
+func (b *Bus) Publish(topic, source string, data any) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+
+	event := Event{Topic: topic, Source: source, Time: time.Now(), Data: data}
+	for _, sub := range subs {
+		if matches(sub.pattern, topic) {
+			deliver(sub, event)
+		}
+	}
+}
+
+// deliver calls the subscriber, recovering panics
+func deliver(sub *subscription, event Event) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err := fmt.Errorf("event subscriber %q panicked on %s: %v", sub.pattern, event.Topic, rec)
+			fmt.Println(err)
+			comm.ReportError(context.Background(), err, debug.Stack(), nil)
+		}
+	}()
+	sub.handler(event)
+}
+
+// matches reports whether topic is covered by pattern
+func matches(pattern, topic string) bool {
+	if pattern == "*" || pattern == topic {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasSuffix(prefix, ".") {
+		return strings.HasPrefix(topic, prefix)
+	}
+	return false
+}
@@ -13,6 +13,9 @@ type FileInfo struct {
 	Mode    fs.FileMode `json:"mode"`
 	ModTime time.Time   `json:"mod_time"`
 	IsDir   bool        `json:"is_dir"`
+	// ETag is an optional validator hint (content hash, inode/mtime, ...) set by
+	// adapters that can provide one cheaply; see StatETag
+	ETag string `json:"etag,omitempty"`
 }
 
 // IFsAdapter defines the interface for filesystem operations
@@ -59,3 +62,31 @@ type IFsAdapter interface {
 	// Close cleans up any resources used by the provider
 	Close() error
 }
+
+// IFsStreamWriter is implemented by writable adapters that can store a file
+// without holding it in memory (e.g. uploads). The file becomes visible under
+// path when the writer is closed successfully.
+type IFsStreamWriter interface {
+	// Create opens path for writing, creating parent directories
+	Create(path string, perm fs.FileMode) (IFsFileWriter, error)
+
+	// CreateNew is Create failing with fs.ErrExist when path already exists.
+	// The name is reserved until the writer is closed or aborted, so
+	// concurrent callers cannot both claim it.
+	CreateNew(path string, perm fs.FileMode) (IFsFileWriter, error)
+
+	// Remove deletes a stored file
+	Remove(path string) error
+}
+
+// IFsFileWriter is a file being written through IFsStreamWriter
+type IFsFileWriter interface {
+	io.Writer
+
+	// Close publishes the written data under the path
+	Close() error
+
+	// Abort discards the written data; a file previously stored under the
+	// path is left untouched
+	Abort() error
+}
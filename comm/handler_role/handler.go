@@ -3,9 +3,11 @@ package handler_role
 import (
 	"fmt"
 	"net/http"
+	"path/filepath"
 	"strings"
 
 	"github.com/go-xlite/wbx/comm"
+	"github.com/go-xlite/wbx/comm/bandwidth"
 	"github.com/go-xlite/wbx/comm/routes"
 	"github.com/gorilla/mux"
 )
@@ -97,13 +99,60 @@ func (pp *PathPrefix) PatchHTML(htmlContent string) string {
 }
 
 type HandlerRole struct {
-	Handler     IHandler
-	CustomMimes map[string]string
-	PathPrefix  *PathPrefix
-	CORS        CORS
-	OnStart     func() error
-	OnStop      func() error
-	OnRequest   func(w http.ResponseWriter, r *http.Request) bool
+	Handler        IHandler
+	CustomMimes    map[string]string
+	DefaultCharset string // Charset applied to textual Content-Types (e.g. "windows-1252"); empty keeps the detected one
+	PathPrefix     *PathPrefix
+	CORS           CORS
+	OnStart        func() error
+	OnStop         func() error
+	OnRequest      func(w http.ResponseWriter, r *http.Request) bool
+	OnContentType  func(path string, detected string) string // Final say on the Content-Type of served files
+	OnPanic        comm.PanicHandler                         // Answers requests whose handler panicked (plain 500 when nil)
+	Bandwidth      *bandwidth.Meter                          // Accounts response bytes per user, see SetBandwidth
+}
+
+// SetPanicHandler sets the callback answering requests that panicked inside this handler
+func (sr *HandlerRole) SetPanicHandler(fn comm.PanicHandler) *HandlerRole {
+	sr.OnPanic = fn
+	return sr
+}
+
+// SetBandwidth reports the response bytes of this handler into meter, which
+// is typically shared by several handlers; it also enforces the meter's quotas
+func (sr *HandlerRole) SetBandwidth(meter *bandwidth.Meter) *HandlerRole {
+	sr.Bandwidth = meter
+	return sr
+}
+
+// Isolate wraps a route handler so a panic in it is recovered, reported and
+// answered through OnPanic instead of tearing down the request without a response.
+// Responses are accounted to the Bandwidth meter when one is set.
+func (sr *HandlerRole) Isolate(fn func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	name := ""
+	if sr.PathPrefix != nil {
+		name = sr.PathPrefix.Get()
+	}
+	if name == "" {
+		name = "/"
+	}
+	handler := http.HandlerFunc(fn)
+	metered := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Read per request so SetBandwidth works after Run
+		if meter := sr.Bandwidth; meter != nil {
+			meter.Middleware(handler).ServeHTTP(w, r)
+			return
+		}
+		handler(w, r)
+	})
+	return comm.RecoverPanics(name, metered, func(w http.ResponseWriter, r *http.Request, err error) {
+		// Read at panic time so SetPanicHandler works after Run
+		if sr.OnPanic != nil {
+			sr.OnPanic(w, r, err)
+			return
+		}
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}).ServeHTTP
 }
 
 func (sr *HandlerRole) Start() error {
@@ -199,6 +248,62 @@ func (sr *HandlerRole) GetMimeType(ext string) string {
 	return comm.Mime.GetType(ext)
 }
 
+// SetDefaultCharset sets the charset applied to textual Content-Types
+func (sr *HandlerRole) SetDefaultCharset(charset string) *HandlerRole {
+	sr.DefaultCharset = charset
+	return sr
+}
+
+// SetContentTypeOverride sets a callback that can replace the detected Content-Type of a served path
+// Return the detected value unchanged to keep it ("" leaves detection to the server)
+func (sr *HandlerRole) SetContentTypeOverride(fn func(path string, detected string) string) *HandlerRole {
+	sr.OnContentType = fn
+	return sr
+}
+
+// ResolveContentType returns the Content-Type for a served file path
+// Order: custom/standard MIME by extension, DefaultCharset for textual types, then OnContentType.
+// An unknown extension resolves to "" so the server sniffs the content instead.
+func (sr *HandlerRole) ResolveContentType(path string) string {
+	ext := filepath.Ext(path)
+	contentType := sr.GetMimeType(ext)
+	if _, custom := sr.CustomMimes[strings.ToLower(ext)]; !custom && contentType == "application/octet-stream" {
+		contentType = ""
+	}
+	if sr.DefaultCharset != "" && isTextualType(contentType) {
+		contentType = withCharset(contentType, sr.DefaultCharset)
+	}
+	if sr.OnContentType != nil {
+		contentType = sr.OnContentType(path, contentType)
+	}
+	return contentType
+}
+
+// isTextualType reports whether a Content-Type carries text and should declare a charset
+func isTextualType(contentType string) bool {
+	mimeType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	return strings.HasPrefix(mimeType, "text/") ||
+		strings.HasSuffix(mimeType, "+xml") ||
+		strings.HasSuffix(mimeType, "+json") ||
+		mimeType == "application/javascript" ||
+		mimeType == "application/json" ||
+		mimeType == "application/xml"
+}
+
+// withCharset replaces (or adds) the charset parameter of a Content-Type
+func withCharset(contentType, charset string) string {
+	parts := strings.Split(contentType, ";")
+	result := strings.TrimSpace(parts[0])
+	for _, param := range parts[1:] {
+		param = strings.TrimSpace(param)
+		if param == "" || strings.HasPrefix(strings.ToLower(param), "charset=") {
+			continue
+		}
+		result += "; " + param
+	}
+	return result + "; charset=" + charset
+}
+
 func (hr *HandlerRole) SetPathPrefix(prefix string) {
 	hr.PathPrefix.Set(prefix)
 }
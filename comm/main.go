@@ -2,6 +2,7 @@ package comm
 
 import (
 	mime "github.com/go-xlite/wbx/comm/mime"
+	respwriter "github.com/go-xlite/wbx/comm/resp_writer"
 	servercore "github.com/go-xlite/wbx/comm/server_core"
 )
 
@@ -9,6 +10,14 @@ type ServerCore = servercore.ServerCore
 
 var NewServerCore = servercore.NewServerCore
 
+// CaptureWriter and WrapResponseWriter live in comm/resp_writer so comm/routes can use them too
+type CaptureWriter = respwriter.CaptureWriter
+
+var (
+	NewCaptureWriter   = respwriter.NewCaptureWriter
+	WrapResponseWriter = respwriter.WrapResponseWriter
+)
+
 type mim struct {
 	GetType           func(ext string) string
 	IsStaticExtension func(ext string) bool
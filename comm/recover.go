@@ -0,0 +1,39 @@
+package comm
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// PanicHandler answers a request whose handler panicked; err is a *PanicError
+type PanicHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// RecoverPanics isolates next from the rest of the server: a panic is logged
+// with name, sent to the global ErrorReporter and answered by onPanic (plain
+// 500 when nil) if no response has started yet. A panic after the response
+// started aborts only this connection.
+func RecoverPanics(name string, next http.Handler, onPanic PanicHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cw := NewCaptureWriter(w)
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			fmt.Printf("Recover [%s] panic serving %s: %v\n", name, r.URL.Path, rec)
+			ReportPanic(r.Context(), rec, r)
+			if cw.WroteHeader() || cw.Hijacked() {
+				panic(http.ErrAbortHandler)
+			}
+			if onPanic != nil {
+				onPanic(cw, r, &PanicError{Value: rec})
+				return
+			}
+			http.Error(cw, "Internal Server Error", http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(cw, r)
+	})
+}
@@ -0,0 +1,112 @@
+// Package respwriter provides the ResponseWriter wrappers of comm, kept in
+// their own package so comm/routes can use them without importing comm.
+package respwriter
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// CaptureWriter wraps an http.ResponseWriter and records the status code and
+// number of body bytes written. Flush, Hijack and Push are passed through to the
+// underlying writer, and Unwrap exposes it to http.ResponseController.
+type CaptureWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+	hijacked    bool
+}
+
+// NewCaptureWriter wraps w, returning w itself if it is already a CaptureWriter
+func NewCaptureWriter(w http.ResponseWriter) *CaptureWriter {
+	if cw, ok := w.(*CaptureWriter); ok {
+		return cw
+	}
+	return &CaptureWriter{ResponseWriter: w}
+}
+
+// WriteHeader records and forwards the status code (only the first call counts)
+func (cw *CaptureWriter) WriteHeader(statusCode int) {
+	if !cw.wroteHeader {
+		cw.status = statusCode
+		// 1xx informational headers may precede the final status
+		cw.wroteHeader = statusCode >= 200 || statusCode == http.StatusSwitchingProtocols
+	}
+	cw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the body size and forwards the data
+func (cw *CaptureWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.status = http.StatusOK
+		cw.wroteHeader = true
+	}
+	n, err := cw.ResponseWriter.Write(b)
+	cw.bytes += int64(n)
+	return n, err
+}
+
+// Status returns the response status code (200 if the handler wrote a body without
+// calling WriteHeader, 0 if nothing has been written yet)
+func (cw *CaptureWriter) Status() int {
+	return cw.status
+}
+
+// BytesWritten returns the number of body bytes written through this writer
+func (cw *CaptureWriter) BytesWritten() int64 {
+	return cw.bytes
+}
+
+// WroteHeader reports whether a final status code has been sent
+func (cw *CaptureWriter) WroteHeader() bool {
+	return cw.wroteHeader
+}
+
+// Hijacked reports whether the connection was taken over by the handler
+func (cw *CaptureWriter) Hijacked() bool {
+	return cw.hijacked
+}
+
+// Flush implements http.Flusher
+func (cw *CaptureWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.status = http.StatusOK
+		cw.wroteHeader = true
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker
+func (cw *CaptureWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("underlying ResponseWriter does not support Hijack")
+	}
+	conn, rw, err := hj.Hijack()
+	if err == nil {
+		cw.hijacked = true
+		if !cw.wroteHeader {
+			cw.status = http.StatusSwitchingProtocols
+			cw.wroteHeader = true
+		}
+	}
+	return conn, rw, err
+}
+
+// Push implements http.Pusher
+func (cw *CaptureWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := cw.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying ResponseWriter (used by http.ResponseController)
+func (cw *CaptureWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
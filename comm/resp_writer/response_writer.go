@@ -0,0 +1,187 @@
+package respwriter
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// WrapResponseWriter returns wrapper exposing exactly the optional interfaces
+// of w: http.Flusher, http.Hijacker, io.ReaderFrom and http.Pusher. Middleware
+// that replaces the ResponseWriter passes its own writer as wrapper, so a
+// handler behind it can still flush an SSE stream or hijack a WebSocket when
+// the server supports it, and sees the interface missing when it does not.
+//
+// Each call goes to wrapper when it implements the method and to w otherwise;
+// ReadFrom without a wrapper implementation copies through wrapper.Write.
+// Unwrap returns w for http.ResponseController.
+func WrapResponseWriter(w, wrapper http.ResponseWriter) http.ResponseWriter {
+	base := &wrappedWriter{ResponseWriter: wrapper, under: w}
+
+	const (
+		flush = 1 << iota
+		hijack
+		readFrom
+		push
+	)
+	var mask int
+	if _, ok := w.(http.Flusher); ok {
+		mask |= flush
+	}
+	if _, ok := w.(http.Hijacker); ok {
+		mask |= hijack
+	}
+	if _, ok := w.(io.ReaderFrom); ok {
+		mask |= readFrom
+	}
+	if _, ok := w.(http.Pusher); ok {
+		mask |= push
+	}
+
+	f, h, rf, p := flusher{base}, hijacker{base}, readerFrom{base}, pusher{base}
+	switch mask {
+	case flush:
+		return struct {
+			*wrappedWriter
+			http.Flusher
+		}{base, f}
+	case hijack:
+		return struct {
+			*wrappedWriter
+			http.Hijacker
+		}{base, h}
+	case flush | hijack:
+		return struct {
+			*wrappedWriter
+			http.Flusher
+			http.Hijacker
+		}{base, f, h}
+	case readFrom:
+		return struct {
+			*wrappedWriter
+			io.ReaderFrom
+		}{base, rf}
+	case flush | readFrom:
+		return struct {
+			*wrappedWriter
+			http.Flusher
+			io.ReaderFrom
+		}{base, f, rf}
+	case hijack | readFrom:
+		return struct {
+			*wrappedWriter
+			http.Hijacker
+			io.ReaderFrom
+		}{base, h, rf}
+	case flush | hijack | readFrom:
+		return struct {
+			*wrappedWriter
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{base, f, h, rf}
+	case push:
+		return struct {
+			*wrappedWriter
+			http.Pusher
+		}{base, p}
+	case flush | push:
+		return struct {
+			*wrappedWriter
+			http.Flusher
+			http.Pusher
+		}{base, f, p}
+	case hijack | push:
+		return struct {
+			*wrappedWriter
+			http.Hijacker
+			http.Pusher
+		}{base, h, p}
+	case flush | hijack | push:
+		return struct {
+			*wrappedWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{base, f, h, p}
+	case readFrom | push:
+		return struct {
+			*wrappedWriter
+			io.ReaderFrom
+			http.Pusher
+		}{base, rf, p}
+	case flush | readFrom | push:
+		return struct {
+			*wrappedWriter
+			http.Flusher
+			io.ReaderFrom
+			http.Pusher
+		}{base, f, rf, p}
+	case hijack | readFrom | push:
+		return struct {
+			*wrappedWriter
+			http.Hijacker
+			io.ReaderFrom
+			http.Pusher
+		}{base, h, rf, p}
+	case flush | hijack | readFrom | push:
+		return struct {
+			*wrappedWriter
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+			http.Pusher
+		}{base, f, h, rf, p}
+	}
+	return base
+}
+
+// wrappedWriter is the middleware writer paired with the writer it wraps
+type wrappedWriter struct {
+	http.ResponseWriter // The middleware's writer
+	under               http.ResponseWriter
+}
+
+// Unwrap returns the wrapped writer (used by http.ResponseController)
+func (ww *wrappedWriter) Unwrap() http.ResponseWriter {
+	return ww.under
+}
+
+type flusher struct{ ww *wrappedWriter }
+
+func (f flusher) Flush() {
+	if fl, ok := f.ww.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+		return
+	}
+	f.ww.under.(http.Flusher).Flush()
+}
+
+type hijacker struct{ ww *wrappedWriter }
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := h.ww.ResponseWriter.(http.Hijacker); ok {
+		return hj.Hijack()
+	}
+	return h.ww.under.(http.Hijacker).Hijack()
+}
+
+type readerFrom struct{ ww *wrappedWriter }
+
+func (r readerFrom) ReadFrom(src io.Reader) (int64, error) {
+	if rf, ok := r.ww.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(src)
+	}
+	// Hide the wrapper's other methods so io.Copy cannot loop back here
+	return io.Copy(struct{ io.Writer }{r.ww.ResponseWriter}, src)
+}
+
+type pusher struct{ ww *wrappedWriter }
+
+func (p pusher) Push(target string, opts *http.PushOptions) error {
+	if ps, ok := p.ww.ResponseWriter.(http.Pusher); ok {
+		return ps.Push(target, opts)
+	}
+	return p.ww.under.(http.Pusher).Push(target, opts)
+}
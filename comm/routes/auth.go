@@ -0,0 +1,87 @@
+package routes
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+// Auth requirements a route can declare at registration
+const (
+	AuthPublic  = "none"    // Served without a session, even under session-protected prefixes
+	AuthSession = "session" // Requires a session, even under skipped prefixes
+)
+
+// Route is a registered route, returned by the registration methods so the
+// route can be annotated in place, e.g. r.GETPathFn("/health", h).Public()
+type Route struct {
+	*mux.Route
+	routes *Routes
+}
+
+// routeAuths stores declared auth requirements by route
+type routeAuths struct {
+	items map[*mux.Route]string
+	mu    sync.RWMutex
+}
+
+// route wraps a newly registered mux route
+func (r *Routes) route(route *mux.Route) *Route {
+	return &Route{Route: route, routes: r}
+}
+
+// Public lets the route through session enforcement
+func (rt *Route) Public() *Route {
+	return rt.SetAuth(AuthPublic)
+}
+
+// RequireSession makes session enforcement apply to the route
+func (rt *Route) RequireSession() *Route {
+	return rt.SetAuth(AuthSession)
+}
+
+// SetAuth declares the auth requirement of the route; any value other than
+// AuthPublic requires a session. It is also shown in the route documentation.
+func (rt *Route) SetAuth(auth string) *Route {
+	ra := &rt.routes.auth
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	if ra.items == nil {
+		ra.items = make(map[*mux.Route]string)
+	}
+	ra.items[rt.Route] = auth
+	return rt
+}
+
+// routeAuth returns the auth declared for route
+func (r *Routes) routeAuth(route *mux.Route) (string, bool) {
+	r.auth.mu.RLock()
+	defer r.auth.mu.RUnlock()
+	auth, ok := r.auth.items[route]
+	return auth, ok
+}
+
+// RouteAuth returns the auth requirement declared by the route that serves req.
+// It reports false when the matching route declared none or no route matches.
+func (r *Routes) RouteAuth(req *http.Request) (string, bool) {
+	r.auth.mu.RLock()
+	declared := len(r.auth.items) > 0
+	r.auth.mu.RUnlock()
+	if !declared {
+		return "", false
+	}
+
+	// Match with the method ServeHTTP will dispatch on
+	if override := r.overrideMethod(req); override != "" {
+		overridden := *req
+		overridden.Method = override
+		req = &overridden
+	}
+
+	var match mux.RouteMatch
+	if !r.Mux.Match(req, &match) || match.Route == nil {
+		return "", false
+	}
+	return r.routeAuth(match.Route)
+}
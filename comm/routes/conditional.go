@@ -0,0 +1,116 @@
+package routes
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// RequestPredicate decides whether conditional middleware runs for a request
+type RequestPredicate func(*http.Request) bool
+
+// Use adds middleware to every route of the router (runs after route matching)
+func (r *Routes) Use(mw ...func(http.Handler) http.Handler) *Routes {
+	for _, m := range mw {
+		r.Mux.Use(mux.MiddlewareFunc(m))
+	}
+	return r
+}
+
+// UseIf adds mw to every route, but only runs it for requests matching predicate;
+// other requests go straight to the route handler. Useful to skip expensive
+// middleware for health checks, internal callers or CORS preflights:
+//
+//	routes.UseIf(routes.Not(routes.IsPreflight), authMiddleware)
+func (r *Routes) UseIf(predicate RequestPredicate, mw func(http.Handler) http.Handler) *Routes {
+	r.Mux.Use(func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if predicate(req) {
+				wrapped.ServeHTTP(w, req)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	})
+	return r
+}
+
+// Not inverts a predicate
+func Not(predicate RequestPredicate) RequestPredicate {
+	return func(req *http.Request) bool {
+		return !predicate(req)
+	}
+}
+
+// IsPreflight matches CORS preflight requests
+func IsPreflight(req *http.Request) bool {
+	return req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// PathIn matches requests whose path equals one of paths
+func PathIn(paths ...string) RequestPredicate {
+	return func(req *http.Request) bool {
+		for _, path := range paths {
+			if req.URL.Path == path {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// PathPrefixIn matches requests whose path starts with one of prefixes
+func PathPrefixIn(prefixes ...string) RequestPredicate {
+	return func(req *http.Request) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(req.URL.Path, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RemoteIn matches requests whose TCP peer address (req.RemoteAddr) lies in
+// one of cidrs. Invalid CIDRs panic, as this is meant for static configuration.
+//
+// WARNING: the peer is not the client when the server sits behind a reverse
+// proxy or CDN: every request then matches (or misses) by the proxy's address.
+// Use ClientIn(comm.ClientIP, ...) to match the client resolved through the
+// trusted proxies, like the server's other address checks.
+func RemoteIn(cidrs ...string) RequestPredicate {
+	return ClientIn(func(req *http.Request) string {
+		host, _, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			return req.RemoteAddr
+		}
+		return host
+	}, cidrs...)
+}
+
+// ClientIn matches requests whose client address, as returned by clientIP
+// (normally comm.ClientIP), lies in one of cidrs. Invalid CIDRs panic, as
+// this is meant for static configuration.
+func ClientIn(clientIP func(*http.Request) string, cidrs ...string) RequestPredicate {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefixes = append(prefixes, netip.MustParsePrefix(cidr))
+	}
+	return func(req *http.Request) bool {
+		addr, err := netip.ParseAddr(clientIP(req))
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+}
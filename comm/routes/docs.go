@@ -0,0 +1,138 @@
+package routes
+
+import (
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+//go:embed docs.html
+var docsTemplateSource string
+
+var docsTemplate = template.Must(template.New("routes").Parse(docsTemplateSource))
+
+// RouteMeta holds documentation attached to a route
+type RouteMeta struct {
+	Description string
+	Tags        []string
+	Auth        string // Free-form auth requirement, e.g. "none", "session", "admin"
+}
+
+// SetAuth sets the auth requirement shown in the route documentation
+func (rm *RouteMeta) SetAuth(auth string) *RouteMeta {
+	rm.Auth = auth
+	return rm
+}
+
+// AddTags appends tags to the route
+func (rm *RouteMeta) AddTags(tags ...string) *RouteMeta {
+	rm.Tags = append(rm.Tags, tags...)
+	return rm
+}
+
+// RouteDoc describes one registered route for documentation output
+type RouteDoc struct {
+	Path        string   `json:"path"`
+	Methods     string   `json:"methods"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Auth        string   `json:"auth,omitempty"`
+}
+
+// routeMetas stores RouteMeta by path template
+type routeMetas struct {
+	items map[string]*RouteMeta
+	mu    sync.RWMutex
+}
+
+// Describe attaches a description and tags to the route registered with the given path
+// (use the same pattern/prefix string passed at registration). Returns the metadata for chaining.
+func (r *Routes) Describe(path, description string, tags ...string) *RouteMeta {
+	r.meta.mu.Lock()
+	defer r.meta.mu.Unlock()
+	if r.meta.items == nil {
+		r.meta.items = make(map[string]*RouteMeta)
+	}
+	meta, ok := r.meta.items[path]
+	if !ok {
+		meta = &RouteMeta{}
+		r.meta.items[path] = meta
+	}
+	meta.Description = description
+	meta.Tags = append(meta.Tags, tags...)
+	return meta
+}
+
+// GetMeta returns the metadata attached to a path, if any
+func (r *Routes) GetMeta(path string) (*RouteMeta, bool) {
+	r.meta.mu.RLock()
+	defer r.meta.mu.RUnlock()
+	if meta, ok := r.meta.items[path]; ok {
+		return meta, true
+	}
+	// Prefix routes are stored normalized with a trailing slash
+	meta, ok := r.meta.items[strings.TrimSuffix(path, "/")]
+	return meta, ok
+}
+
+// GetRouteDocs returns all registered routes merged with their metadata
+func (r *Routes) GetRouteDocs() []RouteDoc {
+	docs := []RouteDoc{}
+	r.Mux.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		pathTemplate, err := route.GetPathTemplate()
+		if err != nil || pathTemplate == "" {
+			return nil
+		}
+
+		methodStr := "ANY"
+		if methods, err := route.GetMethods(); err == nil && len(methods) > 0 {
+			methodStr = strings.Join(methods, ", ")
+		}
+
+		doc := RouteDoc{Path: pathTemplate, Methods: methodStr}
+		if meta, ok := r.GetMeta(pathTemplate); ok {
+			doc.Description = meta.Description
+			doc.Tags = meta.Tags
+			doc.Auth = meta.Auth
+		}
+		if auth, ok := r.routeAuth(route); ok && doc.Auth == "" {
+			doc.Auth = auth
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs
+}
+
+// EnableDocs registers a self-hosted documentation page listing all routes at path (e.g. "/_routes")
+// Append ?format=json to get the raw listing
+func (r *Routes) EnableDocs(path string) {
+	r.Describe(path, "Route documentation", "docs")
+	r.GETPathFn(path, func(w http.ResponseWriter, req *http.Request) {
+		ServeDocs(w, req, "Routes", r.GetRouteDocs())
+	})
+}
+
+// ServeDocs renders route documentation as HTML (or JSON with ?format=json)
+func ServeDocs(w http.ResponseWriter, r *http.Request, title string, docs []RouteDoc) {
+	sort.SliceStable(docs, func(i, j int) bool { return docs[i].Path < docs[j].Path })
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(docs)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	docsTemplate.Execute(w, map[string]any{
+		"Title":  title,
+		"Routes": docs,
+	})
+}
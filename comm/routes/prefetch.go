@@ -0,0 +1,32 @@
+package routes
+
+import "net/http"
+
+// Prefetch announces endpoints the page served by the route calls right after
+// loading (e.g. the JSON an SPA fetches on boot) as Link: rel=preload headers
+// on GET responses, so the browser requests them while the page still loads
+func (rt *Route) Prefetch(urls ...string) *Route {
+	if len(urls) == 0 {
+		return rt
+	}
+	links := make([]string, len(urls))
+	for i, url := range urls {
+		links[i] = PrefetchLink(url)
+	}
+	next := rt.GetHandler()
+	rt.Route.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			for _, link := range links {
+				w.Header().Add("Link", link)
+			}
+		}
+		next.ServeHTTP(w, req)
+	}))
+	return rt
+}
+
+// PrefetchLink formats a Link header value preloading url for a fetch() call
+// with default (same-origin) credentials, which the browser then reuses
+func PrefetchLink(url string) string {
+	return "<" + url + ">; rel=preload; as=fetch; crossorigin=anonymous"
+}
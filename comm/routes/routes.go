@@ -11,6 +11,23 @@ import (
 type Routes struct {
 	Mux  *mux.Router
 	mode int // If 1, strips prefix before passing to handler (webtrail mode). If 0, passes full path (weblite mode)
+
+	// MethodOverride lets POST requests carry the real method in X-HTTP-Method-Override
+	// (for clients behind proxies that block PUT/PATCH/DELETE). Applied by ServeHTTP.
+	MethodOverride bool
+	// AutoHead makes GET routes also answer HEAD requests (body is discarded by net/http)
+	// Only affects routes registered after it is enabled.
+	AutoHead bool
+
+	meta routeMetas // Route documentation attached via Describe
+	auth routeAuths // Auth requirements declared via Route.Public / Route.RequireSession
+}
+
+// overridableMethods lists the methods a POST may be overridden to
+var overridableMethods = map[string]bool{
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
 }
 
 // NewRoutes creates a new Routes instance
@@ -23,44 +40,92 @@ func NewRoutes(mux *mux.Router) *Routes {
 // SetStripPrefix sets whether to strip prefix from paths before passing to handlers
 // Use true for webtrail mode, false for weblite mode (default)
 
+// SetMethodOverride enables or disables X-HTTP-Method-Override support
+func (r *Routes) SetMethodOverride(enabled bool) *Routes {
+	r.MethodOverride = enabled
+	return r
+}
+
+// SetAutoHead enables or disables automatic HEAD handling for GET routes registered afterwards
+func (r *Routes) SetAutoHead(enabled bool) *Routes {
+	r.AutoHead = enabled
+	return r
+}
+
+// ServeHTTP dispatches the request to the router, applying method override first if enabled
+func (r *Routes) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if override := r.overrideMethod(req); override != "" {
+		req.Method = override
+	}
+	r.Mux.ServeHTTP(w, req)
+}
+
+// overrideMethod returns the method a POST overrides to, or "" when none applies
+func (r *Routes) overrideMethod(req *http.Request) string {
+	if !r.MethodOverride || req.Method != http.MethodPost {
+		return ""
+	}
+	override := strings.ToUpper(strings.TrimSpace(req.Header.Get("X-HTTP-Method-Override")))
+	if overridableMethods[override] {
+		return override
+	}
+	return ""
+}
+
+// expandMethods adds HEAD next to GET when AutoHead is enabled
+func (r *Routes) expandMethods(methods ...string) []string {
+	if !r.AutoHead {
+		return methods
+	}
+	hasGet, hasHead := false, false
+	for _, m := range methods {
+		hasGet = hasGet || m == http.MethodGet
+		hasHead = hasHead || m == http.MethodHead
+	}
+	if hasGet && !hasHead {
+		return append(methods, http.MethodHead)
+	}
+	return methods
+}
+
 // HandlePathH registers an http.Handler for the exact path match
-func (r *Routes) HandlePathH(pattern string, handler http.Handler) {
-	r.Mux.Handle(pattern, handler)
+func (r *Routes) HandlePathH(pattern string, handler http.Handler) *Route {
+	return r.route(r.Mux.Handle(pattern, handler))
 }
 
 // HandlePathFn registers a handler function for exact path match
-func (r *Routes) HandlePathFn(pattern string, handler func(http.ResponseWriter, *http.Request)) {
-	r.Mux.HandleFunc(pattern, handler)
+func (r *Routes) HandlePathFn(pattern string, handler func(http.ResponseWriter, *http.Request)) *Route {
+	return r.route(r.Mux.HandleFunc(pattern, handler))
 }
 
 // HandlePathPrefixH registers a handler for all paths under the given prefix
 // The prefix is automatically stripped from the request path before passing to the handler
 // Example: HandlePathPrefixH("/static/", handler) will serve "/static/file.css" as "/file.css" to the handler
-func (r *Routes) HandlePathPrefixH(prefix string, handler http.Handler) {
-	r.handlePathPrefixWithMethod(prefix, handler)
+func (r *Routes) HandlePathPrefixH(prefix string, handler http.Handler) *Route {
+	return r.handlePathPrefixWithMethod(prefix, handler)
 }
 
 // HandlePathPrefixFn registers a handler function for all paths under the prefix
 // The prefix is automatically stripped before passing to the handler
-func (r *Routes) HandlePathPrefixFn(prefix string, handler http.HandlerFunc) {
-	r.handlePathPrefixWithMethod(prefix, handler)
+func (r *Routes) HandlePathPrefixFn(prefix string, handler http.HandlerFunc) *Route {
+	return r.handlePathPrefixWithMethod(prefix, handler)
 }
 
 // HandlePathFnc is a convenience method that accepts a raw handler function and converts it to http.Handler
 // This eliminates the need to wrap with http.HandlerFunc manually
 // Example: HandlePathFnc("/api/endpoint", myFunc) instead of HandlePathH("/api/endpoint", http.HandlerFunc(myFunc))
-func (r *Routes) HandlePathFnc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
-	r.Mux.Handle(pattern, http.HandlerFunc(handler))
+func (r *Routes) HandlePathFnc(pattern string, handler func(http.ResponseWriter, *http.Request)) *Route {
+	return r.route(r.Mux.Handle(pattern, http.HandlerFunc(handler)))
 }
 
 // HandlePathPrefixFnc is a convenience method that accepts a raw handler function for path prefixes
 // The prefix is automatically stripped before passing to the handler
 // Example: HandlePathPrefixFnc("/api/", myFunc) instead of HandlePathPrefixH("/api/", http.HandlerFunc(myFunc))
-func (r *Routes) HandlePathPrefixFnc(prefix string, handler func(http.ResponseWriter, *http.Request)) {
-	r.handlePathPrefixWithMethod(prefix, http.HandlerFunc(handler))
+func (r *Routes) HandlePathPrefixFnc(prefix string, handler func(http.ResponseWriter, *http.Request)) *Route {
+	return r.handlePathPrefixWithMethod(prefix, http.HandlerFunc(handler))
 }
 
-func (r *Routes) ForwardPathFn(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+func (r *Routes) ForwardPathFn(pattern string, handler func(http.ResponseWriter, *http.Request)) *Route {
 	// Wrap the handler to strip the base path and preserve original path in header
 	wrappedHandler := func(w http.ResponseWriter, req *http.Request) {
 		// Save original path in header if not already set
@@ -77,10 +142,10 @@ func (r *Routes) ForwardPathFn(pattern string, handler func(http.ResponseWriter,
 		handler(w, req)
 	}
 
-	r.Mux.HandleFunc(pattern, wrappedHandler)
+	return r.route(r.Mux.HandleFunc(pattern, wrappedHandler))
 }
 
-func (r *Routes) ForwardPathPrefixFn(prefix string, handler func(http.ResponseWriter, *http.Request)) {
+func (r *Routes) ForwardPathPrefixFn(prefix string, handler func(http.ResponseWriter, *http.Request)) *Route {
 	// Normalize prefix
 	prefix = r.normalizePrefix(prefix)
 
@@ -100,7 +165,7 @@ func (r *Routes) ForwardPathPrefixFn(prefix string, handler func(http.ResponseWr
 		handler(w, req)
 	})
 
-	r.Mux.PathPrefix(prefix).Handler(wrappedHandler)
+	return r.route(r.Mux.PathPrefix(prefix).Handler(wrappedHandler))
 }
 
 // Internal helpers for standardizing prefix handling
@@ -112,7 +177,7 @@ func (r *Routes) normalizePrefix(prefix string) string {
 	return prefix
 }
 
-func (r *Routes) handlePathPrefixWithMethod(prefix string, handler http.Handler, methods ...string) {
+func (r *Routes) handlePathPrefixWithMethod(prefix string, handler http.Handler, methods ...string) *Route {
 	prefix = r.normalizePrefix(prefix)
 	var route *mux.Route
 	if r.mode == 1 {
@@ -123,8 +188,9 @@ func (r *Routes) handlePathPrefixWithMethod(prefix string, handler http.Handler,
 		route = r.Mux.PathPrefix(prefix).Handler(handler)
 	}
 	if len(methods) > 0 {
-		route.Methods(methods...)
+		route.Methods(r.expandMethods(methods...)...)
 	}
+	return r.route(route)
 }
 
 // GetRoutes returns all registered routes with their methods
@@ -171,106 +237,106 @@ func (r *Routes) GetRoutes() []map[string]string {
 // Common HTTP method helpers
 
 // GETPathFn registers a GET handler for exact path match
-func (r *Routes) GETPathFn(path string, handler http.HandlerFunc) {
-	r.Mux.HandleFunc(path, handler).Methods(http.MethodGet)
+func (r *Routes) GETPathFn(path string, handler http.HandlerFunc) *Route {
+	return r.route(r.Mux.HandleFunc(path, handler).Methods(r.expandMethods(http.MethodGet)...))
 }
 
 // GETPrefixFn registers a GET handler for path prefix with http.HandlerFunc
-func (r *Routes) GETPrefixFn(prefix string, handler http.HandlerFunc) {
-	r.handlePathPrefixWithMethod(prefix, handler, http.MethodGet)
+func (r *Routes) GETPrefixFn(prefix string, handler http.HandlerFunc) *Route {
+	return r.handlePathPrefixWithMethod(prefix, handler, http.MethodGet)
 }
 
 // GETPrefixFnc registers a GET handler for path prefix with raw function
-func (r *Routes) GETPrefixFnc(prefix string, handler func(http.ResponseWriter, *http.Request)) {
-	r.handlePathPrefixWithMethod(prefix, http.HandlerFunc(handler), http.MethodGet)
+func (r *Routes) GETPrefixFnc(prefix string, handler func(http.ResponseWriter, *http.Request)) *Route {
+	return r.handlePathPrefixWithMethod(prefix, http.HandlerFunc(handler), http.MethodGet)
 }
 
 // POSTPathFn registers a POST handler for exact path match
-func (r *Routes) POSTPathFn(path string, handler http.HandlerFunc) {
-	r.Mux.HandleFunc(path, handler).Methods(http.MethodPost)
+func (r *Routes) POSTPathFn(path string, handler http.HandlerFunc) *Route {
+	return r.route(r.Mux.HandleFunc(path, handler).Methods(http.MethodPost))
 }
 
 // POSTPrefixFn registers a POST handler for path prefix with http.HandlerFunc
-func (r *Routes) POSTPrefixFn(prefix string, handler http.HandlerFunc) {
-	r.handlePathPrefixWithMethod(prefix, handler, http.MethodPost)
+func (r *Routes) POSTPrefixFn(prefix string, handler http.HandlerFunc) *Route {
+	return r.handlePathPrefixWithMethod(prefix, handler, http.MethodPost)
 }
 
 // POSTPrefixFnc registers a POST handler for path prefix with raw function
-func (r *Routes) POSTPrefixFnc(prefix string, handler func(http.ResponseWriter, *http.Request)) {
-	r.handlePathPrefixWithMethod(prefix, http.HandlerFunc(handler), http.MethodPost)
+func (r *Routes) POSTPrefixFnc(prefix string, handler func(http.ResponseWriter, *http.Request)) *Route {
+	return r.handlePathPrefixWithMethod(prefix, http.HandlerFunc(handler), http.MethodPost)
 }
 
 // PUTPathFn registers a PUT handler for exact path match
-func (r *Routes) PUTPathFn(path string, handler http.HandlerFunc) {
-	r.Mux.HandleFunc(path, handler).Methods(http.MethodPut)
+func (r *Routes) PUTPathFn(path string, handler http.HandlerFunc) *Route {
+	return r.route(r.Mux.HandleFunc(path, handler).Methods(http.MethodPut))
 }
 
 // PUTPrefixFn registers a PUT handler for path prefix with http.HandlerFunc
-func (r *Routes) PUTPrefixFn(prefix string, handler http.HandlerFunc) {
-	r.handlePathPrefixWithMethod(prefix, handler, http.MethodPut)
+func (r *Routes) PUTPrefixFn(prefix string, handler http.HandlerFunc) *Route {
+	return r.handlePathPrefixWithMethod(prefix, handler, http.MethodPut)
 }
 
 // PUTPrefixFnc registers a PUT handler for path prefix with raw function
-func (r *Routes) PUTPrefixFnc(prefix string, handler func(http.ResponseWriter, *http.Request)) {
-	r.handlePathPrefixWithMethod(prefix, http.HandlerFunc(handler), http.MethodPut)
+func (r *Routes) PUTPrefixFnc(prefix string, handler func(http.ResponseWriter, *http.Request)) *Route {
+	return r.handlePathPrefixWithMethod(prefix, http.HandlerFunc(handler), http.MethodPut)
 }
 
 // PATCHPathFn registers a PATCH handler for exact path match
-func (r *Routes) PATCHPathFn(path string, handler http.HandlerFunc) {
-	r.Mux.HandleFunc(path, handler).Methods(http.MethodPatch)
+func (r *Routes) PATCHPathFn(path string, handler http.HandlerFunc) *Route {
+	return r.route(r.Mux.HandleFunc(path, handler).Methods(http.MethodPatch))
 }
 
 // PATCHPrefixFn registers a PATCH handler for path prefix with http.HandlerFunc
-func (r *Routes) PATCHPrefixFn(prefix string, handler http.HandlerFunc) {
-	r.handlePathPrefixWithMethod(prefix, handler, http.MethodPatch)
+func (r *Routes) PATCHPrefixFn(prefix string, handler http.HandlerFunc) *Route {
+	return r.handlePathPrefixWithMethod(prefix, handler, http.MethodPatch)
 }
 
 // PATCHPrefixFnc registers a PATCH handler for path prefix with raw function
-func (r *Routes) PATCHPrefixFnc(prefix string, handler func(http.ResponseWriter, *http.Request)) {
-	r.handlePathPrefixWithMethod(prefix, http.HandlerFunc(handler), http.MethodPatch)
+func (r *Routes) PATCHPrefixFnc(prefix string, handler func(http.ResponseWriter, *http.Request)) *Route {
+	return r.handlePathPrefixWithMethod(prefix, http.HandlerFunc(handler), http.MethodPatch)
 }
 
 // DELETEPathFn registers a DELETE handler for exact path match
-func (r *Routes) DELETEPathFn(path string, handler http.HandlerFunc) {
-	r.Mux.HandleFunc(path, handler).Methods(http.MethodDelete)
+func (r *Routes) DELETEPathFn(path string, handler http.HandlerFunc) *Route {
+	return r.route(r.Mux.HandleFunc(path, handler).Methods(http.MethodDelete))
 }
 
 // DELETEPrefixFn registers a DELETE handler for path prefix with http.HandlerFunc
-func (r *Routes) DELETEPrefixFn(prefix string, handler http.HandlerFunc) {
-	r.handlePathPrefixWithMethod(prefix, handler, http.MethodDelete)
+func (r *Routes) DELETEPrefixFn(prefix string, handler http.HandlerFunc) *Route {
+	return r.handlePathPrefixWithMethod(prefix, handler, http.MethodDelete)
 }
 
 // DELETEPrefixFnc registers a DELETE handler for path prefix with raw function
-func (r *Routes) DELETEPrefixFnc(prefix string, handler func(http.ResponseWriter, *http.Request)) {
-	r.handlePathPrefixWithMethod(prefix, http.HandlerFunc(handler), http.MethodDelete)
+func (r *Routes) DELETEPrefixFnc(prefix string, handler func(http.ResponseWriter, *http.Request)) *Route {
+	return r.handlePathPrefixWithMethod(prefix, http.HandlerFunc(handler), http.MethodDelete)
 }
 
 // OPTIONSPathFn registers an OPTIONS handler for exact path match
-func (r *Routes) OPTIONSPathFn(path string, handler http.HandlerFunc) {
-	r.Mux.HandleFunc(path, handler).Methods(http.MethodOptions)
+func (r *Routes) OPTIONSPathFn(path string, handler http.HandlerFunc) *Route {
+	return r.route(r.Mux.HandleFunc(path, handler).Methods(http.MethodOptions))
 }
 
 // OPTIONSPrefixFn registers an OPTIONS handler for path prefix with http.HandlerFunc
-func (r *Routes) OPTIONSPrefixFn(prefix string, handler http.HandlerFunc) {
-	r.handlePathPrefixWithMethod(prefix, handler, http.MethodOptions)
+func (r *Routes) OPTIONSPrefixFn(prefix string, handler http.HandlerFunc) *Route {
+	return r.handlePathPrefixWithMethod(prefix, handler, http.MethodOptions)
 }
 
 // OPTIONSPrefixFnc registers an OPTIONS handler for path prefix with raw function
-func (r *Routes) OPTIONSPrefixFnc(prefix string, handler func(http.ResponseWriter, *http.Request)) {
-	r.handlePathPrefixWithMethod(prefix, http.HandlerFunc(handler), http.MethodOptions)
+func (r *Routes) OPTIONSPrefixFnc(prefix string, handler func(http.ResponseWriter, *http.Request)) *Route {
+	return r.handlePathPrefixWithMethod(prefix, http.HandlerFunc(handler), http.MethodOptions)
 }
 
 // HEADPathFn registers a HEAD handler for exact path match
-func (r *Routes) HEADPathFn(path string, handler http.HandlerFunc) {
-	r.Mux.HandleFunc(path, handler).Methods(http.MethodHead)
+func (r *Routes) HEADPathFn(path string, handler http.HandlerFunc) *Route {
+	return r.route(r.Mux.HandleFunc(path, handler).Methods(http.MethodHead))
 }
 
 // HEADPrefixFn registers a HEAD handler for path prefix with http.HandlerFunc
-func (r *Routes) HEADPrefixFn(prefix string, handler http.HandlerFunc) {
-	r.handlePathPrefixWithMethod(prefix, handler, http.MethodHead)
+func (r *Routes) HEADPrefixFn(prefix string, handler http.HandlerFunc) *Route {
+	return r.handlePathPrefixWithMethod(prefix, handler, http.MethodHead)
 }
 
 // HEADPrefixFnc registers a HEAD handler for path prefix with raw function
-func (r *Routes) HEADPrefixFnc(prefix string, handler func(http.ResponseWriter, *http.Request)) {
-	r.handlePathPrefixWithMethod(prefix, http.HandlerFunc(handler), http.MethodHead)
+func (r *Routes) HEADPrefixFnc(prefix string, handler func(http.ResponseWriter, *http.Request)) *Route {
+	return r.handlePathPrefixWithMethod(prefix, http.HandlerFunc(handler), http.MethodHead)
 }
@@ -0,0 +1,167 @@
+package routes
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	respwriter "github.com/go-xlite/wbx/comm/resp_writer"
+	"github.com/gorilla/mux"
+)
+
+// RouteSize holds the traffic totals of one route template
+type RouteSize struct {
+	Method      string `json:"method"`
+	Template    string `json:"template"`
+	Requests    int64  `json:"requests"`
+	BytesIn     int64  `json:"bytesIn"`  // Request body bytes read by the handler
+	BytesOut    int64  `json:"bytesOut"` // Response body bytes written
+	AvgBytesIn  int64  `json:"avgBytesIn"`
+	AvgBytesOut int64  `json:"avgBytesOut"`
+}
+
+// SizeStats accounts request and response body sizes per route template
+// (e.g. "GET /api/users/{id}"), to find the endpoints dominating bandwidth
+type SizeStats struct {
+	routes map[string]*routeCounters
+	mu     sync.RWMutex
+}
+
+type routeCounters struct {
+	method, template            string
+	requests, bytesIn, bytesOut atomic.Int64
+}
+
+// EnableSizeStats installs size accounting on every route and returns the tracker
+func (r *Routes) EnableSizeStats() *SizeStats {
+	stats := &SizeStats{routes: make(map[string]*routeCounters)}
+	r.Mux.Use(stats.middleware)
+	return stats
+}
+
+func (ss *SizeStats) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		template := req.URL.Path
+		if route := mux.CurrentRoute(req); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				template = tpl
+			}
+		}
+
+		in := &countingBody{ReadCloser: req.Body}
+		if req.Body != nil && req.Body != http.NoBody {
+			req.Body = in
+		}
+		// A fresh writer, so an outer CaptureWriter's earlier bytes are not counted
+		out := &respwriter.CaptureWriter{ResponseWriter: w}
+		next.ServeHTTP(respwriter.WrapResponseWriter(w, out), req)
+
+		counters := ss.counters(req.Method, template)
+		counters.requests.Add(1)
+		counters.bytesIn.Add(in.n)
+		counters.bytesOut.Add(out.BytesWritten())
+	})
+}
+
+func (ss *SizeStats) counters(method, template string) *routeCounters {
+	key := method + " " + template
+	ss.mu.RLock()
+	c, ok := ss.routes[key]
+	ss.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if c, ok = ss.routes[key]; !ok {
+		c = &routeCounters{method: method, template: template}
+		ss.routes[key] = c
+	}
+	return c
+}
+
+// Snapshot returns the totals of all routes
+func (ss *SizeStats) Snapshot() []RouteSize {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	list := make([]RouteSize, 0, len(ss.routes))
+	for _, c := range ss.routes {
+		rs := RouteSize{
+			Method:   c.method,
+			Template: c.template,
+			Requests: c.requests.Load(),
+			BytesIn:  c.bytesIn.Load(),
+			BytesOut: c.bytesOut.Load(),
+		}
+		if rs.Requests > 0 {
+			rs.AvgBytesIn = rs.BytesIn / rs.Requests
+			rs.AvgBytesOut = rs.BytesOut / rs.Requests
+		}
+		list = append(list, rs)
+	}
+	return list
+}
+
+// Top returns the n routes with the highest value of by:
+// "out" (default), "in", "total" or "requests". n <= 0 returns all routes.
+func (ss *SizeStats) Top(n int, by string) []RouteSize {
+	list := ss.Snapshot()
+	value := func(rs RouteSize) int64 {
+		switch by {
+		case "in":
+			return rs.BytesIn
+		case "total":
+			return rs.BytesIn + rs.BytesOut
+		case "requests":
+			return rs.Requests
+		default:
+			return rs.BytesOut
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if vi, vj := value(list[i]), value(list[j]); vi != vj {
+			return vi > vj
+		}
+		return list[i].Template < list[j].Template
+	})
+	if n > 0 && n < len(list) {
+		list = list[:n]
+	}
+	return list
+}
+
+// Reset clears all counters
+func (ss *SizeStats) Reset() {
+	ss.mu.Lock()
+	ss.routes = make(map[string]*routeCounters)
+	ss.mu.Unlock()
+}
+
+// Handler serves the top routes as JSON (?top=10&by=out|in|total|requests)
+func (ss *SizeStats) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		n, err := strconv.Atoi(req.URL.Query().Get("top"))
+		if err != nil {
+			n = 10
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ss.Top(n, req.URL.Query().Get("by")))
+	}
+}
+
+// countingBody counts bytes read from a request body
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (cb *countingBody) Read(p []byte) (int, error) {
+	n, err := cb.ReadCloser.Read(p)
+	cb.n += int64(n)
+	return n, err
+}
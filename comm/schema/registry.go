@@ -0,0 +1,221 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultTypeField is the payload property naming the event type
+const DefaultTypeField = "type"
+
+// Registry maps event types to the schema of their payloads. While enabled,
+// senders (WebCast, WebSock) check every outgoing JSON payload against the
+// schema of its type, catching drift between Go producers and JS consumers.
+// Keep it disabled in production: validation decodes every message.
+type Registry struct {
+	TypeField string // Payload property naming the event type (default "type")
+	Enabled   bool   // Validate outgoing payloads (development mode)
+	Strict    bool   // Drop invalid payloads instead of only reporting them
+	// OnViolation is called for every invalid payload (logs when nil)
+	OnViolation func(eventType string, payload []byte, err error)
+
+	schemas    map[string]*Schema
+	documents  map[string]json.RawMessage
+	mu         sync.RWMutex
+	checked    atomic.Int64
+	violations atomic.Int64
+}
+
+// RegistryStats reports validation activity
+type RegistryStats struct {
+	Types      int   `json:"types"`
+	Checked    int64 `json:"checked"`
+	Violations int64 `json:"violations"`
+}
+
+// NewRegistry creates an empty, disabled registry
+func NewRegistry() *Registry {
+	return &Registry{
+		TypeField: DefaultTypeField,
+		schemas:   make(map[string]*Schema),
+		documents: make(map[string]json.RawMessage),
+	}
+}
+
+// SetEnabled turns validation of outgoing payloads on or off
+func (r *Registry) SetEnabled(enabled bool) *Registry {
+	r.Enabled = enabled
+	return r
+}
+
+// SetStrict makes senders drop payloads that fail validation
+func (r *Registry) SetStrict(strict bool) *Registry {
+	r.Strict = strict
+	return r
+}
+
+// Register adds (or replaces) the JSON Schema document of an event type
+func (r *Registry) Register(eventType string, document []byte) error {
+	s, err := Parse(document)
+	if err != nil {
+		return fmt.Errorf("schema %q: %w", eventType, err)
+	}
+	r.mu.Lock()
+	r.schemas[eventType] = s
+	r.documents[eventType] = json.RawMessage(append([]byte(nil), document...))
+	r.mu.Unlock()
+	return nil
+}
+
+// MustRegister is Register for schemas embedded at build time; it panics on invalid documents
+func (r *Registry) MustRegister(eventType string, document []byte) *Registry {
+	if err := r.Register(eventType, document); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Get returns the schema of an event type
+func (r *Registry) Get(eventType string) (*Schema, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.schemas[eventType]
+	return s, ok
+}
+
+// Types lists the registered event types in order
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	types := make([]string, 0, len(r.schemas))
+	for t := range r.schemas {
+		types = append(types, t)
+	}
+	r.mu.RUnlock()
+	sort.Strings(types)
+	return types
+}
+
+// Validate checks payload against the schema of eventType. Types without a
+// schema are accepted.
+func (r *Registry) Validate(eventType string, payload []byte) error {
+	s, ok := r.Get(eventType)
+	if !ok {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return &ValidationError{Message: "payload is not valid JSON"}
+	}
+	return s.Validate(value)
+}
+
+// Check validates an outgoing payload when the registry is enabled. Objects
+// are checked against the schema named by their TypeField, arrays (batches)
+// item by item; anything else passes. Violations are reported, and returned
+// only in Strict mode so the sender can drop the payload.
+func (r *Registry) Check(payload []byte) error {
+	if r == nil || !r.Enabled {
+		return nil
+	}
+
+	var value any
+	if json.Unmarshal(payload, &value) != nil {
+		return nil // Plain text messages are not schema-checked
+	}
+	items, isBatch := value.([]any)
+	if !isBatch {
+		items = []any{value}
+	}
+
+	var firstErr error
+	for _, item := range items {
+		eventType, err := r.checkValue(item)
+		if err == nil {
+			continue
+		}
+		r.violations.Add(1)
+		r.report(eventType, payload, err)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if r.Strict {
+		return firstErr
+	}
+	return nil
+}
+
+// checkValue validates one decoded event
+func (r *Registry) checkValue(value any) (string, error) {
+	obj, ok := value.(map[string]any)
+	if !ok {
+		return "", nil
+	}
+	eventType, _ := obj[r.typeField()].(string)
+	s, ok := r.Get(eventType)
+	if !ok {
+		return eventType, nil
+	}
+	r.checked.Add(1)
+	return eventType, s.Validate(obj)
+}
+
+func (r *Registry) report(eventType string, payload []byte, err error) {
+	if r.OnViolation != nil {
+		r.OnViolation(eventType, payload, err)
+		return
+	}
+	fmt.Printf("Schema [%s] invalid payload: %v\n", eventType, err)
+}
+
+func (r *Registry) typeField() string {
+	if r.TypeField == "" {
+		return DefaultTypeField
+	}
+	return r.TypeField
+}
+
+// GetStats returns validation counters
+func (r *Registry) GetStats() RegistryStats {
+	r.mu.RLock()
+	types := len(r.schemas)
+	r.mu.RUnlock()
+	return RegistryStats{
+		Types:      types,
+		Checked:    r.checked.Load(),
+		Violations: r.violations.Load(),
+	}
+}
+
+// Handler serves the registered schema documents keyed by event type,
+// or a single document with ?type=name
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+
+		// Documents are replaced, never modified, so they can be written after
+		// releasing the lock; a slow client must not stall Register
+		if eventType := req.URL.Query().Get("type"); eventType != "" {
+			r.mu.RLock()
+			doc, ok := r.documents[eventType]
+			r.mu.RUnlock()
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":"unknown event type"}`))
+				return
+			}
+			w.Write(doc)
+			return
+		}
+		r.mu.RLock()
+		documents := maps.Clone(r.documents)
+		r.mu.RUnlock()
+		json.NewEncoder(w).Encode(documents)
+	}
+}
@@ -0,0 +1,67 @@
+package comm
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// ServeOptions controls how ServeBytes answers a request
+type ServeOptions struct {
+	ContentType  string // Explicit Content-Type (detected from name/data when empty)
+	CacheControl string // Cache-Control value (left untouched when empty)
+	ETag         string // Explicit ETag (derived from data when empty)
+	NoETag       bool   // Disable ETag generation
+}
+
+// ServeBytes writes data as the response to r with full HTTP caching semantics:
+// Content-Type detection, ETag, Last-Modified, conditional requests (304/412),
+// single and multi Range requests (206/416) and HEAD.
+// A zero modTime omits Last-Modified, which is typical for embedded files.
+func ServeBytes(w http.ResponseWriter, r *http.Request, name string, modTime time.Time, data []byte, opts *ServeOptions) {
+	if opts == nil {
+		opts = &ServeOptions{}
+	}
+	header := w.Header()
+
+	if opts.CacheControl != "" {
+		header.Set("Cache-Control", opts.CacheControl)
+	}
+
+	if opts.ContentType != "" {
+		header.Set("Content-Type", opts.ContentType)
+	} else if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", DetectContentType(name, data))
+	}
+
+	if !opts.NoETag {
+		etag := opts.ETag
+		if etag == "" {
+			etag = ETagForBytes(data)
+		}
+		header.Set("ETag", etag)
+	}
+
+	// http.ServeContent handles If-Match, If-None-Match, If-Modified-Since,
+	// If-Unmodified-Since, If-Range, Range and HEAD for us
+	http.ServeContent(w, r, name, modTime, bytes.NewReader(data))
+}
+
+// DetectContentType returns the MIME type for name based on its extension,
+// falling back to sniffing data when the extension is unknown
+func DetectContentType(name string, data []byte) string {
+	mimeType := Mime.GetType(filepath.Ext(name))
+	if mimeType == "application/octet-stream" && len(data) > 0 {
+		return http.DetectContentType(data)
+	}
+	return mimeType
+}
+
+// ETagForBytes returns a strong ETag derived from the content
+func ETagForBytes(data []byte) string {
+	sum := sha1.Sum(data)
+	return `"` + hex.EncodeToString(sum[:10]) + `"`
+}
@@ -0,0 +1,18 @@
+package comm
+
+import "net/http"
+
+// SessionResolver resolves the session behind a request for handlers that
+// enforce authentication themselves
+type SessionResolver interface {
+	// ResolveSession returns the session data and true when r carries a valid session
+	ResolveSession(r *http.Request) (any, bool)
+}
+
+// SessionResolverFunc adapts a function to SessionResolver
+type SessionResolverFunc func(r *http.Request) (any, bool)
+
+// ResolveSession calls f
+func (f SessionResolverFunc) ResolveSession(r *http.Request) (any, bool) {
+	return f(r)
+}
@@ -0,0 +1,41 @@
+package comm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+// SessionRevokedReason is the close reason sent to connections whose session was revoked
+const SessionRevokedReason = "session-revoked"
+
+// SessionRevocationListener closes live connections when their session is revoked.
+// WebSock and WebCast implement it; register them with SessionManager.OnRevoke.
+type SessionRevocationListener interface {
+	// SessionRevoked closes connections opened under sessionKey and returns how many
+	SessionRevoked(sessionKey string) int
+}
+
+type sessionKeyContextKey struct{}
+
+// SessionKeyFor derives the key identifying a session from its token, so
+// connections can be matched to a session without holding the token itself
+func SessionKeyFor(token string) string {
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:16])
+}
+
+// WithSessionKey stores the session key in ctx, see SessionKey
+func WithSessionKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, sessionKeyContextKey{}, key)
+}
+
+// SessionKey returns the key of the session r was authenticated with, or ""
+func SessionKey(r *http.Request) string {
+	key, _ := r.Context().Value(sessionKeyContextKey{}).(string)
+	return key
+}
@@ -8,6 +8,8 @@ import (
 	"net"
 	"net/http"
 	"strings"
+
+	"github.com/go-xlite/wbx/comm"
 )
 
 // CompressionLevel controls how aggressively to compress
@@ -57,7 +59,9 @@ func defaultCompressibleTypes() map[string]bool {
 	}
 }
 
-// gzipResponseWriter wraps http.ResponseWriter to provide gzip compression
+// gzipResponseWriter wraps http.ResponseWriter to provide gzip compression.
+// Wrap hands it out through comm.WrapResponseWriter, so Flush, Hijack,
+// ReadFrom and Push are only visible when the underlying writer has them.
 type gzipResponseWriter struct {
 	io.Writer
 	http.ResponseWriter
@@ -66,26 +70,39 @@ type gzipResponseWriter struct {
 	headerWritten  bool
 	shouldCompress bool
 	closed         bool
+	hijacked       bool // The connection was taken over, nothing may be written anymore
 }
 
-// Write implements io.Writer
-func (w *gzipResponseWriter) Write(b []byte) (int, error) {
-	if !w.headerWritten {
-		// Set content type if not already set
-		if w.Header().Get("Content-Type") == "" {
-			w.Header().Set("Content-Type", http.DetectContentType(b))
-		}
+// decide picks whether to compress right before the header is sent; b is the
+// first body chunk, used to detect the content type when none is set
+func (w *gzipResponseWriter) decide(statusCode int, b []byte) {
+	w.headerWritten = true
 
-		// Determine if we should compress based on content type
-		w.shouldCompress = w.isCompressible()
+	// Set content type if not already set
+	if w.Header().Get("Content-Type") == "" && b != nil {
+		w.Header().Set("Content-Type", http.DetectContentType(b))
+	}
 
-		if w.shouldCompress {
-			w.Header().Set("Content-Encoding", "gzip")
-			w.Header().Set("Vary", "Accept-Encoding")
-			w.Header().Del("Content-Length") // Length will change with compression
-		}
+	// Bodyless responses and content the handler encoded itself stay as they are
+	if statusCode == http.StatusNoContent || statusCode == http.StatusNotModified ||
+		w.Header().Get("Content-Encoding") != "" {
+		return
+	}
+
+	// Determine if we should compress based on content type
+	w.shouldCompress = w.isCompressible()
 
-		w.headerWritten = true
+	if w.shouldCompress {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Vary", "Accept-Encoding")
+		w.Header().Del("Content-Length") // Length will change with compression
+	}
+}
+
+// Write implements io.Writer
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.headerWritten {
+		w.decide(http.StatusOK, b)
 	}
 
 	if w.shouldCompress {
@@ -97,11 +114,26 @@ func (w *gzipResponseWriter) Write(b []byte) (int, error) {
 
 // WriteHeader implements http.ResponseWriter
 func (w *gzipResponseWriter) WriteHeader(statusCode int) {
+	// 1xx informational responses precede the real header
+	if !w.headerWritten && statusCode >= 200 {
+		w.decide(statusCode, nil)
+	}
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
+// ReadFrom implements io.ReaderFrom, keeping sendfile for uncompressed bodies
+func (w *gzipResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok && w.headerWritten && !w.shouldCompress {
+		return rf.ReadFrom(src)
+	}
+	return io.Copy(struct{ io.Writer }{w}, src)
+}
+
 // Flush implements http.Flusher
 func (w *gzipResponseWriter) Flush() {
+	if !w.headerWritten {
+		w.decide(http.StatusOK, nil)
+	}
 	if w.shouldCompress && w.gzipWriter != nil {
 		w.gzipWriter.Flush()
 	}
@@ -110,12 +142,19 @@ func (w *gzipResponseWriter) Flush() {
 	}
 }
 
-// Hijack implements http.Hijacker
+// Hijack implements http.Hijacker. A hijacked connection is passed through
+// as is: compression is abandoned and Close writes nothing.
 func (w *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	if hj, ok := w.ResponseWriter.(http.Hijacker); ok {
-		return hj.Hijack()
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("underlying ResponseWriter does not support Hijack")
+	}
+	conn, rw, err := hj.Hijack()
+	if err == nil {
+		w.hijacked = true
+		w.shouldCompress = false
 	}
-	return nil, nil, errors.New("underlying ResponseWriter does not support Hijack")
+	return conn, rw, err
 }
 
 // Close closes the gzip writer
@@ -125,7 +164,7 @@ func (w *gzipResponseWriter) Close() error {
 	}
 	w.closed = true
 
-	if w.shouldCompress && w.gzipWriter != nil {
+	if !w.hijacked && w.shouldCompress && w.gzipWriter != nil {
 		return w.gzipWriter.Close()
 	}
 	return nil
@@ -198,46 +237,9 @@ func (c *Compressor) Disable() *Compressor {
 // Handler returns an HTTP middleware handler for compression
 func (c *Compressor) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip if compression is disabled
-		if !c.config.Enabled {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Skip if client doesn't accept gzip
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Skip if already compressed
-		if w.Header().Get("Content-Encoding") != "" {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Create gzip writer
-		gz, err := gzip.NewWriterLevel(w, int(c.config.Level))
-		if err != nil {
-			next.ServeHTTP(w, r)
-			return
-		}
-		defer gz.Close()
-
-		// Wrap response writer
-		gzw := &gzipResponseWriter{
-			Writer:         gz,
-			ResponseWriter: w,
-			config:         c.config,
-			gzipWriter:     gz,
-			headerWritten:  false,
-			shouldCompress: false,
-			closed:         false,
-		}
-		defer gzw.Close()
-
-		// Call next handler
-		next.ServeHTTP(gzw, r)
+		cw, done := c.Wrap(w, r)
+		defer done()
+		next.ServeHTTP(cw, r)
 	})
 }
 
@@ -248,7 +250,8 @@ func (c *Compressor) HandlerFunc(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// Wrap wraps a response writer with compression support
+// Wrap wraps a response writer with compression support. The returned writer
+// supports the same optional interfaces (Flusher, Hijacker, ...) as w.
 func (c *Compressor) Wrap(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, func() error) {
 	// Skip if compression is disabled
 	if !c.config.Enabled {
@@ -265,6 +268,11 @@ func (c *Compressor) Wrap(w http.ResponseWriter, r *http.Request) (http.Response
 		return w, func() error { return nil }
 	}
 
+	// Upgrades (WebSocket) take over the connection; wrapping would only get in the way
+	if IsUpgradeRequest(r) {
+		return w, func() error { return nil }
+	}
+
 	// Create gzip writer
 	gz, err := gzip.NewWriterLevel(w, int(c.config.Level))
 	if err != nil {
@@ -282,7 +290,7 @@ func (c *Compressor) Wrap(w http.ResponseWriter, r *http.Request) (http.Response
 		closed:         false,
 	}
 
-	return gzw, gzw.Close
+	return comm.WrapResponseWriter(w, gzw), gzw.Close
 }
 
 // Utility functions
@@ -305,6 +313,22 @@ func IsCompressibleType(contentType string) bool {
 		strings.HasPrefix(mimeType, "application/xml")
 }
 
+// IsUpgradeRequest reports whether r asks to switch protocols (e.g. a
+// WebSocket handshake), whose response must not be compressed
+func IsUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for token := range strings.SplitSeq(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // AcceptsGzip checks if the request accepts gzip encoding
 func AcceptsGzip(r *http.Request) bool {
 	return strings.Contains(r.Header.Get("Accept-Encoding"), "gzip")
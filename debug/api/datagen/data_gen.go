@@ -6,16 +6,76 @@ import (
 	"time"
 )
 
+// FixtureEpoch is the reference time of seeded generators, so dates derived
+// from "days ago" are stable across runs
+var FixtureEpoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
 type DataGen struct {
 	rand *rand.Rand
+	seed int64
+	now  time.Time           // Reference time for past dates, zero = time.Now()
+	ids  map[string]struct{} // IDs handed out by NewID
 }
 
 func NewDataGen() *DataGen {
 	return &DataGen{
 		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+		ids:  make(map[string]struct{}),
+	}
+}
+
+// NewDataGenWithSeed creates a generator that produces the same data for the
+// same seed, with dates relative to FixtureEpoch instead of the current time
+func NewDataGenWithSeed(seed int64) *DataGen {
+	return &DataGen{
+		rand: rand.New(rand.NewSource(seed)),
+		seed: seed,
+		now:  FixtureEpoch,
+		ids:  make(map[string]struct{}),
 	}
 }
 
+// Seed returns the seed of a generator created by NewDataGenWithSeed (0 otherwise)
+func (dg *DataGen) Seed() int64 {
+	return dg.seed
+}
+
+// SetReferenceTime sets the time PastDate counts back from
+func (dg *DataGen) SetReferenceTime(t time.Time) *DataGen {
+	dg.now = t
+	return dg
+}
+
+// Now returns the reference time, time.Now() unless fixed by a seed or SetReferenceTime
+func (dg *DataGen) Now() time.Time {
+	if dg.now.IsZero() {
+		return time.Now()
+	}
+	return dg.now
+}
+
+// PastDate formats the reference time minus daysAgo, see GeneratePastDate
+func (dg *DataGen) PastDate(daysAgo int) string {
+	return dg.Now().AddDate(0, 0, -daysAgo).Format("2006-01-02 15:04:05")
+}
+
+// NewID returns prefix followed by 16 hex digits drawn from the generator.
+// IDs are unique per generator and follow from the seed.
+func (dg *DataGen) NewID(prefix string) string {
+	for {
+		id := fmt.Sprintf("%s%016x", prefix, dg.rand.Uint64())
+		if _, taken := dg.ids[id]; !taken {
+			dg.ids[id] = struct{}{}
+			return id
+		}
+	}
+}
+
+// ReserveID marks id as taken, e.g. for records imported from a fixture
+func (dg *DataGen) ReserveID(id string) {
+	dg.ids[id] = struct{}{}
+}
+
 // Random selection helpers
 func (dg *DataGen) RandomChoice(items []string) string {
 	if len(items) == 0 {
@@ -0,0 +1,79 @@
+package server_data
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+)
+
+// Fixture is a generated instance set saved as JSON, so UI tests can load
+// exactly the data they were written against
+type Fixture struct {
+	Seed      int64             `json:"seed,omitempty"`
+	Instances []*ServerInstance `json:"instances"`
+}
+
+// Instances returns a copy of the current instance list; the instances are
+// shared with the generator
+func (sdg *ServersDataGen) Instances() []*ServerInstance {
+	sdg.mu.RLock()
+	defer sdg.mu.RUnlock()
+	return slices.Clone(sdg.instances)
+}
+
+// ExportFixture writes the current instance set as JSON
+func (sdg *ServersDataGen) ExportFixture(w io.Writer) error {
+	sdg.mu.RLock()
+	defer sdg.mu.RUnlock()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&Fixture{Seed: sdg.Seed(), Instances: sdg.instances})
+}
+
+// ImportFixture replaces the instance set with one written by ExportFixture
+func (sdg *ServersDataGen) ImportFixture(r io.Reader) error {
+	var fixture Fixture
+	if err := json.NewDecoder(r).Decode(&fixture); err != nil {
+		return fmt.Errorf("decode fixture: %w", err)
+	}
+	sdg.mu.Lock()
+	defer sdg.mu.Unlock()
+	for _, inst := range fixture.Instances {
+		sdg.ReserveID(inst.ID)
+	}
+	sdg.setInstances(fixture.Instances)
+	return nil
+}
+
+// SaveFixture writes the current instance set to path
+func (sdg *ServersDataGen) SaveFixture(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := sdg.ExportFixture(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadFixture replaces the instance set with the fixture at path
+func (sdg *ServersDataGen) LoadFixture(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return sdg.ImportFixture(f)
+}
+
+// HandleFixtureRequest downloads the current instance set as a fixture
+func (sdg *ServersDataGen) HandleFixtureRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="servers-fixture.json"`)
+	sdg.ExportFixture(w)
+}
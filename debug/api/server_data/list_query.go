@@ -0,0 +1,152 @@
+package server_data
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ListQuery selects, orders and pages the rows of the list endpoint. It is
+// read from the query string:
+//
+//	?region=eu-west-1,us-east-1&state=running&q=web&sort=-CPUCores&offset=0&limit=50&columns=ID,Hostname
+type ListQuery struct {
+	Regions []string // Any of, from ?region=
+	Zones   []string // Any of, from ?zone=
+	States  []string // Any of, from ?state=
+	Types   []string // Any of the instance types of the filters endpoint, from ?type=
+	Search  string   // Case-insensitive hostname/ID substring, from ?q=
+	Sort    string   // Column name, "-" prefix for descending, from ?sort=
+	Offset  int
+	Limit   int      // 0 = all rows
+	Columns []string // Subset of columns to return, from ?columns=
+}
+
+// ParseListQuery reads a ListQuery from r's query string
+func ParseListQuery(r *http.Request) (*ListQuery, error) {
+	values := r.URL.Query()
+	lq := &ListQuery{
+		Regions: splitValues(values, "region"),
+		Zones:   splitValues(values, "zone"),
+		States:  splitValues(values, "state"),
+		Types:   values["type"], // Types contain commas themselves
+		Search:  strings.ToLower(strings.TrimSpace(values.Get("q"))),
+		Sort:    values.Get("sort"),
+		Columns: splitValues(values, "columns"),
+	}
+	var err error
+	if lq.Offset, err = parseCount(values, "offset"); err != nil {
+		return nil, err
+	}
+	if lq.Limit, err = parseCount(values, "limit"); err != nil {
+		return nil, err
+	}
+	return lq, nil
+}
+
+func splitValues(values url.Values, key string) []string {
+	var out []string
+	for _, v := range values[key] {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+func parseCount(values url.Values, key string) (int, error) {
+	s := values.Get(key)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s", key)
+	}
+	return n, nil
+}
+
+// Matches reports whether item passes the filters
+func (lq *ListQuery) Matches(item *InstanceListItem) bool {
+	if len(lq.Regions) > 0 && !slices.Contains(lq.Regions, item.Region) {
+		return false
+	}
+	if len(lq.Zones) > 0 && !slices.Contains(lq.Zones, item.Zone) {
+		return false
+	}
+	if len(lq.States) > 0 && !slices.Contains(lq.States, item.State) {
+		return false
+	}
+	if len(lq.Types) > 0 && !slices.Contains(lq.Types, instanceType(item)) {
+		return false
+	}
+	if lq.Search != "" &&
+		!strings.Contains(strings.ToLower(item.Hostname), lq.Search) &&
+		!strings.Contains(strings.ToLower(item.ID), lq.Search) {
+		return false
+	}
+	return true
+}
+
+// Apply filters, sorts and pages list into positional data. Total is the
+// number of matching rows before paging.
+func (sdg *ServersDataGen) Apply(lq *ListQuery, list []*InstanceListItem) (*ListResponse, error) {
+	matched := make([]*InstanceListItem, 0, len(list))
+	for _, item := range list {
+		if lq.Matches(item) {
+			matched = append(matched, item)
+		}
+	}
+	resp := sdg.transformToPositionalData(matched)
+	resp.Total = len(matched)
+
+	if lq.Sort != "" {
+		column, desc := strings.CutPrefix(lq.Sort, "-")
+		col := slices.Index(resp.Columns, column)
+		if col < 0 {
+			return nil, fmt.Errorf("unknown sort column %q", column)
+		}
+		slices.SortStableFunc(resp.Data, func(a, b []any) int {
+			c := compareCells(a[col], b[col])
+			if desc {
+				return -c
+			}
+			return c
+		})
+	}
+
+	start := min(lq.Offset, len(resp.Data))
+	end := len(resp.Data)
+	if lq.Limit > 0 {
+		end = start + min(lq.Limit, end-start) // start+Limit may overflow
+	}
+	resp.Data = resp.Data[start:end]
+	if len(lq.Columns) > 0 {
+		return resp.Select(lq.Columns...)
+	}
+	return resp, nil
+}
+
+// compareCells orders two positional values of the same column
+func compareCells(a, b any) int {
+	switch av := a.(type) {
+	case int:
+		bv, _ := b.(int)
+		return cmp.Compare(av, bv)
+	case string:
+		bv, _ := b.(string)
+		return strings.Compare(av, bv)
+	}
+	return 0
+}
+
+// instanceType formats the instance type offered by the filters endpoint
+func instanceType(item *InstanceListItem) string {
+	return fmt.Sprintf("%d vCPU, %d GB RAM", item.CPUCores, item.RAMTotalGB)
+}
@@ -0,0 +1,164 @@
+package server_data
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	hl1 "github.com/go-xlite/wbx/utils"
+	"github.com/gorilla/mux"
+)
+
+// DefaultChangeEvent is the SSE event name of instance change notifications
+const DefaultChangeEvent = "servers"
+
+// Change types sent in InstanceChange.Type
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// Broadcaster delivers named events to connected clients; WebCast and
+// SSEHandler implement it
+type Broadcaster interface {
+	BroadcastEvent(event string, data any) (int, error)
+}
+
+// InstanceChange is broadcast after every mutation. Item is the list row of
+// the instance (nil for deletions), in the same shape as the list endpoint.
+type InstanceChange struct {
+	Type string            `json:"type"`
+	ID   string            `json:"id"`
+	Item *InstanceListItem `json:"item,omitempty"`
+}
+
+// SetChangeBroadcaster sends an InstanceChange as event (DefaultChangeEvent
+// when empty) through b after every create, update and delete
+func (sdg *ServersDataGen) SetChangeBroadcaster(b Broadcaster, event string) *ServersDataGen {
+	if event == "" {
+		event = DefaultChangeEvent
+	}
+	sdg.mu.Lock()
+	defer sdg.mu.Unlock()
+	sdg.changes = b
+	sdg.changeEvent = event
+	return sdg
+}
+
+// HandleCreateRequest adds an instance (POST). Fields missing from the JSON
+// body are generated; the ID is always assigned by the server.
+func (sdg *ServersDataGen) HandleCreateRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sdg.mu.Lock()
+	inst := sdg.GenerateSingleInstance(len(sdg.instances))
+	id := inst.ID
+	if err := json.NewDecoder(r.Body).Decode(inst); err != nil {
+		sdg.mu.Unlock()
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	inst.ID = id
+	sdg.setInstances(append(slices.Clone(sdg.instances), inst))
+	sdg.mu.Unlock()
+
+	sdg.notify(ChangeCreated, inst)
+	hl1.Helpers.WriteJSON(w, http.StatusCreated, inst)
+}
+
+// HandleInstanceRequest updates (PATCH, merging the JSON body into the
+// instance) or removes (DELETE) the instance named by the {id} route variable
+func (sdg *ServersDataGen) HandleInstanceRequest(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	switch r.Method {
+	case http.MethodPatch:
+		sdg.update(w, r, id)
+	case http.MethodDelete:
+		sdg.delete(w, id)
+	default:
+		w.Header().Set("Allow", "PATCH, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (sdg *ServersDataGen) update(w http.ResponseWriter, r *http.Request, id string) {
+	sdg.mu.Lock()
+	i := sdg.indexOf(id)
+	if i < 0 {
+		sdg.mu.Unlock()
+		http.Error(w, "Instance not found", http.StatusNotFound)
+		return
+	}
+
+	// Merge into a copy so a bad body leaves the instance untouched
+	updated, err := cloneInstance(sdg.instances[i])
+	if err == nil {
+		err = json.NewDecoder(r.Body).Decode(updated)
+	}
+	if err != nil {
+		sdg.mu.Unlock()
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	updated.ID = id
+	instances := slices.Clone(sdg.instances)
+	instances[i] = updated
+	sdg.setInstances(instances)
+	sdg.mu.Unlock()
+
+	sdg.notify(ChangeUpdated, updated)
+	hl1.Helpers.WriteJSON(w, http.StatusOK, updated)
+}
+
+func (sdg *ServersDataGen) delete(w http.ResponseWriter, id string) {
+	sdg.mu.Lock()
+	i := sdg.indexOf(id)
+	if i < 0 {
+		sdg.mu.Unlock()
+		http.Error(w, "Instance not found", http.StatusNotFound)
+		return
+	}
+	removed := sdg.instances[i]
+	sdg.setInstances(slices.Delete(slices.Clone(sdg.instances), i, i+1))
+	sdg.mu.Unlock()
+
+	sdg.notify(ChangeDeleted, removed)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// indexOf returns the position of instance id, or -1; sdg.mu must be held
+func (sdg *ServersDataGen) indexOf(id string) int {
+	return slices.IndexFunc(sdg.instances, func(inst *ServerInstance) bool {
+		return inst.ID == id
+	})
+}
+
+// notify broadcasts a change to the configured broadcaster, if any
+func (sdg *ServersDataGen) notify(changeType string, inst *ServerInstance) {
+	sdg.mu.RLock()
+	b, event := sdg.changes, sdg.changeEvent
+	sdg.mu.RUnlock()
+	if b == nil {
+		return
+	}
+	change := InstanceChange{Type: changeType, ID: inst.ID}
+	if changeType != ChangeDeleted {
+		change.Item = sdg.transformToListView([]*ServerInstance{inst})[0]
+	}
+	b.BroadcastEvent(event, change)
+}
+
+// cloneInstance deep-copies inst through its JSON form
+func cloneInstance(inst *ServerInstance) (*ServerInstance, error) {
+	data, err := json.Marshal(inst)
+	if err != nil {
+		return nil, err
+	}
+	clone := &ServerInstance{}
+	return clone, json.Unmarshal(data, clone)
+}
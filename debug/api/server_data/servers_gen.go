@@ -3,6 +3,7 @@ package server_data
 import (
 	"fmt"
 	"net/http"
+	"sync"
 
 	datagen "github.com/go-xlite/wbx/debug/api/datagen"
 	hl1 "github.com/go-xlite/wbx/utils"
@@ -113,13 +114,14 @@ type ServersDataGen struct {
 	instances    []*ServerInstance
 	instanceList []*InstanceListItem
 	listData     *ListResponse
+	mu           sync.RWMutex // Guards the instance set against the mutation endpoints
+
+	changes     Broadcaster // Receives change events, see SetChangeBroadcaster
+	changeEvent string
 }
 
 // ListResponse contains column mapping and positional data
-type ListResponse struct {
-	Columns []string `json:"columns"`
-	Data    [][]any  `json:"data"`
-}
+type ListResponse = hl1.PositionalList
 
 func NewServersDataGen() *ServersDataGen {
 	return &ServersDataGen{
@@ -127,9 +129,24 @@ func NewServersDataGen() *ServersDataGen {
 	}
 }
 
+// NewServersDataGenWithSeed creates a generator producing the same instances
+// for the same seed, for stable UI tests
+func NewServersDataGenWithSeed(seed int64) *ServersDataGen {
+	return &ServersDataGen{
+		DataGen: datagen.NewDataGenWithSeed(seed),
+	}
+}
+
 // Initialize generates all instances and prepares the optimized list
 func (sdg *ServersDataGen) Initialize(count int) {
-	sdg.instances = sdg.GenerateInstances(count)
+	sdg.mu.Lock()
+	defer sdg.mu.Unlock()
+	sdg.setInstances(sdg.GenerateInstances(count))
+}
+
+// setInstances replaces the instance set and rebuilds the list views; sdg.mu must be held
+func (sdg *ServersDataGen) setInstances(instances []*ServerInstance) {
+	sdg.instances = instances
 	sdg.instanceList = sdg.transformToListView(sdg.instances)
 	sdg.listData = sdg.transformToPositionalData(sdg.instanceList)
 }
@@ -173,45 +190,38 @@ func (sdg *ServersDataGen) transformToListView(instances []*ServerInstance) []*I
 
 // transformToPositionalData converts list items to positional array format
 func (sdg *ServersDataGen) transformToPositionalData(list []*InstanceListItem) *ListResponse {
-	columns := []string{
-		"ID", "Hostname", "State", "Region", "Zone",
-		"LaunchedAt", "Uptime", "CPUCores", "RAMTotalGB",
-		"PublicIPv4", "PrivateIPv4",
-	}
-
-	data := make([][]any, 0, len(list))
-	for _, item := range list {
-		row := []any{
-			item.ID,
-			item.Hostname,
-			item.State,
-			item.Region,
-			item.Zone,
-			item.LaunchedAt,
-			item.Uptime,
-			item.CPUCores,
-			item.RAMTotalGB,
-			item.PublicIPv4,
-			item.PrivateIPv4,
-		}
-		data = append(data, row)
-	}
-
-	return &ListResponse{
-		Columns: columns,
-		Data:    data,
-	}
+	data, _ := hl1.PositionalFromStructs(list)
+	return data
 }
 
-// HandleListRequest returns the optimized list view
+// HandleListRequest returns the optimized list view, filtered, sorted and
+// paged by the query parameters when present, see ListQuery
 func (sdg *ServersDataGen) HandleListRequest(w http.ResponseWriter, r *http.Request) {
-	hl1.Helpers.WriteJSON(w, http.StatusOK, sdg.listData)
+	sdg.mu.RLock()
+	defer sdg.mu.RUnlock()
+	if r.URL.RawQuery == "" {
+		hl1.Helpers.WriteJSON(w, http.StatusOK, sdg.listData)
+		return
+	}
+	lq, err := ParseListQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp, err := sdg.Apply(lq, sdg.instanceList)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hl1.Helpers.WriteJSON(w, http.StatusOK, resp)
 }
 
 // HandleDetailsRequest returns full instance data by ID
 func (sdg *ServersDataGen) HandleDetailsRequest(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
+	sdg.mu.RLock()
+	defer sdg.mu.RUnlock()
 	for _, inst := range sdg.instances {
 		if inst.ID == id {
 			hl1.Helpers.WriteJSON(w, http.StatusOK, inst)
@@ -236,6 +246,8 @@ func (sdg *ServersDataGen) HandleFiltersRequest(w http.ResponseWriter, r *http.R
 	statesMap := make(map[string]bool)
 	typesMap := make(map[string]bool)
 
+	sdg.mu.RLock()
+	defer sdg.mu.RUnlock()
 	for _, inst := range sdg.instanceList {
 		if inst.Region != "" {
 			regionsMap[inst.Region] = true
@@ -248,8 +260,7 @@ func (sdg *ServersDataGen) HandleFiltersRequest(w http.ResponseWriter, r *http.R
 		}
 		// Generate instance type string
 		if inst.CPUCores > 0 && inst.RAMTotalGB > 0 {
-			instanceType := fmt.Sprintf("%d vCPU, %d GB RAM", inst.CPUCores, inst.RAMTotalGB)
-			typesMap[instanceType] = true
+			typesMap[instanceType(inst)] = true
 		}
 	}
 
@@ -287,7 +298,7 @@ func (sdg *ServersDataGen) GenerateInstanceList(count int) []*ServerInstanceList
 		region := sdg.DataGen.RandomChoice(datagen.DatacenterRegions)
 		zone := sdg.DataGen.RandomChoice(datagen.DatacenterZones)
 		hostname := fmt.Sprintf("i-%s-%s-%04d", region, zone, sdg.DataGen.RandomInt(1000, 9999))
-		id := fmt.Sprintf("i-%016x", sdg.DataGen.RandomInt(100000000, 999999999))
+		id := sdg.DataGen.NewID("i-")
 
 		records = append(records, &ServerInstanceListItem{
 			ID:       id,
@@ -302,11 +313,11 @@ func (sdg *ServersDataGen) GenerateSingleInstance(seed int) *ServerInstance {
 	region := sdg.DataGen.RandomChoice(datagen.DatacenterRegions)
 	zone := sdg.DataGen.RandomChoice(datagen.DatacenterZones)
 	hostname := fmt.Sprintf("i-%s-%s-%04d", region, zone, sdg.DataGen.RandomInt(1000, 9999))
-	id := fmt.Sprintf("i-%016x", sdg.DataGen.RandomInt(100000000, 999999999))
+	id := sdg.DataGen.NewID("i-")
 
 	// Generate launch time (random time in the past 365 days)
 	daysAgo := sdg.DataGen.RandomInt(0, 365)
-	launchedAt := datagen.GeneratePastDate(daysAgo)
+	launchedAt := sdg.DataGen.PastDate(daysAgo)
 	uptime := datagen.GenerateUptime(daysAgo)
 
 	return &ServerInstance{
@@ -6,6 +6,9 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/go-xlite/wbx/comm"
+	"github.com/go-xlite/wbx/comm/audit"
+	"github.com/go-xlite/wbx/services/webauth"
 	"github.com/go-xlite/wbx/weblite"
 )
 
@@ -21,6 +24,7 @@ type AuthService struct {
 	users          map[string]*User
 	mu             sync.RWMutex
 	sessionManager *weblite.SessionManager
+	webAuth        *webauth.WebAuth // Shapes responses (form redirects, customizer)
 }
 
 func NewWebAuthService() *AuthService {
@@ -35,6 +39,22 @@ func (s *AuthService) SetSessionManager(sm *weblite.SessionManager) *AuthService
 	return s
 }
 
+// SetWebAuth sets the WebAuth whose Respond shapes login responses, so form
+// logins get redirects and its ResponseCustomizer applies
+func (s *AuthService) SetWebAuth(wt *webauth.WebAuth) *AuthService {
+	s.webAuth = wt
+	return s
+}
+
+// respond reports result through the WebAuth, or as plain JSON without one
+func (s *AuthService) respond(w http.ResponseWriter, r *http.Request, result *webauth.AuthResult) {
+	wt := s.webAuth
+	if wt == nil {
+		wt = webauth.NewWebAuth()
+	}
+	wt.Respond(w, r, result)
+}
+
 // AddUser adds a user to the auth service
 func (s *AuthService) AddUser(username, password, role string) *AuthService {
 	s.mu.Lock()
@@ -62,17 +82,14 @@ func (s *AuthService) ValidateCredentials(username, password string) (*User, boo
 // Login handles user login
 func (s *AuthService) Login(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		s.respond(w, r, &webauth.AuthResult{Action: "login", Status: http.StatusMethodNotAllowed, Error: "method_not_allowed", Message: "method not allowed"})
 		return
 	}
 
-	var req struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	// JSON body or form fields (the Sway login page posts a form)
+	req, err := webauth.ReadCredentials(r)
+	if err != nil {
+		s.respond(w, r, &webauth.AuthResult{Action: "login", Status: http.StatusBadRequest, Error: "invalid_request", Message: "invalid request body"})
 		return
 	}
 
@@ -80,36 +97,39 @@ func (s *AuthService) Login(w http.ResponseWriter, r *http.Request) {
 	req.Password = strings.TrimSpace(req.Password)
 
 	if req.Username == "" || req.Password == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "username and password required"})
+		s.respond(w, r, &webauth.AuthResult{Action: "login", Status: http.StatusBadRequest, Error: "missing_credentials", Message: "username and password required"})
 		return
 	}
 
 	user, valid := s.ValidateCredentials(req.Username, req.Password)
 	if !valid {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid credentials"})
+		s.respond(w, r, &webauth.AuthResult{Action: "login", Error: "invalid_credentials", Message: "invalid credentials", Actor: req.Username})
 		return
 	}
 
 	// Issue session token
 	if s.sessionManager != nil && s.sessionManager.Service != nil {
 		sessionData := map[string]interface{}{
-			"user_id":  user.Username,
-			"username": user.Username,
-			"role":     user.Role,
+			"user_id":    user.Username,
+			"username":   user.Username,
+			"role":       user.Role,
+			"ip":         comm.ClientIP(r),
+			"user_agent": r.UserAgent(),
 		}
 		token, err := s.sessionManager.Service.Issue(sessionData)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create session"})
+			s.respond(w, r, &webauth.AuthResult{Action: "login", Status: http.StatusInternalServerError, Error: "session_failed", Message: "failed to create session", Actor: user.Username})
 			return
 		}
-		// Set session cookie (24 hours)
-		s.sessionManager.SetCookieWithExpiry(w, token, 86400)
+		// Set the session cookie of the app scope the request belongs to (24 hours)
+		s.sessionManager.SetCookieWithExpiryFor(w, r, token, 86400)
 	}
 
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"success":  true,
-		"username": user.Username,
-		"role":     user.Role,
+	s.respond(w, r, &webauth.AuthResult{
+		Action:  "login",
+		Success: true,
+		Data:    map[string]any{"username": user.Username, "role": user.Role},
+		Actor:   user.Username,
 	})
 }
 
@@ -120,14 +140,11 @@ func (s *AuthService) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get session cookie and revoke it
+	// Revoke the session behind the cookie (records the auth.logout audit event)
 	if s.sessionManager != nil {
-		if cookie, err := r.Cookie(s.sessionManager.CookieName); err == nil {
-			if s.sessionManager.Service != nil {
-				s.sessionManager.Service.Revoke(cookie.Value)
-			}
-		}
-		s.sessionManager.ClearCookie(w)
+		s.sessionManager.Logout(w, r, "")
+	} else {
+		audit.EmitRequest(r, audit.TypeLogout, audit.OutcomeSuccess, "", nil)
 	}
 
 	writeJSON(w, http.StatusOK, map[string]string{"success": "logged out"})
@@ -145,7 +162,7 @@ func (s *AuthService) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cookie, err := r.Cookie(s.sessionManager.CookieName)
+	cookie, err := r.Cookie(s.sessionManager.CookieNameFor(r))
 	if err != nil {
 		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "no session"})
 		return
@@ -153,12 +170,12 @@ func (s *AuthService) RefreshToken(w http.ResponseWriter, r *http.Request) {
 
 	newToken, err := s.sessionManager.Service.Refresh(cookie.Value)
 	if err != nil {
-		s.sessionManager.ClearCookie(w)
+		s.sessionManager.ClearCookieFor(w, r)
 		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "session expired"})
 		return
 	}
 
-	s.sessionManager.SetCookieWithExpiry(w, newToken, 86400)
+	s.sessionManager.SetCookieWithExpiryFor(w, r, newToken, 86400)
 	writeJSON(w, http.StatusOK, map[string]string{"success": "token refreshed"})
 }
 
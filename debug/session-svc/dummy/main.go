@@ -4,8 +4,12 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/go-xlite/wbx/comm"
+	"github.com/go-xlite/wbx/services/webauth"
 )
 
 // SessionData represents the data stored in a session
@@ -16,6 +20,11 @@ type SessionData struct {
 	CreatedAt time.Time
 	ExpiresAt time.Time
 	Data      map[string]interface{}
+
+	ID        string // Stable identifier exposed by session listings
+	LastSeen  time.Time
+	IP        string
+	UserAgent string
 }
 
 // MySessionService is a non-persistent in-memory session service
@@ -44,8 +53,8 @@ func (s *MySessionService) SetTTL(ttl time.Duration) *MySessionService {
 }
 
 func (s *MySessionService) Validate(token string) (any, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	session, exists := s.sessions[token]
 	if !exists {
@@ -57,6 +66,7 @@ func (s *MySessionService) Validate(token string) (any, error) {
 		return nil, errors.New("session expired")
 	}
 
+	session.LastSeen = time.Now()
 	return session, nil
 }
 
@@ -66,11 +76,18 @@ func (s *MySessionService) Issue(data any) (string, error) {
 		return "", err
 	}
 
+	id, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
 	now := time.Now()
 	sessionData := &SessionData{
 		CreatedAt: now,
 		ExpiresAt: now.Add(s.ttl),
 		Data:      make(map[string]interface{}),
+		ID:        id[:16],
+		LastSeen:  now,
 	}
 
 	// If data is provided, extract common fields
@@ -84,6 +101,12 @@ func (s *MySessionService) Issue(data any) (string, error) {
 		if email, ok := dataMap["email"].(string); ok {
 			sessionData.Email = email
 		}
+		if ip, ok := dataMap["ip"].(string); ok {
+			sessionData.IP = ip
+		}
+		if userAgent, ok := dataMap["user_agent"].(string); ok {
+			sessionData.UserAgent = userAgent
+		}
 		sessionData.Data = dataMap
 	}
 
@@ -123,6 +146,10 @@ func (s *MySessionService) Refresh(token string) (string, error) {
 		CreatedAt: time.Now(),
 		ExpiresAt: time.Now().Add(s.ttl),
 		Data:      session.Data,
+		ID:        session.ID,
+		LastSeen:  time.Now(),
+		IP:        session.IP,
+		UserAgent: session.UserAgent,
 	}
 
 	// Store new session and remove old one
@@ -151,6 +178,62 @@ func (s *MySessionService) GetSessionCount() int {
 	return len(s.sessions)
 }
 
+// LookupSession resolves a token to its session info (webauth.SessionDirectory)
+func (s *MySessionService) LookupSession(token string) (webauth.SessionInfo, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, exists := s.sessions[token]
+	if !exists || time.Now().After(session.ExpiresAt) {
+		return webauth.SessionInfo{}, false
+	}
+	return session.info(), true
+}
+
+// ListUserSessions returns the active sessions of userID, most recently seen first
+func (s *MySessionService) ListUserSessions(userID string) ([]webauth.SessionInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	list := []webauth.SessionInfo{}
+	for _, session := range s.sessions {
+		if session.UserID == userID && now.Before(session.ExpiresAt) {
+			list = append(list, session.info())
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].LastSeen.After(list[j].LastSeen) })
+	return list, nil
+}
+
+// RevokeUserSessions revokes all sessions of userID except exceptID and
+// returns the session keys of the revoked tokens
+func (s *MySessionService) RevokeUserSessions(userID, exceptID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var revoked []string
+	for token, session := range s.sessions {
+		if session.UserID == userID && session.ID != exceptID {
+			delete(s.sessions, token)
+			revoked = append(revoked, comm.SessionKeyFor(token))
+		}
+	}
+	return revoked, nil
+}
+
+func (d *SessionData) info() webauth.SessionInfo {
+	return webauth.SessionInfo{
+		ID:        d.ID,
+		UserID:    d.UserID,
+		CreatedAt: d.CreatedAt,
+		LastSeen:  d.LastSeen,
+		IP:        d.IP,
+		UserAgent: d.UserAgent,
+		Device:    webauth.DeviceLabel(d.UserAgent),
+	}
+}
+
 // cleanupExpired removes expired sessions periodically
 func (s *MySessionService) cleanupExpired() {
 	ticker := time.NewTicker(5 * time.Minute)
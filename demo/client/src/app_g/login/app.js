@@ -80,8 +80,9 @@ loginForm.addEventListener('submit', async (e) => {
         if (result.success) {
             showSuccess(`Welcome, ${result.username}! Redirecting...`);
             setTimeout(() => {
-                console.log("PATH PREFIX:", window.PATH_PREFIX);
-                window.location.href = '/w/' + window.PATH_PREFIX.split('/').pop() + '/home/';
+                // Back to the page that required the login, else the app home
+                const home = '/w/' + window.PATH_PREFIX.split('/').pop() + '/home/';
+                window.location.href = result.redirect && result.redirect !== '/' ? result.redirect : home;
             }, 1000);
         } else {
             showError(result.error || 'Login failed');
@@ -12,11 +12,14 @@ export class AuthManager {
      * Login with username and password
      * @param {string} username 
      * @param {string} password 
-     * @returns {Promise<{success: boolean, username?: string, role?: string, error?: string}>}
+     * @returns {Promise<{success: boolean, username?: string, role?: string, redirect?: string, error?: string}>}
      */
     async login(username, password) {
         try {
-            const response = await fetch(`${this.baseUrl}/login`, {
+            // Pass the return-to URL on so the server answers with where to go next
+            const next = new URLSearchParams(window.location.search).get('next');
+            const query = next ? `?next=${encodeURIComponent(next)}` : '';
+            const response = await fetch(`${this.baseUrl}/login${query}`, {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
@@ -28,11 +31,12 @@ export class AuthManager {
             const data = await response.json();
 
             if (response.ok && data.success) {
-                this._notifyAuthChange(true, data);
-                return { success: true, username: data.username, role: data.role };
+                const user = data.data || {};
+                this._notifyAuthChange(true, user);
+                return { success: true, username: user.username, role: user.role, redirect: data.redirect };
             }
 
-            return { success: false, error: data.error || 'Login failed' };
+            return { success: false, error: data.message || data.error || 'Login failed' };
         } catch (err) {
             return { success: false, error: err.message || 'Network error' };
         }
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"log"
+	"net/http"
 	"time"
 
 	rtx "github.com/go-xlite/rtx"
@@ -19,6 +20,7 @@ import (
 	webapp "github.com/go-xlite/wbx/roots/webapp"
 	servers "github.com/go-xlite/wbx/services"
 	auth "github.com/go-xlite/wbx/services/webauth"
+	"github.com/go-xlite/wbx/services/webproxy"
 	"github.com/go-xlite/wbx/services/websock"
 	"github.com/go-xlite/wbx/weblite"
 )
@@ -53,15 +55,17 @@ func main() {
 		"addresses":           "0.0.0.0,[::]",
 		"https_redirect_port": "8080",
 	})
-	server.AddPortListener(map[string]string{
-		"protocol":        "https",
-		"ports":           "8080",
-		"addresses":       "0.0.0.0,[::]",
-		"ssl_cert_path":   "../../certs/cert",
-		"ssl_key_path":    "../../certs/priv",
-		"domains_allowed": "localhost,pong.gtn.one",
-		"enable_http3":    "true",
-	})
+	// HTTP/3 is enabled by the http3 build tag
+	if err := server.AddPortListenerConfig(weblite.PortListenerConfig{
+		Protocol:       "https",
+		Ports:          []string{"8080"},
+		Addresses:      []string{"0.0.0.0", "::"},
+		SSLCertPath:    "../../certs/cert",
+		SSLKeyPath:     "../../certs/priv",
+		AllowedDomains: []string{"localhost", "pong.gtn.one"},
+	}); err != nil {
+		log.Fatal(err)
+	}
 
 	// === Server-Sent Events (SSE) ===
 	// Create webcast server for SSE connections
@@ -69,8 +73,7 @@ func main() {
 	// Create SSE handler
 	sseHandler := handlers.NewSSEHandler(sseServer)
 	sseHandler.SetPathPrefix("/w/xt23/sse")
-	server.GetRoutes().HandlePathPrefixFn(sseHandler.PathPrefix.Get(), sseServer.OnRequest)
-	sseHandler.Init()
+	sseHandler.Mount(server)
 	// Start dummy SSE event streamer
 	debugsse.StartDummyStreamer(sseHandler, 3*time.Second)
 
@@ -89,8 +92,10 @@ func main() {
 	// === Webproxy (Reverse Proxy) ===
 	// Create webproxy server pointing to external service
 	proxyServer, _ := servers.NewWebProxy("https://file-drop.gtn.one:8080/xt21/")
+	proxyServer.SetMaxRequestBody(512 << 20) // Uploads stream through, capped at 512MB
 	proxyHandler := handlers.NewProxyHandler(proxyServer)
 	proxyHandler.SetPathPrefix("/s/xt23/proxy")
+	proxyHandler.SetHeaderPolicy(webproxy.DefaultHeaderPolicy())
 	server.GetRoutes().HandlePathPrefixFn(proxyHandler.PathPrefix.Get(), proxyHandler.HandleProxy())
 
 	// === WebSocket Handler ===
@@ -129,6 +134,10 @@ func main() {
 	wbtServersApi.GetRoutes().HandlePathFn("/servers/a/list", serversData.HandleListRequest)
 	wbtServersApi.GetRoutes().HandlePathFn("/servers/i/{id}/details", serversData.HandleDetailsRequest)
 	wbtServersApi.GetRoutes().HandlePathFn("/servers/a/filters", serversData.HandleFiltersRequest)
+	wbtServersApi.GetRoutes().HandlePathFn("/servers/a/fixture", serversData.HandleFixtureRequest)
+	wbtServersApi.GetRoutes().HandlePathFn("/servers/a/instances", serversData.HandleCreateRequest)
+	wbtServersApi.GetRoutes().HandlePathFn("/servers/i/{id}", serversData.HandleInstanceRequest)
+	serversData.SetChangeBroadcaster(sseHandler, "")
 
 	apiHandler := wbx.NewApiHandler(wbtServersApi)
 	apiHandler.SetPathPrefix("/a/xt23/trail")
@@ -142,9 +151,11 @@ func main() {
 
 	authServer := auth.NewWebAuth()
 	authServer.Auth = authSvc
+	authSvc.SetWebAuth(authServer)
 	authServer.Init()
 
-	authHandler := handler_auth.NewAuthHandler(authServer)
+	authHandler := handler_auth.NewAuthHandler(authServer).
+		SetSessionDirectory(sess_svc, sessionMgr)
 	authHandler.SetPathPrefix("/g/xt23/auth")
 	authHandler.Run()
 
@@ -169,6 +180,16 @@ func main() {
 	swayHandlerG.SetPathPrefix("/g/xt23")
 	swayHandlerG.Run(server)
 
+	// === Admin Dashboard ===
+	wbx.EnableAdminUI(server, "/_admin", func(r *http.Request) bool {
+		_, ok := weblite.GetSessionContext(r.Context())
+		return ok
+	}).
+		AddSource("sse", func() any { return sseHandler.GetStats() }).
+		AddSource("ws", func() any { return wsServer.GetStats() }).
+		AddSource("proxy", func() any { return proxyServer.GetStats() }).
+		AddSource("sessions", func() any { return sess_svc.GetSessionCount() })
+
 	clr := clientroot.NewClientRoot()
 	app := webapp.NewWebApp()
 	app.Fs = clr.Content
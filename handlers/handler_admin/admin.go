@@ -0,0 +1,175 @@
+package handleradmin
+
+import (
+	"embed"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	embed_fs "github.com/go-xlite/wbx/comm/adapter_fs/embed_fs"
+	"github.com/go-xlite/wbx/services/webcast"
+	"github.com/go-xlite/wbx/services/websway"
+	hl1 "github.com/go-xlite/wbx/utils"
+	"github.com/go-xlite/wbx/weblite"
+)
+
+//go:embed ui/*
+var content embed.FS
+
+// DefaultInterval is how often the dashboard stream pushes a fresh snapshot
+const DefaultInterval = 2 * time.Second
+
+// AdminUI is an embedded ops dashboard showing server state, registered
+// routes and any stats sources added with AddSource. Snapshots are pushed
+// to the browser over SSE while at least one dashboard is open.
+//
+// Endpoints (all behind Guard):
+//
+//	{prefix}/              dashboard page
+//	{prefix}/api/snapshot  current snapshot as JSON
+//	{prefix}/api/routes    registered routes as JSON
+//	{prefix}/api/stream    snapshot stream (SSE)
+type AdminUI struct {
+	Prefix   string
+	Guard    func(r *http.Request) bool // nil allows every request
+	Interval time.Duration
+
+	server  *weblite.WebLite
+	sway    *websway.WebSway
+	cast    *webcast.WebCast
+	sources map[string]func() any
+	mu      sync.RWMutex
+	pushing atomic.Bool
+}
+
+// EnableAdminUI mounts the dashboard on server under prefix (e.g. "/_admin").
+// guard is consulted for every dashboard request; pass nil only on trusted networks.
+func EnableAdminUI(server *weblite.WebLite, prefix string, guard func(r *http.Request) bool) *AdminUI {
+	prefix = "/" + strings.Trim(prefix, "/")
+
+	fsProvider := embed_fs.NewEmbedFS(&content)
+	fsProvider.SetBasePath("ui")
+	sway := websway.NewWebSway()
+	sway.FsProvider = fsProvider
+	sway.CacheMaxAge = 0
+
+	ui := &AdminUI{
+		Prefix:   prefix,
+		Guard:    guard,
+		Interval: DefaultInterval,
+		server:   server,
+		sway:     sway,
+		cast:     webcast.NewWebCast(),
+		sources:  make(map[string]func() any),
+	}
+
+	routes := server.GetRoutes()
+	routes.HandlePathFn(prefix, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, prefix+"/", http.StatusFound)
+	})
+	routes.GETPathFn(prefix+"/api/snapshot", ui.guarded(func(w http.ResponseWriter, r *http.Request) {
+		hl1.Helpers.WriteJSON(w, http.StatusOK, ui.Snapshot())
+	}))
+	routes.GETPathFn(prefix+"/api/routes", ui.guarded(func(w http.ResponseWriter, r *http.Request) {
+		hl1.Helpers.WriteJSON(w, http.StatusOK, server.GetRoutes().GetRoutes())
+	}))
+	routes.GETPathFn(prefix+"/api/stream", ui.guarded(ui.handleStream))
+	routes.ForwardPathPrefixFn(prefix+"/", ui.guarded(sway.ServeFile))
+	return ui
+}
+
+// AddSource adds a named stats section to every snapshot, e.g.
+// AddSource("sse", sseHandler.GetStats). fn must be safe for concurrent use.
+func (ui *AdminUI) AddSource(name string, fn func() any) *AdminUI {
+	ui.mu.Lock()
+	ui.sources[name] = fn
+	ui.mu.Unlock()
+	return ui
+}
+
+// SetInterval sets how often snapshots are pushed to open dashboards
+func (ui *AdminUI) SetInterval(d time.Duration) *AdminUI {
+	ui.Interval = d
+	return ui
+}
+
+// Snapshot collects server state and all sources
+func (ui *AdminUI) Snapshot() map[string]any {
+	ui.mu.RLock()
+	names := make([]string, 0, len(ui.sources))
+	for name := range ui.sources {
+		names = append(names, name)
+	}
+	sources := make(map[string]any, len(names))
+	for _, name := range names {
+		sources[name] = ui.sources[name]()
+	}
+	ui.mu.RUnlock()
+	sort.Strings(names)
+
+	return map[string]any{
+		"type":      "snapshot",
+		"timestamp": time.Now().Format(time.RFC3339),
+		"server": map[string]any{
+			"name":      ui.server.Name,
+			"running":   ui.server.IsRunning(),
+			"addresses": ui.server.GetAddr(),
+			"listeners": len(ui.server.PortListeners),
+			"routes":    len(ui.server.GetRoutes().GetRoutes()),
+			"clients":   ui.cast.GetClientCount(),
+		},
+		"order":   names,
+		"sources": sources,
+	}
+}
+
+// guarded rejects requests the Guard does not allow
+func (ui *AdminUI) guarded(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ui.Guard != nil && !ui.Guard(r) {
+			hl1.Helpers.WriteJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleStream streams snapshots to one dashboard
+func (ui *AdminUI) handleStream(w http.ResponseWriter, r *http.Request) {
+	ui.cast.StreamToClient(webcast.StreamConfig{
+		ClientID: fmt.Sprintf("admin_%d", time.Now().UnixNano()),
+		W:        w,
+		R:        r,
+		OnConnect: func(clientID string) {
+			ui.cast.SendJSONToClient(clientID, ui.Snapshot())
+			if ui.pushing.CompareAndSwap(false, true) {
+				go ui.push()
+			}
+		},
+	})
+}
+
+// push broadcasts snapshots until the last dashboard disconnects
+func (ui *AdminUI) push() {
+	interval := ui.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if ui.cast.GetClientCount() == 0 {
+			ui.pushing.Store(false)
+			// A dashboard may have connected between the check and the store
+			if ui.cast.GetClientCount() == 0 || !ui.pushing.CompareAndSwap(false, true) {
+				return
+			}
+		}
+		ui.cast.BroadcastJSON(ui.Snapshot())
+	}
+}
@@ -0,0 +1,104 @@
+(function () {
+  'use strict';
+
+  // Dashboard pages live at {prefix}/, the API next to them
+  var base = location.pathname.replace(/[^/]*$/, '');
+  var routes = [];
+
+  function text(value) {
+    if (value === null || value === undefined) return '';
+    if (typeof value === 'object') return JSON.stringify(value, null, 2);
+    return String(value);
+  }
+
+  function cell(tag, value) {
+    var el = document.createElement(tag);
+    if (typeof value === 'object' && value !== null) {
+      var pre = document.createElement('pre');
+      pre.textContent = text(value);
+      el.appendChild(pre);
+    } else {
+      el.textContent = text(value);
+    }
+    return el;
+  }
+
+  function fillTable(table, data) {
+    table.textContent = '';
+    Object.keys(data || {}).forEach(function (key) {
+      var row = document.createElement('tr');
+      row.appendChild(cell('th', key));
+      row.appendChild(cell('td', data[key]));
+      table.appendChild(row);
+    });
+  }
+
+  function renderSources(snapshot) {
+    var container = document.getElementById('sources');
+    container.textContent = '';
+    (snapshot.order || []).forEach(function (name) {
+      var section = document.createElement('section');
+      var title = document.createElement('h2');
+      title.textContent = name;
+      section.appendChild(title);
+
+      var value = snapshot.sources[name];
+      if (value !== null && typeof value === 'object' && !Array.isArray(value)) {
+        var table = document.createElement('table');
+        fillTable(table, value);
+        section.appendChild(table);
+      } else {
+        section.appendChild(cell('div', value));
+      }
+      container.appendChild(section);
+    });
+  }
+
+  function render(snapshot) {
+    if (!snapshot || snapshot.type !== 'snapshot') return;
+    document.getElementById('server-name').textContent = 'wbx admin - ' + snapshot.server.name;
+    fillTable(document.getElementById('server'), Object.assign({ updated: snapshot.timestamp }, snapshot.server));
+    renderSources(snapshot);
+  }
+
+  function renderRoutes() {
+    var filter = document.getElementById('route-filter').value.toLowerCase();
+    var table = document.getElementById('routes');
+    table.textContent = '';
+    routes.filter(function (route) {
+      return !filter || route.path.toLowerCase().indexOf(filter) !== -1;
+    }).forEach(function (route) {
+      var row = document.createElement('tr');
+      row.appendChild(cell('th', route.methods));
+      row.appendChild(cell('td', route.path));
+      table.appendChild(row);
+    });
+  }
+
+  function setStatus(label, cls) {
+    var el = document.getElementById('status');
+    el.textContent = label;
+    el.className = 'status ' + (cls || '');
+  }
+
+  function connect() {
+    var source = new EventSource(base + 'api/stream');
+    source.addEventListener('open', function () { setStatus('live', 'live'); });
+    source.addEventListener('message', function (event) {
+      try { render(JSON.parse(event.data)); } catch (e) { /* ignore malformed frames */ }
+    });
+    source.addEventListener('error', function () { setStatus('reconnecting', 'down'); });
+  }
+
+  fetch(base + 'api/snapshot', { credentials: 'same-origin' })
+    .then(function (res) { return res.json(); })
+    .then(render)
+    .catch(function () { setStatus('unavailable', 'down'); });
+
+  fetch(base + 'api/routes', { credentials: 'same-origin' })
+    .then(function (res) { return res.json(); })
+    .then(function (list) { routes = list || []; renderRoutes(); });
+
+  document.getElementById('route-filter').addEventListener('input', renderRoutes);
+  connect();
+})();
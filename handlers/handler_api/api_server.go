@@ -6,7 +6,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/go-xlite/wbx/comm"
 	handler_role "github.com/go-xlite/wbx/comm/handler_role"
+	"github.com/go-xlite/wbx/middleware"
 	"github.com/go-xlite/wbx/services/webtrail"
 	hl1 "github.com/go-xlite/wbx/utils"
 	"github.com/go-xlite/wbx/weblite"
@@ -19,8 +21,16 @@ var content embed.FS
 // Features: JSON serialization, CORS support, request validation, error handling
 type ApiHandler struct {
 	*handler_role.HandlerRole
-	Timeout time.Duration
-	trail   *webtrail.WebTrail
+	Timeout   time.Duration
+	Coalescer *middleware.Coalescer // Optional: merges concurrent identical GET requests
+	// Decompressor optionally decodes gzip/deflate/zstd request bodies before the routes read them
+	Decompressor *middleware.Decompressor
+	trail        *webtrail.WebTrail
+
+	// API-wide policies, applied to every route under PathPrefix before WebTrail
+	Sessions      comm.SessionResolver    // Optional: requests without a session get 401
+	SessionExempt []string                // Paths (relative to PathPrefix) reachable without a session; a trailing "/" matches the subtree
+	RateLimiter   *middleware.RateLimiter // Optional: per-client token bucket, 429 when exceeded
 }
 
 // NewApiHandler creates a new API handler with sensible defaults
@@ -36,6 +46,25 @@ func NewApiHandler(server *webtrail.WebTrail) *ApiHandler {
 	}
 }
 
+// EnableCoalescing merges concurrent identical GET requests (same route, query and caller)
+// into a single backend call. Must be called before Run.
+func (as *ApiHandler) EnableCoalescing() *middleware.Coalescer {
+	if as.Coalescer == nil {
+		as.Coalescer = middleware.NewCoalescer()
+	}
+	return as.Coalescer
+}
+
+// EnableDecompression decodes compressed request bodies (gzip, deflate and
+// encodings added with SetDecoder) of at most maxSize decoded bytes before the
+// routes bind them. Must be called before Run.
+func (as *ApiHandler) EnableDecompression(maxSize int64) *middleware.Decompressor {
+	if as.Decompressor == nil {
+		as.Decompressor = middleware.NewDecompressor(maxSize)
+	}
+	return as.Decompressor
+}
+
 func (as *ApiHandler) Run() {
 	// No-op for now; could be used to initialize resources if needed
 	server := weblite.Provider.Servers.GetByIndex(0)
@@ -52,8 +81,17 @@ func (as *ApiHandler) Run() {
 		hl1.Helpers.WriteNotFound(w)
 	})
 
-	server.GetRoutes().ForwardPathPrefixFn(as.PathPrefix.Get(), func(w http.ResponseWriter, r *http.Request) {
-		as.trail.OnRequest(w, r)
-	})
+	trailHandler := http.Handler(http.HandlerFunc(as.trail.OnRequest))
+	if as.Coalescer != nil {
+		trailHandler = as.Coalescer.Handler(trailHandler)
+	}
+	if as.Decompressor != nil {
+		trailHandler = as.Decompressor.Handler(trailHandler)
+	}
+	trailHandler = as.wrapPolicies(trailHandler)
+
+	server.GetRoutes().ForwardPathPrefixFn(as.PathPrefix.Get(), as.Isolate(func(w http.ResponseWriter, r *http.Request) {
+		trailHandler.ServeHTTP(w, r)
+	}))
 
 }
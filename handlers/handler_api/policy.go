@@ -0,0 +1,88 @@
+package weblite
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-xlite/wbx/comm"
+	"github.com/go-xlite/wbx/middleware"
+	hl1 "github.com/go-xlite/wbx/utils"
+	"github.com/go-xlite/wbx/weblite"
+)
+
+// SetCORS configures the CORS policy for the whole API prefix.
+// With enabled=false no CORS headers are sent and preflights fall through to the routes.
+func (as *ApiHandler) SetCORS(enabled bool, origins ...string) *ApiHandler {
+	as.CORS.SetCORS(enabled, origins...)
+	return as
+}
+
+// RequireSession rejects API requests without a valid session with 401 JSON.
+// The resolved session is stored in the request context (see weblite.GetSessionContext).
+// exempt lists paths relative to the prefix that stay public; a trailing "/" exempts the subtree.
+func (as *ApiHandler) RequireSession(resolver comm.SessionResolver, exempt ...string) *ApiHandler {
+	as.Sessions = resolver
+	as.SessionExempt = append(as.SessionExempt, exempt...)
+	return as
+}
+
+// SetRateLimit limits each client IP to rps requests per second with the given burst.
+// Use SetRateLimiter for a custom key (session, API key). Must be called before Run.
+func (as *ApiHandler) SetRateLimit(rps float64, burst int) *middleware.RateLimiter {
+	as.RateLimiter = middleware.NewRateLimiter(rps, burst)
+	return as.RateLimiter
+}
+
+// SetRateLimiter installs an existing limiter, e.g. one shared across handlers
+func (as *ApiHandler) SetRateLimiter(rl *middleware.RateLimiter) *ApiHandler {
+	as.RateLimiter = rl
+	return as
+}
+
+// wrapPolicies applies CORS, rate limiting and session checks in that order,
+// so preflights are never limited or rejected and rejections still carry CORS headers.
+func (as *ApiHandler) wrapPolicies(next http.Handler) http.Handler {
+	if as.Sessions != nil {
+		next = as.sessionHandler(next)
+	}
+	if as.RateLimiter != nil {
+		next = as.RateLimiter.Handler(next)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if as.CORS.EnableCORS {
+			as.CORS.ApplyCORS(w, r)
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sessionHandler resolves the session for every non-exempt request
+func (as *ApiHandler) sessionHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if as.isSessionExempt(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		data, ok := as.Sessions.ResolveSession(r)
+		if !ok {
+			w.Header().Set("Cache-Control", "no-store")
+			hl1.Helpers.WriteJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(weblite.SetSessionContext(r.Context(), data)))
+	})
+}
+
+// isSessionExempt reports whether path matches one of SessionExempt
+func (as *ApiHandler) isSessionExempt(path string) bool {
+	for _, p := range as.SessionExempt {
+		if p == path || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
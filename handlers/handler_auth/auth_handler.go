@@ -36,6 +36,36 @@ func NewAuthHandler(auth *webauth.WebAuth) *AuthHandler {
 	}
 }
 
+// SetResponseCustomizer shapes the JSON payloads returned by the auth endpoints
+func (as *AuthHandler) SetResponseCustomizer(fn webauth.ResponseCustomizer) *AuthHandler {
+	as.auth.SetResponseCustomizer(fn)
+	return as
+}
+
+// SetLoginPage sets the HTML login page used for form-POST login failures
+func (as *AuthHandler) SetLoginPage(path string) *AuthHandler {
+	as.auth.SetLoginPage(path)
+	return as
+}
+
+// SetSessionDirectory exposes session listing and logout-everywhere endpoints
+// (/sessions, /logout-all) backed by dir, using sm's session cookie; the live
+// connections of revoked sessions are closed through sm's OnRevoke listeners
+func (as *AuthHandler) SetSessionDirectory(dir webauth.SessionDirectory, sm *weblite.SessionManager) *AuthHandler {
+	as.auth.SetSessionDirectory(dir, sm.CookieName)
+	as.auth.SessionCookieFor = sm.CookieNameFor
+	as.auth.ClearSession = sm.ClearCookieFor
+	as.auth.NotifyRevoked = sm.NotifyRevokedKeys
+	return as
+}
+
+// SetRecovery exposes password reset and email verification endpoints;
+// the application supplies the account store and mailer
+func (as *AuthHandler) SetRecovery(rec *webauth.Recovery) *AuthHandler {
+	as.auth.SetRecovery(rec)
+	return as
+}
+
 func (as *AuthHandler) Run() {
 	// No-op for now; could be used to initialize resources if needed
 	server := weblite.Provider.Servers.GetByIndex(0)
@@ -52,8 +82,8 @@ func (as *AuthHandler) Run() {
 		hl1.Helpers.WriteNotFound(w)
 	})
 
-	server.GetRoutes().ForwardPathPrefixFn("/g/xt23/auth", func(w http.ResponseWriter, r *http.Request) {
+	server.GetRoutes().ForwardPathPrefixFn("/g/xt23/auth", as.Isolate(func(w http.ResponseWriter, r *http.Request) {
 		as.auth.OnRequest(w, r)
-	})
+	}))
 
 }
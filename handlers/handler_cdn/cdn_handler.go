@@ -11,11 +11,22 @@ import (
 type CdnHandler struct {
 	*handler_role.HandlerRole
 	webcdn *webcdn.WebCdn
+
+	// Signed URLs, hotlink protection and rejection behavior, see protection.go
+	signSecret          []byte
+	signedPaths         []string
+	hotlinkEnabled      bool
+	hotlinkAllowEmpty   bool
+	hotlinkHosts        []string
+	rejectRedirect      string
+	rejectWatermark     []byte
+	rejectWatermarkType string
 }
 
 // NewCdnHandler creates a new CdnHandler
 func NewCdnHandler(cdn *webcdn.WebCdn) *CdnHandler {
 	handlerRole := handler_role.NewHandler()
+	cdn.ContentTypeFor = handlerRole.ResolveContentType
 	return &CdnHandler{
 		HandlerRole: handlerRole,
 		webcdn:      cdn,
@@ -24,9 +35,9 @@ func NewCdnHandler(cdn *webcdn.WebCdn) *CdnHandler {
 
 // Run registers the CDN handler routes
 func (ch *CdnHandler) Run() {
-	ch.Handler.GetRoutes().ForwardPathPrefixFn(ch.PathPrefix.Get(), func(w http.ResponseWriter, r *http.Request) {
+	ch.Handler.GetRoutes().ForwardPathPrefixFn(ch.PathPrefix.Get(), ch.Isolate(ch.protect(func(w http.ResponseWriter, r *http.Request) {
 		ch.webcdn.OnRequest(w, r)
-	})
+	})))
 }
 
 // GetWebCdn returns the underlying WebCdn instance for direct configuration
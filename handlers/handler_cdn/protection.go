@@ -0,0 +1,164 @@
+package handlercdn
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query parameters carrying the URL signature
+const (
+	ExpiresParam   = "expires"
+	SignatureParam = "sig"
+)
+
+// SetSignedURLs requires an HMAC signature made with secret (see SignURL) on
+// requests below the given paths, relative to the prefix (all paths when none
+// given). Assets are then only cached privately.
+func (ch *CdnHandler) SetSignedURLs(secret []byte, paths ...string) *CdnHandler {
+	ch.signSecret = secret
+	ch.signedPaths = paths
+	ch.webcdn.PrivateCache = true
+	return ch
+}
+
+// SignURL returns the URL of the asset at relPath (relative to the prefix)
+// with a signature valid for ttl
+func (ch *CdnHandler) SignURL(relPath string, ttl time.Duration) string {
+	relPath = "/" + strings.TrimPrefix(relPath, "/")
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	query := url.Values{ExpiresParam: {expires}, SignatureParam: {ch.signature(relPath, expires)}}
+	return ch.PathPrefix.Suffix(relPath) + "?" + query.Encode()
+}
+
+// signature is the hex HMAC-SHA256 of "PATH\nEXPIRES"
+func (ch *CdnHandler) signature(relPath, expires string) string {
+	mac := hmac.New(sha256.New, ch.signSecret)
+	mac.Write([]byte(relPath + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validSignature checks the signature of a request for relPath, if one is required
+func (ch *CdnHandler) validSignature(r *http.Request, relPath string) bool {
+	if len(ch.signSecret) == 0 || !underAny(relPath, ch.signedPaths) {
+		return true
+	}
+	query := r.URL.Query()
+	expires := query.Get(ExpiresParam)
+	sig := strings.ToLower(query.Get(SignatureParam))
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || sig == "" || time.Now().Unix() > unix {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(ch.signature(relPath, expires)))
+}
+
+// SetHotlinkProtection rejects requests whose Origin or Referer names a site
+// other than this one or allowedHosts ("*.example.com" matches subdomains).
+// Requests without either header pass when allowEmpty is set, as privacy
+// settings and direct visits send none. Responses vary by those headers and
+// are only cached privately.
+func (ch *CdnHandler) SetHotlinkProtection(allowEmpty bool, allowedHosts ...string) *CdnHandler {
+	ch.hotlinkEnabled = true
+	ch.webcdn.PrivateCache = true
+	ch.hotlinkAllowEmpty = allowEmpty
+	ch.hotlinkHosts = allowedHosts
+	return ch
+}
+
+// allowedReferrer checks the Origin (or else Referer) header against the allowlist
+func (ch *CdnHandler) allowedReferrer(r *http.Request) bool {
+	if !ch.hotlinkEnabled {
+		return true
+	}
+	source := r.Header.Get("Origin")
+	if source == "" || source == "null" {
+		source = r.Header.Get("Referer")
+	}
+	if source == "" {
+		return ch.hotlinkAllowEmpty
+	}
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if self, _, _ := strings.Cut(r.Host, ":"); strings.EqualFold(host, self) {
+		return true
+	}
+	for _, allowed := range ch.hotlinkHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRejectRedirect redirects rejected requests to target instead of answering 403
+func (ch *CdnHandler) SetRejectRedirect(target string) *CdnHandler {
+	ch.rejectRedirect = target
+	return ch
+}
+
+// SetRejectWatermark answers rejected requests with data (e.g. a "hotlinked
+// from example.com" image) instead of 403; it takes precedence over a redirect
+func (ch *CdnHandler) SetRejectWatermark(data []byte, contentType string) *CdnHandler {
+	ch.rejectWatermark = data
+	ch.rejectWatermarkType = contentType
+	return ch
+}
+
+// reject answers a request that failed the signature or hotlink check
+func (ch *CdnHandler) reject(w http.ResponseWriter, r *http.Request) {
+	// The answer depends on the request, so keep it out of shared caches
+	w.Header().Set("Cache-Control", "no-store")
+	switch {
+	case ch.rejectWatermark != nil:
+		w.Header().Set("Content-Type", ch.rejectWatermarkType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(ch.rejectWatermark)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method != http.MethodHead {
+			w.Write(ch.rejectWatermark)
+		}
+	case ch.rejectRedirect != "":
+		http.Redirect(w, r, ch.rejectRedirect, http.StatusFound)
+	default:
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	}
+}
+
+// protect applies the signature and hotlink checks before next
+func (ch *CdnHandler) protect(next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		relPath := path.Clean("/" + r.URL.Path)
+		if ch.hotlinkEnabled {
+			w.Header().Add("Vary", "Origin, Referer")
+		}
+		if !ch.validSignature(r, relPath) || !ch.allowedReferrer(r) {
+			ch.reject(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// underAny reports whether p lies below one of paths (true when paths is empty)
+func underAny(p string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, prefix := range paths {
+		prefix = path.Clean("/" + prefix)
+		if prefix == "/" || p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package handlermedia
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/go-xlite/wbx/comm"
+	"github.com/go-xlite/wbx/weblite"
+)
+
+// AccessLevel is who may stream the files of a directory
+type AccessLevel int
+
+const (
+	// AccessPublic lets anyone stream (default for directories without a rule)
+	AccessPublic AccessLevel = iota
+	// AccessSession requires a session resolved by the session resolver
+	AccessSession
+	// AccessRole requires a session holding one of the rule's roles
+	AccessRole
+)
+
+// DirAccess is the access rule of a media directory and everything below it
+type DirAccess struct {
+	Dir   string
+	Level AccessLevel
+	Roles []string // Any of these roles grants access (AccessRole only)
+}
+
+// SetSessionResolver sets how sessions are resolved for protected directories.
+// hasRole reports whether a resolved session holds a role; without it
+// AccessRole directories are closed to everyone.
+func (mh *MediaHandler) SetSessionResolver(resolver comm.SessionResolver, hasRole func(session any, role string) bool) *MediaHandler {
+	mh.Sessions = resolver
+	mh.HasRole = hasRole
+	return mh
+}
+
+// SetDirAccess sets the access rule of dir (relative to the media root) and
+// its subdirectories; the rule of the deepest configured directory applies
+func (mh *MediaHandler) SetDirAccess(dir string, level AccessLevel, roles ...string) *MediaHandler {
+	dir = cleanDir(dir)
+	for i := range mh.dirAccess {
+		if mh.dirAccess[i].Dir == dir {
+			mh.dirAccess[i] = DirAccess{Dir: dir, Level: level, Roles: roles}
+			return mh
+		}
+	}
+	mh.dirAccess = append(mh.dirAccess, DirAccess{Dir: dir, Level: level, Roles: roles})
+	return mh
+}
+
+// AccessFor returns the rule covering a media path
+func (mh *MediaHandler) AccessFor(filePath string) DirAccess {
+	filePath = cleanDir(filePath)
+	best := DirAccess{Level: AccessPublic}
+	bestLen := -1
+	for _, rule := range mh.dirAccess {
+		if !inDir(filePath, rule.Dir) || len(rule.Dir) <= bestLen {
+			continue
+		}
+		best, bestLen = rule, len(rule.Dir)
+	}
+	return best
+}
+
+// authorize checks the rule covering filePath for r. It returns the request to
+// continue with (carrying the session in its context), or nil when 401 or 403
+// was written instead.
+func (mh *MediaHandler) authorize(w http.ResponseWriter, r *http.Request, filePath string) *http.Request {
+	rule := mh.AccessFor(filePath)
+	if rule.Level == AccessPublic {
+		return r
+	}
+
+	var session any
+	ok := false
+	if mh.Sessions != nil {
+		session, ok = mh.Sessions.ResolveSession(r)
+	}
+	if !ok {
+		w.Header().Set("Cache-Control", "no-store")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+
+	if rule.Level == AccessRole && !mh.hasAnyRole(session, rule.Roles) {
+		w.Header().Set("Cache-Control", "no-store")
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil
+	}
+	return r.WithContext(weblite.SetSessionContext(r.Context(), session))
+}
+
+// hasAnyRole reports whether session holds one of roles
+func (mh *MediaHandler) hasAnyRole(session any, roles []string) bool {
+	if mh.HasRole == nil {
+		return false
+	}
+	for _, role := range roles {
+		if mh.HasRole(session, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPrivate reports whether responses for filePath must stay out of shared caches
+func (mh *MediaHandler) isPrivate(filePath string) bool {
+	return mh.AccessFor(filePath).Level != AccessPublic
+}
+
+// cleanDir normalizes a media path to the form "a/b" ("" for the root)
+func cleanDir(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+// inDir reports whether p is dir or lies below it
+func inDir(p, dir string) bool {
+	return dir == "" || p == dir || strings.HasPrefix(p, dir+"/")
+}
@@ -2,29 +2,45 @@ package handlermedia
 
 import (
 	"net/http"
+	"path"
 	"strings"
 	"time"
 
+	"github.com/go-xlite/wbx/comm"
 	handler_role "github.com/go-xlite/wbx/comm/handler_role"
 	"github.com/go-xlite/wbx/services/webstream"
+	hl1 "github.com/go-xlite/wbx/utils"
 )
 
+// MediaUploadTypes are the sniffed content types HandleUpload accepts by default.
+// Several containers (flac, aac, mov) have no signature in the sniffer and are
+// reported as application/octet-stream, so the extension check does the rest.
+var MediaUploadTypes = []string{"video/", "audio/", "application/ogg", "application/octet-stream"}
+
 // MediaHandler handles video and audio streaming with range request support
 // This is a thin wrapper that delegates to the webstream server
 type MediaHandler struct {
 	*handler_role.HandlerRole
 	webstream *webstream.WebStream
+
+	// Directory access rules, see access.go
+	Sessions  comm.SessionResolver
+	HasRole   func(session any, role string) bool
+	dirAccess []DirAccess
 }
 
 // NewMediaHandler creates a new media handler
 func NewMediaHandler(ws *webstream.WebStream) *MediaHandler {
 	handlerRole := handler_role.NewHandler()
 	handlerRole.Handler = ws
+	ws.ContentTypeFor = handlerRole.ResolveContentType
 
-	return &MediaHandler{
+	mh := &MediaHandler{
 		HandlerRole: handlerRole,
 		webstream:   ws,
 	}
+	ws.PrivateCaching = mh.isPrivate
+	return mh
 }
 
 // SetBufferSize sets the streaming buffer size
@@ -54,7 +70,7 @@ func (mh *MediaHandler) ServeMedia(w http.ResponseWriter, r *http.Request, fileP
 
 // HandleMedia creates an HTTP handler for serving media
 func (mh *MediaHandler) HandleMedia() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+	return mh.Isolate(func(w http.ResponseWriter, r *http.Request) {
 		// Extract file path from URL
 		filePath := strings.TrimPrefix(r.URL.Path, mh.PathPrefix.Get())
 		filePath = strings.TrimPrefix(filePath, "/")
@@ -63,7 +79,90 @@ func (mh *MediaHandler) HandleMedia() http.HandlerFunc {
 			http.Error(w, "No media file specified", http.StatusBadRequest)
 			return
 		}
+		if r = mh.authorize(w, r, filePath); r == nil {
+			return
+		}
 
 		mh.ServeMedia(w, r, filePath)
-	}
+	})
+}
+
+// HandlePlaylist creates an HTTP handler returning the playlist of the directory
+// given by the "dir" query parameter, with stream URLs pointing at HandleMedia
+func (mh *MediaHandler) HandlePlaylist() http.HandlerFunc {
+	return mh.Isolate(func(w http.ResponseWriter, r *http.Request) {
+		dir := r.URL.Query().Get("dir")
+		if r = mh.authorize(w, r, dir); r == nil {
+			return
+		}
+		mh.webstream.ServePlaylist(w, r, dir, mh.PathPrefix.Get())
+	})
+}
+
+// HandleVariants creates an HTTP handler listing the formats a media title is
+// available in, e.g. GET <prefix>/variants/movies/foo lists foo.webm and
+// foo.mp4 with stream URLs pointing at HandleMedia. Mount it at
+// PathPrefix.Suffix("variants") ahead of HandleMedia.
+func (mh *MediaHandler) HandleVariants() http.HandlerFunc {
+	return mh.Isolate(func(w http.ResponseWriter, r *http.Request) {
+		filePath := strings.TrimPrefix(r.URL.Path, mh.PathPrefix.Suffix("variants"))
+		filePath = strings.TrimPrefix(filePath, "/")
+
+		if filePath == "" {
+			http.Error(w, "No media file specified", http.StatusBadRequest)
+			return
+		}
+		if r = mh.authorize(w, r, filePath); r == nil {
+			return
+		}
+		mh.webstream.ServeVariants(w, r, filePath, mh.PathPrefix.Get())
+	})
+}
+
+// SetVariantOrder ranks variant extensions, most preferred first
+func (mh *MediaHandler) SetVariantOrder(exts ...string) *MediaHandler {
+	mh.webstream.VariantOrder = exts
+	return mh
+}
+
+// SetDurationProvider sets the callback reporting media durations for playlists
+func (mh *MediaHandler) SetDurationProvider(fn func(path string) time.Duration) *MediaHandler {
+	mh.webstream.DurationFor = fn
+	return mh
+}
+
+// HandleUpload creates an HTTP handler storing multipart uploads into the media
+// directory given by the "dir" query parameter and answering 201 with the
+// stored files. Only allowed extensions are accepted and, unless limits says
+// otherwise, only content sniffed as one of MediaUploadTypes.
+func (mh *MediaHandler) HandleUpload(limits hl1.UploadLimits) http.HandlerFunc {
+	return mh.Isolate(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		partLimits := limits
+		if len(partLimits.AllowedExtensions) == 0 {
+			for ext, ok := range mh.webstream.AllowedExtensions {
+				if ok {
+					partLimits.AllowedExtensions = append(partLimits.AllowedExtensions, ext)
+				}
+			}
+		}
+		if len(partLimits.AllowedTypes) == 0 {
+			partLimits.AllowedTypes = MediaUploadTypes
+		}
+
+		// Keep uploads inside the adapter root
+		dir := strings.TrimPrefix(path.Clean("/"+r.URL.Query().Get("dir")), "/")
+
+		result, err := hl1.Helpers.ReceiveUploads(w, r, mh.webstream.FsAdapter, dir, partLimits)
+		if err != nil {
+			hl1.Helpers.WriteUploadError(w, err)
+			return
+		}
+		hl1.Helpers.WriteJSON(w, http.StatusCreated, result)
+	})
 }
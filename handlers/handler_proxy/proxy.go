@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	handler_role "github.com/go-xlite/wbx/comm/handler_role"
+	handlerroot "github.com/go-xlite/wbx/handlers/handler_root"
 	"github.com/go-xlite/wbx/services/webproxy"
 )
 
@@ -28,7 +29,7 @@ func NewProxyHandler(wp *webproxy.WebProxy) *ProxyHandler {
 
 // HandleProxy creates an HTTP handler for the proxy
 func (ph *ProxyHandler) HandleProxy() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+	return ph.Isolate(func(w http.ResponseWriter, r *http.Request) {
 		// Extract path from URL
 		path := strings.TrimPrefix(r.URL.Path, ph.PathPrefix.Get())
 		path = strings.TrimPrefix(path, "/")
@@ -38,5 +39,30 @@ func (ph *ProxyHandler) HandleProxy() http.HandlerFunc {
 
 		// Delegate to webproxy
 		ph.webproxy.OnRequest(w, r)
+	})
+}
+
+// SetSafetyPolicy enables SSRF protection for this handler's proxy, e.g.
+// webproxy.DefaultSafetyPolicy() when targets depend on request data
+func (ph *ProxyHandler) SetSafetyPolicy(policy *webproxy.SafetyPolicy) *ProxyHandler {
+	ph.webproxy.SetSafetyPolicy(policy)
+	return ph
+}
+
+// SetHeaderPolicy rewrites upstream response headers, e.g.
+// webproxy.DefaultHeaderPolicy() to hide backend details and add security headers
+func (ph *ProxyHandler) SetHeaderPolicy(policy *webproxy.HeaderPolicy) *ProxyHandler {
+	ph.webproxy.SetHeaderPolicy(policy)
+	return ph
+}
+
+// UseErrorPages renders upstream errors with the root handler's server error
+// page, so proxied and local failures look the same. The page is used as an
+// html/template over webproxy.ProxyError; without one the default stays.
+func (ph *ProxyHandler) UseErrorPages(root *handlerroot.RootHandler) error {
+	page := root.ErrorPage(http.StatusBadGateway)
+	if page == nil {
+		return nil
 	}
+	return ph.webproxy.SetErrorPage(page)
 }
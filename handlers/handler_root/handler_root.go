@@ -1,8 +1,11 @@
 package handlerroot
 
 import (
+	"fmt"
+	"net/http"
 	"time"
 
+	"github.com/go-xlite/wbx/comm"
 	handler_role "github.com/go-xlite/wbx/comm/handler_role"
 )
 
@@ -12,7 +15,7 @@ type RootHandler struct {
 	*handler_role.HandlerRole
 	CacheMaxAge     time.Duration
 	NotFoundPage    []byte
-	ServerErrorPage []byte
+	ServerErrorPage []byte // May use {{.Status}}, {{.Title}} and {{.Message}} when shared with proxies
 }
 
 // NewRootHandler creates a RootHandler wrapper around an existing handler instance
@@ -24,3 +27,20 @@ func NewRootHandler(handler handler_role.IHandler) *RootHandler {
 		CacheMaxAge: 24 * time.Hour,
 	}
 }
+
+// ServeFile serves a root-level file (favicon.ico, robots.txt, ...) with caching,
+// conditional and range request support
+func (rh *RootHandler) ServeFile(w http.ResponseWriter, r *http.Request, name string, modTime time.Time, data []byte) {
+	comm.ServeBytes(w, r, name, modTime, data, &comm.ServeOptions{
+		CacheControl: fmt.Sprintf("public, max-age=%d", int(rh.CacheMaxAge.Seconds())),
+	})
+}
+
+// ErrorPage returns the configured page for status (NotFoundPage for 404,
+// ServerErrorPage for everything else), or nil if none is set
+func (rh *RootHandler) ErrorPage(status int) []byte {
+	if status == http.StatusNotFound && rh.NotFoundPage != nil {
+		return rh.NotFoundPage
+	}
+	return rh.ServerErrorPage
+}
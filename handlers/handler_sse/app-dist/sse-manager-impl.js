@@ -1 +1 @@
-class B{#q=!1;#F=0;#V;#j=null;#A=null;#N;#K=null;#B=null;#Q=null;#R=null;#U=null;#W;#X;#Y;#Z;#$;#x;#z;#O;constructor(j,q={}){this.#V=j,this.#z=this.#H(),this.#N="sse-coord-"+btoa(j).replace(/=/g,""),this.ops={reconnect:q.reconnect??!0,reconnectInterval:q.reconnectInterval??5000,heartbeatInterval:q.heartbeatInterval??2000,electionTimeout:q.electionTimeout??500,failoverCheckInterval:q.failoverCheckInterval??1e4},this.#W=[],this.#X=[],this.#Y=[],this.#Z=[],this.#$=[],this.#x=[],this.#O=()=>this.disconnect(),window.addEventListener("beforeunload",this.#O),window.addEventListener("pagehide",this.#O)}#H(){return Date.now().toString()+"-"+Math.random().toString(36).substr(2,9)}connect(){if(this.#F!==0)return;if(this.#j)this.#j.close(),this.#j=null;this.#F=1,this.#f()}disconnect(){if(this.ops.reconnect=!1,this.#I(),this.#j)this.#j.onopen=null,this.#j.onmessage=null,this.#j.onerror=null,this.#j.close(),this.#j=null;if(this.#F=0,this.#q=!1,this.#A)this.#A.postMessage({type:105,instanceId:this.#z}),this.#A.close(),this.#A=null;if(this.#O)window.removeEventListener("beforeunload",this.#O),window.removeEventListener("pagehide",this.#O);this.#J(3)}#f(){try{this.#A=new BroadcastChannel(this.#N),this.#A.onmessage=(j)=>{this.#E(j.data)},this.#A.postMessage({type:101,instanceId:this.#z}),this.#B=setTimeout(()=>{this.#G()},this.ops.electionTimeout)}catch(j){this.#L()}}#E(j){switch(j.type){case 101:if(this.#q)this.#A.postMessage({type:102,instanceId:this.#z});break;case 102:if(!this.#q&&j.instanceId!==this.#z)clearTimeout(this.#B),this.#w();break;case 103:if(!this.#q&&j.instanceId!==this.#z)this.#U=Date.now();break;case 104:if(!this.#q)this.#J(0,j.message);break;case 105:if(!this.#q&&j.instanceId!==this.#z)clearTimeout(this.#B),this.#B=setTimeout(()=>this.#G(),100);break;case 106:if(j.instanceId!==this.#z)if(j.instanceId<this.#z)if(this.#q)this.#D();else clearTimeout(this.#B);else this.#A.postMessage({type:107,instanceId:this.#z});break;case 107:if(j.instanceId<this.#z&&j.instanceId!==this.#z){if(clearTimeout(this.#B),this.#q)this.#D()}break;case 100:if(j.instanceId!==this.#z)if(this.#q){if(j.instanceId<this.#z)this.#D()}else this.#U=Date.now();break}}#G(){if(!this.#A){this.#L();return}this.#A.postMessage({type:106,instanceId:this.#z}),clearTimeout(this.#B),this.#B=setTimeout(()=>{if(!this.#q)this.#L()},500)}#L(){if(this.#q)return;if(this.#q=!0,this.#F=2,this.#A)this.#A.postMessage({type:100,instanceId:this.#z});this.#M(),this.#K=setInterval(()=>{if(this.#A&&this.#q)this.#A.postMessage({type:103,instanceId:this.#z})},this.ops.heartbeatInterval),this.#J(4)}#w(){if(!this.#q&&this.#j)return;this.#q=!1,this.#F=2,this.#U=Date.now(),this.#R=setInterval(()=>{if(Date.now()-(this.#U||0)>this.ops.failoverCheckInterval)this.#G()},this.ops.failoverCheckInterval),this.#J(5),this.#J(1)}#D(){if(this.#j)this.#j.close(),this.#j=null;if(this.#K)clearInterval(this.#K),this.#K=null;this.#q=!1,this.#w()}#M(){if(this.#j)this.#j.onopen=null,this.#j.onmessage=null,this.#j.onerror=null,this.#j.close(),this.#j=null;this.#j=new EventSource(this.#V),this.#j.onopen=()=>{this.#F=2,this.#J(1)},this.#j.onmessage=(j)=>{if(this.#J(0,j.data),this.#q&&this.#A)this.#A.postMessage({type:104,message:j.data,instanceId:this.#z})},this.#j.onerror=(j)=>{if(this.#J(2,j),this.#j.readyState===EventSource.CLOSED)this.#_()}}#_(){if(this.#F=0,this.#j)this.#j.close(),this.#j=null;if(this.#J(3),this.ops.reconnect&&this.#q)this.#Q=setTimeout(()=>{if(this.#q)this.#M()},this.ops.reconnectInterval)}#I(){if(this.#K)clearInterval(this.#K),this.#K=null;if(this.#B)clearTimeout(this.#B),this.#B=null;if(this.#Q)clearTimeout(this.#Q),this.#Q=null;if(this.#R)clearInterval(this.#R),this.#R=null}on(j,q){switch(j){case 0:this.#W.push(q);break;case 1:this.#X.push(q);break;case 2:this.#Y.push(q);break;case 3:this.#Z.push(q);break;case 4:this.#$.push(q);break;case 5:this.#x.push(q);break}}#J(j,q){let z;switch(j){case 0:z=this.#W;break;case 1:z=this.#X;break;case 2:z=this.#Y;break;case 3:z=this.#Z;break;case 4:z=this.#$;break;case 5:z=this.#x;break}if(z)z.forEach((A)=>A(q))}isPrimaryConnection(){return this.#q}getConnectionState(){return this.#F}getState(){let j={};return j.connectionState=this.#F,j.isPrimary=this.#q,j.instanceId=this.#z,j.url=this.#V,j}}export{B as SSEManager};
+class e{#t=!1;#n=0;#S;#e=null;#i=null;#T;#c=null;#a=null;#m=null;#l=null;#d=null;#k;#f;#v;#u;#g;#y;#h;#s;#o;constructor(s,t={}){this.#S=s,this.#s=this.#L(),this.#T="sse-coord-"+btoa(s).replace(/=/g,""),this.ops={reconnect:t.reconnect??!0,reconnectInterval:t.reconnectInterval??5000,heartbeatInterval:t.heartbeatInterval??2000,electionTimeout:t.electionTimeout??500,failoverCheckInterval:t.failoverCheckInterval??1e4},this.#k=[],this.#f=[],this.#v=[],this.#u=[],this.#g=[],this.#y=[],this.#h=new Map,this.#o=()=>this.disconnect(),window.addEventListener("beforeunload",this.#o),window.addEventListener("pagehide",this.#o)}#L(){return Date.now().toString()+"-"+Math.random().toString(36).substr(2,9)}connect(){if(this.#n!==0)return;if(this.#e)this.#e.close(),this.#e=null;this.#n=1,this.#D()}disconnect(){this.ops.reconnect=!1,this.#M();if(this.#e)this.#e.onopen=null,this.#e.onmessage=null,this.#e.onerror=null,this.#e.close(),this.#e=null;this.#n=0,this.#t=!1;if(this.#i)this.#i.postMessage({type:105,instanceId:this.#s}),this.#i.close(),this.#i=null;if(this.#o)window.removeEventListener("beforeunload",this.#o),window.removeEventListener("pagehide",this.#o);this.#r(3)}#D(){try{this.#i=new BroadcastChannel(this.#T),this.#i.onmessage=(t)=>{this.#N(t.data)},this.#i.postMessage({type:101,instanceId:this.#s}),this.#a=setTimeout(()=>{this.#p()},this.ops.electionTimeout)}catch(t){this.#b()}}#N(t){switch(t.type){case 101:if(this.#t)this.#i.postMessage({type:102,instanceId:this.#s});break;case 102:if(!this.#t&&t.instanceId!==this.#s)clearTimeout(this.#a),this.#C();break;case 103:if(!this.#t&&t.instanceId!==this.#s)this.#d=Date.now();break;case 104:if(!this.#t)if(t.event)this.#E(t.event,t.message);else this.#r(0,t.message);break;case 105:if(!this.#t&&t.instanceId!==this.#s)clearTimeout(this.#a),this.#a=setTimeout(()=>this.#p(),100);break;case 106:if(t.instanceId!==this.#s)if(t.instanceId<this.#s){if(this.#t)this.#I();else clearTimeout(this.#a)}else this.#i.postMessage({type:107,instanceId:this.#s});break;case 107:if(t.instanceId<this.#s&&t.instanceId!==this.#s){clearTimeout(this.#a);if(this.#t)this.#I()}break;case 100:if(t.instanceId!==this.#s)if(this.#t){if(t.instanceId<this.#s)this.#I()}else this.#d=Date.now();break}}#p(){if(!this.#i){this.#b();return}this.#i.postMessage({type:106,instanceId:this.#s}),clearTimeout(this.#a),this.#a=setTimeout(()=>{if(!this.#t)this.#b()},500)}#b(){if(this.#t)return;this.#t=!0,this.#n=2;if(this.#i)this.#i.postMessage({type:100,instanceId:this.#s});this.#P(),this.#c=setInterval(()=>{if(this.#i&&this.#t)this.#i.postMessage({type:103,instanceId:this.#s})},this.ops.heartbeatInterval),this.#r(4)}#C(){if(!this.#t&&this.#e)return;this.#t=!1,this.#n=2,this.#d=Date.now(),this.#l=setInterval(()=>{let t=Date.now()-(this.#d||0);if(t>this.ops.failoverCheckInterval)this.#p()},this.ops.failoverCheckInterval),this.#r(5),this.#r(1)}#I(){if(this.#e)this.#e.close(),this.#e=null;if(this.#c)clearInterval(this.#c),this.#c=null;this.#t=!1,this.#C()}#P(){if(this.#e)this.#e.onopen=null,this.#e.onmessage=null,this.#e.onerror=null,this.#e.close(),this.#e=null;this.#e=new EventSource(this.#S),this.#e.onopen=()=>{this.#n=2,this.#r(1)},this.#e.onmessage=(t)=>{this.#r(0,t.data);if(this.#t&&this.#i)this.#i.postMessage({type:104,message:t.data,instanceId:this.#s})},this.#h.forEach((s,t)=>this.#w(t)),this.#e.addEventListener("close",(s)=>{let t=null;try{t=JSON.parse(s.data).reason}catch(i){return}if(t==="session-revoked")this.ops.reconnect=!1,this.#e.close(),this.#_()}),this.#e.onerror=(t)=>{this.#r(2,t);if(this.#e.readyState===EventSource.CLOSED)this.#_()}}#_(){this.#n=0;if(this.#e)this.#e.close(),this.#e=null;this.#r(3);if(this.ops.reconnect&&this.#t)this.#m=setTimeout(()=>{if(this.#t)this.#P()},this.ops.reconnectInterval)}#M(){if(this.#c)clearInterval(this.#c),this.#c=null;if(this.#a)clearTimeout(this.#a),this.#a=null;if(this.#m)clearTimeout(this.#m),this.#m=null;if(this.#l)clearInterval(this.#l),this.#l=null}on(s,t){switch(s){case 0:this.#k.push(t);break;case 1:this.#f.push(t);break;case 2:this.#v.push(t);break;case 3:this.#u.push(t);break;case 4:this.#g.push(t);break;case 5:this.#y.push(t);break}}onEvent(t,s){if(!this.#h.has(t)){this.#h.set(t,[]);if(this.#e)this.#w(t)}this.#h.get(t).push(s)}#w(t){this.#e.addEventListener(t,(s)=>{this.#E(t,s.data);if(this.#t&&this.#i)this.#i.postMessage({type:104,event:t,message:s.data,instanceId:this.#s})})}#E(s,i){let t=this.#h.get(s);if(t)t.forEach(a=>a(i))}#r(s,i){let t;switch(s){case 0:t=this.#k;break;case 1:t=this.#f;break;case 2:t=this.#v;break;case 3:t=this.#u;break;case 4:t=this.#g;break;case 5:t=this.#y;break}if(t)t.forEach(a=>a(i))}isPrimaryConnection(){return this.#t}getConnectionState(){return this.#n}getState(){let t={};t.connectionState=this.#n,t.isPrimary=this.#t,t.instanceId=this.#s,t.url=this.#S;return t}}export{e as SSEManager};
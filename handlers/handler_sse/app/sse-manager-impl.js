@@ -34,6 +34,10 @@ const EVENT_CLOSE = 3
 const EVENT_PRIMARY = 4
 const EVENT_SECONDARY = 5
 
+// Close reason of a stream whose auth session was revoked (must match
+// comm/session_revocation.go); reconnecting would only be refused
+const SESSION_REVOKED_REASON = 'session-revoked'
+
 class SSEManager {
     #isPrimary = false;
     #connectionState = STATE_DISCONNECTED;
@@ -52,6 +56,7 @@ class SSEManager {
     #callbacks_close;
     #callbacks_primary;
     #callbacks_secondary;
+    #callbacks_named;
     #instanceId;
     #boundCleanup;
     
@@ -75,6 +80,7 @@ class SSEManager {
         this.#callbacks_close = [];
         this.#callbacks_primary = [];
         this.#callbacks_secondary = [];
+        this.#callbacks_named = new Map();
         
         // Auto-cleanup on page unload
         this.#boundCleanup = () => this.disconnect();
@@ -217,7 +223,11 @@ class SSEManager {
                 
             case MESSAGE:
                 if (!this.#isPrimary) {
-                    this.#triggerCallback(EVENT_MESSAGE, data.message);
+                    if (data.event) {
+                        this.#triggerNamed(data.event, data.message);
+                    } else {
+                        this.#triggerCallback(EVENT_MESSAGE, data.message);
+                    }
                 }
                 break;
                 
@@ -412,6 +422,23 @@ class SSEManager {
             }
         };
         
+        this.#callbacks_named.forEach((_, name) => this.#listenNamed(name));
+        
+        // A revoked session ends the stream for good
+        this.#eventSource.addEventListener('close', (event) => {
+            let reason = null;
+            try {
+                reason = JSON.parse(event.data).reason;
+            } catch (e) {
+                return;
+            }
+            if (reason === SESSION_REVOKED_REASON) {
+                this.ops.reconnect = false;
+                this.#eventSource.close();
+                this.#handleConnectionLost();
+            }
+        });
+        
         this.#eventSource.onerror = (error) => {
             this.#triggerCallback(EVENT_ERROR, error);
             
@@ -483,6 +510,47 @@ class SSEManager {
         }
     }
     
+    /**
+     * Register a callback for a named server event (SendEventToClient / BroadcastEvent)
+     */
+    onEvent(name, callback) {
+        if (!this.#callbacks_named.has(name)) {
+            this.#callbacks_named.set(name, []);
+            if (this.#eventSource) {
+                this.#listenNamed(name);
+            }
+        }
+        this.#callbacks_named.get(name).push(callback);
+    }
+    
+    /**
+     * Forward a named event of the EventSource to its callbacks and other tabs
+     */
+    #listenNamed(name) {
+        this.#eventSource.addEventListener(name, (event) => {
+            this.#triggerNamed(name, event.data);
+            
+            if (this.#isPrimary && this.#broadcastChannel) {
+                this.#broadcastChannel.postMessage({
+                    type: MESSAGE,
+                    event: name,
+                    message: event.data,
+                    instanceId: this.#instanceId
+                });
+            }
+        });
+    }
+    
+    /**
+     * Trigger callbacks of a named event
+     */
+    #triggerNamed(name, data) {
+        const callbacks = this.#callbacks_named.get(name);
+        if (callbacks) {
+            callbacks.forEach(callback => callback(data));
+        }
+    }
+    
     /**
      * Trigger event callbacks
      */
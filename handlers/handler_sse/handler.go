@@ -8,6 +8,7 @@ import (
 	"time"
 
 	handler_role "github.com/go-xlite/wbx/comm/handler_role"
+	"github.com/go-xlite/wbx/comm/schema"
 	"github.com/go-xlite/wbx/services/webcast"
 	hl1 "github.com/go-xlite/wbx/utils"
 )
@@ -23,6 +24,14 @@ type SSEHandler struct {
 	OnClientConnect    func(clientID string)
 	OnClientDisconnect func(clientID string)
 	OnClientRequest    func(req *SSEClientReq)
+
+	// MaxConnectionDuration and IdleTimeout end streams with a close event asking
+	// the client to reconnect, e.g. to stay under load balancer idle limits (0 = disabled)
+	MaxConnectionDuration time.Duration
+	IdleTimeout           time.Duration
+
+	sendEnabled   bool
+	authorizeSend func(r *http.Request) bool
 }
 
 // NewSSEHandler creates a new SSE handler
@@ -43,6 +52,18 @@ func (sh *SSEHandler) SetKeepAliveInterval(interval time.Duration) *SSEHandler {
 	return sh
 }
 
+// SetMaxConnectionDuration sets the maximum lifetime of an SSE connection
+func (sh *SSEHandler) SetMaxConnectionDuration(d time.Duration) *SSEHandler {
+	sh.MaxConnectionDuration = d
+	return sh
+}
+
+// SetIdleTimeout sets how long a connection may go without messages before it is closed
+func (sh *SSEHandler) SetIdleTimeout(d time.Duration) *SSEHandler {
+	sh.IdleTimeout = d
+	return sh
+}
+
 // HandleSSE creates an HTTP handler for SSE connections
 func (sh *SSEHandler) HandleSSE(w http.ResponseWriter, r *http.Request) {
 	clientReq := &SSEClientReq{
@@ -76,6 +97,24 @@ func (sh *SSEHandler) Init() {
 	sh.webcast.GetRoutes().ForwardPathFn(sh.PathPrefix.Suffix("stream"), sh.HandleSSE)
 }
 
+// SetSchemas validates outgoing payloads against registry while it is enabled
+// and serves the schemas at {prefix}/schemas once mounted
+func (sh *SSEHandler) SetSchemas(registry *schema.Registry) *SSEHandler {
+	sh.webcast.SetSchemas(registry)
+	return sh
+}
+
+// Drain closes all streams spread over window with a reconnect hint and
+// turns new streams away, see WebCast.Drain
+func (sh *SSEHandler) Drain(window time.Duration) int {
+	return sh.webcast.Drain(window)
+}
+
+// SessionRevoked closes the streams of a revoked auth session, see WebCast.SessionRevoked
+func (sh *SSEHandler) SessionRevoked(sessionKey string) int {
+	return sh.webcast.SessionRevoked(sessionKey)
+}
+
 // Broadcast sends a message to all connected clients
 func (sh *SSEHandler) Broadcast(message string) int {
 	return sh.webcast.Broadcast(message)
@@ -86,6 +125,22 @@ func (sh *SSEHandler) BroadcastJSON(data any) (int, error) {
 	return sh.webcast.BroadcastJSON(data)
 }
 
+// SetBatchWindow enables batching of BroadcastBatched/BroadcastCoalesced within window
+func (sh *SSEHandler) SetBatchWindow(window time.Duration) *SSEHandler {
+	sh.webcast.SetBatchWindow(window)
+	return sh
+}
+
+// BroadcastBatched queues data to be sent with the next batch
+func (sh *SSEHandler) BroadcastBatched(data any) {
+	sh.webcast.BroadcastBatched(data)
+}
+
+// BroadcastCoalesced queues data for the next batch, keeping only the latest item per key
+func (sh *SSEHandler) BroadcastCoalesced(key string, data any) {
+	sh.webcast.BroadcastCoalesced(key, data)
+}
+
 // SendToClient sends a message to a specific client
 func (sh *SSEHandler) SendToClient(clientID string, message string) bool {
 	return sh.webcast.SendToClient(clientID, message)
@@ -96,6 +151,26 @@ func (sh *SSEHandler) SendJSONToClient(clientID string, data any) (bool, error)
 	return sh.webcast.SendJSONToClient(clientID, data)
 }
 
+// SendEventToClient sends data to a specific client as a named event
+func (sh *SSEHandler) SendEventToClient(clientID, event string, data any) (bool, error) {
+	return sh.webcast.SendEventToClient(clientID, event, data)
+}
+
+// BroadcastEvent sends data to all connected clients as a named event
+func (sh *SSEHandler) BroadcastEvent(event string, data any) (int, error) {
+	return sh.webcast.BroadcastEvent(event, data)
+}
+
+// SendToClientsWithMetadata sends a message to every client whose metadata has key set to value
+func (sh *SSEHandler) SendToClientsWithMetadata(key, value, message string) int {
+	return sh.webcast.SendToClientsWithMetadata(key, value, message)
+}
+
+// SetClientMetadata changes one metadata key of a connected client
+func (sh *SSEHandler) SetClientMetadata(clientID, key, value string) bool {
+	return sh.webcast.SetClientMetadata(clientID, key, value)
+}
+
 // GetClientCount returns the number of connected clients
 func (sh *SSEHandler) GetClientCount() int {
 	return sh.webcast.GetClientCount()
@@ -141,6 +216,9 @@ func (sc *SSEClientReq) Accept() {
 		Metadata:          sc.Metadata,
 		OnConnect:         sc.handler.OnClientConnect,
 		OnDisconnect:      sc.handler.OnClientDisconnect,
+
+		MaxConnectionDuration: sc.handler.MaxConnectionDuration,
+		IdleTimeout:           sc.handler.IdleTimeout,
 	})
 }
 
@@ -0,0 +1,82 @@
+package handlersse
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-xlite/wbx/comm/audit"
+	"github.com/go-xlite/wbx/comm/events"
+	handler_role "github.com/go-xlite/wbx/comm/handler_role"
+	hl1 "github.com/go-xlite/wbx/utils"
+)
+
+// sendRequest is the body accepted by the send endpoint
+type sendRequest struct {
+	ClientID string          `json:"clientId"`
+	Message  json.RawMessage `json:"message"`
+}
+
+// EnableSendEndpoint exposes POST {prefix}/send on Mount for pushing a message to one client.
+// authorize is consulted for every request; pass nil only on trusted networks.
+func (sh *SSEHandler) EnableSendEndpoint(authorize func(r *http.Request) bool) *SSEHandler {
+	sh.sendEnabled = true
+	sh.authorizeSend = authorize
+	return sh
+}
+
+// Mount registers the handler on server under its PathPrefix in one call:
+// the client scripts ({prefix}/p/*.js), the event stream ({prefix}/stream),
+// stats ({prefix}/stats), event schemas ({prefix}/schemas) and, if enabled, the per-client send endpoint ({prefix}/send).
+// Client events go to the server's event bus unless the WebCast already has one.
+func (sh *SSEHandler) Mount(server handler_role.IHandler) *SSEHandler {
+	if bus, ok := server.(interface{ Events() *events.Bus }); ok && sh.webcast.Events == nil {
+		sh.webcast.SetEvents(bus.Events())
+	}
+	server.GetRoutes().HandlePathPrefixFn(sh.PathPrefix.Get(), sh.Isolate(sh.webcast.OnRequest))
+	sh.Init()
+
+	routes := sh.webcast.GetRoutes()
+	routes.GETPathFn(sh.PathPrefix.Suffix("stats"), func(w http.ResponseWriter, r *http.Request) {
+		hl1.Helpers.WriteJSON(w, http.StatusOK, sh.GetStats())
+	})
+	routes.GETPathFn(sh.PathPrefix.Suffix("schemas"), func(w http.ResponseWriter, r *http.Request) {
+		if sh.webcast.Schemas == nil {
+			hl1.Helpers.WriteNotFound(w)
+			return
+		}
+		sh.webcast.Schemas.Handler()(w, r)
+	})
+	if sh.sendEnabled {
+		// Record every send, denied ones included, as an admin.request audit event
+		send := audit.Middleware(nil)(http.HandlerFunc(sh.handleSend))
+		routes.POSTPathFn(sh.PathPrefix.Suffix("send"), send.ServeHTTP)
+	}
+	return sh
+}
+
+// handleSend delivers {"clientId": "...", "message": ...} to a single client.
+// String messages are sent verbatim, anything else as JSON.
+func (sh *SSEHandler) handleSend(w http.ResponseWriter, r *http.Request) {
+	if sh.authorizeSend != nil && !sh.authorizeSend(r) {
+		hl1.Helpers.WriteJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+
+	var req sendRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil || req.ClientID == "" || len(req.Message) == 0 {
+		hl1.Helpers.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "clientId and message are required"})
+		return
+	}
+
+	message := string(req.Message)
+	var text string
+	if json.Unmarshal(req.Message, &text) == nil {
+		message = text
+	}
+
+	if !sh.SendToClient(req.ClientID, message) {
+		hl1.Helpers.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "client not connected"})
+		return
+	}
+	hl1.Helpers.WriteJSON(w, http.StatusOK, map[string]bool{"sent": true})
+}
@@ -2,10 +2,13 @@ package swayhandler
 
 import (
 	"embed"
+	"fmt"
 	"net/http"
 	"strings"
 
+	"github.com/go-xlite/wbx/comm"
 	handler_role "github.com/go-xlite/wbx/comm/handler_role"
+	"github.com/go-xlite/wbx/services/webauth"
 	"github.com/go-xlite/wbx/services/websway"
 	hl1 "github.com/go-xlite/wbx/utils"
 	"github.com/go-xlite/wbx/weblite"
@@ -20,20 +23,81 @@ type SwayHandler struct {
 	*handler_role.HandlerRole
 	LoginPage string
 	sway      *websway.WebSway
+
+	// Session enforcement (disabled while Sessions is nil), see session.go
+	Sessions          comm.SessionResolver
+	SessionScope      string          // SessionScopeHTML (default) or SessionScopeAll
+	SessionExemptExts map[string]bool // Extensions served without a session under SessionScopeAll
+	ReturnToParam     string          // Login redirect parameter carrying the original URL ("" disables)
+
+	// ContentSecurityPolicy is sent with HTML pages ("" sends none unless an
+	// app manifest adds sources); manifests are loaded by Run, see manifest.go
+	ContentSecurityPolicy string
+	manifests             map[string]*AppManifest
 }
 
 // NewSwayHandler creates a SwayHandler wrapper around an existing handler instance
 func NewSwayHandler(sway *websway.WebSway) *SwayHandler {
 	handlerRole := handler_role.NewHandler()
+	sway.ContentTypeFor = handlerRole.ResolveContentType
 
 	return &SwayHandler{
-		sway:        sway,
-		HandlerRole: handlerRole,
-		LoginPage:   "/login",
+		sway:          sway,
+		HandlerRole:   handlerRole,
+		LoginPage:     "/login",
+		ReturnToParam: webauth.DefaultReturnParam,
+		SessionExemptExts: map[string]bool{
+			".js": true, ".css": true, ".ico": true, ".png": true, ".svg": true,
+			".woff": true, ".woff2": true, ".webmanifest": true,
+		},
 	}
 }
 
+// AddPreload announces critical assets of an entry point (app directory such as
+// "index") with Link: rel=preload headers on its HTML pages
+func (ws *SwayHandler) AddPreload(entry string, assets ...websway.PreloadAsset) *SwayHandler {
+	ws.sway.AddPreload(entry, assets...)
+	return ws
+}
+
+// AddPrefetch announces API endpoints the HTML pages of entry call on boot,
+// e.g. AddPrefetch("index", "/api/me", "/api/config")
+func (ws *SwayHandler) AddPrefetch(entry string, urls ...string) *SwayHandler {
+	ws.sway.AddPrefetch(entry, urls...)
+	return ws
+}
+
+// SetEarlyHints sends the preload links as 103 Early Hints where supported
+func (ws *SwayHandler) SetEarlyHints(enabled bool) *SwayHandler {
+	ws.sway.SetEarlyHints(enabled)
+	return ws
+}
+
+// SetImmutableAssets caches fingerprinted assets such as app.abc123.js for a
+// year as immutable instead of for the sway CacheMaxAge
+func (ws *SwayHandler) SetImmutableAssets(enabled bool) *SwayHandler {
+	ws.sway.SetImmutableAssets(enabled)
+	return ws
+}
+
+// SetIncludes enables server-side includes such as <!--#include virtual="header" -->
+// in HTML pages; vars supplies <!--#echo var="..." --> values (may be nil)
+func (ws *SwayHandler) SetIncludes(enabled bool, vars func(r *http.Request) map[string]string) *SwayHandler {
+	ws.sway.SetIncludes(enabled).SetIncludeVars(vars)
+	return ws
+}
+
+// SetLocales serves localized pages (index.de.html or de/index.html) by
+// Accept-Language, falling back to the default bundle in defaultLocale
+func (ws *SwayHandler) SetLocales(defaultLocale string, supported ...string) *SwayHandler {
+	ws.sway.SetLocales(defaultLocale, supported...)
+	return ws
+}
+
 func (ws *SwayHandler) Run(wbl *weblite.WebLite) {
+	if err := ws.LoadManifests(); err != nil {
+		fmt.Printf("[SwayHandler] app manifests not loaded: %v\n", err)
+	}
 
 	wbl.GetRoutes().ForwardPathPrefixFn("/m/xlite/sway/p", func(w http.ResponseWriter, r *http.Request) {
 		if strings.HasSuffix(r.URL.Path, ".js") {
@@ -44,7 +108,7 @@ func (ws *SwayHandler) Run(wbl *weblite.WebLite) {
 		hl1.Helpers.WriteNotFound(w)
 	})
 
-	wbl.GetRoutes().ForwardPathPrefixFn(ws.PathPrefix.Suffix("/"), func(w http.ResponseWriter, r *http.Request) {
+	wbl.GetRoutes().ForwardPathPrefixFn(ws.PathPrefix.Suffix("/"), ws.Isolate(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/index/p/sw.js" {
 			ws.sway.ServeServiceWorker("/index/sw.js", ws.PathPrefix.Suffix("/"), w, r)
 			return
@@ -55,12 +119,26 @@ func (ws *SwayHandler) Run(wbl *weblite.WebLite) {
 			return
 		}
 
+		if ws.isManifestFile(r.URL.Path) {
+			ws.sway.NotFound(w, r)
+			return
+		}
+
+		if r = ws.enforceSession(w, r); r == nil {
+			return
+		}
+		ws.applyFallback(r)
+		ws.applyCSP(w, r.URL.Path)
 		ws.sway.ServeFile(w, r)
-	})
+	}))
 
-	wbl.GetRoutes().HandlePathFn(ws.PathPrefix.Get(), func(w http.ResponseWriter, r *http.Request) {
+	wbl.GetRoutes().HandlePathFn(ws.PathPrefix.Get(), ws.Isolate(func(w http.ResponseWriter, r *http.Request) {
 		r.URL.Path = "/"
+		if r = ws.enforceSession(w, r); r == nil {
+			return
+		}
+		ws.applyCSP(w, r.URL.Path)
 		ws.sway.ServeFile(w, r)
-	})
+	}))
 
 }
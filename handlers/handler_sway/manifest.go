@@ -0,0 +1,265 @@
+package swayhandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-xlite/wbx/services/websway"
+)
+
+// ManifestName is the file inside an app directory describing the app
+const ManifestName = "sway.json"
+
+// Auth requirements of manifest apps and routes
+const (
+	AuthPublic  = "public"  // No session required
+	AuthSession = "session" // Every file requires a session
+	AuthHTML    = "html"    // Only HTML documents require a session
+)
+
+// DefaultCSP is the policy manifest CSP additions extend when the handler has none
+const DefaultCSP = "default-src 'self'"
+
+// AppManifest describes an app bundle, read from <app>/sway.json when the
+// handler is mounted:
+//
+//	{
+//	  "auth": "html",
+//	  "routes": [
+//	    {"path": "/admin/", "auth": "session"},
+//	    {"path": "/app/", "fallback": "app/index.html"}
+//	  ],
+//	  "csp": {"img-src": ["data:"], "connect-src": ["https://api.example.com"]},
+//	  "preload": ["/index/p/app.js"]
+//	}
+type AppManifest struct {
+	Auth    string              `json:"auth,omitempty"`    // Default for the app; the handler's SessionScope when empty
+	Routes  []ManifestRoute     `json:"routes,omitempty"`  // The deepest matching route applies
+	CSP     map[string][]string `json:"csp,omitempty"`     // Sources added to the handler's policy per directive
+	Preload []string            `json:"preload,omitempty"` // Assets announced on the app's HTML pages
+
+	policy string // Content-Security-Policy computed at load
+}
+
+// ManifestRoute configures the files below Path, relative to the app directory
+type ManifestRoute struct {
+	Path string `json:"path"`
+	Auth string `json:"auth,omitempty"`
+	// Fallback is served (relative to the app directory) for missing files
+	// below Path, for client-side routing
+	Fallback string `json:"fallback,omitempty"`
+}
+
+// SetContentSecurityPolicy sets the policy sent with HTML pages; app manifests
+// add their sources to it
+func (ws *SwayHandler) SetContentSecurityPolicy(policy string) *SwayHandler {
+	ws.ContentSecurityPolicy = policy
+	return ws
+}
+
+// LoadManifests reads the manifest of every app directory; Run calls it
+func (ws *SwayHandler) LoadManifests() error {
+	fsp := ws.sway.FsProvider
+	entries, err := fsp.ListDir("")
+	if err != nil {
+		return err
+	}
+
+	manifests := make(map[string]*AppManifest)
+	for _, entry := range entries {
+		if !entry.IsDir {
+			continue
+		}
+		file := path.Join(entry.Name, ManifestName)
+		if !fsp.Exists(file) {
+			continue
+		}
+		data, err := fsp.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		manifest := &AppManifest{}
+		if err := json.Unmarshal(data, manifest); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if err := manifest.validate(); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if len(manifest.CSP) > 0 {
+			manifest.policy = mergeCSP(ws.ContentSecurityPolicy, manifest.CSP)
+		}
+		for _, url := range manifest.Preload {
+			ws.sway.AddPreload(entry.Name, websway.Preload(url))
+		}
+		manifests[entry.Name] = manifest
+	}
+	ws.manifests = manifests
+	return nil
+}
+
+// validate checks the auth values and normalizes route paths, deepest first
+func (m *AppManifest) validate() error {
+	valid := func(auth string) bool {
+		return auth == "" || auth == AuthPublic || auth == AuthSession || auth == AuthHTML
+	}
+	if !valid(m.Auth) {
+		return fmt.Errorf("unknown auth %q", m.Auth)
+	}
+	for i := range m.Routes {
+		route := &m.Routes[i]
+		if !valid(route.Auth) {
+			return fmt.Errorf("route %s: unknown auth %q", route.Path, route.Auth)
+		}
+		route.Path = strings.TrimPrefix(path.Clean("/"+route.Path), "/")
+		if route.Fallback != "" {
+			route.Fallback = strings.TrimPrefix(path.Clean("/"+route.Fallback), "/")
+		}
+	}
+	csp := make(map[string][]string, len(m.CSP))
+	for name, sources := range m.CSP {
+		name = strings.ToLower(name)
+		csp[name] = append(csp[name], sources...)
+	}
+	m.CSP = csp
+	sort.SliceStable(m.Routes, func(i, j int) bool {
+		return len(m.Routes[i].Path) > len(m.Routes[j].Path)
+	})
+	return nil
+}
+
+// appFile resolves a request path to its app and the file path inside the app
+func (ws *SwayHandler) appFile(requestPath string) (app, file string, ok bool) {
+	storagePath, err := ws.sway.ExtractStoragePath(requestPath, "/", ws.sway.PathBase)
+	if err != nil {
+		return "", "", false
+	}
+	app, file, _ = strings.Cut(path.Clean(strings.ReplaceAll(storagePath, "\\", "/")), "/")
+	return app, file, true
+}
+
+// manifestFor returns the manifest of the app serving requestPath and the
+// route covering it (nil when no route matches)
+func (ws *SwayHandler) manifestFor(requestPath string) (*AppManifest, *ManifestRoute, string) {
+	app, file, ok := ws.appFile(requestPath)
+	if !ok {
+		return nil, nil, ""
+	}
+	manifest := ws.manifests[app]
+	if manifest == nil {
+		return nil, nil, file
+	}
+	for i := range manifest.Routes {
+		route := &manifest.Routes[i]
+		if route.Path == "" || file == route.Path || strings.HasPrefix(file, route.Path+"/") {
+			return manifest, route, file
+		}
+	}
+	return manifest, nil, file
+}
+
+// manifestAuth returns the auth requirement the manifests set for requestPath ("" if none)
+func (ws *SwayHandler) manifestAuth(requestPath string) string {
+	manifest, route, _ := ws.manifestFor(requestPath)
+	if manifest == nil {
+		return ""
+	}
+	if route != nil && route.Auth != "" {
+		return route.Auth
+	}
+	return manifest.Auth
+}
+
+// isManifestFile reports whether requestPath asks for an app manifest, which is never served
+func (ws *SwayHandler) isManifestFile(requestPath string) bool {
+	_, file, ok := ws.appFile(requestPath)
+	return ok && file == ManifestName
+}
+
+// applyFallback rewrites requests for missing files below a route with a
+// fallback to that fallback
+func (ws *SwayHandler) applyFallback(r *http.Request) {
+	_, route, _ := ws.manifestFor(r.URL.Path)
+	if route == nil || route.Fallback == "" {
+		return
+	}
+	storagePath, err := ws.sway.ExtractStoragePath(r.URL.Path, "/", ws.sway.PathBase)
+	if err != nil || ws.sway.FsProvider.Exists(storagePath) {
+		return
+	}
+	app, _, _ := ws.appFile(r.URL.Path)
+	r.URL.Path = "/" + app + "/" + ws.sway.VirtualDirSegment + "/" + route.Fallback
+}
+
+// applyCSP sets the Content-Security-Policy of HTML documents
+func (ws *SwayHandler) applyCSP(w http.ResponseWriter, requestPath string) {
+	_, file, ok := ws.appFile(requestPath)
+	if !ok || !isHTMLPath(file) {
+		return
+	}
+	policy := ws.ContentSecurityPolicy
+	if manifest, _, _ := ws.manifestFor(requestPath); manifest != nil && manifest.policy != "" {
+		policy = manifest.policy
+	}
+	if policy != "" {
+		w.Header().Set("Content-Security-Policy", policy)
+	}
+}
+
+// mergeCSP adds sources to the directives of base (DefaultCSP when empty);
+// additions are keyed by lowercase directive name.
+// Directives new to the policy start from the default-src sources, as they
+// would otherwise drop what default-src allowed.
+func mergeCSP(base string, additions map[string][]string) string {
+	if base == "" {
+		base = DefaultCSP
+	}
+	var names []string
+	directives := make(map[string][]string)
+	for part := range strings.SplitSeq(base, ";") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if _, seen := directives[name]; !seen {
+			names = append(names, name)
+		}
+		directives[name] = fields[1:]
+	}
+
+	added := make([]string, 0, len(additions))
+	for name := range additions {
+		added = append(added, name)
+	}
+	sort.Strings(added)
+	for _, name := range added {
+		sources, exists := directives[name]
+		if !exists {
+			sources = slices.Clone(directives["default-src"])
+			names = append(names, name)
+		}
+		for _, source := range additions[name] {
+			if !slices.Contains(sources, source) {
+				sources = append(sources, source)
+			}
+		}
+		directives[name] = sources
+	}
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, strings.TrimSpace(name+" "+strings.Join(directives[name], " ")))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// isHTMLPath reports whether a file is served as an HTML document
+func isHTMLPath(file string) bool {
+	ext := strings.ToLower(path.Ext(file))
+	return ext == "" || ext == ".html" || ext == ".htm"
+}
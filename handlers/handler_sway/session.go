@@ -0,0 +1,107 @@
+package swayhandler
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-xlite/wbx/comm"
+	"github.com/go-xlite/wbx/services/webauth"
+	hl1 "github.com/go-xlite/wbx/utils"
+	"github.com/go-xlite/wbx/weblite"
+)
+
+// Session enforcement scopes
+const (
+	SessionScopeHTML = "html" // Only HTML documents require a session (default)
+	SessionScopeAll  = "all"  // Every file requires a session except exempt extensions
+)
+
+// SetSessionResolver enables session enforcement: requests in scope without a
+// valid session are redirected to LoginPage (or get 401 JSON for XHR/fetch)
+func (ws *SwayHandler) SetSessionResolver(resolver comm.SessionResolver) *SwayHandler {
+	ws.Sessions = resolver
+	return ws
+}
+
+// SetSessionScope selects which files require a session (SessionScopeHTML or SessionScopeAll)
+func (ws *SwayHandler) SetSessionScope(scope string) *SwayHandler {
+	ws.SessionScope = scope
+	return ws
+}
+
+// AddSessionExemptExtensions lets files with these extensions through without a
+// session when the scope is SessionScopeAll (e.g. ".css", ".woff2")
+func (ws *SwayHandler) AddSessionExemptExtensions(exts ...string) *SwayHandler {
+	if ws.SessionExemptExts == nil {
+		ws.SessionExemptExts = make(map[string]bool)
+	}
+	for _, ext := range exts {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		ws.SessionExemptExts[strings.ToLower(ext)] = true
+	}
+	return ws
+}
+
+// requiresSession reports whether path is covered by the session scope; app
+// manifests override the scope for their files
+func (ws *SwayHandler) requiresSession(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	isHTML := ext == "" || ext == ".html" || ext == ".htm"
+
+	switch ws.manifestAuth(path) {
+	case AuthPublic:
+		return false
+	case AuthSession:
+		return true
+	case AuthHTML:
+		return isHTML
+	}
+	if ws.Sessions == nil {
+		return false
+	}
+
+	if ws.SessionScope != SessionScopeAll {
+		return isHTML
+	}
+	return isHTML || !ws.SessionExemptExts[ext]
+}
+
+// enforceSession resolves the session for r when required. It returns the
+// request to continue with (carrying the session in its context), or nil when
+// a redirect or 401 was written instead. Without a resolver only manifests can
+// require a session, and such files stay closed.
+func (ws *SwayHandler) enforceSession(w http.ResponseWriter, r *http.Request) *http.Request {
+	if !ws.requiresSession(r.URL.Path) {
+		return r
+	}
+
+	if ws.Sessions != nil {
+		if data, ok := ws.Sessions.ResolveSession(r); ok {
+			return r.WithContext(weblite.SetSessionContext(r.Context(), data))
+		}
+	}
+
+	if isXHR(r) || ws.LoginPage == "" {
+		w.Header().Set("Cache-Control", "no-store")
+		hl1.Helpers.WriteJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return nil
+	}
+	// r.RequestURI keeps the full original URL even after prefix forwarding
+	http.Redirect(w, r, webauth.LoginURL(ws.LoginPage, ws.ReturnToParam, r.RequestURI), http.StatusFound)
+	return nil
+}
+
+// isXHR reports whether r comes from script (XHR/fetch) rather than navigation
+func isXHR(r *http.Request) bool {
+	if r.Header.Get("X-Requested-With") == "XMLHttpRequest" {
+		return true
+	}
+	if mode := r.Header.Get("Sec-Fetch-Mode"); mode != "" {
+		return mode != "navigate"
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html")
+}
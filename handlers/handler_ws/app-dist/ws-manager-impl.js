@@ -1,2 +1 @@
-var O=1,Q=2,V=4,X=8,Y=1,Z=4,_=1,$=2,A=4,P=1,R=2,E=4,L=8;var f=1,q=2,W=4,h=8;class J{#B;#$;#D;#G;#A;#Z;#F;#j;#P;#V;#_;#h;#O;#X;#Y;#Q;#z;#R;#E;#C;constructor(j={}){if(!j.wsRoute||!j.wsWorkerRoute||!j.endpoint)throw Error("wsRoute, wsWorkerRoute, and endpoint options are required");this.#B=Object.assign({debug:!1,wsRoute:j.wsRoute,workerRoute:j.wsWorkerRoute,autoConnect:!0,reconnectOnDisconnect:!0,maxReconnectAttempts:10,endpoint:j.endpoint,sessionStrategy:2,connIdStorageKey:"ws-conn-id",sessionIdStorageKey:"ws-session-id",modePrefStorageKey:"ws-mode-pref",coordinationChannel:"ws-coordination",coordinationHeartbeat:2000,assumeDisconnectedAfter:1e4},j),this.#B.endpointKey=this.#B.endpoint.replace(/\//g,"-"),this.connectionId=this.#l(),this.sessionId=this.#u(),this.sessionData=this.#y(),this.sessionStrategy=this.#B.sessionStrategy;let z=localStorage.getItem(this.#N("modePref"));if(this.connectionMode=z?parseInt(z,10):null,this.connectionState=1,this.broadcastChannel=null,this.#$=0,this.#D=null,this.#G=null,this.#A={[1]:[],[2]:[],[8]:[],[4]:[]},this.#Z={},this.#F=!1,this.#j=this.#w(),this.#P=null,this.#V=null,this.#_=!!this.connectionMode,this.#B.autoConnect)setTimeout(()=>this.connect(),0)}connect(){if(this.broadcastChannel&&!this.#F)return;if(this.connectionMode)switch(this.connectionMode){case 1:if(typeof SharedWorker<"u"){this.connectViaSharedWorker();return}break;case 4:this.connectDirectly();return}if(typeof SharedWorker<"u")this.connectViaSharedWorker();else this.connectDirectly()}setPreferredMode(j){if([1,4].includes(j)){if(this.connectionMode!==j)this.#M();return localStorage.setItem(this.#N("modePref"),j),this.connectionMode=j,this.#_=!0,!0}return!1}clearPreferredMode(){localStorage.removeItem(this.#N("modePref")),this.connectionMode=null,this.#_=!1}connectViaSharedWorker(){if(this.#M(),this.sessionStrategy=4,!this.broadcastChannel)this.initConnectionCoordination();try{this.#G=new SharedWorker(this.#B.workerRoute),this.#G.port.start(),this.#G.port.addEventListener("message",(j)=>{this.#K(j.data)}),this.#G.port.postMessage({type:1,connectionId:this.connectionId}),this.connectionMode=1,this.#G.onerror=(j)=>{if(this.#H("SharedWorker error",j),this.#G=null,!this.#_)this.connectDirectly();else this.#H("Not falling back because mode was explicitly set"),this.connectionState=8,this.#J(4,{message:"SharedWorker connection failed"})}}catch(j){if(this.#H("Failed to initialize SharedWorker",j),!this.#_)this.connectDirectly();else this.#H("Not falling back because mode was explicitly set"),this.connectionState=8,this.#J(4,{message:"SharedWorker initialization failed"})}}connectDirectly(){if(this.#M(),this.broadcastChannel)try{this.#x(),this.broadcastChannel.close(),this.broadcastChannel=null,this.#z=null}catch(D){}let j=this.#q(),B=`${window.location.protocol==="https:"?"wss:":"ws:"}//${window.location.host}${this.#B.wsRoute}?connid=${j}&sessionid=${this.sessionId}`;this.#D=new WebSocket(B),this.connectionMode=4,this.#D.onopen=()=>{this.connectionState=4,this.#$=0,this.#J(2)},this.#D.onclose=()=>{if(this.connectionState=1,this.#J(8),this.#B.reconnectOnDisconnect&&!this.#_)this.#S()},this.#D.onerror=(D)=>{this.#H("Direct WebSocket error",D),this.#J(4,D)},this.#D.onmessage=(D)=>{D.data.split(`
-`).filter((G)=>G.trim()).forEach((G)=>{this.#J(1,G)})}}#M(){if(this.connectionState=1,this.#G){try{if(this.#G.port.postMessage({type:2}),this.#G.port.close(),typeof this.#G.terminate==="function")this.#G.terminate()}catch(j){}this.#G=null}if(this.#D){try{this.#D.close()}catch(j){}this.#D=null}if(this.#C)clearTimeout(this.#C),this.#C=null;if(this.broadcastChannel)try{if(this.#F)this.broadcastChannel.postMessage({type:256,id:this.#j,timestamp:Date.now()});this.broadcastChannel.close(),this.broadcastChannel=null}catch(j){}}#p(){if(this.#h)window.removeEventListener("message",this.#h),this.#h=null}send(j){if(typeof j!=="string")j=JSON.stringify(j);if(this.broadcastChannel&&!this.#F){if(!this.#Q)this.#Q=new Map;let z=j,B=Date.now();if(this.#Q.has(z)){let F=this.#Q.get(z);if(B-F.timestamp<1000)return!0}let D=`${this.#j}-${Date.now()}-${Math.random().toString(36).substr(2,9)}`;return this.#Q.set(z,{requestId:D,timestamp:B}),setTimeout(()=>{if(this.#Q)this.#Q.delete(z)},5000),this.broadcastChannel.postMessage({type:1,requestId:D,senderId:this.#j,id:this.#j,message:j,timestamp:B}),!0}if(this.connectionState!==4)return this.#H("Cannot send message, not connected"),!1;switch(this.connectionMode){case 1:this.#G.port.postMessage({type:4,data:j});break;case 4:if(this.#D&&this.#D.readyState===WebSocket.OPEN)this.#D.send(j);else return!1;break;default:return!1}return!0}#K(j){switch(j.type){case 8:this.connectionState=4,this.#J(2);break;case 16:if(this.connectionState=1,this.#J(8),this.#B.reconnectOnDisconnect)this.#G.port.postMessage({type:128,connectionId:this.connectionId});break;case 32:if(this.#J(1,j.data),this.broadcastChannel&&this.#F)this.broadcastChannel.postMessage({type:4,id:this.#j,message:j.data,timestamp:Date.now()});break;case 64:this.#H("WebSocket error via SharedWorker",j.error),this.#J(4,j.error);break;default:this.#H("Unknown message from SharedWorker",j)}}#T(j){try{if(!j||!j.type)return;if([1,2].includes(j.type)&&j.senderId===this.#j)return;switch(j.type){case 8:case 16:if(this.#z&&j.id!==this.#j){let z=this.#z.has(j.id);if(this.#z.set(j.id,{id:j.id,isPrimary:j.isPrimary,lastSeen:Date.now()}),!z)this.#L()}break;case 32:if(this.#j>j.id)this.broadcastChannel.postMessage({type:64,id:this.#j,timestamp:Date.now()}),setTimeout(()=>this.#W(),100);break;case 64:if(this.#V)clearTimeout(this.#V),this.#V=null;break;case 128:if(j.id!==this.#j)this.#m();break;case 256:if(j.id!==this.#j)setTimeout(()=>this.#W(),100+Math.random()*400);break;case 4:if(j.id!==this.#j){if(!this.#F)this.#J(1,j.message)}break;case 1:if(this.#F&&j.id!==this.#j&&j.senderId!==this.#j){let z=j.requestId;if(!this.#X)this.#X=new Set;if(this.#X.has(z)){this.broadcastChannel.postMessage({type:2,requestId:j.requestId,targetId:j.senderId,senderId:this.#j,success:!1,duplicate:!0,timestamp:Date.now(),id:this.#j});return}this.#X.add(z),setTimeout(()=>{if(this.#X)this.#X.delete(z)},1e4);let B=!1;if(this.connectionState!==4){this.#H("Cannot forward message, primary not connected"),this.broadcastChannel.postMessage({type:2,requestId:j.requestId,targetId:j.senderId,senderId:this.#j,success:!1,error:"not_connected",timestamp:Date.now(),id:this.#j});return}switch(this.connectionMode){case 1:if(this.#G)this.#G.port.postMessage({type:4,data:j.message}),B=!0;break;case 4:if(this.#D&&this.#D.readyState===WebSocket.OPEN)this.#D.send(j.message),B=!0;break}this.broadcastChannel.postMessage({type:2,requestId:j.requestId,targetId:j.senderId,senderId:this.#j,success:B,timestamp:Date.now(),id:this.#j})}break;case 2:if(!this.#F&&j.targetId===this.#j){if(j.duplicate);else if(j.error==="not_connected");}break;case 512:if(this.#z&&j.id!==this.#j&&j.tabs){let z=!1;if(j.tabs.forEach((B)=>{if(B.id!==this.#j){let D=this.#z.has(B.id),F=D?this.#z.get(B.id):null;if(!D||F&&F.isPrimary!==B.isPrimary)this.#z.set(B.id,B),z=!0}}),z)this.#f(8,Array.from(this.#z.values()))}break;case 1024:if(this.#B.sessionStrategy===2&&j.id!==this.#j)this.sessionData=j.sessionData;break;default:this.#H(`Unknown coordination message type: ${j.type}`,j);break}}catch(z){this.#H("Error handling coordination message",z)}}#S(){if(this.#$>=this.#B.maxReconnectAttempts){this.#H("Maximum reconnection attempts reached");return}let j=Math.min(1000*Math.pow(2,this.#$),30000);this.#$++,setTimeout(()=>{if(this.connectionState===1)this.connectDirectly()},j)}on(j,z){if(this.#A[j])this.#A[j].push(z);return this}#J(j,z){if(j===1){let B=typeof z==="string"?z:JSON.stringify(z);if(!this.#O)this.#O=new Map;let D=Date.now(),F=this.#O.get(B);if(F&&D-F<1000)return;if(this.#O.set(B,D),!this.#R)this.#R=setInterval(()=>{let G=Date.now()-5000;if(this.#O)this.#O.forEach((H,N)=>{if(H<G)this.#O.delete(N)})},1e4)}if(this.#A[j])this.#A[j].forEach((B)=>{try{B(z)}catch(D){}})}#N(j){return`${this.#B[j+"StorageKey"]}${this.#B.endpointKey}`}#u(){if(this.#B.sessionStrategy===1)return this.#w();let j=this.#N("sessionId"),z=localStorage.getItem(j);if(!z)z=this.#q(),localStorage.setItem(j,z);return z}#k(){return this.sessionId=this.#q(),localStorage.setItem(this.#N("sessionId"),this.sessionId),this.sessionData={},this.sessionId}#y(){if(this.#B.sessionStrategy===1)return{};let j=this.#N("sessionData"),z=localStorage.getItem(j);return z?JSON.parse(z):{}}#I(){if(this.#B.sessionStrategy===1)return;let j=this.#N("sessionData");if(localStorage.setItem(j,JSON.stringify(this.sessionData)),this.broadcastChannel)this.broadcastChannel.postMessage({type:1024,sessionData:this.sessionData,timestamp:Date.now()})}setSessionValue(j,z){this.sessionData[j]=z,this.#I()}getSessionValue(j){return this.sessionData[j]}deleteSessionValue(j){delete this.sessionData[j],this.#I()}clearSession(){this.sessionData={},this.#k();let j=this.#N("sessionData");localStorage.removeItem(j)}#l(){let j=this.#N("connId"),z=localStorage.getItem(j);if(!z)z=this.#q(),localStorage.setItem(j,z);return z}#v(){return this.connectionId=this.#q(),localStorage.setItem(this.#N("connId"),this.connectionId),this.connectionId}disconnect(j=!1){switch(this.connectionState=1,this.connectionMode){case 1:if(this.#G){try{this.#G.port.postMessage({type:2}),this.#G.port.close()}catch(z){}this.#G=null}break;case 4:if(this.#D){try{this.#D.onopen=null,this.#D.onmessage=null,this.#D.onerror=null,this.#D.onclose=null,this.#D.close()}catch(z){}this.#D=null}break}if(!j&&this.#F&&this.broadcastChannel)this.broadcastChannel.postMessage({type:256,id:this.#j,timestamp:Date.now()});this.#J(8)}resetConnection(){return this.disconnect(),this.clearPreferredMode(),this.connectionId=this.#v(),this.connect()}#H(j,z){if(this.#B.debug)if(z)window.console.log(`[WebSocketManager] ${j}`,z);else window.console.log(`[WebSocketManager] ${j}`)}#i(){try{let j=new SharedWorker(this.#B.workerRoute);j.port.start(),j.port.postMessage({type:256}),setTimeout(()=>{try{j.port.close()}catch(z){}},100)}catch(j){}try{let z=`${window.location.protocol==="https:"?"wss:":"ws:"}//${window.location.host}${this.#B.wsRoute}?connid=${this.connectionId}&cleanup=1`,B=new WebSocket(z);B.onopen=()=>{B.send(JSON.stringify({type:1,previousMode:1,newMode:this.connectionMode})),setTimeout(()=>B.close(),50)}}catch(j){}}initConnectionCoordination(){if(typeof BroadcastChannel>"u")return!1;try{return this.broadcastChannel=new BroadcastChannel(this.#B.coordinationChannel),this.broadcastChannel.onmessage=(j)=>{if(!this.#Y)this.#Y=new Map;let z=JSON.stringify(j.data),B=Date.now(),D=this.#Y.get(z);if(D&&B-D<100)return;if(this.#Y.set(z,B),!this.#E)this.#E=setInterval(()=>{let F=Date.now()-1000;this.#Y.forEach((G,H)=>{if(G<F)this.#Y.delete(H)})},5000);this.#T(j.data)},this.#c(),this.#f(1),!0}catch(j){return this.#H("Error initializing coordination",j),!1}}#c(){this.#x(),this.#U(),this.#P=setInterval(()=>{this.#g()},this.#B.coordinationHeartbeat),this.#W(),this.#z=new Map,this.#z.set(this.#j,{id:this.#j,isPrimary:this.#F,lastSeen:Date.now()}),setInterval(()=>{this.#b()},this.#B.coordinationHeartbeat*2),document.addEventListener("visibilitychange",()=>{if(document.visibilityState==="visible"){if(this.#F)this.#F=!1;this.#U(),this.#W()}}),window.addEventListener("beforeunload",()=>{if(this.#F)this.broadcastChannel.postMessage({type:256,id:this.#j,timestamp:Date.now()})})}#x(){if(this.#P)clearInterval(this.#P),this.#P=null;if(this.#V)clearTimeout(this.#V),this.#V=null}#U(){if(!this.broadcastChannel)return;if(this.broadcastChannel.postMessage({type:8,id:this.#j,timestamp:Date.now(),isPrimary:this.#F,connectionState:this.connectionState}),this.#z)this.#z.set(this.#j,{id:this.#j,isPrimary:this.#F,lastSeen:Date.now()}),this.#L()}#b(){if(!this.#z)return;let z=Date.now()-this.#B.assumeDisconnectedAfter*2,B=!1;if(this.#z.forEach((D,F)=>{if(D.lastSeen<z)this.#z.delete(F),B=!0}),B)this.#L()}#L(){if(!this.#z)return;let j=Array.from(this.#z.values());if(this.#f(8,j),this.broadcastChannel)this.broadcastChannel.postMessage({type:512,id:this.#j,timestamp:Date.now(),tabs:j})}#g(){if(!this.broadcastChannel)return;this.broadcastChannel.postMessage({type:16,id:this.#j,timestamp:Date.now(),isPrimary:this.#F,connectionState:this.connectionState})}#W(){if(!this.broadcastChannel)return;this.broadcastChannel.postMessage({type:32,id:this.#j,timestamp:Date.now()}),this.#V=setTimeout(()=>{this.#r()},500)}#r(){if(this.#F)return;if(this.#F=!0,this.broadcastChannel.postMessage({type:128,id:this.#j,timestamp:Date.now()}),this.connectionState!==4)this.connect();if(this.#z)this.#z.set(this.#j,{id:this.#j,isPrimary:!0,lastSeen:Date.now()}),this.#L();this.#f(2)}#m(){if(!this.#F)return;if(this.#F=!1,this.connectionState===4)this.disconnect(!0);if(this.#z)this.#z.set(this.#j,{id:this.#j,isPrimary:!1,lastSeen:Date.now()}),this.#L();this.#f(4)}onCoordinationEvent(j,z){if(!this.#Z[j])this.#Z[j]=[];return this.#Z[j].push(z),this}#f(j,z){if(this.#Z[j])this.#Z[j].forEach((B)=>{try{B(z)}catch(D){}})}#w(){return Date.now().toString()+Math.random().toString(36).substring(2,9)}#q(){return Date.now().toString()+Math.random().toString(36).substring(2,9)}dispose(){if(this.disconnect(),this.#R)clearInterval(this.#R),this.#R=null;if(this.#E)clearInterval(this.#E),this.#E=null;if(this.#O)this.#O.clear();if(this.#X)this.#X.clear();if(this.#Y)this.#Y.clear();if(this.#Q)this.#Q.clear();if(this.broadcastChannel){if(this.#x(),this.#F)this.broadcastChannel.postMessage({type:256,id:this.#j,timestamp:Date.now()});this.broadcastChannel.close(),this.broadcastChannel=null}}getKnownTabs(){return this.#z?Array.from(this.#z.values()):[]}isPrimary(){return this.#F}}async function C(j={}){return new Promise((z)=>{let B=()=>{let D=new J(j);if(D.connectionMode===1||j.enableCoordination===!0){if(D.initConnectionCoordination)D.initConnectionCoordination()}z(D)};if(document.readyState==="loading")document.addEventListener("DOMContentLoaded",B);else B()})}export{C as createWebSocketManager,J as WebSocketManager,X as STATE_ERROR,O as STATE_DISCONNECTED,Q as STATE_CONNECTING,V as STATE_CONNECTED,A as SESSION_SHARED_CONNECTION,$ as SESSION_SHARED,_ as SESSION_ISOLATED,Y as MODE_WORKER,Z as MODE_DIRECT,R as EVENT_OPEN,P as EVENT_MESSAGE,E as EVENT_ERROR,L as EVENT_CLOSE,h as COORD_CB_TABS_UPDATED,f as COORD_CB_ENABLED,W as COORD_CB_BECAME_SECONDARY,q as COORD_CB_BECAME_PRIMARY};
+var e=1,I=2,s=4,h=8,t=1,i=4,c=1,d=2,g=4,n=1,a=2,o=4,r=8,p=1,f=2,m=4,l=8,u="subscribe",b="unsubscribe",k="publish";function y(M){if(!M||M.code!==1012||!M.reason||M.reason.indexOf("draining")!==0)return null;let T=/retry=(\d+)/.exec(M.reason);return T?parseInt(T[1],10):null}function S(T){if(typeof T!=="string"||T.indexOf('{"type":"')!==0||T.indexOf('"seq":')===-1)return null;let M;try{M=JSON.parse(T)}catch(D){return null}if(!M||typeof M.seq!=="number")return null;if(M.type!=="msg"&&M.type!=="skip"&&M.type!=="resync")return null;return M}class C{#i;#T;#e;#o;#S;#m;#n;#t;#w;#g;#b;#W;#l;#f;#p;#d;#s;#M;#C;#E;#u;#a;#k;#P;constructor(M={}){if(!M.wsRoute||!M.wsWorkerRoute||!M.endpoint)throw Error("wsRoute, wsWorkerRoute, and endpoint options are required");this.#i=Object.assign({debug:!1,wsRoute:M.wsRoute,workerRoute:M.wsWorkerRoute,autoConnect:!0,reconnectOnDisconnect:!0,maxReconnectAttempts:10,endpoint:M.endpoint,sessionStrategy:2,connIdStorageKey:"ws-conn-id",sessionIdStorageKey:"ws-session-id",modePrefStorageKey:"ws-mode-pref",coordinationChannel:"ws-coordination",coordinationHeartbeat:2000,assumeDisconnectedAfter:1e4},M),this.#i.endpointKey=this.#i.endpoint.replace(/\//g,"-"),this.connectionId=this.#H(),this.sessionId=this.#z(),this.sessionData=this.#j(),this.sessionStrategy=this.#i.sessionStrategy;let T=localStorage.getItem(this.#h("modePref"));this.connectionMode=T?parseInt(T,10):null,this.connectionState=1,this.broadcastChannel=null,this.#T=0,this.#e=null,this.#o=null,this.#S={[1]:[],[2]:[],[8]:[],[4]:[]},this.#m={},this.#n=!1,this.#t=this.#x(),this.#w=null,this.#g=null,this.#b=!!this.connectionMode,this.#u=new Map,this.on(2,()=>this.#Q()),this.#a=null,this.#k=null,this.#P=null;if(this.#i.autoConnect)setTimeout(()=>this.connect(),0)}connect(){if(this.broadcastChannel&&!this.#n)return;if(this.connectionMode)switch(this.connectionMode){case 1:if(typeof SharedWorker!=="undefined"){this.connectViaSharedWorker();return}break;case 4:this.connectDirectly();return}if(typeof SharedWorker!=="undefined")this.connectViaSharedWorker();else this.connectDirectly()}setPreferredMode(M){if([1,4].includes(M)){if(this.connectionMode!==M)this.#q();localStorage.setItem(this.#h("modePref"),M),this.connectionMode=M,this.#b=!0;return!0}return!1}clearPreferredMode(){localStorage.removeItem(this.#h("modePref")),this.connectionMode=null,this.#b=!1}connectViaSharedWorker(){this.#q(),this.sessionStrategy=4;if(!this.broadcastChannel)this.initConnectionCoordination();try{this.#o=new SharedWorker(this.#i.workerRoute),this.#o.port.start(),this.#o.port.addEventListener("message",(M)=>{this.#_(M.data)}),this.#o.port.postMessage({type:1,connectionId:this.connectionId}),this.connectionMode=1,this.#o.onerror=(M)=>{this.#r("SharedWorker error",M),this.#o=null;if(!this.#b)this.connectDirectly();else this.#r("Not falling back because mode was explicitly set"),this.connectionState=8,this.#c(4,{message:"SharedWorker connection failed"})}}catch(M){this.#r("Failed to initialize SharedWorker",M);if(!this.#b)this.connectDirectly();else this.#r("Not falling back because mode was explicitly set"),this.connectionState=8,this.#c(4,{message:"SharedWorker initialization failed"})}}connectDirectly(){this.#q();if(this.broadcastChannel)try{this.#R(),this.broadcastChannel.close(),this.broadcastChannel=null,this.#s=null}catch(P){}let T=this.#I(),D=window.location.protocol==="https:"?"wss:":"ws:",M=`${D}//${window.location.host}${this.#i.wsRoute}?connid=${T}&sessionid=${this.sessionId}`;if(this.#a!==null)M+=`&seq=${this.#a}`;this.#e=new WebSocket(M),this.connectionMode=4,this.#e.onopen=()=>{this.connectionState=4,this.#T=0,this.#c(2)},this.#e.onclose=(P)=>{this.connectionState=1,this.#c(8,{code:P.code,reason:P.reason});if(P.code===1008&&P.reason&&P.reason.indexOf("sessionid")===0)this.#A();let v=P.code===1008&&P.reason==="session-revoked";if(this.#i.reconnectOnDisconnect&&!this.#b&&!v)this.#L(y(P))},this.#e.onerror=(P)=>{this.#r("Direct WebSocket error",P),this.#c(4,P)},this.#e.onmessage=(P)=>{let v=P.data.split('\n').filter(q=>q.trim());v.forEach(q=>{q=this.#Z(q);if(q!==null)this.#c(1,q)})}}#q(){this.connectionState=1;if(this.#o){try{this.#o.port.postMessage({type:2}),this.#o.port.close();if(typeof this.#o.terminate==="function")this.#o.terminate()}catch(M){}this.#o=null}if(this.#e){try{this.#e.close()}catch(M){}this.#e=null}if(this.#E)clearTimeout(this.#E),this.#E=null;if(this.broadcastChannel)try{if(this.#n)this.broadcastChannel.postMessage({type:256,id:this.#t,timestamp:Date.now()});this.broadcastChannel.close(),this.broadcastChannel=null}catch(M){}}#et(){if(this.#W)window.removeEventListener("message",this.#W),this.#W=null}send(M){if(typeof M!=="string")M=JSON.stringify(M);if(this.broadcastChannel&&!this.#n){if(!this.#d)this.#d=new Map;let T=M,D=Date.now();if(this.#d.has(T)){let v=this.#d.get(T);if(D-v.timestamp<1000)return!0}let P=`${this.#t}-${Date.now()}-${Math.random().toString(36).substr(2,9)}`;this.#d.set(T,{requestId:P,timestamp:D}),setTimeout(()=>{if(this.#d)this.#d.delete(T)},5000),this.broadcastChannel.postMessage({type:1,requestId:P,senderId:this.#t,id:this.#t,message:M,timestamp:D});return!0}if(this.connectionState!==4){this.#r("Cannot send message, not connected");return!1}switch(this.connectionMode){case 1:this.#o.port.postMessage({type:4,data:M});break;case 4:if(this.#e&&this.#e.readyState===WebSocket.OPEN)this.#e.send(M);else return!1;break;default:return!1}return!0}#_(M){switch(M.type){case 8:this.connectionState=4,this.#c(2);break;case 16:this.connectionState=1,this.#c(8,{code:M.code,reason:M.reason});if(this.#i.reconnectOnDisconnect)this.#o.port.postMessage({type:128,connectionId:this.connectionId});break;case 32:this.#c(1,M.data);if(this.broadcastChannel&&this.#n)this.broadcastChannel.postMessage({type:4,id:this.#t,message:M.data,timestamp:Date.now()});break;case 64:this.#r("WebSocket error via SharedWorker",M.error),this.#c(4,M.error);break;default:this.#r("Unknown message from SharedWorker",M)}}#B(M){try{if(!M||!M.type)return;if([1,2].includes(M.type)&&M.senderId===this.#t)return;switch(M.type){case 8:case 16:if(this.#s&&M.id!==this.#t){let T=this.#s.has(M.id);this.#s.set(M.id,{id:M.id,isPrimary:M.isPrimary,lastSeen:Date.now()});if(!T)this.#y()}break;case 32:if(this.#t>M.id)this.broadcastChannel.postMessage({type:64,id:this.#t,timestamp:Date.now()}),setTimeout(()=>this.#v(),100);break;case 64:if(this.#g)clearTimeout(this.#g),this.#g=null;break;case 128:if(M.id!==this.#t)this.#J();break;case 256:if(M.id!==this.#t)setTimeout(()=>this.#v(),100+Math.random()*400);break;case 4:if(M.id!==this.#t)if(!this.#n)this.#c(1,M.message);else{}break;case 1:if(this.#n&&M.id!==this.#t&&M.senderId!==this.#t){let T=M.requestId;if(!this.#f)this.#f=new Set;if(this.#f.has(T)){this.broadcastChannel.postMessage({type:2,requestId:M.requestId,targetId:M.senderId,senderId:this.#t,success:!1,duplicate:!0,timestamp:Date.now(),id:this.#t});return}this.#f.add(T),setTimeout(()=>{if(this.#f)this.#f.delete(T)},1e4);let D=!1;if(this.connectionState!==4){this.#r("Cannot forward message, primary not connected"),this.broadcastChannel.postMessage({type:2,requestId:M.requestId,targetId:M.senderId,senderId:this.#t,success:!1,error:"not_connected",timestamp:Date.now(),id:this.#t});return}switch(this.connectionMode){case 1:if(this.#o)this.#o.port.postMessage({type:4,data:M.message}),D=!0;break;case 4:if(this.#e&&this.#e.readyState===WebSocket.OPEN)this.#e.send(M.message),D=!0;break}this.broadcastChannel.postMessage({type:2,requestId:M.requestId,targetId:M.senderId,senderId:this.#t,success:D,timestamp:Date.now(),id:this.#t})}break;case 2:if(!this.#n&&M.targetId===this.#t)if(M.duplicate){}else if(M.error==="not_connected"){}else{}break;case 512:if(this.#s&&M.id!==this.#t&&M.tabs){let T=!1;M.tabs.forEach(D=>{if(D.id!==this.#t){let P=this.#s.has(D.id),v=P?this.#s.get(D.id):null;if(!P||(v&&v.isPrimary!==D.isPrimary))this.#s.set(D.id,D),T=!0}});if(T)this.#D(8,Array.from(this.#s.values()))}break;case 1024:if(this.#i.sessionStrategy===2&&M.id!==this.#t)this.sessionData=M.sessionData;break;default:this.#r(`Unknown coordination message type: ${M.type}`,M);break}}catch(T){this.#r("Error handling coordination message",T)}}#L(M){if(this.#T>=this.#i.maxReconnectAttempts){this.#r("Maximum reconnection attempts reached");return}let T=M!==null&&M!==undefined?M:Math.min(1000*Math.pow(2,this.#T),3e4);if(M===null||M===undefined)this.#T++;setTimeout(()=>{if(this.connectionState===1)this.connectDirectly()},T)}subscribe(M,D){let T=this.#u.get(M);if(!T)T=new Set,this.#u.set(M,T),this.send({type:"subscribe",topic:M});if(D)T.add(D);return()=>this.unsubscribe(M,D)}unsubscribe(M,D){let T=this.#u.get(M);if(!T)return;if(D){T.delete(D);if(T.size>0)return}this.#u.delete(M),this.send({type:"unsubscribe",topic:M})}getTopics(){return Array.from(this.#u.keys())}#Q(){this.#u.forEach((T,M)=>{this.send({type:"subscribe",topic:M})})}#Z(T){let M=S(T);if(!M)return T;if(M.type==="resync"){this.#a=M.seq,this.#k=null;return T}if(this.#a!==null){if(M.seq<=this.#a)return null;if(M.seq>this.#a+1){if(this.#k!==this.#a)this.#k=this.#a,this.#N("resume",this.#a);return null}}this.#a=M.seq,this.#k=null,this.#X();if(M.type==="skip")return null;return typeof M.data==="string"?M.data:JSON.stringify(M.data)}#X(){if(this.#P)return;this.#P=setTimeout(()=>{this.#P=null,this.#N("ack",this.#a)},200)}#N(M,T){if(this.#e&&this.#e.readyState===WebSocket.OPEN)this.#e.send(JSON.stringify({type:M,seq:T}))}#V(T){if(typeof T!=="string"||T.indexOf('"topic"')===-1)return;let M;try{M=JSON.parse(T)}catch(P){return}if(M.type==="error"&&this.#u.has(M.topic))return;if(M.type!=="publish")return;let D=this.#u.get(M.topic);if(!D)return;D.forEach(P=>{try{P(M.data,M.topic)}catch(v){}})}on(M,T){if(this.#S[M])this.#S[M].push(T);return this}#c(T,M){if(T===1){let D=typeof M==="string"?M:JSON.stringify(M);if(!this.#l)this.#l=new Map;let P=Date.now(),v=this.#l.get(D);if(v&&P-v<1000)return;this.#l.set(D,P);if(!this.#M)this.#M=setInterval(()=>{let q=Date.now()-5000;if(this.#l)this.#l.forEach((R,W)=>{if(R<q)this.#l.delete(W)})},1e4);this.#V(M)}if(this.#S[T])this.#S[T].forEach(D=>{try{D(M)}catch(P){}})}#h(M){let T=this.#i[M+"StorageKey"];return`${T}${this.#i.endpointKey}`}#z(){if(this.#i.sessionStrategy===1)return this.#x();let T=this.#h("sessionId"),M=localStorage.getItem(T);if(!M)M=this.#I(),localStorage.setItem(T,M);return M}#A(){this.sessionId=this.#I(),localStorage.setItem(this.#h("sessionId"),this.sessionId),this.sessionData={},this.#a=null,this.#k=null;return this.sessionId}#j(){if(this.#i.sessionStrategy===1)return{};let T=this.#h("sessionData"),M=localStorage.getItem(T);return M?JSON.parse(M):{}}#K(){if(this.#i.sessionStrategy===1)return;let M=this.#h("sessionData");localStorage.setItem(M,JSON.stringify(this.sessionData));if(this.broadcastChannel)this.broadcastChannel.postMessage({type:1024,sessionData:this.sessionData,timestamp:Date.now()})}setSessionValue(M,T){this.sessionData[M]=T,this.#K()}getSessionValue(M){return this.sessionData[M]}deleteSessionValue(M){delete this.sessionData[M],this.#K()}clearSession(){this.sessionData={},this.#A();let M=this.#h("sessionData");localStorage.removeItem(M)}#H(){let T=this.#h("connId"),M=localStorage.getItem(T);if(!M)M=this.#I(),localStorage.setItem(T,M);return M}#G(){this.connectionId=this.#I(),localStorage.setItem(this.#h("connId"),this.connectionId);return this.connectionId}disconnect(M=!1){this.connectionState=1;switch(this.connectionMode){case 1:if(this.#o){try{this.#o.port.postMessage({type:2}),this.#o.port.close()}catch(T){}this.#o=null}break;case 4:if(this.#e){try{this.#e.onopen=null,this.#e.onmessage=null,this.#e.onerror=null,this.#e.onclose=null,this.#e.close()}catch(T){}this.#e=null}break}if(!M&&this.#n&&this.broadcastChannel)this.broadcastChannel.postMessage({type:256,id:this.#t,timestamp:Date.now()});this.#c(8)}resetConnection(){this.disconnect(),this.clearPreferredMode(),this.connectionId=this.#G();return this.connect()}#r(M,T){if(this.#i.debug)if(T)window.console.log(`[WebSocketManager] ${M}`,T);else window.console.log(`[WebSocketManager] ${M}`)}#tt(){try{let M=new SharedWorker(this.#i.workerRoute);M.port.start(),M.port.postMessage({type:256}),setTimeout(()=>{try{M.port.close()}catch(T){}},100)}catch(M){}try{let T=window.location.protocol==="https:"?"wss:":"ws:",D=`${T}//${window.location.host}${this.#i.wsRoute}?connid=${this.connectionId}&cleanup=1`,M=new WebSocket(D);M.onopen=()=>{M.send(JSON.stringify({type:1,previousMode:1,newMode:this.connectionMode})),setTimeout(()=>M.close(),50)}}catch(M){}}initConnectionCoordination(){if(typeof BroadcastChannel==="undefined")return!1;try{this.broadcastChannel=new BroadcastChannel(this.#i.coordinationChannel),this.broadcastChannel.onmessage=(M)=>{if(!this.#p)this.#p=new Map;let T=JSON.stringify(M.data),D=Date.now(),P=this.#p.get(T);if(P&&D-P<100)return;this.#p.set(T,D);if(!this.#C)this.#C=setInterval(()=>{let v=Date.now()-1000;this.#p.forEach((q,R)=>{if(q<v)this.#p.delete(R)})},5000);this.#B(M.data)},this.#Y(),this.#D(1);return!0}catch(M){this.#r("Error initializing coordination",M);return!1}}#Y(){this.#R(),this.#O(),this.#w=setInterval(()=>{this.#U()},this.#i.coordinationHeartbeat),this.#v(),this.#s=new Map,this.#s.set(this.#t,{id:this.#t,isPrimary:this.#n,lastSeen:Date.now()}),setInterval(()=>{this.#F()},this.#i.coordinationHeartbeat*2),document.addEventListener("visibilitychange",()=>{if(document.visibilityState==="visible"){if(this.#n)this.#n=!1;this.#O(),this.#v()}}),window.addEventListener("beforeunload",()=>{if(this.#n)this.broadcastChannel.postMessage({type:256,id:this.#t,timestamp:Date.now()})})}#R(){if(this.#w)clearInterval(this.#w),this.#w=null;if(this.#g)clearTimeout(this.#g),this.#g=null}#O(){if(!this.broadcastChannel)return;this.broadcastChannel.postMessage({type:8,id:this.#t,timestamp:Date.now(),isPrimary:this.#n,connectionState:this.connectionState});if(this.#s)this.#s.set(this.#t,{id:this.#t,isPrimary:this.#n,lastSeen:Date.now()}),this.#y()}#F(){if(!this.#s)return;let T=Date.now(),D=T-(this.#i.assumeDisconnectedAfter*2),M=!1;this.#s.forEach((P,v)=>{if(P.lastSeen<D)this.#s.delete(v),M=!0});if(M)this.#y()}#y(){if(!this.#s)return;let M=Array.from(this.#s.values());this.#D(8,M);if(this.broadcastChannel)this.broadcastChannel.postMessage({type:512,id:this.#t,timestamp:Date.now(),tabs:M})}#U(){if(!this.broadcastChannel)return;this.broadcastChannel.postMessage({type:16,id:this.#t,timestamp:Date.now(),isPrimary:this.#n,connectionState:this.connectionState})}#v(){if(!this.broadcastChannel)return;this.broadcastChannel.postMessage({type:32,id:this.#t,timestamp:Date.now()}),this.#g=setTimeout(()=>{this.#$()},500)}#$(){if(this.#n)return;this.#n=!0,this.broadcastChannel.postMessage({type:128,id:this.#t,timestamp:Date.now()});if(this.connectionState!==4)this.connect();if(this.#s)this.#s.set(this.#t,{id:this.#t,isPrimary:!0,lastSeen:Date.now()}),this.#y();this.#D(2)}#J(){if(!this.#n)return;this.#n=!1;if(this.connectionState===4)this.disconnect(!0);if(this.#s)this.#s.set(this.#t,{id:this.#t,isPrimary:!1,lastSeen:Date.now()}),this.#y();this.#D(4)}onCoordinationEvent(M,T){if(!this.#m[M])this.#m[M]=[];this.#m[M].push(T);return this}#D(M,T){if(this.#m[M])this.#m[M].forEach(D=>{try{D(T)}catch(P){}})}#x(){return Date.now().toString()+Math.random().toString(36).substring(2,9)}#I(){return Date.now().toString()+Math.random().toString(36).substring(2,9)}dispose(){this.disconnect();if(this.#M)clearInterval(this.#M),this.#M=null;if(this.#C)clearInterval(this.#C),this.#C=null;if(this.#l)this.#l.clear();if(this.#f)this.#f.clear();if(this.#p)this.#p.clear();if(this.#d)this.#d.clear();if(this.broadcastChannel){this.#R();if(this.#n)this.broadcastChannel.postMessage({type:256,id:this.#t,timestamp:Date.now()});this.broadcastChannel.close(),this.broadcastChannel=null}}getKnownTabs(){return this.#s?Array.from(this.#s.values()):[]}isPrimary(){return this.#n}}async function w(M={}){return new Promise((D)=>{let T=()=>{let P=new C(M);if(P.connectionMode===1||M.enableCoordination===!0)if(P.initConnectionCoordination)P.initConnectionCoordination();D(P)};if(document.readyState==="loading")document.addEventListener("DOMContentLoaded",T);else T()})}export{w as createWebSocketManager,C as WebSocketManager,e as STATE_DISCONNECTED,I as STATE_CONNECTING,s as STATE_CONNECTED,h as STATE_ERROR,t as MODE_WORKER,i as MODE_DIRECT,c as SESSION_ISOLATED,d as SESSION_SHARED,g as SESSION_SHARED_CONNECTION,n as EVENT_MESSAGE,a as EVENT_OPEN,o as EVENT_ERROR,r as EVENT_CLOSE,u as TOPIC_SUBSCRIBE,b as TOPIC_UNSUBSCRIBE,k as TOPIC_PUBLISH,p as COORD_CB_ENABLED,f as COORD_CB_BECAME_PRIMARY,m as COORD_CB_BECAME_SECONDARY,l as COORD_CB_TABS_UPDATED};
@@ -1,2 +1 @@
-var y=1,U=2,A=4,P=8,B=16,S=32,H=64,G=128,u=256,V=new Set,w=null,j=null,Y=0,q=null,X=!1,Z=!1,b=10;self.onconnect=function(F){let z=F.ports[0];if(V.add(z),z.start(),z.addEventListener("message",function(J){v(J.data,z)}),z.addEventListener("close",function(){if(V.delete(z),V.size===0&&w)w.close(),w=null,clearTimeout(q)}),w&&w.readyState===WebSocket.OPEN)z.postMessage({type:P,connectionId:j})};function v(F,z){switch(F.type){case y:if(!w||w.readyState!==WebSocket.OPEN)X=!1,Y=0,clearTimeout(q),Z=!1,f(F.connectionId);break;case U:if(w)w.close(),w=null;break;case A:if(w&&w.readyState===WebSocket.OPEN)w.send(F.data);else z.postMessage({type:H,error:"Socket not connected"});break;case G:break;case u:if(w)w.close(),w=null;Q({type:B,reason:"global_shutdown"}),V.forEach((J)=>{try{J.close()}catch($){}}),V.clear();try{self.close()}catch(J){}break}}function f(F){if(w&&(w.readyState===WebSocket.CONNECTING||w.readyState===WebSocket.OPEN))return;j=F||D();let z=self.location.protocol==="https:"?"wss:":"ws:",J=self.location.host,$=new URL(self.location.href).searchParams.get("endpoint")||"/ws/connect",h=`${z}//${J}${$}?connid=${j}`;try{w=new WebSocket(h),w.onopen=function(){Y=0,X=!1,Z=!1,clearTimeout(q),Q({type:P,connectionId:j})},w.onclose=function(){if(w=null,Q({type:B}),Z=!1,V.size>0&&!X)M()},w.onerror=function(x){if(!X)Q({type:H,error:"WebSocket error"})},w.onmessage=function(x){x.data.split(`
-`).filter((L)=>L.trim()).forEach((L)=>{Q({type:S,data:L})})}}catch(x){if(w=null,!X)Q({type:H,error:"Failed to create WebSocket connection"});if(Z=!1,V.size>0&&!X)M()}}function Q(F){V.forEach((z)=>{try{z.postMessage(F)}catch(J){}})}function M(){if(Z)return;if(Y>=b){X=!0,Q({type:H,error:"Maximum reconnection attempts reached"});return}Z=!0;let F=2000*Math.pow(2,Y),z=60000,J=0.8+Math.random()*0.4,$=Math.min(Math.floor(F*J),z);Y++,Q({type:G,attempt:Y,delay:$}),clearTimeout(q),q=setTimeout(()=>{f(j)},$)}function D(){return Date.now().toString()+Math.random().toString(36).substring(2,9)}
+var n=new Set,r=new Map,e=null,l=null,c=0,a=null,s=!1,i=!1,t=null,f=null,d=null;self.onconnect=function(M){let w=M.ports[0];n.add(w),w.start(),w.addEventListener("message",function(W){b(W.data,w)}),w.addEventListener("close",function(){n.delete(w),g(w);if(n.size===0&&e)e.close(),e=null,clearTimeout(a)});if(e&&e.readyState===WebSocket.OPEN)w.postMessage({type:8,connectionId:l})};function b(w,M){switch(w.type){case 1:if(!e||e.readyState!==WebSocket.OPEN)s=!1,c=0,clearTimeout(a),i=!1,p(w.connectionId);break;case 2:if(e)e.close(),e=null;break;case 4:if(h(w.data,M))break;if(e&&e.readyState===WebSocket.OPEN)e.send(w.data);else M.postMessage({type:64,error:"Socket not connected"});break;case 128:break;case 256:if(e)e.close(),e=null;o({type:16,reason:"global_shutdown"}),n.forEach(W=>{try{W.close()}catch(q){}}),n.clear();try{self.close()}catch(W){}break}}function p(M){if(e&&(e.readyState===WebSocket.CONNECTING||e.readyState===WebSocket.OPEN))return;l=M||E();let W=self.location.protocol==="https:"?"wss:":"ws:",q=self.location.host,P=new URL(self.location.href).searchParams.get("endpoint")||"/ws/connect",w=`${W}//${q}${P}?connid=${l}`;if(t!==null)w+=`&seq=${t}`;try{e=new WebSocket(w),e.onopen=function(){c=0,s=!1,i=!1,clearTimeout(a),r.forEach((x,T)=>u("subscribe",T)),o({type:8,connectionId:l})},e.onclose=function(T){e=null,o({type:16,code:T.code,reason:T.reason}),i=!1;let x=T.code===1008&&T.reason==="session-revoked";if(n.size>0&&!s&&!x)S(O(T));else{}},e.onerror=function(T){if(!s)o({type:64,error:"WebSocket error"})},e.onmessage=function(T){let x=T.data.split('\n').filter(z=>z.trim());x.forEach(z=>{z=m(z);if(z===null)return;o({type:32,data:z})})}}catch(T){e=null;if(!s)o({type:64,error:"Failed to create WebSocket connection"});i=!1;if(n.size>0&&!s)S()}}function h(W,q){if(typeof W!=="string"||W.indexOf("subscribe")===-1)return!1;let w;try{w=JSON.parse(W)}catch(P){return!1}if(!w||!w.topic||(w.type!=="subscribe"&&w.type!=="unsubscribe"))return!1;let M=r.get(w.topic);if(w.type==="subscribe"){if(!M)M=new Set,r.set(w.topic,M),u("subscribe",w.topic);M.add(q);return!0}if(M){M.delete(q);if(M.size===0)r.delete(w.topic),u("unsubscribe",w.topic)}return!0}function g(w){r.forEach((M,W)=>{if(M.delete(w)&&M.size===0)r.delete(W),u("unsubscribe",W)})}function u(w,M){if(e&&e.readyState===WebSocket.OPEN)e.send(JSON.stringify({type:w,topic:M}))}function k(M){if(typeof M!=="string"||M.indexOf('{"type":"')!==0||M.indexOf('"seq":')===-1)return null;let w;try{w=JSON.parse(M)}catch(W){return null}if(!w||typeof w.seq!=="number")return null;if(w.type!=="msg"&&w.type!=="skip"&&w.type!=="resync")return null;return w}function m(M){let w=k(M);if(!w)return M;if(w.type==="resync"){t=w.seq,f=null;return M}if(t!==null){if(w.seq<=t)return null;if(w.seq>t+1){if(f!==t)f=t,y("resume",t);return null}}t=w.seq,f=null;if(!d)d=setTimeout(()=>{d=null,y("ack",t)},200);if(w.type==="skip")return null;return typeof w.data==="string"?w.data:JSON.stringify(w.data)}function y(w,M){if(e&&e.readyState===WebSocket.OPEN)e.send(JSON.stringify({type:w,seq:M}))}function o(w){n.forEach(M=>{try{M.postMessage(w)}catch(W){}})}function O(w){if(!w||w.code!==1012||!w.reason||w.reason.indexOf("draining")!==0)return null;let M=/retry=(\d+)/.exec(w.reason);return M?parseInt(M[1],10):null}function S(w){if(i)return;if(c>=10){s=!0,o({type:64,error:"Maximum reconnection attempts reached"});return}i=!0;let W=2000*Math.pow(2,c),q=6e4,P=0.8+(Math.random()*0.4),M=Math.min(Math.floor(W*P),q);if(w!==null&&w!==undefined)M=w;else c++;o({type:128,attempt:c,delay:M}),clearTimeout(a),a=setTimeout(()=>{p(l)},M)}function E(){return Date.now().toString()+Math.random().toString(36).substring(2,9)}
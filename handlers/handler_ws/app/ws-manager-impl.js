@@ -66,6 +66,67 @@ const COORD_CB_TABS_UPDATED = 8;
 const MSG_TYPE_MODE_CHANGE = 1;
 const ERROR_NOT_CONNECTED = 2;
 
+// Topic protocol message types (must match services/websock/topics.go)
+const TOPIC_SUBSCRIBE = 'subscribe';
+const TOPIC_UNSUBSCRIBE = 'unsubscribe';
+const TOPIC_PUBLISH = 'publish';
+const TOPIC_ERROR = 'error';
+
+
+// Close code and reason prefix of a server that is draining for a deploy
+// (must match comm/drain.go and services/websock/drain.go)
+const CLOSE_SERVICE_RESTART = 1012;
+const DRAIN_REASON = 'draining';
+
+// Close code and reason prefix of a connection refused over its session ID
+// (must match services/websock/id_policy.go)
+const CLOSE_POLICY_VIOLATION = 1008;
+const SESSION_ID_REASON = 'sessionid';
+
+// Close reason of a connection whose auth session was revoked (must match
+// comm/session_revocation.go); reconnecting would only be refused
+const SESSION_REVOKED_REASON = 'session-revoked';
+
+// Reliable delivery message types (must match services/websock/reliable.go)
+const RELIABLE_MSG = 'msg';
+const RELIABLE_SKIP = 'skip';
+const RELIABLE_ACK = 'ack';
+const RELIABLE_RESUME = 'resume';
+const RELIABLE_RESYNC = 'resync';
+const RELIABLE_ACK_DELAY = 200; // ms, acks of a burst are sent as one
+
+/**
+ * Extract the reconnect delay from a drain close ("draining retry=<ms>"), or null
+ */
+function drainRetryHint(event) {
+    if (!event || event.code !== CLOSE_SERVICE_RESTART || !event.reason || event.reason.indexOf(DRAIN_REASON) !== 0) {
+        return null;
+    }
+    const match = /retry=(\d+)/.exec(event.reason);
+    return match ? parseInt(match[1], 10) : null;
+}
+
+/**
+ * Parse a reliable delivery envelope ({"type":"msg","seq":42,"data":...}), or null for other messages
+ */
+function parseReliable(message) {
+    if (typeof message !== 'string' || message.indexOf('{"type":"') !== 0 || message.indexOf('"seq":') === -1) {
+        return null;
+    }
+    let envelope;
+    try {
+        envelope = JSON.parse(message);
+    } catch (e) {
+        return null;
+    }
+    if (!envelope || typeof envelope.seq !== 'number') {
+        return null;
+    }
+    if (envelope.type !== RELIABLE_MSG && envelope.type !== RELIABLE_SKIP && envelope.type !== RELIABLE_RESYNC) {
+        return null;
+    }
+    return envelope;
+}
 
 class WebSocketManager {
     // Private fields
@@ -89,6 +150,10 @@ class WebSocketManager {
     #messageCleanupInterval;
     #coordinationCleanupInterval;
     #reconnectTimeout;
+    #topics;
+    #lastSeq;
+    #resumeFrom;
+    #ackTimeout;
 
     constructor(options = {}) {
         if (!options.wsRoute || !options.wsWorkerRoute || !options.endpoint) {
@@ -141,6 +206,15 @@ class WebSocketManager {
         this.#electionTimeout = null;
         this.#explicitModeSet = !!this.connectionMode;
 
+        // Topic subscriptions: topic -> Set of callbacks, restored after every (re)connect
+        this.#topics = new Map();
+        this.on(EVENT_OPEN, () => this.#resubscribeTopics());
+
+        // Reliable delivery: last sequence processed (null until the first one arrives)
+        this.#lastSeq = null;
+        this.#resumeFrom = null;
+        this.#ackTimeout = null;
+
         if (this.#options.autoConnect) {
             setTimeout(() => this.connect(), 0);
         }
@@ -300,7 +374,11 @@ class WebSocketManager {
         const tabSpecificId = this.#generateConnectionId();
         
         const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-        const url = `${protocol}//${window.location.host}${this.#options.wsRoute}?connid=${tabSpecificId}&sessionid=${this.sessionId}`;
+        let url = `${protocol}//${window.location.host}${this.#options.wsRoute}?connid=${tabSpecificId}&sessionid=${this.sessionId}`;
+        if (this.#lastSeq !== null) {
+            // Resume the reliable sequence where this tab left off
+            url += `&seq=${this.#lastSeq}`;
+        }
         
         this.#socket = new WebSocket(url);
         this.connectionMode = MODE_DIRECT;
@@ -312,13 +390,20 @@ class WebSocketManager {
             this.#triggerCallback(EVENT_OPEN);
         };
         
-        this.#socket.onclose = () => {
-            console.log('[WS] Direct WebSocket closed');
+        this.#socket.onclose = (event) => {
+            console.log('[WS] Direct WebSocket closed', event.code, event.reason);
             this.connectionState = STATE_DISCONNECTED;
-            this.#triggerCallback(EVENT_CLOSE);
-            
-            if (this.#options.reconnectOnDisconnect && !this.#explicitModeSet) {
-                this.#attemptReconnect();
+            // Pass the close code/reason along (e.g. 1009 "message too large")
+            this.#triggerCallback(EVENT_CLOSE, { code: event.code, reason: event.reason });
+
+            // The server refused our session ID; come back with a fresh one
+            if (event.code === CLOSE_POLICY_VIOLATION && event.reason && event.reason.indexOf(SESSION_ID_REASON) === 0) {
+                this.#resetSessionId();
+            }
+
+            const revoked = event.code === CLOSE_POLICY_VIOLATION && event.reason === SESSION_REVOKED_REASON;
+            if (this.#options.reconnectOnDisconnect && !this.#explicitModeSet && !revoked) {
+                this.#attemptReconnect(drainRetryHint(event));
             }
         };
         
@@ -331,7 +416,10 @@ class WebSocketManager {
             // Split on newlines in case server batches messages
             const messages = event.data.split('\n').filter(msg => msg.trim());
             messages.forEach(message => {
-                this.#triggerCallback(EVENT_MESSAGE, message);
+                message = this.#unwrapReliable(message);
+                if (message !== null) {
+                    this.#triggerCallback(EVENT_MESSAGE, message);
+                }
             });
         };
     }
@@ -511,7 +599,7 @@ class WebSocketManager {
             case WORKER_DISCONNECTED:
                 console.log('[WS] WebSocket disconnected via SharedWorker');
                 this.connectionState = STATE_DISCONNECTED;
-                this.#triggerCallback(EVENT_CLOSE);
+                this.#triggerCallback(EVENT_CLOSE, { code: data.code, reason: data.reason });
                 
                 if (this.#options.reconnectOnDisconnect) {
                     this.#worker.port.postMessage({
@@ -777,14 +865,19 @@ class WebSocketManager {
     /**
      * Attempt to reconnect with exponential backoff
      */
-    #attemptReconnect() {
+    #attemptReconnect(hintMs) {
         if (this.#reconnectAttempts >= this.#options.maxReconnectAttempts) {
             this.#log('Maximum reconnection attempts reached');
             return;
         }
         
-        const delay = Math.min(1000 * Math.pow(2, this.#reconnectAttempts), 30000);
-        this.#reconnectAttempts++;
+        // A draining server names its own delay; it is not a failure, so no backoff
+        const delay = hintMs !== null && hintMs !== undefined
+            ? hintMs
+            : Math.min(1000 * Math.pow(2, this.#reconnectAttempts), 30000);
+        if (hintMs === null || hintMs === undefined) {
+            this.#reconnectAttempts++;
+        }
         
         console.log(`[WS] Attempting to reconnect in ${delay}ms (attempt ${this.#reconnectAttempts}`);
         
@@ -795,6 +888,151 @@ class WebSocketManager {
         }, delay);
     }
 
+    /**
+     * Subscribe to a server topic; callback receives the published data and the topic.
+     * Returns a function that removes this subscription.
+     */
+    subscribe(topic, callback) {
+        let callbacks = this.#topics.get(topic);
+        if (!callbacks) {
+            callbacks = new Set();
+            this.#topics.set(topic, callbacks);
+            this.send({ type: TOPIC_SUBSCRIBE, topic: topic });
+        }
+        if (callback) {
+            callbacks.add(callback);
+        }
+        return () => this.unsubscribe(topic, callback);
+    }
+
+    /**
+     * Remove a topic callback (or all callbacks when omitted); the server
+     * subscription ends with the last one
+     */
+    unsubscribe(topic, callback) {
+        const callbacks = this.#topics.get(topic);
+        if (!callbacks) {
+            return;
+        }
+        if (callback) {
+            callbacks.delete(callback);
+            if (callbacks.size > 0) {
+                return;
+            }
+        }
+        this.#topics.delete(topic);
+        this.send({ type: TOPIC_UNSUBSCRIBE, topic: topic });
+    }
+
+    /**
+     * Get the topics this manager is subscribed to
+     */
+    getTopics() {
+        return Array.from(this.#topics.keys());
+    }
+
+    /**
+     * Re-send subscriptions after a (re)connect; the server forgets them with the connection
+     */
+    #resubscribeTopics() {
+        this.#topics.forEach((callbacks, topic) => {
+            this.send({ type: TOPIC_SUBSCRIBE, topic: topic });
+        });
+    }
+
+    /**
+     * Unwrap a reliable delivery envelope of the direct socket, returning the
+     * payload to deliver or null. Duplicates and skips are dropped, a gap asks
+     * the server to resume, and resync is delivered as-is so the app reloads.
+     * Shared connections are unwrapped by the SharedWorker.
+     */
+    #unwrapReliable(message) {
+        const envelope = parseReliable(message);
+        if (!envelope) {
+            return message;
+        }
+        if (envelope.type === RELIABLE_RESYNC) {
+            this.#lastSeq = envelope.seq;
+            this.#resumeFrom = null;
+            return message;
+        }
+        if (this.#lastSeq !== null) {
+            if (envelope.seq <= this.#lastSeq) {
+                return null; // Retransmitted
+            }
+            if (envelope.seq > this.#lastSeq + 1) {
+                // Missed messages; the server replays them, including this one
+                if (this.#resumeFrom !== this.#lastSeq) {
+                    this.#resumeFrom = this.#lastSeq;
+                    this.#sendReliable(RELIABLE_RESUME, this.#lastSeq);
+                }
+                return null;
+            }
+        }
+        this.#lastSeq = envelope.seq;
+        this.#resumeFrom = null;
+        this.#scheduleAck();
+        if (envelope.type === RELIABLE_SKIP) {
+            return null;
+        }
+        return typeof envelope.data === 'string' ? envelope.data : JSON.stringify(envelope.data);
+    }
+
+    /**
+     * Acknowledge the last processed sequence once a burst of messages is over
+     */
+    #scheduleAck() {
+        if (this.#ackTimeout) {
+            return;
+        }
+        this.#ackTimeout = setTimeout(() => {
+            this.#ackTimeout = null;
+            this.#sendReliable(RELIABLE_ACK, this.#lastSeq);
+        }, RELIABLE_ACK_DELAY);
+    }
+
+    /**
+     * Send a reliable delivery control message on the direct socket
+     */
+    #sendReliable(type, seq) {
+        if (this.#socket && this.#socket.readyState === WebSocket.OPEN) {
+            this.#socket.send(JSON.stringify({ type: type, seq: seq }));
+        }
+    }
+
+    /**
+     * Deliver publish envelopes to topic callbacks
+     */
+    #dispatchTopicMessage(data) {
+        if (typeof data !== 'string' || data.indexOf('"topic"') === -1) {
+            return;
+        }
+        let message;
+        try {
+            message = JSON.parse(data);
+        } catch (e) {
+            return;
+        }
+        if (message.type === TOPIC_ERROR && this.#topics.has(message.topic)) {
+            console.warn('[WS] Subscription refused', message.topic, message.error);
+            return;
+        }
+        if (message.type !== TOPIC_PUBLISH) {
+            return;
+        }
+        const callbacks = this.#topics.get(message.topic);
+        if (!callbacks) {
+            return;
+        }
+        callbacks.forEach(callback => {
+            try {
+                callback(message.data, message.topic);
+            } catch (error) {
+                console.error('Error in topic callback', error);
+            }
+        });
+    }
+
     /**
      * Register event callbacks
      */
@@ -842,6 +1080,8 @@ class WebSocketManager {
                     }
                 }, 10000);
             }
+
+            this.#dispatchTopicMessage(data);
         }
         
         // Existing callback triggering code
@@ -890,6 +1130,9 @@ class WebSocketManager {
         this.sessionId = this.#generateConnectionId();
         localStorage.setItem(this.#getStorageKey('sessionId'), this.sessionId);
         this.sessionData = {};
+        // Sequences are counted per session
+        this.#lastSeq = null;
+        this.#resumeFrom = null;
         return this.sessionId;
     }
 
@@ -1570,6 +1813,9 @@ export {
     EVENT_OPEN,
     EVENT_ERROR,
     EVENT_CLOSE,
+    TOPIC_SUBSCRIBE,
+    TOPIC_UNSUBSCRIBE,
+    TOPIC_PUBLISH,
     COORD_CB_ENABLED,
     COORD_CB_BECAME_PRIMARY,
     COORD_CB_BECAME_SECONDARY,
@@ -21,9 +21,35 @@ const WORKER_ERROR = 64;
 const WORKER_RECONNECT = 128;
 const WORKER_GLOBAL_SHUTDOWN = 256;
 
+// Close code and reason prefix of a server that is draining for a deploy
+// (must match comm/drain.go and services/websock/drain.go)
+const CLOSE_SERVICE_RESTART = 1012;
+const DRAIN_REASON = 'draining';
+
+// Close code and reason of a connection whose auth session was revoked
+// (must match comm/session_revocation.go); reconnecting would only be refused
+const CLOSE_POLICY_VIOLATION = 1008;
+const SESSION_REVOKED_REASON = 'session-revoked';
+
+// Topic protocol message types (must match services/websock/topics.go)
+const TOPIC_SUBSCRIBE = 'subscribe';
+const TOPIC_UNSUBSCRIBE = 'unsubscribe';
+
+// Reliable delivery message types (must match services/websock/reliable.go)
+const RELIABLE_MSG = 'msg';
+const RELIABLE_SKIP = 'skip';
+const RELIABLE_ACK = 'ack';
+const RELIABLE_RESUME = 'resume';
+const RELIABLE_RESYNC = 'resync';
+const RELIABLE_ACK_DELAY = 200; // ms, acks of a burst are sent as one
+
 // Track all connected clients
 const clients = new Set();
 
+// Topic subscriptions of the shared socket: topic -> Set of ports that want it.
+// The server sees one subscription per topic however many tabs asked for it.
+const topicPorts = new Map();
+
 // Store WebSocket instance
 let socket = null;
 let connectionId = null;
@@ -33,6 +59,13 @@ let givenUp = false;
 let isReconnecting = false;
 const maxReconnectAttempts = 10;
 
+// Reliable delivery state of the shared socket: last sequence processed (null
+// until the first one arrives), the sequence a resume was requested from, and
+// the pending ack
+let lastSeq = null;
+let resumeFrom = null;
+let ackTimeout = null;
+
 // Handle messages from connected clients
 self.onconnect = function(e) {
     const port = e.ports[0];
@@ -48,6 +81,7 @@ self.onconnect = function(e) {
     // Handle client disconnection
     port.addEventListener('close', function() {
         clients.delete(port);
+        releaseTopics(port);
         
         // If no more clients are connected, close the WebSocket
         if (clients.size === 0 && socket) {
@@ -91,6 +125,9 @@ function handleClientMessage(message, sourcePort) {
             break;
             
         case WORKER_SEND:
+            if (handleTopicRequest(message.data, sourcePort)) {
+                break;
+            }
             if (socket && socket.readyState === WebSocket.OPEN) {
                 console.log(`${timestamp()} [SharedWorker] Sending data`);
                 socket.send(message.data);
@@ -162,7 +199,11 @@ function connectWebSocket(connId) {
     const endpoint = new URL(self.location.href).searchParams.get('endpoint') || '/ws/connect';
     
 
-    const url = `${protocol}//${host}${endpoint}?connid=${connectionId}`;
+    let url = `${protocol}//${host}${endpoint}?connid=${connectionId}`;
+    if (lastSeq !== null) {
+        // Resume the reliable sequence where the shared socket left off
+        url += `&seq=${lastSeq}`;
+    }
     console.log(`${timestamp()} [SharedWorker] Creating WebSocket to ${url}`);
     
     try {
@@ -176,6 +217,9 @@ function connectWebSocket(connId) {
             isReconnecting = false;
             clearTimeout(reconnectTimeout);
             
+            // The server forgets subscriptions with the connection
+            topicPorts.forEach((ports, topic) => sendTopicMessage(TOPIC_SUBSCRIBE, topic));
+
             // Notify all clients that the connection is established
             broadcastToClients({
                 type: WORKER_CONNECTED,
@@ -183,13 +227,15 @@ function connectWebSocket(connId) {
             });
         };
         
-        socket.onclose = function() {
+        socket.onclose = function(event) {
             console.log(`${timestamp()} [SharedWorker] ✗ WebSocket CLOSED - isReconnecting was: ${isReconnecting}, attempts: ${reconnectAttempts}`);
             socket = null;
             
             // Notify all clients
             broadcastToClients({
-                type: WORKER_DISCONNECTED
+                type: WORKER_DISCONNECTED,
+                code: event.code,
+                reason: event.reason
             });
             
             // Reset the reconnecting flag - this connection attempt is complete
@@ -198,8 +244,9 @@ function connectWebSocket(connId) {
             
             // Schedule the next reconnection attempt
             console.log(`${timestamp()} [SharedWorker] Checking reconnect conditions - clients: ${clients.size}, givenUp: ${givenUp}`);
-            if (clients.size > 0 && !givenUp) {
-                reconnectWithBackoff();
+            const revoked = event.code === CLOSE_POLICY_VIOLATION && event.reason === SESSION_REVOKED_REASON;
+            if (clients.size > 0 && !givenUp && !revoked) {
+                reconnectWithBackoff(drainRetryHint(event));
             } else {
                 console.log(`${timestamp()} [SharedWorker] Skipping reconnect`);
             }
@@ -224,6 +271,10 @@ function connectWebSocket(connId) {
             // Split on newlines in case server batches messages
             const messages = event.data.split('\n').filter(msg => msg.trim());
             messages.forEach(message => {
+                message = unwrapReliable(message);
+                if (message === null) {
+                    return;
+                }
                 broadcastToClients({
                     type: WORKER_MESSAGE,
                     data: message
@@ -252,6 +303,128 @@ function connectWebSocket(connId) {
     }
 }
 
+// Track subscribe/unsubscribe requests per port, forwarding only the first
+// subscribe and the last unsubscribe of a topic. Returns true if handled.
+function handleTopicRequest(data, port) {
+    if (typeof data !== 'string' || data.indexOf('subscribe') === -1) {
+        return false;
+    }
+    let request;
+    try {
+        request = JSON.parse(data);
+    } catch (e) {
+        return false;
+    }
+    if (!request || !request.topic || (request.type !== TOPIC_SUBSCRIBE && request.type !== TOPIC_UNSUBSCRIBE)) {
+        return false;
+    }
+
+    let ports = topicPorts.get(request.topic);
+    if (request.type === TOPIC_SUBSCRIBE) {
+        if (!ports) {
+            ports = new Set();
+            topicPorts.set(request.topic, ports);
+            sendTopicMessage(TOPIC_SUBSCRIBE, request.topic);
+        }
+        ports.add(port);
+        return true;
+    }
+
+    if (ports) {
+        ports.delete(port);
+        if (ports.size === 0) {
+            topicPorts.delete(request.topic);
+            sendTopicMessage(TOPIC_UNSUBSCRIBE, request.topic);
+        }
+    }
+    return true;
+}
+
+// Drop every subscription held by a closed port
+function releaseTopics(port) {
+    topicPorts.forEach((ports, topic) => {
+        if (ports.delete(port) && ports.size === 0) {
+            topicPorts.delete(topic);
+            sendTopicMessage(TOPIC_UNSUBSCRIBE, topic);
+        }
+    });
+}
+
+// Send a topic protocol message if the socket is open (subscriptions are replayed on open)
+function sendTopicMessage(type, topic) {
+    if (socket && socket.readyState === WebSocket.OPEN) {
+        socket.send(JSON.stringify({ type: type, topic: topic }));
+    }
+}
+
+// Parse a reliable delivery envelope ({"type":"msg","seq":42,"data":...}), or null for other messages
+function parseReliable(message) {
+    if (typeof message !== 'string' || message.indexOf('{"type":"') !== 0 || message.indexOf('"seq":') === -1) {
+        return null;
+    }
+    let envelope;
+    try {
+        envelope = JSON.parse(message);
+    } catch (e) {
+        return null;
+    }
+    if (!envelope || typeof envelope.seq !== 'number') {
+        return null;
+    }
+    if (envelope.type !== RELIABLE_MSG && envelope.type !== RELIABLE_SKIP && envelope.type !== RELIABLE_RESYNC) {
+        return null;
+    }
+    return envelope;
+}
+
+// Unwrap a reliable delivery envelope, returning the payload to forward or
+// null. Duplicates and skips are dropped, a gap asks the server to resume, and
+// resync is forwarded as-is so the tabs reload their state.
+function unwrapReliable(message) {
+    const envelope = parseReliable(message);
+    if (!envelope) {
+        return message;
+    }
+    if (envelope.type === RELIABLE_RESYNC) {
+        lastSeq = envelope.seq;
+        resumeFrom = null;
+        return message;
+    }
+    if (lastSeq !== null) {
+        if (envelope.seq <= lastSeq) {
+            return null; // Retransmitted
+        }
+        if (envelope.seq > lastSeq + 1) {
+            // Missed messages; the server replays them, including this one
+            if (resumeFrom !== lastSeq) {
+                resumeFrom = lastSeq;
+                sendReliable(RELIABLE_RESUME, lastSeq);
+            }
+            return null;
+        }
+    }
+    lastSeq = envelope.seq;
+    resumeFrom = null;
+    if (!ackTimeout) {
+        // Acknowledge the last processed sequence once a burst is over
+        ackTimeout = setTimeout(() => {
+            ackTimeout = null;
+            sendReliable(RELIABLE_ACK, lastSeq);
+        }, RELIABLE_ACK_DELAY);
+    }
+    if (envelope.type === RELIABLE_SKIP) {
+        return null;
+    }
+    return typeof envelope.data === 'string' ? envelope.data : JSON.stringify(envelope.data);
+}
+
+// Send a reliable delivery control message if the socket is open
+function sendReliable(type, seq) {
+    if (socket && socket.readyState === WebSocket.OPEN) {
+        socket.send(JSON.stringify({ type: type, seq: seq }));
+    }
+}
+
 // Send message to all connected clients
 function broadcastToClients(message) {
     clients.forEach(client => {
@@ -263,8 +436,17 @@ function broadcastToClients(message) {
     });
 }
 
-// Reconnect with exponential backoff
-function reconnectWithBackoff() {
+// Extract the reconnect delay from a drain close ("draining retry=<ms>"), or null
+function drainRetryHint(event) {
+    if (!event || event.code !== CLOSE_SERVICE_RESTART || !event.reason || event.reason.indexOf(DRAIN_REASON) !== 0) {
+        return null;
+    }
+    const match = /retry=(\d+)/.exec(event.reason);
+    return match ? parseInt(match[1], 10) : null;
+}
+
+// Reconnect with exponential backoff (or after hintMs when the server named a delay)
+function reconnectWithBackoff(hintMs) {
     console.log(`${timestamp()} [SharedWorker] ══════ reconnectWithBackoff CALLED ══════`);
     console.log(`${timestamp()} [SharedWorker] State: isReconnecting=${isReconnecting}, attempts=${reconnectAttempts}, givenUp=${givenUp}`);
     
@@ -294,9 +476,14 @@ function reconnectWithBackoff() {
     
     // Add jitter of ±20% to prevent thundering herd problem
     const jitter = 0.8 + (Math.random() * 0.4); // Random value between 0.8 and 1.2
-    const delay = Math.min(Math.floor(baseDelay * jitter), maxDelay);
+    let delay = Math.min(Math.floor(baseDelay * jitter), maxDelay);
     
-    reconnectAttempts++;
+    if (hintMs !== null && hintMs !== undefined) {
+        // A draining server is not a failure: use its delay and keep the attempt count
+        delay = hintMs;
+    } else {
+        reconnectAttempts++;
+    }
     
     console.log(`${timestamp()} [SharedWorker] ⏲ SCHEDULING reconnect: attempt ${reconnectAttempts} in ${delay}ms (base: ${baseDelay}ms, jitter: ${jitter.toFixed(2)})`);
     
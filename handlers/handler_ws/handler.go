@@ -4,6 +4,7 @@ import (
 	"embed"
 	"net/http"
 	"strings"
+	"time"
 
 	wsh "github.com/go-xlite/wbx/handler/ws"
 	"github.com/go-xlite/wbx/services/websock"
@@ -37,14 +38,21 @@ func NewWsHandler(ws *websock.WebSock, name string) *WsHandler {
 	return wsh
 }
 
-// Run starts the WebSocket handler and registers all routes
+// Run starts the WebSocket handler and registers all routes on the first WebLite server
 func (wsh *WsHandler) Run() {
-	// Start the websock server
 	server := weblite.Provider.Servers.GetByIndex(0)
 	if server == nil {
 		panic("No WebLite server available to register WebSocket handler")
 	}
+	wsh.Mount(server)
+}
 
+// Mount starts the WebSocket handler and registers all routes on server.
+// Client events go to the server's event bus unless the WebSock already has one.
+func (wsh *WsHandler) Mount(server *weblite.WebLite) *WsHandler {
+	if wsh.websock.Events == nil {
+		wsh.websock.SetEvents(server.Events())
+	}
 	server.GetRoutes().ForwardPathPrefixFn("/m/xlite/ws/p", func(w http.ResponseWriter, r *http.Request) {
 		if strings.HasSuffix(r.URL.Path, ".js") {
 			data, _ := efs.ReadFile("app-dist" + r.URL.Path)
@@ -54,9 +62,9 @@ func (wsh *WsHandler) Run() {
 		hl1.Helpers.WriteNotFound(w)
 
 	})
-	server.GetRoutes().ForwardPathPrefixFn(wsh.PathPrefix.Get(), func(w http.ResponseWriter, r *http.Request) {
+	server.GetRoutes().ForwardPathPrefixFn(wsh.PathPrefix.Get(), wsh.Isolate(func(w http.ResponseWriter, r *http.Request) {
 		wsh.websock.OnRequest(w, r)
-	})
+	}))
 
 	go wsh.websock.Run()
 
@@ -65,4 +73,45 @@ func (wsh *WsHandler) Run() {
 		wsh.EndpointRoute,
 		wsh.GetUserInfo,
 	)
+	return wsh
+}
+
+// Drain closes all connections spread over window with a reconnect hint and
+// turns new connections away, see WebSock.Drain
+func (wsh *WsHandler) Drain(window time.Duration) int {
+	return wsh.websock.Drain(window)
+}
+
+// GetClientCount returns the number of connected clients
+func (wsh *WsHandler) GetClientCount() int {
+	return wsh.websock.GetClientCount()
+}
+
+// HijacksConnections reports that connections leave the HTTP server, see WebSock.HijacksConnections
+func (wsh *WsHandler) HijacksConnections() bool {
+	return true
+}
+
+// SessionRevoked closes the connections of a revoked auth session, see WebSock.SessionRevoked
+func (wsh *WsHandler) SessionRevoked(sessionKey string) int {
+	return wsh.websock.SessionRevoked(sessionKey)
+}
+
+// SetSessionStrategy sets whether connections with the same sessionid share a
+// session, see websock.SessionStrategy
+func (wsh *WsHandler) SetSessionStrategy(strategy websock.SessionStrategy) *WsHandler {
+	wsh.websock.SetSessionStrategy(strategy)
+	return wsh
+}
+
+// SetIDPolicy sets how client-supplied connid and sessionid values are
+// validated, see websock.IDPolicy
+func (wsh *WsHandler) SetIDPolicy(policy *websock.IDPolicy) *WsHandler {
+	wsh.websock.SetIDPolicy(policy)
+	return wsh
+}
+
+// GetClients returns a snapshot of connected clients including their measured latency
+func (wsh *WsHandler) GetClients() []websock.WsClientInfo {
+	return wsh.websock.GetClientInfos()
 }
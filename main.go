@@ -1,6 +1,8 @@
 package wbx
 
 import (
+	"github.com/go-xlite/wbx/comm"
+	admin "github.com/go-xlite/wbx/handlers/handler_admin"
 	sa "github.com/go-xlite/wbx/handlers/handler_api"
 	sc "github.com/go-xlite/wbx/handlers/handler_cdn"
 	media "github.com/go-xlite/wbx/handlers/handler_media"
@@ -43,6 +45,7 @@ type SSEHandler = sse.SSEHandler
 type WsHandler = ws.WsHandler
 type ProxyHandler = pxy.ProxyHandler
 type MediaHandler = media.MediaHandler
+type AdminUI = admin.AdminUI
 
 // Constructor functions
 var NewApiHandler = sa.NewApiHandler
@@ -54,6 +57,16 @@ var NewWsHandler = ws.NewWsHandler
 var NewProxyHandler = pxy.NewProxyHandler
 var NewMediaHandler = media.NewMediaHandler
 
+// EnableAdminUI mounts the embedded ops dashboard, see handleradmin.AdminUI
+var EnableAdminUI = admin.EnableAdminUI
+
+// Debug output, e.g. wbx.SetDebug("websock", true), see comm.Debugf
+type Logger = comm.Logger
+
+var SetDebug = comm.SetDebug
+var SetDebugLogger = comm.SetDebugLogger
+var SetDebugRate = comm.SetDebugRate
+
 // Utility functions
 var WriteJSON = hl1.Helpers.WriteJSON
 var WriteHTMLText = hl1.Helpers.WriteHTMLText
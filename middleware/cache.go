@@ -0,0 +1,301 @@
+package middleware
+
+import (
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResponseCache stores complete responses (status, headers, body) in an in-memory LRU
+// for a fixed TTL. It honors the Vary response header and skips responses marked
+// no-store/private or carrying Set-Cookie. Only GET and HEAD requests are cached.
+type ResponseCache struct {
+	TTL        time.Duration
+	MaxEntries int // LRU capacity (default: 1000)
+	// KeyFunc identifies a cacheable request; an empty key bypasses the cache
+	// Default: method + path + query, bypassing requests with credentials.
+	// Include the caller in the key to cache per-user data.
+	KeyFunc func(r *http.Request) string
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	vary    map[string][]string // base key -> request headers named by Vary
+	counts  map[string]int      // base key -> cached variants, to prune vary
+	stats   CacheStats
+}
+
+// CacheStats tracks response cache effectiveness
+type CacheStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Entries   int   `json:"entries"`
+}
+
+type cacheEntry struct {
+	key     string
+	baseKey string
+	res     *bufferedResponse
+	expires time.Time
+}
+
+// NewResponseCache creates a response cache; keyFunc may be nil to use DefaultCacheKey
+func NewResponseCache(ttl time.Duration, keyFunc func(r *http.Request) string) *ResponseCache {
+	if keyFunc == nil {
+		keyFunc = DefaultCacheKey
+	}
+	return &ResponseCache{
+		TTL:        ttl,
+		MaxEntries: 1000,
+		KeyFunc:    keyFunc,
+		lru:        list.New(),
+		entries:    make(map[string]*list.Element),
+		vary:       make(map[string][]string),
+		counts:     make(map[string]int),
+	}
+}
+
+// CacheMiddleware returns a middleware caching responses for ttl, keyed by keyFunc
+func CacheMiddleware(ttl time.Duration, keyFunc func(r *http.Request) string) func(http.Handler) http.Handler {
+	return NewResponseCache(ttl, keyFunc).Handler
+}
+
+// DefaultCacheKey keys a request by method, path and query. Requests carrying
+// an Authorization or Cookie header bypass the cache, as their responses may
+// be specific to the caller.
+func DefaultCacheKey(r *http.Request) string {
+	if r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+		return ""
+	}
+	return r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery
+}
+
+// SetMaxEntries sets the LRU capacity
+func (c *ResponseCache) SetMaxEntries(n int) *ResponseCache {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.MaxEntries = n
+	c.evictOverflow()
+	return c
+}
+
+// Invalidate removes all cached variants for a key as produced by KeyFunc
+func (c *ResponseCache) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeWhere(func(e *cacheEntry) bool { return e.baseKey == key })
+}
+
+// InvalidateFunc removes all entries whose key matches the predicate
+func (c *ResponseCache) InvalidateFunc(match func(key string) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeWhere(func(e *cacheEntry) bool { return match(e.baseKey) })
+}
+
+// InvalidatePath removes all entries whose request path starts with prefix
+// Only meaningful with key functions that embed the path like DefaultCacheKey
+func (c *ResponseCache) InvalidatePath(prefix string) {
+	c.InvalidateFunc(func(key string) bool {
+		_, rest, found := strings.Cut(key, " ")
+		if !found {
+			rest = key
+		}
+		return strings.HasPrefix(rest, prefix)
+	})
+}
+
+// Purge removes every cached response
+func (c *ResponseCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	c.entries = make(map[string]*list.Element)
+	c.vary = make(map[string][]string)
+	c.counts = make(map[string]int)
+}
+
+// GetStats returns cache statistics
+func (c *ResponseCache) GetStats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	return stats
+}
+
+// Handler returns an HTTP middleware handler serving cached responses when fresh
+func (c *ResponseCache) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		baseKey := c.KeyFunc(r)
+		if baseKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Client-forced revalidation skips lookup but still refreshes the entry
+		bypass := strings.Contains(r.Header.Get("Cache-Control"), "no-cache")
+		if !bypass {
+			if res := c.lookup(baseKey, r); res != nil {
+				w.Header().Set("X-Cache", "HIT")
+				res.replay(w)
+				return
+			}
+		}
+
+		res := newBufferedResponse()
+		next.ServeHTTP(res, r)
+		if isCacheableResponse(res) {
+			c.store(baseKey, r, res)
+		}
+		w.Header().Set("X-Cache", "MISS")
+		res.replay(w)
+	})
+}
+
+// lookup returns a fresh cached response for the request or nil
+func (c *ResponseCache) lookup(baseKey string, r *http.Request) *bufferedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := variantKey(baseKey, c.vary[baseKey], r)
+	elem, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(elem)
+		c.stats.Misses++
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	c.stats.Hits++
+	return entry.res
+}
+
+// store records a response under the variant key derived from its Vary header
+func (c *ResponseCache) store(baseKey string, r *http.Request, res *bufferedResponse) {
+	varyHeaders := parseVary(res.header)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !equalHeaderNames(c.vary[baseKey], varyHeaders) {
+		// The set of varying headers changed, older variants are no longer addressable
+		c.removeWhere(func(e *cacheEntry) bool { return e.baseKey == baseKey })
+		c.vary[baseKey] = varyHeaders
+	}
+
+	key := variantKey(baseKey, varyHeaders, r)
+	entry := &cacheEntry{key: key, baseKey: baseKey, res: res, expires: time.Now().Add(c.TTL)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.counts[baseKey]++
+	c.evictOverflow()
+}
+
+// evictOverflow drops least recently used entries beyond MaxEntries (caller holds mu)
+func (c *ResponseCache) evictOverflow() {
+	for c.MaxEntries > 0 && c.lru.Len() > c.MaxEntries {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// remove deletes one entry, forgetting the Vary names of its base key with
+// the last variant (caller holds mu)
+func (c *ResponseCache) remove(elem *list.Element) {
+	entry := elem.Value.(*cacheEntry)
+	c.lru.Remove(elem)
+	delete(c.entries, entry.key)
+	if c.counts[entry.baseKey]--; c.counts[entry.baseKey] <= 0 {
+		delete(c.counts, entry.baseKey)
+		delete(c.vary, entry.baseKey)
+	}
+}
+
+// removeWhere deletes entries matching the predicate (caller holds mu)
+func (c *ResponseCache) removeWhere(match func(e *cacheEntry) bool) {
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if match(elem.Value.(*cacheEntry)) {
+			c.remove(elem)
+		}
+		elem = next
+	}
+}
+
+// isCacheableResponse reports whether a response may be shared between requests
+func isCacheableResponse(res *bufferedResponse) bool {
+	if res.statusCode() != http.StatusOK {
+		return false
+	}
+	if res.header.Get("Set-Cookie") != "" {
+		return false
+	}
+	cacheControl := strings.ToLower(res.header.Get("Cache-Control"))
+	if strings.Contains(cacheControl, "no-store") || strings.Contains(cacheControl, "private") {
+		return false
+	}
+	for _, name := range parseVary(res.header) {
+		if name == "*" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseVary returns the canonical request header names listed in Vary
+func parseVary(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// variantKey extends a base key with the request values of the varying headers
+func variantKey(baseKey string, varyHeaders []string, r *http.Request) string {
+	if len(varyHeaders) == 0 {
+		return baseKey
+	}
+	var sb strings.Builder
+	sb.WriteString(baseKey)
+	for _, name := range varyHeaders {
+		sb.WriteString("|")
+		sb.WriteString(name)
+		sb.WriteString("=")
+		sb.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	return sb.String()
+}
+
+func equalHeaderNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
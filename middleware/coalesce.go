@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Coalescer merges concurrent identical GET/HEAD requests into a single backend call.
+// The first request (the leader) runs the handler while the others wait and receive
+// a copy of its buffered response. Only use it on finite responses (not SSE/WebSocket).
+type Coalescer struct {
+	// KeyFunc identifies identical requests; requests with an empty key are not coalesced
+	// Default: method + path + query + Authorization + Cookie (so users never share responses)
+	KeyFunc func(r *http.Request) string
+
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+	stats CoalescerStats
+}
+
+// CoalescerStats tracks how many requests were served by the leader vs. shared
+type CoalescerStats struct {
+	Executed  int64 `json:"executed"`
+	Coalesced int64 `json:"coalesced"`
+}
+
+type coalescedCall struct {
+	done chan struct{}
+	res  *bufferedResponse
+	ok   bool // false if the leader panicked
+}
+
+// NewCoalescer creates a Coalescer using DefaultCoalesceKey
+func NewCoalescer() *Coalescer {
+	return &Coalescer{
+		KeyFunc: DefaultCoalesceKey,
+		calls:   make(map[string]*coalescedCall),
+	}
+}
+
+// DefaultCoalesceKey keys a request by method, path, query and the caller's credentials
+func DefaultCoalesceKey(r *http.Request) string {
+	return r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery +
+		"|" + r.Header.Get("Authorization") + "|" + r.Header.Get("Cookie")
+}
+
+// SetKeyFunc sets the function used to identify identical requests
+func (c *Coalescer) SetKeyFunc(fn func(r *http.Request) string) *Coalescer {
+	c.KeyFunc = fn
+	return c
+}
+
+// GetStats returns coalescing statistics
+func (c *Coalescer) GetStats() CoalescerStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Handler returns an HTTP middleware handler that coalesces identical GET/HEAD requests
+func (c *Coalescer) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		keyFunc := c.KeyFunc
+		if keyFunc == nil {
+			keyFunc = DefaultCoalesceKey
+		}
+		key := keyFunc(r)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c.mu.Lock()
+		if c.calls == nil {
+			c.calls = make(map[string]*coalescedCall)
+		}
+		if call, exists := c.calls[key]; exists {
+			c.stats.Coalesced++
+			c.mu.Unlock()
+
+			<-call.done
+			if call.ok {
+				call.res.replay(w)
+			} else {
+				// Leader failed, run the handler ourselves
+				next.ServeHTTP(w, r)
+			}
+			return
+		}
+
+		call := &coalescedCall{done: make(chan struct{})}
+		c.calls[key] = call
+		c.stats.Executed++
+		c.mu.Unlock()
+
+		defer func() {
+			c.mu.Lock()
+			delete(c.calls, key)
+			c.mu.Unlock()
+			close(call.done)
+		}()
+
+		res := newBufferedResponse()
+		next.ServeHTTP(res, r)
+		call.res = res
+		call.ok = true
+		res.replay(w)
+	})
+}
+
+// HandlerFunc returns an HTTP middleware handler func that coalesces identical requests
+func (c *Coalescer) HandlerFunc(next http.HandlerFunc) http.HandlerFunc {
+	return c.Handler(next).ServeHTTP
+}
@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	hl1 "github.com/go-xlite/wbx/utils"
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultMaxDecompressedSize caps decompressed request bodies unless configured otherwise
+const DefaultMaxDecompressedSize = 10 << 20
+
+// DecoderFunc wraps a compressed body in a reader yielding the decoded bytes
+type DecoderFunc func(body io.Reader) (io.ReadCloser, error)
+
+// zstdMaxWindow caps the zstd window a body may use, the 8MB RFC 8878 asks
+// HTTP decoders to support, so a crafted frame cannot demand a huge buffer
+const zstdMaxWindow = 8 << 20
+
+// Decompressor transparently decodes request bodies sent with Content-Encoding
+// gzip, deflate or zstd, so handlers read plain JSON. Further encodings can be
+// added with SetDecoder. Bodies in an unknown encoding get 415, corrupt
+// ones 400; reading past MaxSize decoded bytes fails with *http.MaxBytesError.
+type Decompressor struct {
+	MaxSize int64 // Limit of the decoded body in bytes
+
+	decoders map[string]DecoderFunc
+	mu       sync.Mutex
+	stats    DecompressorStats
+}
+
+// DecompressorStats counts decoded and refused request bodies
+type DecompressorStats struct {
+	Decompressed int64 `json:"decompressed"`
+	Rejected     int64 `json:"rejected"`
+}
+
+// NewDecompressor creates a Decompressor for gzip, deflate and zstd bodies of at most
+// maxSize decoded bytes (DefaultMaxDecompressedSize when 0)
+func NewDecompressor(maxSize int64) *Decompressor {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDecompressedSize
+	}
+	return &Decompressor{
+		MaxSize: maxSize,
+		decoders: map[string]DecoderFunc{
+			"gzip":    decodeGzip,
+			"x-gzip":  decodeGzip,
+			"deflate": decodeDeflate,
+			"zstd":    decodeZstd,
+		},
+	}
+}
+
+// SetDecoder registers a decoder for an encoding, e.g. "br" backed by a
+// Brotli package, or replaces a built-in one
+func (d *Decompressor) SetDecoder(encoding string, decoder DecoderFunc) *Decompressor {
+	d.decoders[strings.ToLower(encoding)] = decoder
+	return d
+}
+
+// GetStats returns decompression statistics
+func (d *Decompressor) GetStats() DecompressorStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats
+}
+
+// Handler returns an HTTP middleware handler that decodes compressed request bodies
+func (d *Decompressor) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings := contentEncodings(r.Header.Get("Content-Encoding"))
+		if len(encodings) == 0 || r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		for _, encoding := range encodings {
+			if d.decoders[encoding] == nil {
+				d.countRejected()
+				w.Header().Set("Accept-Encoding", d.supported())
+				hl1.Helpers.WriteJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "unsupported content encoding"})
+				return
+			}
+		}
+
+		// Encodings are listed in the order they were applied, so undo them backwards
+		body := io.ReadCloser(r.Body)
+		closers := []io.Closer{r.Body}
+		for _, encoding := range slices.Backward(encodings) {
+			decoded, err := d.decoders[encoding](body)
+			if err != nil {
+				d.countRejected()
+				// Release the decoders already stacked, not just the original body
+				(&decodedBody{closers: closers}).Close()
+				hl1.Helpers.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid " + encoding + " body"})
+				return
+			}
+			body = decoded
+			closers = append(closers, decoded)
+		}
+
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+		r.Body = &decodedBody{Reader: http.MaxBytesReader(w, body, d.MaxSize), closers: closers}
+
+		d.mu.Lock()
+		d.stats.Decompressed++
+		d.mu.Unlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HandlerFunc returns an HTTP middleware handler func that decodes compressed request bodies
+func (d *Decompressor) HandlerFunc(next http.HandlerFunc) http.HandlerFunc {
+	return d.Handler(next).ServeHTTP
+}
+
+func (d *Decompressor) countRejected() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stats.Rejected++
+}
+
+// supported lists the registered encodings for the Accept-Encoding hint of a 415
+func (d *Decompressor) supported() string {
+	names := make([]string, 0, len(d.decoders))
+	for name := range d.decoders {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
+
+// contentEncodings splits a Content-Encoding header, dropping identity
+func contentEncodings(header string) []string {
+	var encodings []string
+	for part := range strings.SplitSeq(header, ",") {
+		encoding := strings.ToLower(strings.TrimSpace(part))
+		if encoding != "" && encoding != "identity" {
+			encodings = append(encodings, encoding)
+		}
+	}
+	return encodings
+}
+
+// decodedBody closes the decoders and the original body together
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	var first error
+	for _, c := range slices.Backward(b.closers) {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func decodeGzip(body io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(body)
+}
+
+// decodeDeflate accepts zlib-wrapped deflate as the spec demands and raw
+// deflate as some clients send it
+func decodeDeflate(body io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(body)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// decodeZstd decodes in the calling goroutine with a bounded window; the
+// decoded size is capped by the MaxBytesReader around it
+func decodeZstd(body io.Reader) (io.ReadCloser, error) {
+	dec, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(zstdMaxWindow))
+	if err != nil {
+		return nil, err
+	}
+	return dec.IOReadCloser(), nil
+}
@@ -0,0 +1,14 @@
+package middleware
+
+import "github.com/go-xlite/wbx/middleware/ratelimit"
+
+// RateLimiter is a per-key token bucket limiter, see ratelimit.Limiter
+type RateLimiter = ratelimit.Limiter
+
+// RateLimiterStats tracks allowed vs. rejected requests
+type RateLimiterStats = ratelimit.Stats
+
+// NewRateLimiter creates a limiter allowing rate requests per second per client IP with the given burst
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return ratelimit.New(rate, burst)
+}
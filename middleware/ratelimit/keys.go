@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"net/http"
+
+	"github.com/go-xlite/wbx/comm"
+)
+
+// KeyFunc identifies the caller of a request; an empty key is not limited
+type KeyFunc func(r *http.Request) string
+
+// ByIP keys requests by client IP, honouring comm.SetTrustedProxies
+func ByIP(r *http.Request) string {
+	return comm.ClientIP(r)
+}
+
+// ByHeader keys requests by the value of header, e.g. an API key
+func ByHeader(header string) KeyFunc {
+	return func(r *http.Request) string {
+		if value := r.Header.Get(header); value != "" {
+			return "header:" + value
+		}
+		return ""
+	}
+}
+
+// BySession keys requests by the session validated by weblite's
+// SessionManager (comm.SessionKey), so forged cookies fall through to the next
+// key and no session token is held by the limiter
+func BySession(r *http.Request) string {
+	if key := comm.SessionKey(r); key != "" {
+		return "session:" + key
+	}
+	return ""
+}
+
+// FirstOf uses the first non-empty key, e.g. FirstOf(BySession, ByIP)
+// limits signed-in users per session and everyone else per IP
+func FirstOf(keys ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		for _, key := range keys {
+			if k := key(r); k != "" {
+				return k
+			}
+		}
+		return ""
+	}
+}
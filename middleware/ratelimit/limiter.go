@@ -0,0 +1,175 @@
+// Package ratelimit provides token bucket rate limiting middleware keyed by
+// client IP, session token, header or any request function.
+//
+// Attach a limiter globally or to path prefixes:
+//
+//	wl.Use(ratelimit.New(10, 20).Handler)
+//	wl.Use(ratelimit.New(1, 5).SetKeyFunc(ratelimit.ByHeader("X-API-Key")).ForPrefix("/api/"))
+//	routes.UseIf(routes.PathPrefixIn("/login"), ratelimit.New(0.2, 3).Handler)
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	hl1 "github.com/go-xlite/wbx/utils"
+)
+
+// Limiter is a per-key token bucket limiter. Each key (client IP by default)
+// may burst up to Burst requests and is then refilled at Rate requests per second.
+// Rejected requests get 429 with a Retry-After header.
+type Limiter struct {
+	Rate  float64 // Tokens added per second
+	Burst int     // Bucket capacity
+	// KeyFunc identifies the caller; requests with an empty key are not limited
+	// Default: ByIP
+	KeyFunc KeyFunc
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	sweep   time.Time
+	stats   Stats
+}
+
+// Stats tracks allowed vs. rejected requests
+type Stats struct {
+	Allowed  int64 `json:"allowed"`
+	Rejected int64 `json:"rejected"`
+	Keys     int   `json:"keys"`
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New creates a limiter allowing rate requests per second per client IP with the given burst
+func New(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		Rate:    rate,
+		Burst:   burst,
+		KeyFunc: ByIP,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// SetKeyFunc sets the function used to identify callers (e.g. BySession or ByHeader)
+func (rl *Limiter) SetKeyFunc(fn KeyFunc) *Limiter {
+	rl.KeyFunc = fn
+	return rl
+}
+
+// Allow consumes a token for key. When the bucket is empty it returns false and
+// how long the caller should wait before the next token is available.
+func (rl *Limiter) Allow(key string) (bool, time.Duration) {
+	now := time.Now()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.buckets == nil {
+		rl.buckets = make(map[string]*tokenBucket)
+	}
+	rl.sweepIdle(now)
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(rl.Burst), last: now}
+		rl.buckets[key] = b
+	} else if rl.Rate > 0 {
+		b.tokens = math.Min(float64(rl.Burst), b.tokens+now.Sub(b.last).Seconds()*rl.Rate)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		rl.stats.Allowed++
+		return true, 0
+	}
+
+	rl.stats.Rejected++
+	if rl.Rate <= 0 {
+		return false, time.Minute
+	}
+	return false, time.Duration((1 - b.tokens) / rl.Rate * float64(time.Second))
+}
+
+// sweepIdle drops buckets that have refilled completely, at most once a minute.
+// Caller must hold rl.mu.
+func (rl *Limiter) sweepIdle(now time.Time) {
+	if rl.Rate <= 0 || now.Sub(rl.sweep) < time.Minute {
+		return
+	}
+	rl.sweep = now
+	full := time.Duration(float64(rl.Burst) / rl.Rate * float64(time.Second))
+	for key, b := range rl.buckets {
+		if now.Sub(b.last) > full {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// GetStats returns rate limiting statistics
+func (rl *Limiter) GetStats() Stats {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	stats := rl.stats
+	stats.Keys = len(rl.buckets)
+	return stats
+}
+
+// Handler returns an HTTP middleware handler that rejects callers over the limit with 429
+func (rl *Limiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyFunc := rl.KeyFunc
+		if keyFunc == nil {
+			keyFunc = ByIP
+		}
+		key := keyFunc(r)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if ok, wait := rl.Allow(key); !ok {
+			secs := int(math.Ceil(wait.Seconds()))
+			if secs < 1 {
+				secs = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(secs))
+			hl1.Helpers.WriteJSON(w, http.StatusTooManyRequests, map[string]string{"error": "rate limit exceeded"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HandlerFunc returns an HTTP middleware handler func that rate limits requests
+func (rl *Limiter) HandlerFunc(next http.HandlerFunc) http.HandlerFunc {
+	return rl.Handler(next).ServeHTTP
+}
+
+// ForPrefix returns middleware limiting only requests whose path starts with
+// one of prefixes; other requests pass untouched. Meant for WebLite.Use, which
+// runs before routing; on Routes, UseIf with routes.PathPrefixIn does the same.
+func (rl *Limiter) ForPrefix(prefixes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		limited := rl.Handler(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					limited.ServeHTTP(w, r)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
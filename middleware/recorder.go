@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// bufferedResponse records a complete response (status, headers, body) in memory
+// so it can be replayed to one or more clients
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{header: make(http.Header)}
+}
+
+// Header implements http.ResponseWriter
+func (br *bufferedResponse) Header() http.Header {
+	return br.header
+}
+
+// WriteHeader implements http.ResponseWriter
+func (br *bufferedResponse) WriteHeader(statusCode int) {
+	// 1xx informational responses (e.g. 103 Early Hints) cannot be replayed
+	if br.status == 0 && statusCode >= 200 {
+		br.status = statusCode
+	}
+}
+
+// Write implements http.ResponseWriter
+func (br *bufferedResponse) Write(b []byte) (int, error) {
+	if br.status == 0 {
+		br.status = http.StatusOK
+	}
+	return br.body.Write(b)
+}
+
+// statusCode returns the recorded status, defaulting to 200
+func (br *bufferedResponse) statusCode() int {
+	if br.status == 0 {
+		return http.StatusOK
+	}
+	return br.status
+}
+
+// replay writes the recorded response to w
+func (br *bufferedResponse) replay(w http.ResponseWriter) {
+	dst := w.Header()
+	for key, values := range br.header {
+		dst[key] = append([]string(nil), values...)
+	}
+	w.WriteHeader(br.statusCode())
+	w.Write(br.body.Bytes())
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-xlite/wbx/comm"
+)
+
+// ReportErrors reports panics and 5xx responses from next to the global
+// comm.ErrorReporter. A panic is answered with 500 when nothing has been
+// written yet; otherwise the connection is aborted as net/http would do.
+func ReportErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cw := comm.NewCaptureWriter(w)
+		ctx, reported := comm.WithReportTracking(r.Context())
+		r = r.WithContext(ctx)
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				comm.ReportPanic(r.Context(), rec, r)
+				if cw.WroteHeader() || cw.Hijacked() {
+					panic(http.ErrAbortHandler)
+				}
+				http.Error(cw, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			// Skip 5xx already reported by the handler itself (e.g. proxy errors)
+			if cw.Status() >= http.StatusInternalServerError && !*reported {
+				comm.ReportError(r.Context(), &comm.StatusError{Status: cw.Status()}, nil, r)
+			}
+		}()
+		next.ServeHTTP(cw, r)
+	})
+}
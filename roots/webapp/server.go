@@ -2,9 +2,9 @@ package webapp
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/go-xlite/wbx/comm"
-	hl1 "github.com/go-xlite/wbx/utils"
 )
 
 type WebApp struct {
@@ -38,7 +38,11 @@ func (wt *WebApp) ServeDefaultFavIcon(w http.ResponseWriter, r *http.Request) bo
 	if wt.Fs != nil {
 		data, err := wt.Fs.ReadFile("favicon.ico")
 		if err == nil {
-			hl1.Helpers.WriteFavIcon(w, r, data)
+			var modTime time.Time
+			if info, err := wt.Fs.Stat("favicon.ico"); err == nil {
+				modTime = info.ModTime
+			}
+			comm.ServeBytes(w, r, "favicon.ico", modTime, data, nil)
 			return true
 		}
 	}
@@ -20,13 +20,36 @@ type WebAuth struct {
 	PathBase string // Optional base path for convenience (e.g., "/api" for documentation)
 	NotFound http.HandlerFunc
 	Auth     IWebAuthProvider
+
+	ReturnParam          string   // Parameter carrying the post-login destination (default "next")
+	AllowedRedirectHosts []string // Hosts allowed as absolute return-to URLs
+
+	// Response shaping, see Respond
+	LoginPage       string             // HTML form logins are sent back here on failure
+	SuccessRedirect string             // HTML success destination without a return-to URL
+	Customizer      ResponseCustomizer // Optional JSON payload hook
+
+	Sessions      SessionDirectory // Optional, enables /sessions and /logout-all
+	SessionCookie string           // Cookie identifying the current session
+	// SessionCookieFor names the session cookie of a request when cookies are
+	// scoped per mounted app (weblite SessionManager.CookieNameFor); it takes
+	// precedence over SessionCookie
+	SessionCookieFor func(r *http.Request) string
+	ClearSession     func(w http.ResponseWriter, r *http.Request) // Optional, clears the session cookie on logout-all
+	// NotifyRevoked closes the live connections of sessions revoked by
+	// logout-all, given their comm.SessionKeyFor keys (optional)
+	NotifyRevoked func(sessionKeys ...string) int
+
+	Recovery *Recovery // Optional password reset / email verification, see SetRecovery
 }
 
 // NewWebAuth creates a new WebAuth instance with proper routing capabilities
 func NewWebAuth() *WebAuth {
 	wt := &WebAuth{
-		ServerCore: comm.NewServerCore(),
-		PathBase:   "",
+		ServerCore:      comm.NewServerCore(),
+		PathBase:        "",
+		ReturnParam:     DefaultReturnParam,
+		SuccessRedirect: "/",
 	}
 	wt.NotFound = http.NotFound
 	return wt
@@ -42,4 +65,10 @@ func (wt *WebAuth) Init() {
 	wt.Mux.HandleFunc("/refresh", wt.Auth.RefreshToken)
 	wt.Mux.HandleFunc("/register", wt.Auth.RegisterUser)
 	wt.Mux.HandleFunc("/me", wt.Auth.GetCurrentUser)
+	wt.Mux.HandleFunc("/sessions", wt.handleSessions)
+	wt.Mux.HandleFunc("/logout-all", wt.handleLogoutAll)
+	wt.Mux.HandleFunc("/password/reset-request", wt.handleResetRequest)
+	wt.Mux.HandleFunc("/password/reset", wt.handlePasswordReset)
+	wt.Mux.HandleFunc("/email/verify-request", wt.handleVerifyRequest)
+	wt.Mux.HandleFunc("/email/verify", wt.handleEmailVerify)
 }
@@ -0,0 +1,296 @@
+package webauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-xlite/wbx/comm"
+	"github.com/go-xlite/wbx/middleware/ratelimit"
+)
+
+// linkMailTimeout bounds the background lookup and delivery of a link email
+const linkMailTimeout = 30 * time.Second
+
+// AuthMessage is an account email handed to the application's mailer
+type AuthMessage struct {
+	Kind    string    // PurposePasswordReset or PurposeEmailVerify
+	To      string    // Recipient email address
+	Subject string    // Account subject the token was issued for
+	Link    string    // Ready-to-send link containing the token
+	Token   string    // Raw token, for apps building their own links
+	Expires time.Time // Token expiry
+}
+
+// AuthMailer delivers account emails; applications only supply this
+type AuthMailer interface {
+	Send(ctx context.Context, msg AuthMessage) error
+}
+
+// AuthMailerFunc adapts a function to AuthMailer
+type AuthMailerFunc func(ctx context.Context, msg AuthMessage) error
+
+// Send calls f(ctx, msg)
+func (f AuthMailerFunc) Send(ctx context.Context, msg AuthMessage) error {
+	return f(ctx, msg)
+}
+
+// RecoveryAccounts is the account store backing the recovery endpoints
+type RecoveryAccounts interface {
+	// SubjectForEmail resolves an email address to an account subject
+	SubjectForEmail(email string) (string, bool)
+	// SetPassword replaces the password of subject
+	SetPassword(subject, password string) error
+	// MarkEmailVerified flags the email of subject as verified
+	MarkEmailVerified(subject string) error
+}
+
+// Recovery wires password reset and email verification endpoints:
+//
+//	POST /password/reset-request  {email}            -> mails a reset link
+//	POST /password/reset          {token, password}  -> sets the new password
+//	POST /email/verify-request    {email}            -> mails a verification link
+//	GET|POST /email/verify        ?token=            -> marks the email verified
+//
+// Request endpoints always answer success so they cannot be used to probe for
+// accounts, and are throttled per email address and per client IP. A completed
+// password reset revokes every existing session of the account.
+type Recovery struct {
+	Tokens            *ActionTokens
+	Accounts          RecoveryAccounts
+	Mailer            AuthMailer
+	ResetURL          string // Page receiving reset links, e.g. "https://app/reset" (?token= is added)
+	VerifyURL         string // Page receiving verification links (?token= is added)
+	MinPasswordLength int    // Default 8
+	// AddressLimiter throttles link requests per email address and purpose
+	// Default: 3 burst, then one every 5 minutes
+	AddressLimiter *ratelimit.Limiter
+	// IPLimiter throttles link requests per client IP
+	// Default: 10 burst, then one per minute
+	IPLimiter *ratelimit.Limiter
+}
+
+// SetRecovery enables the password reset and email verification endpoints
+func (wt *WebAuth) SetRecovery(rec *Recovery) *WebAuth {
+	if rec.MinPasswordLength <= 0 {
+		rec.MinPasswordLength = 8
+	}
+	if rec.AddressLimiter == nil {
+		rec.AddressLimiter = ratelimit.New(1.0/300, 3)
+	}
+	if rec.IPLimiter == nil {
+		rec.IPLimiter = ratelimit.New(1.0/60, 10)
+	}
+	wt.Recovery = rec
+	return wt
+}
+
+func (wt *WebAuth) handleResetRequest(w http.ResponseWriter, r *http.Request) {
+	wt.handleLinkRequest(w, r, "password_reset_request", PurposePasswordReset)
+}
+
+func (wt *WebAuth) handleVerifyRequest(w http.ResponseWriter, r *http.Request) {
+	wt.handleLinkRequest(w, r, "email_verify_request", PurposeEmailVerify)
+}
+
+// handleLinkRequest issues a token for the account owning the posted email and mails it
+func (wt *WebAuth) handleLinkRequest(w http.ResponseWriter, r *http.Request, action, purpose string) {
+	rec := wt.Recovery
+	if rec == nil {
+		wt.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		wt.Respond(w, r, &AuthResult{Action: action, Status: http.StatusMethodNotAllowed, Error: "method_not_allowed"})
+		return
+	}
+	fields, err := readFields(r)
+	email := strings.TrimSpace(fields["email"])
+	if err != nil || email == "" {
+		wt.Respond(w, r, &AuthResult{Action: action, Status: http.StatusBadRequest, Error: "email_required"})
+		return
+	}
+	// Unknown addresses count too, so a 429 reveals nothing about the account
+	if wait, limited := rec.throttle(r, purpose, email); limited {
+		secs := int(math.Ceil(wait.Seconds()))
+		if secs < 1 {
+			secs = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(secs))
+		wt.Respond(w, r, &AuthResult{Action: action, Status: http.StatusTooManyRequests, Error: "rate_limited"})
+		return
+	}
+
+	// Look up and mail in the background so the response time does not reveal
+	// whether the account exists
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), linkMailTimeout)
+	go func() {
+		defer cancel()
+		if err := rec.sendLink(ctx, purpose, email); err != nil {
+			comm.ReportError(ctx, fmt.Errorf("webauth: failed to send %s email: %w", purpose, err), nil, nil)
+		}
+	}()
+	wt.Respond(w, r, &AuthResult{Action: action, Success: true, Status: http.StatusAccepted})
+}
+
+// throttle consumes a link request from the per-IP and per-address limiters
+// and reports whether either is exhausted, with how long to wait
+func (rec *Recovery) throttle(r *http.Request, purpose, email string) (time.Duration, bool) {
+	if rec.IPLimiter != nil {
+		if ok, wait := rec.IPLimiter.Allow(comm.ClientIP(r)); !ok {
+			return wait, true
+		}
+	}
+	if rec.AddressLimiter != nil {
+		if ok, wait := rec.AddressLimiter.Allow(purpose + ":" + strings.ToLower(email)); !ok {
+			return wait, true
+		}
+	}
+	return 0, false
+}
+
+// sendLink issues a token for the account owning email and mails it; unknown
+// addresses are silently ignored
+func (rec *Recovery) sendLink(ctx context.Context, purpose, email string) error {
+	subject, ok := rec.Accounts.SubjectForEmail(email)
+	if !ok {
+		return nil
+	}
+	token, expires, err := rec.Tokens.Issue(purpose, subject)
+	if err != nil {
+		return err
+	}
+	base := rec.ResetURL
+	if purpose == PurposeEmailVerify {
+		base = rec.VerifyURL
+	}
+	return rec.Mailer.Send(ctx, AuthMessage{
+		Kind:    purpose,
+		To:      email,
+		Subject: subject,
+		Link:    tokenLink(base, token),
+		Token:   token,
+		Expires: expires,
+	})
+}
+
+// handlePasswordReset redeems a reset token and sets the new password
+func (wt *WebAuth) handlePasswordReset(w http.ResponseWriter, r *http.Request) {
+	const action = "password_reset"
+	rec := wt.Recovery
+	if rec == nil {
+		wt.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		wt.Respond(w, r, &AuthResult{Action: action, Status: http.StatusMethodNotAllowed, Error: "method_not_allowed"})
+		return
+	}
+	fields, err := readFields(r)
+	if err != nil {
+		wt.Respond(w, r, &AuthResult{Action: action, Status: http.StatusBadRequest, Error: "invalid_request"})
+		return
+	}
+	password := fields["password"]
+	if len(password) < rec.MinPasswordLength {
+		wt.Respond(w, r, &AuthResult{Action: action, Status: http.StatusBadRequest, Error: "password_too_short",
+			Message: fmt.Sprintf("password must be at least %d characters", rec.MinPasswordLength)})
+		return
+	}
+	// Redeem first so concurrent requests with the same token cannot both succeed
+	subject, err := rec.Tokens.Redeem(fields["token"], PurposePasswordReset)
+	if err != nil {
+		wt.Respond(w, r, tokenErrorResult(action, err))
+		return
+	}
+	if err := rec.Accounts.SetPassword(subject, password); err != nil {
+		// Hand the token back so the user can retry with the same link
+		rec.Tokens.Release(fields["token"], PurposePasswordReset)
+		comm.ReportError(r.Context(), fmt.Errorf("webauth: password reset failed: %w", err), nil, r)
+		wt.Respond(w, r, &AuthResult{Action: action, Status: http.StatusInternalServerError, Error: "reset_failed", Actor: subject})
+		return
+	}
+	// Sign out everywhere: whoever held the old password may hold a session too
+	if wt.Sessions != nil {
+		revoked, err := wt.Sessions.RevokeUserSessions(subject, "")
+		if err != nil {
+			comm.ReportError(r.Context(), fmt.Errorf("webauth: revoking sessions after password reset failed: %w", err), nil, r)
+		}
+		if wt.NotifyRevoked != nil && len(revoked) > 0 {
+			wt.NotifyRevoked(revoked...)
+		}
+	}
+	wt.Respond(w, r, &AuthResult{Action: action, Success: true, Actor: subject})
+}
+
+// handleEmailVerify redeems a verification token
+func (wt *WebAuth) handleEmailVerify(w http.ResponseWriter, r *http.Request) {
+	const action = "email_verify"
+	rec := wt.Recovery
+	if rec == nil {
+		wt.NotFound(w, r)
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if token == "" && r.Method == http.MethodPost {
+		if fields, err := readFields(r); err == nil {
+			token = fields["token"]
+		}
+	}
+	subject, err := rec.Tokens.Redeem(token, PurposeEmailVerify)
+	if err != nil {
+		wt.Respond(w, r, tokenErrorResult(action, err))
+		return
+	}
+	if err := rec.Accounts.MarkEmailVerified(subject); err != nil {
+		wt.Respond(w, r, &AuthResult{Action: action, Status: http.StatusInternalServerError, Error: "verify_failed", Actor: subject})
+		return
+	}
+	wt.Respond(w, r, &AuthResult{Action: action, Success: true, Actor: subject})
+}
+
+func tokenErrorResult(action string, err error) *AuthResult {
+	code := "invalid_token"
+	switch {
+	case errors.Is(err, ErrTokenExpired):
+		code = "token_expired"
+	case errors.Is(err, ErrTokenUsed):
+		code = "token_used"
+	}
+	return &AuthResult{Action: action, Status: http.StatusBadRequest, Error: code}
+}
+
+// tokenLink appends token to base as the token query parameter
+func tokenLink(base, token string) string {
+	u, err := url.Parse(base)
+	if err != nil || base == "" {
+		return base
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// readFields decodes a flat JSON object or form body into string fields
+func readFields(r *http.Request) (map[string]string, error) {
+	fields := map[string]string{}
+	if isFormRequest(r) {
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		for key := range r.PostForm {
+			fields[key] = r.PostForm.Get(key)
+		}
+		return fields, nil
+	}
+	err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20)).Decode(&fields)
+	return fields, err
+}
@@ -0,0 +1,182 @@
+package webauth
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-xlite/wbx/comm/audit"
+	hl1 "github.com/go-xlite/wbx/utils"
+)
+
+// AuthResult is the outcome of an auth action, reported by providers through Respond
+type AuthResult struct {
+	Action   string // "login", "logout", "refresh", "register", "me"
+	Success  bool
+	Status   int    // HTTP status for JSON responses (defaults to 200 / 401)
+	Data     any    // Success payload (user, token, ...)
+	Error    string // Machine readable error code, e.g. "invalid_credentials"
+	Message  string // Human readable error message
+	Redirect string // HTML success destination (defaults to the return-to URL)
+	Actor    string // User or subject the action concerns, recorded in the audit log
+}
+
+// ResponseCustomizer shapes the JSON payload for a result; returning nil keeps the default
+type ResponseCustomizer func(r *http.Request, result *AuthResult) any
+
+// Credentials are the login fields accepted as JSON or form data
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Remember bool   `json:"remember"`
+}
+
+// SetResponseCustomizer installs a hook shaping JSON success/error payloads
+func (wt *WebAuth) SetResponseCustomizer(fn ResponseCustomizer) *WebAuth {
+	wt.Customizer = fn
+	return wt
+}
+
+// SetLoginPage sets the HTML login page that form logins return to on failure
+func (wt *WebAuth) SetLoginPage(path string) *WebAuth {
+	wt.LoginPage = path
+	return wt
+}
+
+// ReadCredentials decodes credentials from a JSON body or an urlencoded/multipart form
+func ReadCredentials(r *http.Request) (Credentials, error) {
+	var creds Credentials
+	if isFormRequest(r) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			return creds, err
+		}
+		creds.Username = r.FormValue("username")
+		creds.Password = r.FormValue("password")
+		remember := r.FormValue("remember")
+		creds.Remember = remember == "on" || remember == "true" || remember == "1"
+		return creds, nil
+	}
+	err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20)).Decode(&creds)
+	return creds, err
+}
+
+// WantsHTML reports whether r is a browser form submission expecting a page
+// rather than JSON (form content type, and an Accept header preferring HTML)
+func WantsHTML(r *http.Request) bool {
+	if !isFormRequest(r) {
+		return false
+	}
+	accept := r.Header.Get("Accept")
+	return accept == "" || strings.Contains(accept, "text/html")
+}
+
+// Respond writes result as JSON, or as a redirect for HTML form submissions:
+// success goes to result.Redirect or through RedirectAfterLogin, failure back
+// to LoginPage with ?error=<code> (keeping the return-to parameter). Successful
+// JSON logins carry the return-to URL as "redirect" for script clients.
+func (wt *WebAuth) Respond(w http.ResponseWriter, r *http.Request, result *AuthResult) {
+	auditResult(r, result)
+	w.Header().Set("Cache-Control", "no-store")
+
+	if WantsHTML(r) {
+		if result.Success {
+			if result.Redirect == "" {
+				wt.RedirectAfterLogin(w, r, wt.SuccessRedirect)
+				return
+			}
+			http.Redirect(w, r, SafeRedirect(result.Redirect, "/", wt.AllowedRedirectHosts...), http.StatusSeeOther)
+			return
+		}
+		if wt.LoginPage != "" {
+			http.Redirect(w, r, wt.loginErrorURL(r, result.Error), http.StatusSeeOther)
+			return
+		}
+	}
+
+	status := result.Status
+	if status == 0 {
+		status = http.StatusOK
+		if !result.Success {
+			status = http.StatusUnauthorized
+		}
+	}
+
+	var payload any
+	if wt.Customizer != nil {
+		payload = wt.Customizer(r, result)
+	}
+	if payload == nil {
+		if result.Success {
+			body := map[string]any{"success": true, "data": result.Data}
+			if result.Action == "login" {
+				body["redirect"] = wt.ReturnTo(r, wt.SuccessRedirect)
+			}
+			payload = body
+		} else {
+			payload = map[string]any{"success": false, "error": result.Error, "message": result.Message}
+		}
+	}
+	hl1.Helpers.WriteJSON(w, status, payload)
+}
+
+// auditResult records state-changing auth actions in the audit log
+func auditResult(r *http.Request, result *AuthResult) {
+	var eventType string
+	switch result.Action {
+	case "me", "sessions", "":
+		return
+	case "login":
+		eventType = audit.TypeLogin
+		if !result.Success {
+			eventType = audit.TypeLoginFailed
+		}
+	case "logout":
+		eventType = audit.TypeLogout
+	case "logout_all":
+		eventType = audit.TypeSessionRevoke
+	default:
+		eventType = "auth." + result.Action
+	}
+	outcome := audit.OutcomeSuccess
+	var details map[string]any
+	if !result.Success {
+		outcome = audit.OutcomeFailure
+		details = map[string]any{"error": result.Error}
+	} else if m, ok := result.Data.(map[string]int); ok {
+		details = map[string]any{}
+		for key, value := range m {
+			details[key] = value
+		}
+	}
+	audit.EmitRequest(r, eventType, outcome, result.Actor, details)
+}
+
+// loginErrorURL returns LoginPage carrying the error code and the return-to URL
+func (wt *WebAuth) loginErrorURL(r *http.Request, code string) string {
+	u, err := url.Parse(wt.LoginPage)
+	if err != nil {
+		return wt.LoginPage
+	}
+	q := u.Query()
+	if code == "" {
+		code = "login_failed"
+	}
+	q.Set("error", code)
+	param := wt.ReturnParam
+	if param == "" {
+		param = DefaultReturnParam
+	}
+	if next := r.FormValue(param); next != "" && IsSafeRedirect(next, wt.AllowedRedirectHosts...) {
+		q.Set(param, next)
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func isFormRequest(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data"
+}
@@ -0,0 +1,72 @@
+package webauth
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultReturnParam is the query/form parameter carrying the post-login destination
+const DefaultReturnParam = "next"
+
+// LoginURL returns loginPage with returnTo added as the param query parameter
+func LoginURL(loginPage, param, returnTo string) string {
+	if param == "" || returnTo == "" {
+		return loginPage
+	}
+	u, err := url.Parse(loginPage)
+	if err != nil {
+		return loginPage
+	}
+	q := u.Query()
+	q.Set(param, returnTo)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// IsSafeRedirect reports whether target may be used as a redirect destination:
+// a local absolute path ("/app?x=1"), or an absolute http(s) URL whose host is
+// in allowedHosts. Protocol-relative ("//evil.com") and backslash tricks are rejected.
+func IsSafeRedirect(target string, allowedHosts ...string) bool {
+	if target == "" || strings.ContainsAny(target, "\\\r\n\t") {
+		return false
+	}
+	if strings.HasPrefix(target, "/") {
+		return !strings.HasPrefix(target, "//")
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.User != nil {
+		return false
+	}
+	for _, host := range allowedHosts {
+		if strings.EqualFold(u.Host, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// SafeRedirect returns target when IsSafeRedirect allows it, otherwise fallback
+func SafeRedirect(target, fallback string, allowedHosts ...string) string {
+	if IsSafeRedirect(target, allowedHosts...) {
+		return target
+	}
+	return fallback
+}
+
+// ReturnTo returns the validated post-login destination carried by r
+// (query or form parameter ReturnParam), or fallback
+func (wt *WebAuth) ReturnTo(r *http.Request, fallback string) string {
+	param := wt.ReturnParam
+	if param == "" {
+		param = DefaultReturnParam
+	}
+	return SafeRedirect(r.FormValue(param), fallback, wt.AllowedRedirectHosts...)
+}
+
+// RedirectAfterLogin sends the client back to the page that required the login.
+// Auth providers call it once Login succeeded.
+func (wt *WebAuth) RedirectAfterLogin(w http.ResponseWriter, r *http.Request, fallback string) {
+	http.Redirect(w, r, wt.ReturnTo(r, fallback), http.StatusSeeOther)
+}
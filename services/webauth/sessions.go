@@ -0,0 +1,157 @@
+package webauth
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SessionInfo describes one active session of a user
+type SessionInfo struct {
+	ID        string    `json:"id"` // Opaque identifier, never the session token
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Device    string    `json:"device,omitempty"`
+	Current   bool      `json:"current"`
+}
+
+// SessionDirectory is a session service capable of user-indexed enumeration
+type SessionDirectory interface {
+	// LookupSession resolves a session token to its info
+	LookupSession(token string) (SessionInfo, bool)
+	// ListUserSessions returns all active sessions of userID
+	ListUserSessions(userID string) ([]SessionInfo, error)
+	// RevokeUserSessions revokes every session of userID except exceptID ("" revokes all)
+	// and returns the comm.SessionKeyFor keys of the revoked tokens, so their
+	// live connections can be closed
+	RevokeUserSessions(userID, exceptID string) ([]string, error)
+}
+
+// SetSessionDirectory enables the /sessions and /logout-all endpoints.
+// cookieName is the session cookie used to identify the current session.
+func (wt *WebAuth) SetSessionDirectory(dir SessionDirectory, cookieName string) *WebAuth {
+	wt.Sessions = dir
+	wt.SessionCookie = cookieName
+	return wt
+}
+
+// sessionCookieName returns the session cookie that applies to r
+func (wt *WebAuth) sessionCookieName(r *http.Request) string {
+	if wt.SessionCookieFor != nil {
+		return wt.SessionCookieFor(r)
+	}
+	return wt.SessionCookie
+}
+
+// currentSession resolves the session presented by r
+func (wt *WebAuth) currentSession(r *http.Request) (SessionInfo, bool) {
+	cookie, err := r.Cookie(wt.sessionCookieName(r))
+	if err != nil || cookie.Value == "" {
+		return SessionInfo{}, false
+	}
+	return wt.Sessions.LookupSession(cookie.Value)
+}
+
+// handleSessions lists the active sessions of the current user
+func (wt *WebAuth) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if wt.Sessions == nil {
+		wt.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		wt.Respond(w, r, &AuthResult{Action: "sessions", Status: http.StatusMethodNotAllowed, Error: "method_not_allowed"})
+		return
+	}
+	current, ok := wt.currentSession(r)
+	if !ok {
+		wt.Respond(w, r, &AuthResult{Action: "sessions", Error: "not_authenticated"})
+		return
+	}
+	sessions, err := wt.Sessions.ListUserSessions(current.UserID)
+	if err != nil {
+		wt.Respond(w, r, &AuthResult{Action: "sessions", Status: http.StatusInternalServerError, Error: "list_failed", Message: err.Error()})
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current.ID
+	}
+	wt.Respond(w, r, &AuthResult{Action: "sessions", Success: true, Data: sessions})
+}
+
+// handleLogoutAll revokes every session of the current user.
+// With ?keep_current=1 the calling session stays signed in.
+func (wt *WebAuth) handleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	if wt.Sessions == nil {
+		wt.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		wt.Respond(w, r, &AuthResult{Action: "logout_all", Status: http.StatusMethodNotAllowed, Error: "method_not_allowed"})
+		return
+	}
+	current, ok := wt.currentSession(r)
+	if !ok {
+		wt.Respond(w, r, &AuthResult{Action: "logout_all", Error: "not_authenticated"})
+		return
+	}
+
+	except := ""
+	if keep := r.URL.Query().Get("keep_current"); keep == "1" || keep == "true" {
+		except = current.ID
+	}
+	revoked, err := wt.Sessions.RevokeUserSessions(current.UserID, except)
+	if err != nil {
+		wt.Respond(w, r, &AuthResult{Action: "logout_all", Status: http.StatusInternalServerError, Error: "revoke_failed", Message: err.Error(), Actor: current.UserID})
+		return
+	}
+	if wt.NotifyRevoked != nil {
+		wt.NotifyRevoked(revoked...)
+	}
+	if except == "" {
+		if wt.ClearSession != nil {
+			wt.ClearSession(w, r)
+		} else {
+			http.SetCookie(w, &http.Cookie{Name: wt.sessionCookieName(r), Value: "", Path: "/", MaxAge: -1})
+		}
+	}
+	wt.Respond(w, r, &AuthResult{Action: "logout_all", Success: true, Data: map[string]int{"revoked": len(revoked)}, Actor: current.UserID})
+}
+
+// DeviceLabel derives a short "Browser on OS" label from a User-Agent header
+func DeviceLabel(userAgent string) string {
+	if userAgent == "" {
+		return ""
+	}
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	case strings.Contains(userAgent, "curl/"):
+		browser = "curl"
+	}
+	platform := "unknown OS"
+	switch {
+	case strings.Contains(userAgent, "Android"):
+		platform = "Android"
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		platform = "iOS"
+	case strings.Contains(userAgent, "Windows"):
+		platform = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"), strings.Contains(userAgent, "Macintosh"):
+		platform = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		platform = "Linux"
+	}
+	return browser + " on " + platform
+}
@@ -0,0 +1,155 @@
+package webauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token purposes used by the built-in recovery endpoints
+const (
+	PurposePasswordReset = "password_reset"
+	PurposeEmailVerify   = "email_verify"
+)
+
+var (
+	ErrTokenInvalid = errors.New("webauth: invalid token")
+	ErrTokenExpired = errors.New("webauth: token expired")
+	ErrTokenUsed    = errors.New("webauth: token already used")
+)
+
+// ActionTokens issues signed, single-use, expiring tokens for account actions
+// such as password resets and email verification. Tokens are self-contained
+// (HMAC-SHA256 signed); only the nonces of redeemed tokens are kept in memory.
+type ActionTokens struct {
+	TTL    time.Duration // Token lifetime (default 1h)
+	secret []byte
+	used   map[string]time.Time // nonce -> expiry
+	mu     sync.Mutex
+}
+
+type actionClaims struct {
+	Purpose string `json:"p"`
+	Subject string `json:"s"`
+	Nonce   string `json:"n"`
+	Expires int64  `json:"e"`
+}
+
+// MinSecretLength is the shortest signing secret NewActionTokens accepts
+const MinSecretLength = 32
+
+// NewActionTokens creates a token issuer signing with secret. It panics if
+// secret is shorter than MinSecretLength, since tokens signed with an empty or
+// guessable key could be forged.
+func NewActionTokens(secret []byte, ttl time.Duration) *ActionTokens {
+	if len(secret) < MinSecretLength {
+		panic(fmt.Sprintf("webauth: action token secret must be at least %d bytes", MinSecretLength))
+	}
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	return &ActionTokens{
+		TTL:    ttl,
+		secret: secret,
+		used:   make(map[string]time.Time),
+	}
+}
+
+// Issue creates a token for subject (e.g. a user ID) valid only for purpose
+func (at *ActionTokens) Issue(purpose, subject string) (string, time.Time, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	expires := time.Now().Add(at.TTL)
+	payload, err := json.Marshal(actionClaims{
+		Purpose: purpose,
+		Subject: subject,
+		Nonce:   hex.EncodeToString(buf),
+		Expires: expires.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + at.sign(body), expires, nil
+}
+
+// Redeem validates token for purpose, consumes it and returns its subject
+func (at *ActionTokens) Redeem(token, purpose string) (string, error) {
+	claims, err := at.parse(token, purpose)
+	if err != nil {
+		return "", err
+	}
+
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	now := time.Now()
+	for nonce, exp := range at.used {
+		if now.After(exp) {
+			delete(at.used, nonce)
+		}
+	}
+	if _, used := at.used[claims.Nonce]; used {
+		return "", ErrTokenUsed
+	}
+	at.used[claims.Nonce] = time.Unix(claims.Expires, 0)
+	return claims.Subject, nil
+}
+
+// Release makes a redeemed token usable again, for when the action it
+// authorized failed after Redeem
+func (at *ActionTokens) Release(token, purpose string) {
+	claims, err := at.parse(token, purpose)
+	if err != nil {
+		return
+	}
+	at.mu.Lock()
+	delete(at.used, claims.Nonce)
+	at.mu.Unlock()
+}
+
+// Check validates token for purpose without consuming it
+func (at *ActionTokens) Check(token, purpose string) (string, error) {
+	claims, err := at.parse(token, purpose)
+	if err != nil {
+		return "", err
+	}
+	at.mu.Lock()
+	_, used := at.used[claims.Nonce]
+	at.mu.Unlock()
+	if used {
+		return "", ErrTokenUsed
+	}
+	return claims.Subject, nil
+}
+
+func (at *ActionTokens) parse(token, purpose string) (actionClaims, error) {
+	var claims actionClaims
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(at.sign(body))) {
+		return claims, ErrTokenInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil || json.Unmarshal(payload, &claims) != nil || claims.Purpose != purpose {
+		return claims, ErrTokenInvalid
+	}
+	if time.Now().Unix() > claims.Expires {
+		return claims, ErrTokenExpired
+	}
+	return claims, nil
+}
+
+func (at *ActionTokens) sign(body string) string {
+	mac := hmac.New(sha256.New, at.secret)
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
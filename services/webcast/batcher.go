@@ -0,0 +1,147 @@
+package webcast
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// eventBatcher collects broadcasts for a short window and sends them as a single
+// SSE event whose data is a JSON array. Keyed items replace any earlier item with
+// the same key in the current window, so only the latest state per entity is sent.
+type eventBatcher struct {
+	window time.Duration
+	items  []any
+	keyed  map[string]int // key -> index in items
+	timer  *time.Timer
+	gen    uint64 // Current window, so a timer firing late cannot flush the next one
+	mu     sync.Mutex
+	flush  func(items []any)
+}
+
+func newEventBatcher(window time.Duration, flush func(items []any)) *eventBatcher {
+	return &eventBatcher{
+		window: window,
+		keyed:  make(map[string]int),
+		flush:  flush,
+	}
+}
+
+// add queues data, replacing the pending item for key when key is not empty.
+// It reports whether an earlier item was coalesced away.
+func (eb *eventBatcher) add(key string, data any) bool {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	if key != "" {
+		if idx, ok := eb.keyed[key]; ok {
+			eb.items[idx] = data
+			return true
+		}
+		eb.keyed[key] = len(eb.items)
+	}
+	eb.items = append(eb.items, data)
+
+	if eb.timer == nil {
+		gen := eb.gen
+		eb.timer = time.AfterFunc(eb.window, func() { eb.flushWindow(gen) })
+	}
+	return false
+}
+
+// flushNow sends the pending items (if any) and starts a new window
+func (eb *eventBatcher) flushNow() int {
+	eb.mu.Lock()
+	return eb.flushLocked()
+}
+
+// flushWindow is flushNow for the timer of window gen; it does nothing when
+// that window was already flushed
+func (eb *eventBatcher) flushWindow(gen uint64) {
+	eb.mu.Lock()
+	if gen != eb.gen {
+		eb.mu.Unlock()
+		return
+	}
+	eb.flushLocked()
+}
+
+// flushLocked is flushNow with eb.mu held; it releases eb.mu
+func (eb *eventBatcher) flushLocked() int {
+	items := eb.items
+	eb.gen++
+	eb.items = nil
+	eb.keyed = make(map[string]int)
+	if eb.timer != nil {
+		eb.timer.Stop()
+		eb.timer = nil
+	}
+	eb.mu.Unlock()
+
+	if len(items) == 0 {
+		return 0
+	}
+	eb.flush(items)
+	return len(items)
+}
+
+// SetBatchWindow enables batching for BroadcastBatched and BroadcastCoalesced:
+// items queued within window are sent together as one JSON array event.
+// A window of 0 flushes pending items and sends every later item immediately.
+func (wc *WebCast) SetBatchWindow(window time.Duration) *WebCast {
+	wc.batchMu.Lock()
+	old := wc.batcher
+	wc.batcher = nil
+	if window > 0 {
+		wc.batcher = newEventBatcher(window, wc.flushBatch)
+	}
+	wc.batchMu.Unlock()
+
+	if old != nil {
+		old.flushNow()
+	}
+	return wc
+}
+
+// BroadcastBatched queues data for the next batch (sent immediately as a
+// single-item array when batching is disabled)
+func (wc *WebCast) BroadcastBatched(data any) {
+	wc.BroadcastCoalesced("", data)
+}
+
+// BroadcastCoalesced queues data for the next batch, keeping only the latest
+// item per key within the window (e.g. key = entity ID for telemetry updates)
+func (wc *WebCast) BroadcastCoalesced(key string, data any) {
+	wc.batchMu.Lock()
+	batcher := wc.batcher
+	wc.batchMu.Unlock()
+
+	if batcher == nil {
+		wc.flushBatch([]any{data})
+		return
+	}
+	if batcher.add(key, data) {
+		wc.clientManager.incrementCoalesced()
+	}
+}
+
+// FlushBatch sends any pending batched items right away and returns how many were sent
+func (wc *WebCast) FlushBatch() int {
+	wc.batchMu.Lock()
+	batcher := wc.batcher
+	wc.batchMu.Unlock()
+
+	if batcher == nil {
+		return 0
+	}
+	return batcher.flushNow()
+}
+
+func (wc *WebCast) flushBatch(items []any) {
+	jsonData, err := json.Marshal(items)
+	if err != nil || wc.Schemas.Check(jsonData) != nil {
+		return
+	}
+	wc.clientManager.broadcast(messageFrame(string(jsonData)))
+	wc.clientManager.incrementBatches()
+}
@@ -1,30 +1,47 @@
 package webcast
 
 import (
+	"maps"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // SSEClientManager handles client connections for a specific SSE endpoint
 type SSEClientManager struct {
-	clients map[string]chan string
+	clients map[string]chan string        // Complete SSE frames per client
+	drains  map[string]chan time.Duration // Per-client drain signal carrying the retry hint
+	meta    map[string]map[string]string  // Per-client metadata from StreamConfig, for targeting
 	mutex   sync.RWMutex
 	stats   SSEStats
+
+	sessions map[string]string      // Auth session key per client, see WebCast.SessionRevoked
+	revokes  map[string]chan string // Per-client signal closing the stream without reconnect
+
+	// messagesSent backs stats.MessagesSent; sends only hold the read lock
+	messagesSent atomic.Int64
 }
 
 func newSSEClientManager() *SSEClientManager {
 	return &SSEClientManager{
 		clients: make(map[string]chan string),
+		drains:  make(map[string]chan time.Duration),
+		meta:    make(map[string]map[string]string),
 		stats:   SSEStats{},
+
+		sessions: make(map[string]string),
+		revokes:  make(map[string]chan string),
 	}
 }
 
-func (scm *SSEClientManager) addClient(clientID string) chan string {
+func (scm *SSEClientManager) addClient(clientID string, metadata map[string]string) chan string {
 	scm.mutex.Lock()
 	defer scm.mutex.Unlock()
 
 	client := make(chan string, 10)
 	scm.clients[clientID] = client
+	scm.drains[clientID] = make(chan time.Duration, 1)
+	scm.meta[clientID] = maps.Clone(metadata)
 
 	scm.stats.TotalConnections++
 	scm.stats.CurrentConnections++
@@ -40,6 +57,10 @@ func (scm *SSEClientManager) removeClient(clientID string) {
 	if client, exists := scm.clients[clientID]; exists {
 		close(client)
 		delete(scm.clients, clientID)
+		delete(scm.drains, clientID)
+		delete(scm.meta, clientID)
+		delete(scm.sessions, clientID)
+		delete(scm.revokes, clientID)
 
 		scm.stats.CurrentConnections--
 		scm.stats.LastDisconnectionTime = time.Now()
@@ -61,7 +82,7 @@ func (scm *SSEClientManager) broadcast(message string) int {
 		}
 	}
 
-	scm.stats.MessagesSent += int64(sentCount)
+	scm.messagesSent.Add(int64(sentCount))
 	return sentCount
 }
 
@@ -76,7 +97,7 @@ func (scm *SSEClientManager) sendToClient(clientID string, message string) bool
 
 	select {
 	case client <- message:
-		scm.stats.MessagesSent++
+		scm.messagesSent.Add(1)
 		return true
 	default:
 		// Client buffer full
@@ -85,6 +106,121 @@ func (scm *SSEClientManager) sendToClient(clientID string, message string) bool
 	}
 }
 
+// sendToMatching sends message to every client whose metadata has key set to
+// value; a value of "*" matches any client that has key set at all
+func (scm *SSEClientManager) sendToMatching(key, value, message string) int {
+	scm.mutex.RLock()
+	defer scm.mutex.RUnlock()
+
+	sentCount := 0
+	for clientID, client := range scm.clients {
+		v, ok := scm.meta[clientID][key]
+		if !ok || (value != "*" && v != value) {
+			continue
+		}
+		select {
+		case client <- message:
+			sentCount++
+		default:
+			// Client buffer full, remove it asynchronously
+			go scm.removeClient(clientID)
+		}
+	}
+
+	scm.messagesSent.Add(int64(sentCount))
+	return sentCount
+}
+
+// getMetadata returns a copy of a client's metadata
+func (scm *SSEClientManager) getMetadata(clientID string) (map[string]string, bool) {
+	scm.mutex.RLock()
+	defer scm.mutex.RUnlock()
+
+	if _, exists := scm.clients[clientID]; !exists {
+		return nil, false
+	}
+	return maps.Clone(scm.meta[clientID]), true
+}
+
+// setMetadata sets (or with an empty value removes) one metadata key of a client
+func (scm *SSEClientManager) setMetadata(clientID, key, value string) bool {
+	scm.mutex.Lock()
+	defer scm.mutex.Unlock()
+
+	if _, exists := scm.clients[clientID]; !exists {
+		return false
+	}
+	if value == "" {
+		delete(scm.meta[clientID], key)
+		return true
+	}
+	if scm.meta[clientID] == nil {
+		scm.meta[clientID] = make(map[string]string)
+	}
+	scm.meta[clientID][key] = value
+	return true
+}
+
+// drainSignal returns the channel that asks a client's stream to close
+func (scm *SSEClientManager) drainSignal(clientID string) <-chan time.Duration {
+	scm.mutex.RLock()
+	defer scm.mutex.RUnlock()
+	return scm.drains[clientID]
+}
+
+// drainClient asks a client's stream to close with a reconnect hint
+func (scm *SSEClientManager) drainClient(clientID string, retry time.Duration) bool {
+	scm.mutex.RLock()
+	defer scm.mutex.RUnlock()
+
+	drain, exists := scm.drains[clientID]
+	if !exists {
+		return false
+	}
+	select {
+	case drain <- retry:
+	default:
+	}
+	return true
+}
+
+// setSession records the auth session a client's stream was opened with
+func (scm *SSEClientManager) setSession(clientID, sessionKey string) {
+	scm.mutex.Lock()
+	defer scm.mutex.Unlock()
+	if _, exists := scm.clients[clientID]; !exists {
+		return
+	}
+	scm.sessions[clientID] = sessionKey
+	scm.revokes[clientID] = make(chan string, 1)
+}
+
+// revokeSignal returns the channel that closes a client's stream for good
+func (scm *SSEClientManager) revokeSignal(clientID string) <-chan string {
+	scm.mutex.RLock()
+	defer scm.mutex.RUnlock()
+	return scm.revokes[clientID]
+}
+
+// revokeSession signals every stream opened under sessionKey to close with reason
+func (scm *SSEClientManager) revokeSession(sessionKey, reason string) int {
+	scm.mutex.RLock()
+	defer scm.mutex.RUnlock()
+
+	revoked := 0
+	for clientID, key := range scm.sessions {
+		if key != sessionKey {
+			continue
+		}
+		select {
+		case scm.revokes[clientID] <- reason:
+		default:
+		}
+		revoked++
+	}
+	return revoked
+}
+
 func (scm *SSEClientManager) getClientCount() int {
 	scm.mutex.RLock()
 	defer scm.mutex.RUnlock()
@@ -94,7 +230,9 @@ func (scm *SSEClientManager) getClientCount() int {
 func (scm *SSEClientManager) getStats() SSEStats {
 	scm.mutex.RLock()
 	defer scm.mutex.RUnlock()
-	return scm.stats
+	stats := scm.stats
+	stats.MessagesSent = scm.messagesSent.Load()
+	return stats
 }
 
 func (scm *SSEClientManager) getClients() []string {
@@ -115,6 +253,10 @@ func (scm *SSEClientManager) shutdown() {
 	for clientID, client := range scm.clients {
 		close(client)
 		delete(scm.clients, clientID)
+		delete(scm.drains, clientID)
+		delete(scm.meta, clientID)
+		delete(scm.sessions, clientID)
+		delete(scm.revokes, clientID)
 	}
 
 	scm.stats.CurrentConnections = 0
@@ -126,3 +268,15 @@ func (scm *SSEClientManager) incrementRejections() {
 	defer scm.mutex.Unlock()
 	scm.stats.ConnectionsRejected++
 }
+
+func (scm *SSEClientManager) incrementBatches() {
+	scm.mutex.Lock()
+	defer scm.mutex.Unlock()
+	scm.stats.BatchesSent++
+}
+
+func (scm *SSEClientManager) incrementCoalesced() {
+	scm.mutex.Lock()
+	defer scm.mutex.Unlock()
+	scm.stats.EventsCoalesced++
+}
@@ -0,0 +1,49 @@
+package webcast
+
+import (
+	"time"
+
+	"github.com/go-xlite/wbx/comm"
+)
+
+// DrainReason is the close reason sent to streams ended by Drain
+const DrainReason = comm.DrainReason
+
+// Drain prepares the server for shutdown during a rolling deploy: new streams
+// are sent back right away with a reconnect hint, and open streams are closed
+// one by one spread evenly over window, each with a close event asking the
+// client to reconnect shortly (to another instance); the delay is set by
+// comm.DrainRetryBase and comm.DrainRetryJitter. Drain returns once every
+// stream present at the start was told to close, with how many that were;
+// the streams finish in the background, see GetClientCount. A window of 0 closes
+// all streams at once.
+func (wc *WebCast) Drain(window time.Duration) int {
+	wc.draining.Store(true)
+
+	clients := wc.GetClients()
+	if len(clients) == 0 {
+		return 0
+	}
+	step := window / time.Duration(len(clients))
+
+	drained := 0
+	for i, clientID := range clients {
+		if i > 0 && step > 0 {
+			time.Sleep(step)
+		}
+		if wc.clientManager.drainClient(clientID, comm.DrainRetryHint()) {
+			drained++
+		}
+	}
+	return drained
+}
+
+// IsDraining reports whether Drain was called (and Resume was not)
+func (wc *WebCast) IsDraining() bool {
+	return wc.draining.Load()
+}
+
+// Resume accepts new streams again after Drain, e.g. when a deploy is aborted
+func (wc *WebCast) Resume() {
+	wc.draining.Store(false)
+}
@@ -0,0 +1,93 @@
+package webcast
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// MessageEvent is the event name of Broadcast, SendToClient and friends, which
+// EventSource delivers to onmessage
+const MessageEvent = "message"
+
+// FormatEvent builds a complete SSE frame. Multi-line data is split into one
+// data field per line; line breaks are removed from event and id as they would
+// end the field early. Empty event and id are omitted.
+func FormatEvent(event, id, data string) string {
+	var b strings.Builder
+	if event = stripLineBreaks(event); event != "" {
+		b.WriteString("event: ")
+		b.WriteString(event)
+		b.WriteByte('\n')
+	}
+	if id = stripLineBreaks(id); id != "" {
+		b.WriteString("id: ")
+		b.WriteString(id)
+		b.WriteByte('\n')
+	}
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	for line := range strings.SplitSeq(data, "\n") {
+		b.WriteString("data: ")
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+func stripLineBreaks(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// messageFrame wraps a plain message as a "message" event
+func messageFrame(message string) string {
+	return FormatEvent(MessageEvent, "", message)
+}
+
+// encodeEvent turns data into the event payload: strings and byte slices are
+// sent as they are, anything else as JSON validated against the schemas
+func (wc *WebCast) encodeEvent(data any) (string, error) {
+	var payload []byte
+	switch v := data.(type) {
+	case string:
+		payload = []byte(v)
+	case []byte:
+		payload = v
+	default:
+		var err error
+		if payload, err = json.Marshal(data); err != nil {
+			return "", err
+		}
+	}
+	if err := wc.Schemas.Check(payload); err != nil {
+		return "", err
+	}
+	return string(payload), nil
+}
+
+// nextEventID returns the id of the next named event; clients echo the last
+// one as Last-Event-ID when they reconnect
+func (wc *WebCast) nextEventID() string {
+	return strconv.FormatUint(wc.eventSeq.Add(1), 10)
+}
+
+// SendEventToClient sends data to a client as a named event with its own id,
+// so the client can tell event types apart with addEventListener(event).
+// data is marshaled to JSON unless it is a string or []byte already.
+func (wc *WebCast) SendEventToClient(clientID, event string, data any) (bool, error) {
+	payload, err := wc.encodeEvent(data)
+	if err != nil {
+		return false, err
+	}
+	return wc.clientManager.sendToClient(clientID, FormatEvent(event, wc.nextEventID(), payload)), nil
+}
+
+// BroadcastEvent sends data to all connected clients as a named event, see SendEventToClient
+func (wc *WebCast) BroadcastEvent(event string, data any) (int, error) {
+	payload, err := wc.encodeEvent(data)
+	if err != nil {
+		return 0, err
+	}
+	return wc.clientManager.broadcast(FormatEvent(event, wc.nextEventID(), payload)), nil
+}
@@ -0,0 +1,15 @@
+package webcast
+
+import (
+	comm "github.com/go-xlite/wbx/comm"
+)
+
+// SessionRevoked closes every stream opened under the auth session sessionKey
+// with a close event whose reason is "session-revoked" and no reconnect hint.
+// Register the WebCast with SessionManager.OnRevoke.
+func (wc *WebCast) SessionRevoked(sessionKey string) int {
+	if sessionKey == "" {
+		return 0
+	}
+	return wc.clientManager.revokeSession(sessionKey, comm.SessionRevokedReason)
+}
@@ -4,9 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	comm "github.com/go-xlite/wbx/comm"
+	"github.com/go-xlite/wbx/comm/events"
+	"github.com/go-xlite/wbx/comm/schema"
 )
 
 // WebCast represents a Server-Sent Events (SSE) server for real-time streaming
@@ -16,6 +20,26 @@ type WebCast struct {
 	PathBase      string // Optional base path for convenience (e.g., "/events")
 	NotFound      http.HandlerFunc
 	clientManager *SSEClientManager
+
+	// MaxConnectionDuration closes streams after this long (0 = unlimited)
+	MaxConnectionDuration time.Duration
+	// IdleTimeout closes streams that have not carried a message for this long (0 = never).
+	// Keep-alives do not count as activity.
+	IdleTimeout time.Duration
+
+	batcher *eventBatcher
+	batchMu sync.Mutex
+
+	// Schemas validates outgoing JSON payloads while enabled, see SetSchemas
+	Schemas *schema.Registry
+
+	draining atomic.Bool // See Drain
+
+	// eventSeq numbers the events sent with SendEventToClient and BroadcastEvent
+	eventSeq atomic.Uint64
+
+	// Events receives ClientConnected and ClientDisconnected, see SetEvents
+	Events events.Publisher
 }
 
 // NewWebCast creates a new WebCast instance with proper routing capabilities
@@ -29,6 +53,19 @@ func NewWebCast() *WebCast {
 	return wc
 }
 
+// SetEvents publishes client connects and disconnects to p (e.g. WebLite.Events())
+func (wc *WebCast) SetEvents(p events.Publisher) *WebCast {
+	wc.Events = p
+	return wc
+}
+
+// publish sends an event when a publisher is set
+func (wc *WebCast) publish(topic string, data any) {
+	if wc.Events != nil {
+		wc.Events.Publish(topic, "webcast", data)
+	}
+}
+
 // OnRequest handles an incoming HTTP request using the registered routes
 // This is the main entry point when the main server forwards a request
 func (wc *WebCast) OnRequest(w http.ResponseWriter, r *http.Request) {
@@ -50,9 +87,31 @@ func (wc *WebCast) SetNotFoundHandler(handler http.HandlerFunc) {
 	wc.Mux.NotFoundHandler = handler
 }
 
+// SetMaxConnectionDuration sets the default maximum lifetime of a stream
+func (wc *WebCast) SetMaxConnectionDuration(d time.Duration) *WebCast {
+	wc.MaxConnectionDuration = d
+	return wc
+}
+
+// SetIdleTimeout sets the default idle timeout of a stream
+func (wc *WebCast) SetIdleTimeout(d time.Duration) *WebCast {
+	wc.IdleTimeout = d
+	return wc
+}
+
+// SetSchemas validates outgoing payloads against registry while it is enabled;
+// in strict mode invalid payloads are not sent
+func (wc *WebCast) SetSchemas(registry *schema.Registry) *WebCast {
+	wc.Schemas = registry
+	return wc
+}
+
 // Broadcast sends a message to all connected clients
 func (wc *WebCast) Broadcast(message string) int {
-	return wc.clientManager.broadcast(message)
+	if wc.Schemas.Check([]byte(message)) != nil {
+		return 0
+	}
+	return wc.clientManager.broadcast(messageFrame(message))
 }
 
 // BroadcastJSON sends a JSON message to all connected clients
@@ -61,12 +120,18 @@ func (wc *WebCast) BroadcastJSON(data any) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return wc.clientManager.broadcast(string(jsonData)), nil
+	if err := wc.Schemas.Check(jsonData); err != nil {
+		return 0, err
+	}
+	return wc.clientManager.broadcast(messageFrame(string(jsonData))), nil
 }
 
 // SendToClient sends a message to a specific client
 func (wc *WebCast) SendToClient(clientID string, message string) bool {
-	return wc.clientManager.sendToClient(clientID, message)
+	if wc.Schemas.Check([]byte(message)) != nil {
+		return false
+	}
+	return wc.clientManager.sendToClient(clientID, messageFrame(message))
 }
 
 // SendJSONToClient sends a JSON message to a specific client
@@ -75,7 +140,31 @@ func (wc *WebCast) SendJSONToClient(clientID string, data any) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	return wc.clientManager.sendToClient(clientID, string(jsonData)), nil
+	if err := wc.Schemas.Check(jsonData); err != nil {
+		return false, err
+	}
+	return wc.clientManager.sendToClient(clientID, messageFrame(string(jsonData))), nil
+}
+
+// SendToClientsWithMetadata sends a message to every client whose metadata has
+// key set to value (e.g. "server", "i-abc"); value "*" matches any client that
+// has key set. Returns the number of clients reached.
+func (wc *WebCast) SendToClientsWithMetadata(key, value, message string) int {
+	if wc.Schemas.Check([]byte(message)) != nil {
+		return 0
+	}
+	return wc.clientManager.sendToMatching(key, value, messageFrame(message))
+}
+
+// GetClientMetadata returns a copy of the metadata a connected client was registered with
+func (wc *WebCast) GetClientMetadata(clientID string) (map[string]string, bool) {
+	return wc.clientManager.getMetadata(clientID)
+}
+
+// SetClientMetadata changes one metadata key of a connected client, e.g. when it
+// navigates to another view; an empty value removes the key
+func (wc *WebCast) SetClientMetadata(clientID, key, value string) bool {
+	return wc.clientManager.setMetadata(clientID, key, value)
 }
 
 // GetClientCount returns the number of connected clients
@@ -95,12 +184,14 @@ func (wc *WebCast) GetClients() []string {
 
 // Shutdown closes all client connections
 func (wc *WebCast) Shutdown() {
+	wc.FlushBatch()
 	wc.clientManager.shutdown()
 }
 
-// AddClient adds a new SSE client connection
+// AddClient adds a new SSE client connection; the channel carries complete
+// SSE frames (see FormatEvent) to be written to the stream as they are
 func (wc *WebCast) AddClient(clientID string) chan string {
-	return wc.clientManager.addClient(clientID)
+	return wc.clientManager.addClient(clientID, nil)
 }
 
 // RemoveClient removes an SSE client connection
@@ -122,6 +213,11 @@ type StreamConfig struct {
 	Metadata          map[string]string
 	OnConnect         func(clientID string)
 	OnDisconnect      func(clientID string)
+
+	// Connection limits; zero values fall back to the WebCast defaults
+	MaxConnectionDuration time.Duration
+	IdleTimeout           time.Duration
+	ReconnectDelay        time.Duration // retry hint sent with a timeout close event (default 1s)
 }
 
 // StreamToClient handles the SSE streaming loop for a client
@@ -142,19 +238,36 @@ func (wc *WebCast) StreamToClient(config StreamConfig) {
 
 	config.W.WriteHeader(http.StatusOK)
 
+	// While draining, send new streams straight back with a reconnect hint
+	if wc.draining.Load() {
+		wc.clientManager.incrementRejections()
+		writeReconnectClose(config.W, DrainReason, comm.DrainRetryHint())
+		return
+	}
+
 	// Add this client to the client manager
-	clientChan := wc.AddClient(config.ClientID)
+	clientChan := wc.clientManager.addClient(config.ClientID, config.Metadata)
+	drainC := wc.clientManager.drainSignal(config.ClientID)
+	var revokeC <-chan string
+	sessionKey := comm.SessionKey(config.R)
+	if sessionKey != "" {
+		wc.clientManager.setSession(config.ClientID, sessionKey)
+		revokeC = wc.clientManager.revokeSignal(config.ClientID)
+	}
+	clientEvent := events.ClientData{Transport: "sse", ClientID: config.ClientID, SessionKey: sessionKey}
 	defer func() {
 		wc.RemoveClient(config.ClientID)
 		if config.OnDisconnect != nil {
 			config.OnDisconnect(config.ClientID)
 		}
+		wc.publish(events.ClientDisconnected, clientEvent)
 	}()
 
 	// Notify of connection
 	if config.OnConnect != nil {
 		config.OnConnect(config.ClientID)
 	}
+	wc.publish(events.ClientConnected, clientEvent)
 
 	// Send initial connection event
 	initialPayload := map[string]any{
@@ -182,9 +295,53 @@ func (wc *WebCast) StreamToClient(config StreamConfig) {
 	keepAliveTicker := time.NewTicker(keepAliveDuration)
 	defer keepAliveTicker.Stop()
 
+	maxDuration := config.MaxConnectionDuration
+	if maxDuration <= 0 {
+		maxDuration = wc.MaxConnectionDuration
+	}
+	idleTimeout := config.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = wc.IdleTimeout
+	}
+	reconnectDelay := config.ReconnectDelay
+	if reconnectDelay <= 0 {
+		reconnectDelay = time.Second
+	}
+
+	// nil channels block forever, disabling the corresponding case
+	var lifetimeC, idleC <-chan time.Time
+	if maxDuration > 0 {
+		lifetimeTimer := time.NewTimer(maxDuration)
+		defer lifetimeTimer.Stop()
+		lifetimeC = lifetimeTimer.C
+	}
+	var idleTimer *time.Timer
+	if idleTimeout > 0 {
+		idleTimer = time.NewTimer(idleTimeout)
+		defer idleTimer.Stop()
+		idleC = idleTimer.C
+	}
+
 	ctx := config.R.Context()
 	for {
 		select {
+		case <-lifetimeC:
+			writeReconnectClose(config.W, "max_duration", reconnectDelay)
+			return
+		case <-idleC:
+			writeReconnectClose(config.W, "idle_timeout", reconnectDelay)
+			return
+		case retry := <-drainC:
+			writeReconnectClose(config.W, DrainReason, retry)
+			return
+		case reason := <-revokeC:
+			closeMsg := fmt.Sprintf("{\"type\":\"close\",\"reason\":\"%s\",\"timestamp\":\"%s\"}",
+				reason, time.Now().Format(time.RFC3339))
+			fmt.Fprintf(config.W, "event: close\ndata: %s\n\n", closeMsg)
+			if flusher, ok := config.W.(http.Flusher); ok {
+				flusher.Flush()
+			}
+			return
 		case <-ctx.Done():
 			closeMsg := fmt.Sprintf("{\"type\":\"close\",\"reason\":\"context_done\",\"timestamp\":\"%s\"}",
 				time.Now().Format(time.RFC3339))
@@ -200,7 +357,7 @@ func (wc *WebCast) StreamToClient(config StreamConfig) {
 			if flusher, ok := config.W.(http.Flusher); ok {
 				flusher.Flush()
 			}
-		case message, ok := <-clientChan:
+		case frame, ok := <-clientChan:
 			if !ok {
 				closeMsg := fmt.Sprintf("{\"type\":\"close\",\"reason\":\"channel_closed\",\"timestamp\":\"%s\"}",
 					time.Now().Format(time.RFC3339))
@@ -210,10 +367,24 @@ func (wc *WebCast) StreamToClient(config StreamConfig) {
 				}
 				return
 			}
-			fmt.Fprintf(config.W, "event: message\ndata: %s\n\n", message)
+			fmt.Fprint(config.W, frame)
 			if flusher, ok := config.W.(http.Flusher); ok {
 				flusher.Flush()
 			}
+			if idleTimer != nil {
+				idleTimer.Reset(idleTimeout)
+			}
 		}
 	}
 }
+
+// writeReconnectClose sends a close event asking the client to reconnect after delay.
+// EventSource reconnects by itself once the stream ends; the retry field keeps that quick.
+func writeReconnectClose(w http.ResponseWriter, reason string, delay time.Duration) {
+	closeMsg := fmt.Sprintf("{\"type\":\"close\",\"reason\":\"%s\",\"reconnect\":true,\"timestamp\":\"%s\"}",
+		reason, time.Now().Format(time.RFC3339))
+	fmt.Fprintf(w, "retry: %d\nevent: close\ndata: %s\n\n", delay.Milliseconds(), closeMsg)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -10,4 +10,6 @@ type SSEStats struct {
 	ConnectionsRejected   int64     `json:"connectionsRejected"`
 	LastConnectionTime    time.Time `json:"lastConnectionTime"`
 	LastDisconnectionTime time.Time `json:"lastDisconnectionTime"`
+	BatchesSent           int64     `json:"batchesSent"`
+	EventsCoalesced       int64     `json:"eventsCoalesced"`
 }
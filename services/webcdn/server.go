@@ -3,11 +3,9 @@ package webcdn
 import (
 	"fmt"
 	"net/http"
-	"path/filepath"
 	"time"
 
 	"github.com/go-xlite/wbx/comm"
-	"github.com/go-xlite/wbx/comm/mime"
 )
 
 type AssetRequest struct {
@@ -23,6 +21,11 @@ type WebCdn struct {
 	CacheMaxAge   time.Duration
 	EnableBrowser bool // Allow browser caching
 	EnableETags   bool
+	// ContentTypeFor optionally decides the Content-Type of a served path (detected when nil or "")
+	ContentTypeFor func(path string) string
+	// PrivateCache keeps assets out of shared caches (browser caching only),
+	// for assets whose access depends on the request
+	PrivateCache bool
 }
 
 // NewWebCdn creates a new WebCdn instance with proper routing capabilities
@@ -66,6 +69,20 @@ func (wt *WebCdn) ServeFile(urlPath string, fsProvider comm.IFsAdapter) {
 			return
 		}
 
+		// Stat first so conditional requests are answered without reading the file
+		var modTime time.Time
+		var etag string
+		if info, err := fsProvider.Stat(relativePath); err == nil {
+			modTime = info.ModTime
+			if wt.EnableETags {
+				etag = comm.StatETag(info)
+			}
+		}
+		wt.applyCacheHeaders(w)
+		if comm.ServeNotModified(w, r, etag, modTime) {
+			return
+		}
+
 		// Read file from filesystem provider
 		data, err := fsProvider.ReadFile(relativePath)
 		if err != nil {
@@ -73,11 +90,12 @@ func (wt *WebCdn) ServeFile(urlPath string, fsProvider comm.IFsAdapter) {
 			return
 		}
 
-		// Apply caching and MIME type
-		wt.applyCacheHeaders(w)
-		ext := filepath.Ext(relativePath)
-		w.Header().Set("Content-Type", mime.GetMimeType(ext))
-		w.Write(data)
+		// Let ServeBytes handle MIME type, validators and ranges
+		opts := &comm.ServeOptions{ETag: etag, NoETag: !wt.EnableETags}
+		if wt.ContentTypeFor != nil {
+			opts.ContentType = wt.ContentTypeFor(relativePath)
+		}
+		comm.ServeBytes(w, r, relativePath, modTime, data, opts)
 	})
 }
 
@@ -85,8 +103,10 @@ func (wt *WebCdn) ServeFile(urlPath string, fsProvider comm.IFsAdapter) {
 func (wt *WebCdn) ServeBytes(urlPath string, data []byte, mimeType string) {
 	wt.GetRoutes().HandlePathFn(urlPath, func(w http.ResponseWriter, r *http.Request) {
 		wt.applyCacheHeaders(w)
-		w.Header().Set("Content-Type", mimeType)
-		w.Write(data)
+		comm.ServeBytes(w, r, urlPath, time.Time{}, data, &comm.ServeOptions{
+			ContentType: mimeType,
+			NoETag:      !wt.EnableETags,
+		})
 	})
 }
 
@@ -105,7 +125,11 @@ func (wt *WebCdn) HandlePrefix(path string, handlerFunc func(assetReq *AssetRequ
 // applyCacheHeaders applies appropriate caching headers
 func (wt *WebCdn) applyCacheHeaders(w http.ResponseWriter) {
 	if wt.EnableBrowser {
-		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(wt.CacheMaxAge.Seconds())))
+		scope := "public"
+		if wt.PrivateCache {
+			scope = "private"
+		}
+		w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, int(wt.CacheMaxAge.Seconds())))
 		w.Header().Set("Expires", time.Now().Add(wt.CacheMaxAge).Format(http.TimeFormat))
 	} else {
 		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
@@ -0,0 +1,163 @@
+package webproxy
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"net/url"
+)
+
+// VariantStats tracks traffic routed to one weighted variant
+type VariantStats struct {
+	Weight         int   `json:"weight"`
+	Requests       int64 `json:"requests"`
+	FailedRequests int64 `json:"failedRequests"`
+	BytesProxied   int64 `json:"bytesProxied"`
+}
+
+// proxyVariant is a named target receiving a weighted share of traffic
+type proxyVariant struct {
+	name   string
+	target *url.URL
+	weight int
+	stats  VariantStats
+}
+
+// AddVariant adds a weighted target (e.g. "stable" 95, "canary" 5). Once any
+// variant is configured, requests are split by weight instead of load balanced
+// across AddTarget targets.
+func (wp *WebProxy) AddVariant(name string, targetURL string, weight int) error {
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		return fmt.Errorf("invalid target URL: %w", err)
+	}
+	if weight < 0 {
+		return fmt.Errorf("invalid weight %d for variant %s", weight, name)
+	}
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	for _, v := range wp.variants {
+		if v.name == name {
+			return fmt.Errorf("variant %s already exists", name)
+		}
+	}
+	wp.variants = append(wp.variants, &proxyVariant{
+		name:   name,
+		target: target,
+		weight: weight,
+		stats:  VariantStats{Weight: weight},
+	})
+	return nil
+}
+
+// SetVariantWeight changes the share of an existing variant (e.g. to ramp a canary up)
+func (wp *WebProxy) SetVariantWeight(name string, weight int) *WebProxy {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	for _, v := range wp.variants {
+		if v.name == name {
+			v.weight = weight
+		}
+	}
+	return wp
+}
+
+// SetVariantHeader sets the request header that forces a variant by name (default "X-Proxy-Variant")
+func (wp *WebProxy) SetVariantHeader(header string) *WebProxy {
+	wp.VariantHeader = header
+	return wp
+}
+
+// SetStickyVariants makes variant selection deterministic per key (e.g. a session
+// ID), so a user keeps seeing the same variant while weights are unchanged
+func (wp *WebProxy) SetStickyVariants(keyFunc func(r *http.Request) string) *WebProxy {
+	wp.StickyKey = keyFunc
+	return wp
+}
+
+// SetStickyByCookie keys variant stickiness on the value of the named cookie
+func (wp *WebProxy) SetStickyByCookie(cookieName string) *WebProxy {
+	return wp.SetStickyVariants(func(r *http.Request) string {
+		if cookie, err := r.Cookie(cookieName); err == nil {
+			return cookie.Value
+		}
+		return ""
+	})
+}
+
+// GetVariantStats returns per-variant statistics
+func (wp *WebProxy) GetVariantStats() map[string]VariantStats {
+	wp.mu.RLock()
+	defer wp.mu.RUnlock()
+	stats := make(map[string]VariantStats, len(wp.variants))
+	for _, v := range wp.variants {
+		s := v.stats
+		s.Weight = v.weight
+		stats[v.name] = s
+	}
+	return stats
+}
+
+// pickVariant chooses the variant for r: header override, then sticky key, then random by weight
+func (wp *WebProxy) pickVariant(r *http.Request) *proxyVariant {
+	wp.mu.RLock()
+	defer wp.mu.RUnlock()
+
+	if len(wp.variants) == 0 {
+		return nil
+	}
+
+	header := wp.VariantHeader
+	if header == "" {
+		header = "X-Proxy-Variant"
+	}
+	if forced := r.Header.Get(header); forced != "" {
+		for _, v := range wp.variants {
+			if v.name == forced {
+				return v
+			}
+		}
+	}
+
+	total := 0
+	for _, v := range wp.variants {
+		total += v.weight
+	}
+	if total == 0 {
+		return wp.variants[0]
+	}
+
+	var n int
+	key := ""
+	if wp.StickyKey != nil {
+		key = wp.StickyKey(r)
+	}
+	if key != "" {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		n = int(h.Sum32() % uint32(total))
+	} else {
+		n = rand.Intn(total)
+	}
+
+	for _, v := range wp.variants {
+		if n < v.weight {
+			return v
+		}
+		n -= v.weight
+	}
+	return wp.variants[len(wp.variants)-1]
+}
+
+// recordVariant updates the stats of v after a proxied request
+func (wp *WebProxy) recordVariant(v *proxyVariant, status int, bytes int64) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	v.stats.Requests++
+	v.stats.BytesProxied += bytes
+	if status >= http.StatusInternalServerError {
+		v.stats.FailedRequests++
+	}
+}
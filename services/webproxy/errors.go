@@ -0,0 +1,144 @@
+package webproxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	hl1 "github.com/go-xlite/wbx/utils"
+)
+
+// Error codes reported in proxy error pages
+const (
+	ErrCodeUnavailable     = "upstream_unavailable" // Connection refused / reset, no targets
+	ErrCodeTimeout         = "upstream_timeout"
+	ErrCodeBadGateway      = "bad_gateway"
+	ErrCodeRequestTooLarge = "request_too_large"
+	ErrCodeBlocked         = "blocked"
+)
+
+// ProxyError is the data passed to error templates. It never carries the
+// underlying error text, which would leak internal addresses to clients.
+type ProxyError struct {
+	Status  int    `json:"status"`
+	Code    string `json:"error"`
+	Title   string `json:"-"`
+	Message string `json:"message"`
+	Path    string `json:"-"`
+}
+
+// gzipErrorPagesOver is the body size above which error pages are gzipped
+const gzipErrorPagesOver = 512
+
+// DefaultErrorTemplate renders HTML proxy error pages, the shared error page
+// of the helpers package
+var DefaultErrorTemplate = hl1.ErrorPageTemplate
+
+// SetErrorTemplate sets the HTML template for proxy error pages (executed with a ProxyError)
+func (wp *WebProxy) SetErrorTemplate(tmpl *template.Template) *WebProxy {
+	wp.ErrorTemplate = tmpl
+	return wp
+}
+
+// SetErrorPage parses page as the HTML error template; it may use the
+// ProxyError fields, e.g. {{.Status}} and {{.Message}}
+func (wp *WebProxy) SetErrorPage(page []byte) error {
+	tmpl, err := template.New("proxy-error").Parse(string(page))
+	if err != nil {
+		return err
+	}
+	wp.ErrorTemplate = tmpl
+	return nil
+}
+
+// NewProxyError describes a proxy failure for clients
+func NewProxyError(status int, code string) ProxyError {
+	pe := ProxyError{Status: status, Code: code, Title: http.StatusText(status)}
+	switch code {
+	case ErrCodeUnavailable:
+		pe.Message = "The service is temporarily unavailable. Please try again shortly."
+	case ErrCodeTimeout:
+		pe.Message = "The service took too long to respond. Please try again."
+	case ErrCodeRequestTooLarge:
+		pe.Message = "The request is larger than this service accepts."
+	case ErrCodeBlocked:
+		pe.Message = "Access to this resource is not allowed."
+	default:
+		pe.Message = "The service returned an invalid response."
+	}
+	return pe
+}
+
+// ClassifyError maps a transport error to a status and error code:
+// refused/reset connections give 503, timeouts 504, anything else 502
+func ClassifyError(err error) (int, string) {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return http.StatusGatewayTimeout, ErrCodeTimeout
+	case errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETUNREACH):
+		return http.StatusServiceUnavailable, ErrCodeUnavailable
+	}
+	return http.StatusBadGateway, ErrCodeBadGateway
+}
+
+// writeError renders pe as JSON for API clients and as HTML otherwise
+func (wp *WebProxy) writeError(w http.ResponseWriter, r *http.Request, pe ProxyError) {
+	pe.Path = r.URL.Path
+
+	var body bytes.Buffer
+	contentType := "text/html; charset=utf-8"
+	if wantsJSON(r) {
+		contentType = "application/json"
+		json.NewEncoder(&body).Encode(pe)
+	} else {
+		tmpl := wp.ErrorTemplate
+		if tmpl == nil {
+			tmpl = DefaultErrorTemplate
+		}
+		if tmpl.Execute(&body, pe) != nil {
+			body.Reset()
+			template.HTMLEscape(&body, []byte(pe.Message))
+		}
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", contentType)
+	h.Set("Cache-Control", "no-store")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Del("Content-Length")
+	h.Add("Vary", "Accept, Accept-Encoding")
+
+	data := body.Bytes()
+	if len(data) > gzipErrorPagesOver && strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		gz.Write(data)
+		if gz.Close() == nil {
+			h.Set("Content-Encoding", "gzip")
+			data = compressed.Bytes()
+		}
+	}
+	w.WriteHeader(pe.Status)
+	w.Write(data)
+}
+
+// wantsJSON reports whether the client prefers a JSON error body
+func wantsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "text/html") {
+		return false
+	}
+	return strings.Contains(accept, "json") || r.Header.Get("X-Requested-With") == "XMLHttpRequest"
+}
@@ -0,0 +1,139 @@
+package webproxy
+
+import (
+	"net/http"
+	"strings"
+)
+
+// DefaultStripHeaders reveal backend software or internals
+var DefaultStripHeaders = []string{
+	"Server",
+	"X-Powered-By",
+	"X-AspNet-Version",
+	"X-AspNetMvc-Version",
+	"X-Runtime",
+	"X-Backend-Server",
+	"X-Generator",
+}
+
+// DefaultSecurityHeaders are added to responses that do not set them
+var DefaultSecurityHeaders = map[string]string{
+	"X-Content-Type-Options": "nosniff",
+	"X-Frame-Options":        "SAMEORIGIN",
+	"Referrer-Policy":        "strict-origin-when-cross-origin",
+}
+
+// DefaultHSTS is the Strict-Transport-Security value of DefaultHeaderPolicy
+const DefaultHSTS = "max-age=31536000; includeSubDomains"
+
+// HeaderPolicy rewrites upstream response headers before they reach the
+// client, giving legacy backends uniform edge hardening. Strip runs first,
+// then Set (override), then Defaults (only where the upstream set nothing).
+type HeaderPolicy struct {
+	Strip         []string
+	StripPrefixes []string // e.g. "X-Internal-"
+	Set           map[string]string
+	Defaults      map[string]string
+	HSTS          string // Strict-Transport-Security for requests that arrived over HTTPS ("" = leave as is)
+}
+
+// NewHeaderPolicy creates an empty policy
+func NewHeaderPolicy() *HeaderPolicy {
+	return &HeaderPolicy{
+		Set:      make(map[string]string),
+		Defaults: make(map[string]string),
+	}
+}
+
+// DefaultHeaderPolicy strips DefaultStripHeaders and fills in
+// DefaultSecurityHeaders and HSTS
+func DefaultHeaderPolicy() *HeaderPolicy {
+	hp := NewHeaderPolicy().StripHeader(DefaultStripHeaders...)
+	for key, value := range DefaultSecurityHeaders {
+		hp.DefaultHeader(key, value)
+	}
+	hp.HSTS = DefaultHSTS
+	return hp
+}
+
+// StripHeader removes headers from upstream responses
+func (hp *HeaderPolicy) StripHeader(keys ...string) *HeaderPolicy {
+	hp.Strip = append(hp.Strip, keys...)
+	return hp
+}
+
+// StripPrefix removes every header whose name starts with prefix
+func (hp *HeaderPolicy) StripPrefix(prefixes ...string) *HeaderPolicy {
+	for _, prefix := range prefixes {
+		hp.StripPrefixes = append(hp.StripPrefixes, http.CanonicalHeaderKey(prefix))
+	}
+	return hp
+}
+
+// SetHeader overrides a header on every response
+func (hp *HeaderPolicy) SetHeader(key, value string) *HeaderPolicy {
+	if hp.Set == nil {
+		hp.Set = make(map[string]string)
+	}
+	hp.Set[key] = value
+	return hp
+}
+
+// DefaultHeader sets a header on responses that do not carry it
+func (hp *HeaderPolicy) DefaultHeader(key, value string) *HeaderPolicy {
+	if hp.Defaults == nil {
+		hp.Defaults = make(map[string]string)
+	}
+	hp.Defaults[key] = value
+	return hp
+}
+
+// SetHSTS sets the Strict-Transport-Security value for HTTPS requests ("" disables)
+func (hp *HeaderPolicy) SetHSTS(value string) *HeaderPolicy {
+	hp.HSTS = value
+	return hp
+}
+
+// SetHeaderPolicy applies policy to every upstream response (nil disables it)
+func (wp *WebProxy) SetHeaderPolicy(policy *HeaderPolicy) *WebProxy {
+	wp.HeaderPolicy = policy
+	return wp
+}
+
+// Apply rewrites the headers of an upstream response; https tells whether
+// the client connection was secure
+func (hp *HeaderPolicy) Apply(h http.Header, https bool) {
+	for _, key := range hp.Strip {
+		h.Del(key)
+	}
+	if len(hp.StripPrefixes) > 0 {
+		for key := range h {
+			for _, prefix := range hp.StripPrefixes {
+				if strings.HasPrefix(key, prefix) {
+					delete(h, key)
+					break
+				}
+			}
+		}
+	}
+	for key, value := range hp.Set {
+		h.Set(key, value)
+	}
+	for key, value := range hp.Defaults {
+		if h.Get(key) == "" {
+			h.Set(key, value)
+		}
+	}
+	if https && hp.HSTS != "" && h.Get("Strict-Transport-Security") == "" {
+		h.Set("Strict-Transport-Security", hp.HSTS)
+	}
+}
+
+// applyHeaderPolicy runs the proxy's policy on an upstream response
+func (wp *WebProxy) applyHeaderPolicy(resp *http.Response) {
+	if wp.HeaderPolicy == nil {
+		return
+	}
+	https := resp.Request != nil && resp.Request.Header.Get("X-Forwarded-Proto") == "https"
+	wp.HeaderPolicy.Apply(resp.Header, https)
+}
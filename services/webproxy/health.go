@@ -0,0 +1,57 @@
+package webproxy
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/go-xlite/wbx/comm/events"
+)
+
+// targetHealth remembers the upstream hosts whose last request failed, so
+// health events are published on transitions only
+type targetHealth struct {
+	down map[string]bool
+	mu   sync.Mutex
+}
+
+// SetEvents publishes TargetUnhealthy when an upstream cannot be reached and
+// TargetHealthy once it answers again to p (e.g. WebLite.Events())
+func (wp *WebProxy) SetEvents(p events.Publisher) *WebProxy {
+	wp.Events = p
+	return wp
+}
+
+// markUnhealthy records a failed round trip to host
+func (wp *WebProxy) markUnhealthy(host string, err error) {
+	// The client going away says nothing about the upstream
+	if wp.Events == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	wp.health.mu.Lock()
+	if wp.health.down == nil {
+		wp.health.down = make(map[string]bool)
+	}
+	changed := !wp.health.down[host]
+	wp.health.down[host] = true
+	wp.health.mu.Unlock()
+
+	if changed {
+		wp.Events.Publish(events.TargetUnhealthy, "webproxy", events.TargetData{Target: host, Err: err})
+	}
+}
+
+// markHealthy records a response from host
+func (wp *WebProxy) markHealthy(host string) {
+	if wp.Events == nil {
+		return
+	}
+	wp.health.mu.Lock()
+	changed := wp.health.down[host]
+	delete(wp.health.down, host)
+	wp.health.mu.Unlock()
+
+	if changed {
+		wp.Events.Publish(events.TargetHealthy, "webproxy", events.TargetData{Target: host})
+	}
+}
@@ -0,0 +1,122 @@
+package webproxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrResponseTooLarge is returned when an upstream response exceeds MaxResponseBody
+var ErrResponseTooLarge = errors.New("webproxy: upstream response too large")
+
+// SetMaxRequestBody limits request bodies forwarded upstream; larger uploads
+// are answered with 413 (0 = unlimited)
+func (wp *WebProxy) SetMaxRequestBody(limit int64) *WebProxy {
+	wp.MaxRequestBody = limit
+	return wp
+}
+
+// SetMaxResponseBody limits response bodies relayed to the client; larger
+// responses are answered with 502 (0 = unlimited)
+func (wp *WebProxy) SetMaxResponseBody(limit int64) *WebProxy {
+	wp.MaxResponseBody = limit
+	return wp
+}
+
+// SetFlushInterval sets how often streamed responses are flushed to the
+// client (negative = after every write, e.g. for event streams)
+func (wp *WebProxy) SetFlushInterval(interval time.Duration) *WebProxy {
+	wp.FlushInterval = interval
+	return wp
+}
+
+// rejectOversizeRequest answers 413 for declared bodies over MaxRequestBody and
+// caps bodies of unknown length, which keep streaming until the limit trips
+func (wp *WebProxy) rejectOversizeRequest(w http.ResponseWriter, r *http.Request) bool {
+	if wp.MaxRequestBody <= 0 || r.Body == nil || r.Body == http.NoBody {
+		return false
+	}
+	if r.ContentLength > wp.MaxRequestBody {
+		wp.countOversize(true)
+		// The body is not read, so the connection cannot be reused
+		w.Header().Set("Connection", "close")
+		wp.writeError(w, r, NewProxyError(http.StatusRequestEntityTooLarge, ErrCodeRequestTooLarge))
+		return true
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, wp.MaxRequestBody)
+	return false
+}
+
+// limitResponse rejects responses declaring more than MaxResponseBody and caps
+// the rest. A body of unknown length that crosses the limit mid-stream aborts
+// the connection, since the status line has already been sent by then.
+func (wp *WebProxy) limitResponse(resp *http.Response) error {
+	if wp.MaxResponseBody <= 0 {
+		return nil
+	}
+	if resp.ContentLength > wp.MaxResponseBody {
+		return fmt.Errorf("%w: %d bytes", ErrResponseTooLarge, resp.ContentLength)
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: wp.MaxResponseBody, wp: wp}
+	return nil
+}
+
+// handleLimitError answers errors caused by the body limits: 413 for uploads,
+// 502 for responses. It reports whether err was one of them.
+func (wp *WebProxy) handleLimitError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		wp.countOversize(true)
+		w.Header().Set("Connection", "close")
+		wp.writeError(w, r, NewProxyError(http.StatusRequestEntityTooLarge, ErrCodeRequestTooLarge))
+		return true
+	case errors.Is(err, ErrResponseTooLarge):
+		wp.countOversize(false)
+		wp.writeError(w, r, NewProxyError(http.StatusBadGateway, ErrCodeBadGateway))
+		return true
+	}
+	return false
+}
+
+func (wp *WebProxy) countOversize(request bool) {
+	wp.statsMu.Lock()
+	if request {
+		wp.stats.OversizeRequests++
+	} else {
+		wp.stats.OversizeResponses++
+	}
+	wp.statsMu.Unlock()
+}
+
+// limitedBody fails reads once more than the allowed bytes were streamed
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	wp        *WebProxy
+	tripped   bool
+}
+
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	if lb.remaining <= 0 {
+		// Probe for more data: a body ending exactly at the limit is fine
+		var probe [1]byte
+		n, err := lb.ReadCloser.Read(probe[:])
+		if n == 0 {
+			return 0, err
+		}
+		if !lb.tripped {
+			lb.tripped = true
+			lb.wp.countOversize(false)
+		}
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > lb.remaining {
+		p = p[:lb.remaining]
+	}
+	n, err := lb.ReadCloser.Read(p)
+	lb.remaining -= int64(n)
+	return n, err
+}
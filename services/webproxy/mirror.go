@@ -0,0 +1,189 @@
+package webproxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MirrorStats tracks shadow traffic sent by a Mirror
+type MirrorStats struct {
+	Mirrored int64 `json:"mirrored"` // Requests sent to the shadow target
+	Skipped  int64 `json:"skipped"`  // Sampled requests dropped (body too large or too many in flight)
+	Failed   int64 `json:"failed"`   // Shadow requests that errored or returned 5xx
+}
+
+// Mirror asynchronously copies a percentage of requests to the target of a
+// WebProxy and discards the responses, so a new backend can be exercised with
+// production-shaped traffic. Users are always served by the wrapped handler.
+type Mirror struct {
+	Proxy       *WebProxy
+	Percent     float64  // Share of matching requests to mirror (0-100)
+	Prefixes    []string // Path prefixes to mirror (all paths when empty)
+	MaxBodySize int64    // Requests with larger bodies are not mirrored (default 1MB)
+	MaxInFlight int      // Concurrent shadow requests (default 100)
+	Timeout     time.Duration
+
+	inFlight  chan struct{}
+	transport http.RoundTripper // Shared by all shadow requests
+	setup     sync.Once
+	stats     MirrorStats
+	statsMu   sync.RWMutex
+}
+
+// NewMirror creates a Mirror sending percent% of requests under prefixes to proxy
+func NewMirror(proxy *WebProxy, percent float64, prefixes ...string) *Mirror {
+	return &Mirror{
+		Proxy:       proxy,
+		Percent:     percent,
+		Prefixes:    prefixes,
+		MaxBodySize: 1 << 20,
+		MaxInFlight: 100,
+		Timeout:     proxy.Timeout,
+	}
+}
+
+// Middleware wraps next, mirroring sampled requests in the background. Every
+// handler it returns shares the in-flight limit and the shadow transport.
+func (m *Mirror) Middleware(next http.Handler) http.Handler {
+	m.setup.Do(func() {
+		if m.MaxInFlight <= 0 {
+			m.MaxInFlight = 100
+		}
+		m.inFlight = make(chan struct{}, m.MaxInFlight)
+		m.transport = m.Proxy.newTransport()
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.shouldMirror(r) {
+			m.mirror(r)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetStats returns current mirror statistics
+func (m *Mirror) GetStats() MirrorStats {
+	m.statsMu.RLock()
+	defer m.statsMu.RUnlock()
+	return m.stats
+}
+
+func (m *Mirror) shouldMirror(r *http.Request) bool {
+	if m.Percent <= 0 || r.Header.Get("Upgrade") != "" {
+		return false
+	}
+	if len(m.Prefixes) > 0 {
+		matched := false
+		for _, prefix := range m.Prefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return m.Percent >= 100 || rand.Float64()*100 < m.Percent
+}
+
+// mirror clones r (buffering its body so the original stays readable) and
+// sends the clone to the proxy target in a goroutine
+func (m *Mirror) mirror(r *http.Request) {
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		buf, err := io.ReadAll(io.LimitReader(r.Body, m.MaxBodySize+1))
+		// Restore the original body for the real handler
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+		if err != nil || int64(len(buf)) > m.MaxBodySize {
+			m.count(func(s *MirrorStats) { s.Skipped++ })
+			return
+		}
+		body = buf
+	}
+
+	select {
+	case m.inFlight <- struct{}{}:
+	default:
+		m.count(func(s *MirrorStats) { s.Skipped++ })
+		return
+	}
+
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	shadow := r.Clone(ctx)
+	shadow.Body = io.NopCloser(bytes.NewReader(body))
+	shadow.ContentLength = int64(len(body))
+	shadow.Header.Set("X-Mirrored-Request", "1")
+
+	go func() {
+		defer func() {
+			cancel()
+			<-m.inFlight
+		}()
+
+		target := m.Proxy.getNextTarget()
+		if target == nil {
+			m.count(func(s *MirrorStats) { s.Failed++ })
+			return
+		}
+		// Without the proxy's ModifyResponse and ErrorHandler, shadow traffic
+		// leaves target health, limits and response hooks alone
+		rw := &discardResponse{header: make(http.Header)}
+		proxy := &httputil.ReverseProxy{
+			Director:  m.Proxy.director(target),
+			Transport: m.transport,
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+		}
+		proxy.ServeHTTP(rw, shadow)
+
+		m.count(func(s *MirrorStats) {
+			s.Mirrored++
+			if rw.status >= http.StatusInternalServerError {
+				s.Failed++
+			}
+		})
+	}()
+}
+
+func (m *Mirror) count(update func(s *MirrorStats)) {
+	m.statsMu.Lock()
+	update(&m.stats)
+	m.statsMu.Unlock()
+}
+
+// discardResponse is a ResponseWriter that only records the status code
+type discardResponse struct {
+	header http.Header
+	status int
+}
+
+func (d *discardResponse) Header() http.Header { return d.header }
+
+func (d *discardResponse) Write(b []byte) (int, error) {
+	if d.status == 0 {
+		d.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (d *discardResponse) WriteHeader(statusCode int) {
+	if d.status == 0 {
+		d.status = statusCode
+	}
+}
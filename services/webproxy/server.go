@@ -2,6 +2,7 @@ package webproxy
 
 import (
 	"fmt"
+	"html/template"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/go-xlite/wbx/comm"
+	"github.com/go-xlite/wbx/comm/events"
 )
 
 // ProxyStats tracks statistics for the proxy server
@@ -19,6 +21,9 @@ type ProxyStats struct {
 	FailedRequests     int64     `json:"failedRequests"`
 	BytesProxied       int64     `json:"bytesProxied"`
 	LastRequestTime    time.Time `json:"lastRequestTime"`
+	BlockedRequests    int64     `json:"blockedRequests"`
+	OversizeRequests   int64     `json:"oversizeRequests"`
+	OversizeResponses  int64     `json:"oversizeResponses"`
 }
 
 // WebProxy represents a reverse proxy server
@@ -46,6 +51,34 @@ type WebProxy struct {
 	ErrorHandler    func(w http.ResponseWriter, r *http.Request, err error)
 	FollowRedirects bool
 	LoadBalanceMode string // "round-robin", "random", "first"
+
+	// Weighted variants (canary / A-B routing), see AddVariant
+	variants      []*proxyVariant
+	VariantHeader string                       // Request header forcing a variant by name
+	StickyKey     func(r *http.Request) string // Optional key for sticky variant selection
+
+	// Body limits, see limits.go (0 = unlimited). Bodies are streamed, never buffered.
+	MaxRequestBody  int64
+	MaxResponseBody int64
+	// FlushInterval is how often response data is flushed to the client while
+	// copying (0 = on buffer fill, negative = after every write)
+	FlushInterval time.Duration
+
+	// ErrorTemplate renders HTML error pages (DefaultErrorTemplate when nil), see errors.go
+	ErrorTemplate *template.Template
+
+	// Signer signs proxied requests for backend verification, see signing.go
+	Signer RequestSigner
+
+	// Safety enables SSRF protection for user-influenced targets, see ssrf.go
+	Safety *SafetyPolicy
+
+	// HeaderPolicy strips and injects upstream response headers, see header_policy.go
+	HeaderPolicy *HeaderPolicy
+
+	// Events receives target health changes, see health.go
+	Events events.Publisher
+	health targetHealth
 }
 
 // NewWebProxy creates a new WebProxy instance
@@ -171,9 +204,20 @@ func (wp *WebProxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 	wp.stats.LastRequestTime = time.Now()
 	wp.statsMu.Unlock()
 
-	target := wp.getNextTarget()
+	if wp.rejectOversizeRequest(w, r) {
+		return
+	}
+
+	variant := wp.pickVariant(r)
+	var target *url.URL
+	if variant != nil {
+		target = variant.target
+		w.Header().Set("X-Proxy-Variant", variant.name)
+	} else {
+		target = wp.getNextTarget()
+	}
 	if target == nil {
-		http.Error(w, "No proxy targets configured", http.StatusInternalServerError)
+		wp.writeError(w, r, NewProxyError(http.StatusServiceUnavailable, ErrCodeUnavailable))
 		wp.statsMu.Lock()
 		wp.stats.FailedRequests++
 		wp.statsMu.Unlock()
@@ -182,12 +226,67 @@ func (wp *WebProxy) handleProxy(w http.ResponseWriter, r *http.Request) {
 
 	// Create a reverse proxy for this request
 	proxy := wp.createReverseProxy(target)
-	proxy.ServeHTTP(w, r)
+	cw := comm.NewCaptureWriter(w)
+	proxy.ServeHTTP(cw, r)
+
+	if variant != nil {
+		wp.recordVariant(variant, cw.Status(), cw.BytesWritten())
+	}
+
+	wp.statsMu.Lock()
+	wp.stats.BytesProxied += cw.BytesWritten()
+	if cw.Status() >= http.StatusInternalServerError {
+		wp.stats.FailedRequests++
+	} else {
+		wp.stats.SuccessfulRequests++
+	}
+	wp.statsMu.Unlock()
 }
 
 // createReverseProxy creates a reverse proxy for the given target
 func (wp *WebProxy) createReverseProxy(target *url.URL) *httputil.ReverseProxy {
-	director := func(req *http.Request) {
+	proxy := &httputil.ReverseProxy{
+		Director:      wp.director(target),
+		Transport:     wp.newTransport(),
+		FlushInterval: wp.FlushInterval,
+	}
+
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		wp.markHealthy(target.Host)
+		if err := wp.limitResponse(resp); err != nil {
+			return err
+		}
+		wp.applyHeaderPolicy(resp)
+		// Set custom response modifier if provided
+		if wp.ResponseHandler != nil {
+			return wp.ResponseHandler(resp)
+		}
+		return nil
+	}
+
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		if wp.handleLimitError(w, r, err) || wp.handleUnsafeTarget(w, r, err) {
+			return
+		}
+		wp.markUnhealthy(target.Host, err)
+		// Set custom error handler if provided
+		if wp.ErrorHandler != nil {
+			wp.ErrorHandler(w, r, err)
+			return
+		}
+		// FailedRequests is counted by handleProxy from the 5xx status. The error
+		// itself goes to the reporter only: it names internal addresses.
+		comm.ReportError(r.Context(), fmt.Errorf("proxy %s: %w", target.Host, err), nil, r)
+		wp.writeError(w, r, NewProxyError(ClassifyError(err)))
+	}
+
+	return proxy
+}
+
+// director points requests at target, applying the path, header and
+// forwarding settings
+func (wp *WebProxy) director(target *url.URL) func(req *http.Request) {
+	return func(req *http.Request) {
 		// Preserve original URL for reference
 		originalHost := req.Host
 
@@ -236,42 +335,37 @@ func (wp *WebProxy) createReverseProxy(target *url.URL) *httputil.ReverseProxy {
 		}
 		req.Header.Set("X-Forwarded-Proto", getScheme(req))
 		req.Header.Set("X-Forwarded-Host", originalHost)
-		req.Header.Set("X-Real-IP", req.RemoteAddr)
+		req.Header.Set("X-Real-IP", comm.ClientIP(req))
 
 		// Call custom request modifier if set
 		if wp.RequestModifier != nil {
 			wp.RequestModifier(req)
 		}
 	}
+}
 
-	proxy := &httputil.ReverseProxy{
-		Director: director,
-		Transport: &http.Transport{
-			MaxIdleConns:        100,
-			IdleConnTimeout:     90 * time.Second,
-			TLSHandshakeTimeout: 10 * time.Second,
-			DisableCompression:  false,
-		},
+// newTransport creates the transport requests to the targets are sent with,
+// signing and checking them as configured
+func (wp *WebProxy) newTransport() http.RoundTripper {
+	transport := &http.Transport{
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		DisableCompression:  false,
+		// Timeout bounds the wait for response headers; bodies may stream longer
+		ResponseHeaderTimeout: wp.Timeout,
+		// Let backends refuse large uploads before the body is sent
+		ExpectContinueTimeout: time.Second,
 	}
-
-	// Set custom response modifier if provided
-	if wp.ResponseHandler != nil {
-		proxy.ModifyResponse = wp.ResponseHandler
+	var rt http.RoundTripper = transport
+	if wp.Signer != nil {
+		rt = &signingTransport{RoundTripper: rt, signer: wp.Signer}
 	}
-
-	// Set custom error handler if provided
-	if wp.ErrorHandler != nil {
-		proxy.ErrorHandler = wp.ErrorHandler
-	} else {
-		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-			wp.statsMu.Lock()
-			wp.stats.FailedRequests++
-			wp.statsMu.Unlock()
-			http.Error(w, fmt.Sprintf("Proxy error: %v", err), http.StatusBadGateway)
-		}
+	if wp.Safety != nil {
+		transport.DialContext = wp.Safety.dialer().DialContext
+		rt = &safeTransport{RoundTripper: rt, policy: wp.Safety, followRedirects: wp.FollowRedirects}
 	}
-
-	return proxy
+	return rt
 }
 
 // GetStats returns current proxy statistics
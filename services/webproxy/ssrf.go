@@ -0,0 +1,247 @@
+package webproxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-xlite/wbx/comm/audit"
+)
+
+// ErrUnsafeTarget is returned when a proxied request would reach an address,
+// scheme or redirect chain the SafetyPolicy forbids
+var ErrUnsafeTarget = errors.New("webproxy: unsafe upstream target")
+
+// SafetyPolicy guards proxies whose targets can be influenced by request data
+// (RequestModifier rewrites, variant selection, redirects) against SSRF.
+// Addresses are checked after DNS resolution, at connect time, so names that
+// resolve to internal addresses are caught too.
+type SafetyPolicy struct {
+	AllowedSchemes []string       // Default http and https
+	BlockPrivate   bool           // Block loopback, private, link-local, CGNAT, NAT64, 6to4, multicast and unspecified addresses
+	BlockedCIDRs   []netip.Prefix // Additionally blocked ranges
+	AllowedCIDRs   []netip.Prefix // Exceptions to the blocks above (e.g. a known internal backend)
+	// MaxRedirects is how many upstream redirects the proxy follows itself for
+	// GET/HEAD requests while FollowRedirects is set, each hop checked by this
+	// policy (0 = redirects are passed to the client)
+	MaxRedirects int
+}
+
+// DefaultSafetyPolicy blocks non-public addresses, allows only http(s) and
+// passes redirects through to the client
+func DefaultSafetyPolicy() *SafetyPolicy {
+	return &SafetyPolicy{
+		AllowedSchemes: []string{"http", "https"},
+		BlockPrivate:   true,
+	}
+}
+
+// Allow exempts CIDR ranges (or single addresses) from the blocks
+func (sp *SafetyPolicy) Allow(cidrs ...string) error {
+	for _, cidr := range cidrs {
+		prefix, err := parsePrefix(cidr)
+		if err != nil {
+			return err
+		}
+		sp.AllowedCIDRs = append(sp.AllowedCIDRs, prefix)
+	}
+	return nil
+}
+
+// Block adds CIDR ranges (or single addresses) to block
+func (sp *SafetyPolicy) Block(cidrs ...string) error {
+	for _, cidr := range cidrs {
+		prefix, err := parsePrefix(cidr)
+		if err != nil {
+			return err
+		}
+		sp.BlockedCIDRs = append(sp.BlockedCIDRs, prefix)
+	}
+	return nil
+}
+
+// SetMaxRedirects sets how many redirects are followed server-side
+func (sp *SafetyPolicy) SetMaxRedirects(n int) *SafetyPolicy {
+	sp.MaxRedirects = n
+	return sp
+}
+
+// SetSafetyPolicy enables SSRF protection for this proxy (nil disables it)
+func (wp *WebProxy) SetSafetyPolicy(policy *SafetyPolicy) *WebProxy {
+	wp.Safety = policy
+	return wp
+}
+
+// CheckScheme reports whether scheme may be proxied to
+func (sp *SafetyPolicy) CheckScheme(scheme string) error {
+	allowed := sp.AllowedSchemes
+	if len(allowed) == 0 {
+		allowed = []string{"http", "https"}
+	}
+	if !slices.Contains(allowed, strings.ToLower(scheme)) {
+		return fmt.Errorf("%w: scheme %q", ErrUnsafeTarget, scheme)
+	}
+	return nil
+}
+
+// CheckAddr reports whether ip may be connected to
+func (sp *SafetyPolicy) CheckAddr(ip netip.Addr) error {
+	ip = ip.Unmap()
+	for _, prefix := range sp.AllowedCIDRs {
+		if prefix.Contains(ip) {
+			return nil
+		}
+	}
+	if sp.BlockPrivate && !isPublicAddr(ip) {
+		return fmt.Errorf("%w: %s is not a public address", ErrUnsafeTarget, ip)
+	}
+	for _, prefix := range sp.BlockedCIDRs {
+		if prefix.Contains(ip) {
+			return fmt.Errorf("%w: %s is blocked", ErrUnsafeTarget, ip)
+		}
+	}
+	return nil
+}
+
+// nonPublicPrefixes are globally routable by the netip predicates but shared
+// (carrier-grade NAT, RFC 6598) or able to carry any IPv4 address, private ones
+// included: NAT64 (RFC 6052, RFC 8215), 6to4 (RFC 3056) and the deprecated
+// IPv4-compatible IPv6 addresses
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("::/96"),
+}
+
+// isPublicAddr reports whether ip is a globally routable unicast address.
+// IPv4-mapped addresses are judged by their IPv4 address.
+func isPublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialControl checks the resolved address right before connecting
+func (sp *SafetyPolicy) dialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUnsafeTarget, address)
+	}
+	return sp.CheckAddr(addrPort.Addr())
+}
+
+// dialer returns a dialer enforcing the address rules
+func (sp *SafetyPolicy) dialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   sp.dialControl,
+	}
+}
+
+// handleUnsafeTarget answers 403 for requests stopped by the policy, counts
+// them as blocked and records an audit event. It reports whether err was one.
+func (wp *WebProxy) handleUnsafeTarget(w http.ResponseWriter, r *http.Request, err error) bool {
+	if !errors.Is(err, ErrUnsafeTarget) {
+		return false
+	}
+	wp.statsMu.Lock()
+	wp.stats.BlockedRequests++
+	wp.statsMu.Unlock()
+
+	audit.EmitRequest(r, audit.TypeProxyBlocked, audit.OutcomeDenied, "", map[string]any{"reason": err.Error()})
+	wp.writeError(w, r, NewProxyError(http.StatusForbidden, ErrCodeBlocked))
+	return true
+}
+
+// safeTransport validates schemes and follows redirects within the policy
+type safeTransport struct {
+	http.RoundTripper
+	policy          *SafetyPolicy
+	followRedirects bool
+}
+
+func (st *safeTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if err := st.policy.CheckScheme(r.URL.Scheme); err != nil {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		return nil, err
+	}
+	resp, err := st.RoundTripper.RoundTrip(r)
+	if err != nil || !st.followRedirects || st.policy.MaxRedirects <= 0 {
+		return resp, err
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return resp, nil
+	}
+
+	for hops := 0; isRedirect(resp.StatusCode); hops++ {
+		location, err := resp.Location()
+		if err != nil {
+			return resp, nil // Nothing to follow, let the client see it
+		}
+		if hops >= st.policy.MaxRedirects {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%w: more than %d redirects", ErrUnsafeTarget, st.policy.MaxRedirects)
+		}
+		if err := st.policy.CheckScheme(location.Scheme); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+
+		next := r.Clone(r.Context())
+		next.URL = location
+		next.Host = location.Host
+		next.Body = nil
+		next.ContentLength = 0
+		if location.Host != r.URL.Host {
+			// Credentials are for the original upstream only
+			next.Header.Del("Authorization")
+			next.Header.Del("Cookie")
+		}
+		if resp, err = st.RoundTripper.RoundTrip(next); err != nil {
+			return nil, err
+		}
+		r = next
+	}
+	return resp, nil
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+func parsePrefix(cidr string) (netip.Prefix, error) {
+	if !strings.Contains(cidr, "/") {
+		addr, err := netip.ParseAddr(cidr)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	return netip.ParsePrefix(cidr)
+}
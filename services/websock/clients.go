@@ -0,0 +1,101 @@
+package websock
+
+import (
+	"maps"
+	"time"
+)
+
+// WsClientInfo is a snapshot of a connected client for admin introspection
+type WsClientInfo struct {
+	ID        string    `json:"id"`
+	SessionID string    `json:"sessionId"`
+	UserID    int64     `json:"userId"`
+	Username  string    `json:"username"`
+	LatencyMs float64   `json:"latencyMs"`
+	LastPong  time.Time `json:"lastPong"`
+}
+
+// GetClientCount returns the number of connected clients
+func (ws *WebSock) GetClientCount() int {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	return len(ws.clients)
+}
+
+// HijacksConnections reports that connections leave the HTTP server after the
+// upgrade, so a server shutting down closes and counts them through Drain
+// (weblite StopAndDrain)
+func (ws *WebSock) HijacksConnections() bool {
+	return true
+}
+
+// GetClientInfos returns a snapshot of all connected clients
+func (ws *WebSock) GetClientInfos() []WsClientInfo {
+	ws.mu.RLock()
+	clients := make([]*WsClient, 0, len(ws.clients))
+	for _, client := range ws.clients {
+		clients = append(clients, client)
+	}
+	ws.mu.RUnlock()
+
+	infos := make([]WsClientInfo, 0, len(clients))
+	for _, client := range clients {
+		infos = append(infos, WsClientInfo{
+			ID:        client.ID,
+			SessionID: client.SessionID,
+			UserID:    client.UserID,
+			Username:  client.Username,
+			LatencyMs: float64(client.Latency()) / float64(time.Millisecond),
+			LastPong:  client.LastPong(),
+		})
+	}
+	return infos
+}
+
+// Set stores a per-connection value (e.g. subscriptions, negotiated protocol
+// version) that lives as long as the connection
+func (c *WsClient) Set(key string, value any) {
+	c.valuesMu.Lock()
+	defer c.valuesMu.Unlock()
+	if c.values == nil {
+		c.values = make(map[string]any)
+	}
+	c.values[key] = value
+}
+
+// Get returns a per-connection value
+func (c *WsClient) Get(key string) (any, bool) {
+	c.valuesMu.RLock()
+	defer c.valuesMu.RUnlock()
+	value, ok := c.values[key]
+	return value, ok
+}
+
+// Delete removes a per-connection value
+func (c *WsClient) Delete(key string) {
+	c.valuesMu.Lock()
+	defer c.valuesMu.Unlock()
+	delete(c.values, key)
+}
+
+// Values returns a copy of all per-connection values
+func (c *WsClient) Values() map[string]any {
+	c.valuesMu.RLock()
+	defer c.valuesMu.RUnlock()
+	return maps.Clone(c.values)
+}
+
+// Set stores a value on the sending connection (no-op without a client)
+func (msg *WsMessage) Set(key string, value any) {
+	if msg.Client != nil {
+		msg.Client.Set(key, value)
+	}
+}
+
+// Get returns a value stored on the sending connection
+func (msg *WsMessage) Get(key string) (any, bool) {
+	if msg.Client == nil {
+		return nil, false
+	}
+	return msg.Client.Get(key)
+}
@@ -0,0 +1,60 @@
+package websock
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-xlite/wbx/comm"
+	"github.com/gorilla/websocket"
+)
+
+// DrainReason prefixes the close reason sent to connections ended by Drain;
+// the full reason is "draining retry=<ms>" and closes use code 1012 (service restart)
+const DrainReason = comm.DrainReason
+
+// Drain prepares the server for shutdown during a rolling deploy: new
+// connections are closed right after the upgrade with a reconnect hint, and
+// open connections are closed one by one spread evenly over window with code
+// 1012 and a "draining retry=<ms>" reason the client scripts use as reconnect
+// delay (comm.DrainRetryBase plus jitter). Drain returns once every connection
+// present at the start was sent its close, with how many that were; the
+// connections finish in the background, see GetClientCount. A window of 0
+// closes all connections at once.
+func (ws *WebSock) Drain(window time.Duration) int {
+	ws.draining.Store(true)
+
+	ws.mu.RLock()
+	clients := make([]*WsClient, 0, len(ws.clients))
+	for _, client := range ws.clients {
+		clients = append(clients, client)
+	}
+	ws.mu.RUnlock()
+
+	if len(clients) == 0 {
+		return 0
+	}
+	step := window / time.Duration(len(clients))
+
+	for i, client := range clients {
+		if i > 0 && step > 0 {
+			time.Sleep(step)
+		}
+		client.CloseWithReason(websocket.CloseServiceRestart, drainCloseReason(comm.DrainRetryHint()))
+	}
+	return len(clients)
+}
+
+// IsDraining reports whether Drain was called (and Resume was not)
+func (ws *WebSock) IsDraining() bool {
+	return ws.draining.Load()
+}
+
+// Resume accepts new connections again after Drain, e.g. when a deploy is aborted
+func (ws *WebSock) Resume() {
+	ws.draining.Store(false)
+}
+
+// drainCloseReason formats the close reason carrying the reconnect hint
+func drainCloseReason(retry time.Duration) string {
+	return fmt.Sprintf("%s retry=%d", DrainReason, retry.Milliseconds())
+}
@@ -0,0 +1,184 @@
+package websock
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-xlite/wbx/comm"
+	"github.com/gorilla/websocket"
+)
+
+// DuplicateIDPolicy decides what happens when a connid is already connected
+type DuplicateIDPolicy int
+
+const (
+	// DuplicateReplace closes the older connection of the same user, so a
+	// reconnecting tab takes over its previous connection (default)
+	DuplicateReplace DuplicateIDPolicy = iota
+	// DuplicateReject refuses the new connection while the old one is open
+	DuplicateReject
+	// DuplicateReassign accepts the new connection under a generated connid
+	DuplicateReassign
+)
+
+// Close reasons sent with code 1008 (policy violation) when IDPolicy rejects
+// a connection; the client scripts pick a new ID for the "sessionid" reasons
+const (
+	ReasonInvalidConnID    = "connid invalid"
+	ReasonInvalidSessionID = "sessionid invalid"
+	ReasonConnIDInUse      = "connid in use"
+	ReasonSessionForeign   = "sessionid belongs to another user"
+)
+
+// IDPolicy validates the connid and sessionid query parameters of new connections
+type IDPolicy struct {
+	MaxLength int // Longest accepted ID (default 128)
+	// AllowedChars lists the characters IDs may contain besides ASCII letters
+	// and digits (default "-_.:")
+	AllowedChars string
+	Duplicates   DuplicateIDPolicy
+	// AllowForeignIDs lets a user take over a connid or join a session held
+	// by another user ID; off by default so IDs cannot be hijacked
+	AllowForeignIDs bool
+	// Validate runs after the built-in checks; a non-empty result rejects the
+	// connection with that close reason
+	Validate func(r *http.Request, userID int64, connID, sessionID string) string
+}
+
+// DefaultIDPolicy returns the policy new WebSock instances start with
+func DefaultIDPolicy() *IDPolicy {
+	return &IDPolicy{MaxLength: 128, AllowedChars: "-_.:"}
+}
+
+// SetIDPolicy sets how client-supplied connection and session IDs are
+// validated (nil accepts any ID, replacing duplicates)
+func (ws *WebSock) SetIDPolicy(policy *IDPolicy) *WebSock {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.idPolicy = policy
+	return ws
+}
+
+// validID checks the length and charset of a client-supplied ID; empty IDs
+// are valid because the server generates one
+func (p *IDPolicy) validID(id string) bool {
+	if id == "" {
+		return true
+	}
+	if p.MaxLength > 0 && len(id) > p.MaxLength {
+		return false
+	}
+	for _, c := range id {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || strings.ContainsRune(p.AllowedChars, c) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// idOwner identifies who holds a connid or session: the user ID, or for
+// anonymous connections (user ID 0) the auth session they were opened with
+type idOwner struct {
+	userID     int64
+	sessionKey string
+}
+
+// owns reports whether o may take over an ID held by holder. Anonymous
+// connections share user ID 0, so they only match on their auth session; an
+// anonymous holder without one cannot be told apart from a reload of the
+// same page, so its IDs are free to take over.
+func (o idOwner) owns(holder idOwner) bool {
+	if holder.userID == 0 && holder.sessionKey == "" {
+		return true
+	}
+	if o.userID != 0 {
+		return o.userID == holder.userID
+	}
+	return holder.userID == 0 && o.sessionKey != "" && o.sessionKey == holder.sessionKey
+}
+
+// admitIDs applies the ID policy to a new connection and returns its connid
+// and session ID, or a close reason when it must be rejected. The checks, the
+// connid reservation and the session creation happen under one lock, so
+// concurrent connections cannot both claim the same IDs.
+func (ws *WebSock) admitIDs(r *http.Request, userID int64, username, connID, requested string) (string, string, string) {
+	ws.mu.RLock()
+	policy := ws.idPolicy
+	strategy := ws.sessionStrategy
+	ws.mu.RUnlock()
+
+	if policy != nil {
+		if !policy.validID(connID) {
+			return "", "", ReasonInvalidConnID
+		}
+		if !policy.validID(requested) {
+			return "", "", ReasonInvalidSessionID
+		}
+	}
+	owner := idOwner{userID: userID, sessionKey: comm.SessionKey(r)}
+
+	ws.mu.Lock()
+	if policy != nil && connID != "" {
+		if holder, taken := ws.connIDHolderLocked(connID); taken {
+			switch {
+			case !owner.owns(holder) && !policy.AllowForeignIDs:
+				ws.mu.Unlock()
+				return "", "", ReasonConnIDInUse
+			case policy.Duplicates == DuplicateReject:
+				ws.mu.Unlock()
+				return "", "", ReasonConnIDInUse
+			case policy.Duplicates == DuplicateReassign:
+				connID = GenerateConnectionID()
+			}
+		}
+	}
+	if connID == "" {
+		connID = GenerateConnectionID()
+	}
+
+	sessionID := sessionIDFor(strategy, requested)
+	session, exists := ws.sessions[sessionID]
+	// Isolated sessions are scoped to the connection, so only shared ones can be joined
+	if exists && policy != nil && !policy.AllowForeignIDs && strategy == SessionShared &&
+		!owner.owns(idOwner{userID: session.UserID, sessionKey: session.ownerKey}) {
+		ws.mu.Unlock()
+		return "", "", ReasonSessionForeign
+	}
+	if exists {
+		session.LastSeen = time.Now()
+	} else {
+		ws.sessions[sessionID] = newSession(sessionID, userID, username, owner.sessionKey)
+	}
+	ws.admitted[connID] = owner
+	ws.mu.Unlock()
+
+	if policy != nil && policy.Validate != nil {
+		if reason := policy.Validate(r, userID, connID, requested); reason != "" {
+			ws.mu.Lock()
+			delete(ws.admitted, connID)
+			if !exists {
+				delete(ws.sessions, sessionID)
+			}
+			ws.mu.Unlock()
+			return "", "", reason
+		}
+	}
+	return connID, sessionID, ""
+}
+
+// connIDHolderLocked returns the owner of a connected or admitted connid (caller holds mu)
+func (ws *WebSock) connIDHolderLocked(connID string) (idOwner, bool) {
+	if existing, ok := ws.clients[connID]; ok {
+		return idOwner{userID: existing.UserID, sessionKey: existing.sessionKey}, true
+	}
+	holder, ok := ws.admitted[connID]
+	return holder, ok
+}
+
+// rejectConnection closes an upgraded connection with 1008 and reason
+func rejectConnection(conn *websocket.Conn, reason string) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason), time.Now().Add(time.Second))
+	conn.Close()
+}
@@ -0,0 +1,245 @@
+package websock
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"sync"
+)
+
+// Reliable delivery protocol, enabled with SetReliableDelivery. Session
+// messages are delivered as
+//
+//	{"type":"msg","seq":42,"data":...}
+//
+// with seq counting up per session. A client excluded by SendToSessionExcept
+// receives {"type":"skip","seq":42} instead, so the sequence stays gap-free.
+// Clients acknowledge what they processed with {"type":"ack","seq":42} and
+// ask for a retransmission with {"type":"resume","seq":41} (or by connecting
+// with ?seq=41), receiving every retained message after that sequence.
+// When the requested messages are no longer retained, or the client asks for
+// sequences the session never sent (the server restarted or the session was
+// recreated), the server answers {"type":"resync","seq":<latest>} and the
+// client has to reload its state. Messages the client acknowledged are not
+// retransmitted.
+// Clients should drop sequences they already processed, as a retransmission
+// can overlap messages queued while reconnecting.
+const (
+	MsgSequenced = "msg"
+	MsgSkip      = "skip"
+	MsgAck       = "ack"
+	MsgResume    = "resume"
+	MsgResync    = "resync"
+)
+
+// sequencedMessage is the envelope of reliable delivery messages in both directions
+type sequencedMessage struct {
+	Type string          `json:"type"`
+	Seq  uint64          `json:"seq"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// sessionLog numbers the messages of one session and retains the latest for retransmission
+type sessionLog struct {
+	mu      sync.Mutex
+	seq     uint64
+	entries []logEntry // Ascending by seq, at most the reliable window
+}
+
+type logEntry struct {
+	seq     uint64
+	message []byte // Encoded MsgSequenced envelope
+	skip    []byte // Encoded MsgSkip envelope
+	except  string // Client ID that got skip instead of message
+}
+
+// SetReliableDelivery enables sequence numbers on session messages, retaining
+// the last window messages of each session for retransmission (0 disables)
+func (ws *WebSock) SetReliableDelivery(window int) *WebSock {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.reliableWindow = max(window, 0)
+	return ws
+}
+
+// IsReliable reports whether reliable delivery is enabled
+func (ws *WebSock) IsReliable() bool {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	return ws.reliableWindow > 0
+}
+
+// LastSeq returns the latest sequence number of a session (0 if none was sent)
+func (session *WsSession) LastSeq() uint64 {
+	session.history.mu.Lock()
+	defer session.history.mu.Unlock()
+	return session.history.seq
+}
+
+// LastAck returns the highest sequence the client acknowledged
+func (c *WsClient) LastAck() uint64 {
+	return c.lastAck.Load()
+}
+
+// sendSequenced numbers data as the next message of sessionID, retains it and
+// queues it for the session's clients. Clients with a full queue miss it and
+// recover it with a resume.
+func (ws *WebSock) sendSequenced(sessionID string, data []byte, except string) bool {
+	ws.mu.RLock()
+	session, ok := ws.sessions[sessionID]
+	window := ws.reliableWindow
+	ws.mu.RUnlock()
+	if !ok {
+		return false
+	}
+
+	payload, _ := encodePayload(data)
+	history := &session.history
+	history.mu.Lock()
+	defer history.mu.Unlock()
+
+	history.seq++
+	entry := logEntry{seq: history.seq, except: except}
+	entry.message, _ = json.Marshal(sequencedMessage{Type: MsgSequenced, Seq: history.seq, Data: payload})
+	entry.skip, _ = json.Marshal(sequencedMessage{Type: MsgSkip, Seq: history.seq})
+	history.entries = append(history.entries, entry)
+	if len(history.entries) > window {
+		history.entries = append(history.entries[:0], history.entries[len(history.entries)-window:]...)
+	}
+
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	sent := false
+	for _, client := range ws.clients {
+		if client.SessionID != sessionID {
+			continue
+		}
+		select {
+		case client.Send <- entry.forClient(client.ID):
+			ws.incrementMessagesSent()
+			sent = sent || client.ID != except
+		default:
+			// Client buffer full; the client notices the gap and resumes
+		}
+	}
+	return sent
+}
+
+// forClient returns what clientID receives for the entry
+func (e *logEntry) forClient(clientID string) []byte {
+	if e.except != "" && e.except == clientID {
+		return e.skip
+	}
+	return e.message
+}
+
+// resume queues every retained message of the client's session after seq,
+// or a resync when some of them are no longer retained
+func (ws *WebSock) resume(client *WsClient, after uint64) {
+	session, ok := ws.GetSession(client.SessionID)
+	if !ok {
+		return
+	}
+	session.history.mu.Lock()
+	defer session.history.mu.Unlock()
+	ws.replayLocked(&session.history, client, after)
+}
+
+// replayLocked queues the retained messages after seq for client; history.mu
+// must be held so no new message is sequenced in between
+func (ws *WebSock) replayLocked(history *sessionLog, client *WsClient, after uint64) {
+	if after < history.seq {
+		after = max(after, client.lastAck.Load())
+	}
+	if after == history.seq {
+		return
+	}
+
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	if ws.clients[client.ID] != client {
+		// Gone already, its Send channel may be closed
+		return
+	}
+	if after > history.seq || len(history.entries) == 0 || history.entries[0].seq > after+1 {
+		data, _ := json.Marshal(sequencedMessage{Type: MsgResync, Seq: history.seq})
+		select {
+		case client.Send <- data:
+			client.lastAck.Store(history.seq)
+		default:
+		}
+		return
+	}
+	for _, entry := range history.entries {
+		if entry.seq <= after {
+			continue
+		}
+		select {
+		case client.Send <- entry.forClient(client.ID):
+			ws.incrementMessagesSent()
+		default:
+			// Queue full; the client resumes again from what it got
+			return
+		}
+	}
+}
+
+// registerResuming adds a client that connected with ?seq= and replays what
+// it missed before any newer message can reach it
+func (ws *WebSock) registerResuming(client *WsClient) bool {
+	after, ok := client.resumeAfter()
+	if !ok {
+		return false
+	}
+	session, ok := ws.GetSession(client.SessionID)
+	if !ok {
+		return false
+	}
+	session.history.mu.Lock()
+	defer session.history.mu.Unlock()
+	ws.addClient(client)
+	ws.replayLocked(&session.history, client, after)
+	return true
+}
+
+// handleReliableMessage processes ack and resume messages and reports whether
+// message was one (protocol messages are not passed to OnMessage)
+func (ws *WebSock) handleReliableMessage(client *WsClient, message []byte) bool {
+	trimmed := bytes.TrimSpace(message)
+	if len(trimmed) == 0 || trimmed[0] != '{' || !ws.IsReliable() ||
+		(!bytes.Contains(trimmed, []byte(MsgAck)) && !bytes.Contains(trimmed, []byte(MsgResume))) {
+		return false
+	}
+	var req sequencedMessage
+	if json.Unmarshal(trimmed, &req) != nil {
+		return false
+	}
+
+	switch req.Type {
+	case MsgAck:
+		for {
+			last := client.lastAck.Load()
+			if req.Seq <= last || client.lastAck.CompareAndSwap(last, req.Seq) {
+				break
+			}
+		}
+	case MsgResume:
+		ws.resume(client, req.Seq)
+	default:
+		return false
+	}
+	return true
+}
+
+// resumeAfter returns the sequence a reconnecting client asked to resume after
+func (c *WsClient) resumeAfter() (uint64, bool) {
+	if c.resumeSeq == "" || !c.WebSock.IsReliable() {
+		return 0, false
+	}
+	after, err := strconv.ParseUint(c.resumeSeq, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	c.lastAck.Store(after)
+	return after, true
+}
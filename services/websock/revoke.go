@@ -0,0 +1,28 @@
+package websock
+
+import (
+	"github.com/go-xlite/wbx/comm"
+	"github.com/gorilla/websocket"
+)
+
+// SessionRevoked closes every connection opened under the auth session
+// sessionKey with code 1008 and reason "session-revoked"; the client scripts
+// do not reconnect after it. Register the WebSock with SessionManager.OnRevoke.
+func (ws *WebSock) SessionRevoked(sessionKey string) int {
+	if sessionKey == "" {
+		return 0
+	}
+	ws.mu.RLock()
+	var revoked []*WsClient
+	for _, client := range ws.clients {
+		if client.sessionKey == sessionKey {
+			revoked = append(revoked, client)
+		}
+	}
+	ws.mu.RUnlock()
+
+	for _, client := range revoked {
+		client.CloseWithReason(websocket.ClosePolicyViolation, comm.SessionRevokedReason)
+	}
+	return len(revoked)
+}
@@ -1,13 +1,19 @@
 package websock
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-xlite/wbx/comm"
+	"github.com/go-xlite/wbx/comm/events"
+	"github.com/go-xlite/wbx/comm/schema"
 	"github.com/gorilla/websocket"
 )
 
@@ -28,6 +34,10 @@ type WsSession struct {
 	CreatedAt time.Time
 	LastSeen  time.Time
 	mu        sync.RWMutex
+
+	history sessionLog // Sequenced messages, see SetReliableDelivery
+
+	ownerKey string // Auth session of the creator, tells anonymous owners apart, see IDPolicy
 }
 
 // WsClient represents a connected WebSocket client
@@ -39,8 +49,40 @@ type WsClient struct {
 	Conn      *websocket.Conn
 	Send      chan []byte
 	WebSock   *WebSock
+
+	latency   time.Duration // Round-trip time of the last ping/pong
+	lastPong  time.Time
+	pingSeq   uint64    // Payload of the last ping, see pingPayload
+	pingSent  time.Time // When it was sent, zero once answered
+	latencyMu sync.RWMutex
+
+	values   map[string]any // Per-connection state, see Set/Get
+	valuesMu sync.RWMutex
+
+	topics map[string]bool // Subscribed topics, guarded by WebSock.topicsMu
+
+	// RequestedSessionID is the sessionid sent by the client; it differs from
+	// SessionID under SessionIsolated
+	RequestedSessionID string
+	isolated           bool // Session is private to this connection
+
+	lastAck   atomic.Uint64 // Highest sequence acknowledged, see SetReliableDelivery
+	resumeSeq string        // ?seq= of a reconnecting client
+
+	sessionKey string // Auth session the connection was opened with, see SessionRevoked
+
+	fragmentSize int // Outbound frame payload limit, fixed at upgrade like the write buffer
 }
 
+// Default message size limits
+const (
+	DefaultMaxMessageSize = 4096 // Maximum inbound message size in bytes
+	DefaultFragmentSize   = 1024 // Maximum outbound frame payload in bytes
+)
+
+// errMessageTooLarge is returned by readMessage when a message exceeds MaxMessageSize
+var errMessageTooLarge = errors.New("websock: message too large")
+
 // WebSock represents a WebSocket server for real-time bidirectional communication
 // Similar to Webcast but for WebSocket connections
 type WebSock struct {
@@ -48,6 +90,13 @@ type WebSock struct {
 	PathBase string // Optional base path for convenience (e.g., "/ws")
 	NotFound http.HandlerFunc
 
+	// MaxMessageSize is the largest inbound message accepted (0 = unlimited).
+	// Larger messages close the connection with 1009 "message too large".
+	MaxMessageSize int64
+	// FragmentSize is the largest payload written in a single outbound frame;
+	// bigger messages are split into continuation frames
+	FragmentSize int
+
 	// WebSocket specific fields
 	clients     map[string]*WsClient
 	userClients map[int64]map[string]bool
@@ -59,6 +108,27 @@ type WebSock struct {
 	stats       WorkerStats
 	statsMu     sync.RWMutex
 	onMessage   func(msg *WsMessage)
+
+	onLatencyUpdate func(client *WsClient, latency time.Duration)
+
+	// Schemas validates outgoing JSON messages while enabled, see SetSchemas
+	Schemas *schema.Registry
+
+	// Topic subscriptions, see topics.go
+	topics         map[string]map[*WsClient]bool
+	topicsMu       sync.RWMutex
+	authorizeTopic func(client *WsClient, topic string) bool
+
+	draining atomic.Bool // See Drain
+
+	sessionStrategy SessionStrategy    // See SetSessionStrategy
+	reliableWindow  int                // Retained messages per session, see SetReliableDelivery
+	idPolicy        *IDPolicy          // Checks on client-supplied IDs, see SetIDPolicy
+	admitted        map[string]idOwner // connids passed admitIDs but not yet registered
+
+	// Events receives ClientConnected and ClientDisconnected, see SetEvents
+	Events      events.Publisher
+	clientQueue clientEventQueue // Delivers Events off the Run goroutine
 }
 
 // NewWebSock creates a new WebSock instance with proper routing capabilities
@@ -68,14 +138,18 @@ func NewWebSock() *WebSock {
 		clients:     make(map[string]*WsClient),
 		userClients: make(map[int64]map[string]bool),
 		sessions:    make(map[string]*WsSession),
+		admitted:    make(map[string]idOwner),
 		register:    make(chan *WsClient),
 		unregister:  make(chan *WsClient),
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
-			WriteBufferSize: 1024,
+			WriteBufferSize: DefaultFragmentSize,
 			CheckOrigin:     func(r *http.Request) bool { return true },
 		},
-		stats: WorkerStats{},
+		stats:          WorkerStats{},
+		MaxMessageSize: DefaultMaxMessageSize,
+		FragmentSize:   DefaultFragmentSize,
+		idPolicy:       DefaultIDPolicy(),
 	}
 	ws.NotFound = http.NotFound
 	return ws
@@ -83,7 +157,7 @@ func NewWebSock() *WebSock {
 
 // OnRequest handles an incoming HTTP request using the registered routes
 func (ws *WebSock) OnRequest(w http.ResponseWriter, r *http.Request) {
-	fmt.Printf("[WebSock] OnRequest: %s %s\n", r.Method, r.URL.Path)
+	comm.Debugf("websock", "OnRequest: %s %s", r.Method, r.URL.Path)
 	ws.Mux.ServeHTTP(w, r)
 }
 
@@ -101,56 +175,64 @@ func (ws *WebSock) SetNotFoundHandler(handler http.HandlerFunc) {
 	ws.Mux.NotFoundHandler = handler
 }
 
+// SetMaxMessageSize sets the largest inbound message accepted (0 = unlimited)
+func (ws *WebSock) SetMaxMessageSize(size int64) *WebSock {
+	ws.MaxMessageSize = size
+	return ws
+}
+
+// SetFragmentSize sets the largest payload written per outbound frame.
+// Applies to connections established after the call.
+func (ws *WebSock) SetFragmentSize(size int) *WebSock {
+	if size <= 0 {
+		size = DefaultFragmentSize
+	}
+	ws.FragmentSize = size
+	// gorilla/websocket emits a frame each time its write buffer fills,
+	// so the buffer size is the fragment size
+	ws.upgrader.WriteBufferSize = size
+	return ws
+}
+
+// SetSchemas validates outgoing messages against registry while it is enabled;
+// in strict mode invalid messages are not sent
+func (ws *WebSock) SetSchemas(registry *schema.Registry) *WebSock {
+	ws.Schemas = registry
+	return ws
+}
+
 // OnMessage sets the message handler callback
 func (ws *WebSock) OnMessage(handler func(msg *WsMessage)) {
 	ws.onMessage = handler
 
 }
 
+// OnLatencyUpdate sets the callback invoked whenever a client's ping round-trip is measured
+func (ws *WebSock) OnLatencyUpdate(handler func(client *WsClient, latency time.Duration)) {
+	ws.onLatencyUpdate = handler
+}
+
 // Run starts the WebSocket server processing loop
 func (ws *WebSock) Run() {
 	for {
 		select {
 		case client := <-ws.register:
-			ws.mu.Lock()
-
-			// If a client with this ID already exists, close it first
-			if existingClient, exists := ws.clients[client.ID]; exists {
-				fmt.Printf("[WebSock] Duplicate connection ID detected: %s - closing old connection\n", client.ID)
-
-				// Remove the old client from maps BEFORE closing to prevent unregister from affecting new client
-				delete(ws.clients, existingClient.ID)
-				if clients, ok := ws.userClients[existingClient.UserID]; ok {
-					delete(clients, existingClient.ID)
-					if len(clients) == 0 {
-						delete(ws.userClients, existingClient.UserID)
-					}
-				}
-
-				// Close the old connection in the background
-				// Don't close the channel here - let readPump->unregister handle it
-				go func(c *WsClient) {
-					c.Conn.Close()
-				}(existingClient)
+			if !ws.registerResuming(client) {
+				ws.addClient(client)
 			}
 
-			ws.clients[client.ID] = client
-
-			if _, ok := ws.userClients[client.UserID]; !ok {
-				ws.userClients[client.UserID] = make(map[string]bool)
-			}
-			ws.userClients[client.UserID][client.ID] = true
-			ws.mu.Unlock()
-
 			ws.statsMu.Lock()
 			ws.stats.TotalConnections++
 			ws.statsMu.Unlock()
+			ws.publishClient(events.ClientConnected, client)
 
 		case client := <-ws.unregister:
 			ws.mu.Lock()
 			// Only delete if this exact client instance is still in the map
 			// (prevents deleting a newer client with the same ID)
+			removed := false
 			if existingClient, ok := ws.clients[client.ID]; ok && existingClient == client {
+				removed = true
 				delete(ws.clients, client.ID)
 				close(client.Send)
 
@@ -162,8 +244,101 @@ func (ws *WebSock) Run() {
 				}
 			}
 			ws.mu.Unlock()
+			ws.leaveAllTopics(client)
+			if removed {
+				ws.releaseSession(client)
+				ws.publishClient(events.ClientDisconnected, client)
+			}
+		}
+	}
+}
+
+// SetEvents publishes client connects and disconnects to p (e.g. WebLite.Events())
+func (ws *WebSock) SetEvents(p events.Publisher) *WebSock {
+	ws.Events = p
+	return ws
+}
+
+// publishClient announces a connection change of client. Subscribers run on
+// the queue's goroutine, never on Run's, so they may call back into the
+// WebSock and a slow one does not hold up other connections.
+func (ws *WebSock) publishClient(topic string, client *WsClient) {
+	if ws.Events == nil {
+		return
+	}
+	p := ws.Events
+	data := events.ClientData{Transport: "ws", ClientID: client.ID, SessionKey: client.sessionKey}
+	ws.clientQueue.push(func() {
+		p.Publish(topic, "websock", data)
+	})
+}
+
+// clientEventQueue runs queued publications one at a time in order, on a
+// goroutine that exists only while the queue is not empty
+type clientEventQueue struct {
+	mu      sync.Mutex
+	pending []func()
+	running bool
+}
+
+func (q *clientEventQueue) push(publish func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, publish)
+	if !q.running {
+		q.running = true
+		go q.run()
+	}
+}
+
+func (q *clientEventQueue) run() {
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.pending = nil
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		publish := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+		publish()
+	}
+}
+
+// addClient registers client, closing an older connection with the same ID
+func (ws *WebSock) addClient(client *WsClient) {
+	ws.mu.Lock()
+
+	// If a client with this ID already exists, close it first
+	if existingClient, exists := ws.clients[client.ID]; exists {
+		comm.Debugf("websock", "Duplicate connection ID detected: %s - closing old connection", client.ID)
+
+		// Remove the old client from maps BEFORE closing to prevent unregister from affecting new client
+		delete(ws.clients, existingClient.ID)
+		if clients, ok := ws.userClients[existingClient.UserID]; ok {
+			delete(clients, existingClient.ID)
+			if len(clients) == 0 {
+				delete(ws.userClients, existingClient.UserID)
+			}
 		}
+
+		// Close the old connection in the background
+		// Don't close the channel here - let readPump->unregister handle it
+		go func(c *WsClient) {
+			c.Conn.Close()
+		}(existingClient)
 	}
+
+	ws.clients[client.ID] = client
+	delete(ws.admitted, client.ID)
+
+	if _, ok := ws.userClients[client.UserID]; !ok {
+		ws.userClients[client.UserID] = make(map[string]bool)
+	}
+	ws.userClients[client.UserID][client.ID] = true
+	ws.mu.Unlock()
 }
 
 // HandleConnection upgrades HTTP connection to WebSocket and manages the client
@@ -175,18 +350,23 @@ func (ws *WebSock) HandleConnection(wr http.ResponseWriter, r *http.Request, use
 		return
 	}
 
-	if connID == "" {
-		connID = GenerateConnectionID()
+	// While draining, send new connections straight back with a reconnect hint
+	if ws.draining.Load() {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseServiceRestart, drainCloseReason(comm.DrainRetryHint())), time.Now().Add(time.Second))
+		conn.Close()
+		return
 	}
 
-	// Get or create session
-	sessionID := r.URL.Query().Get("sessionid")
-	if sessionID == "" {
-		sessionID = GenerateConnectionID()
+	// Reject malformed IDs and IDs held by another user before they reach the
+	// maps; admitted connections get their session, private to the connection
+	// under SessionIsolated
+	requested := r.URL.Query().Get("sessionid")
+	connID, sessionID, reason := ws.admitIDs(r, userID, username, connID, requested)
+	if reason != "" {
+		rejectConnection(conn, reason)
+		return
 	}
 
-	_ = ws.GetOrCreateSession(sessionID, userID, username)
-
 	client := &WsClient{
 		ID:        connID,
 		SessionID: sessionID,
@@ -195,6 +375,12 @@ func (ws *WebSock) HandleConnection(wr http.ResponseWriter, r *http.Request, use
 		Conn:      conn,
 		Send:      make(chan []byte, 256),
 		WebSock:   ws,
+
+		RequestedSessionID: requested,
+		isolated:           ws.GetSessionStrategy() == SessionIsolated,
+		resumeSeq:          r.URL.Query().Get("seq"), // Replayed on register, see SetReliableDelivery
+		sessionKey:         comm.SessionKey(r),
+		fragmentSize:       ws.FragmentSize,
 	}
 
 	ws.register <- client
@@ -224,7 +410,12 @@ func (ws *WebSock) HandleCleanupConnection(wr http.ResponseWriter, r *http.Reque
 	conn.Close()
 
 	ws.mu.Lock()
-	if client, ok := ws.clients[connID]; ok {
+	client, ok := ws.clients[connID]
+	// Only the owner may clean up a connection unless the ID policy allows foreign IDs
+	if ok && client.UserID != userID && ws.idPolicy != nil && !ws.idPolicy.AllowForeignIDs {
+		ok = false
+	}
+	if ok {
 		delete(ws.clients, connID)
 		close(client.Send)
 
@@ -236,10 +427,16 @@ func (ws *WebSock) HandleCleanupConnection(wr http.ResponseWriter, r *http.Reque
 		}
 	}
 	ws.mu.Unlock()
+	if ok {
+		ws.releaseSession(client)
+	}
 }
 
 // SendToUser sends a message to all connections of a specific user
 func (ws *WebSock) SendToUser(userID int64, message []byte) {
+	if ws.Schemas.Check(message) != nil {
+		return
+	}
 	ws.mu.RLock()
 	defer ws.mu.RUnlock()
 
@@ -264,6 +461,9 @@ func (ws *WebSock) SendToUser(userID int64, message []byte) {
 
 // SendToClient sends a message to a specific client connection
 func (ws *WebSock) SendToClient(clientID string, message []byte) bool {
+	if ws.Schemas.Check(message) != nil {
+		return false
+	}
 	ws.mu.RLock()
 	client, ok := ws.clients[clientID]
 	ws.mu.RUnlock()
@@ -283,6 +483,9 @@ func (ws *WebSock) SendToClient(clientID string, message []byte) bool {
 
 // Broadcast sends a message to all connected clients
 func (ws *WebSock) Broadcast(message []byte) {
+	if ws.Schemas.Check(message) != nil {
+		return
+	}
 	ws.mu.RLock()
 	defer ws.mu.RUnlock()
 
@@ -304,14 +507,7 @@ func (ws *WebSock) GetOrCreateSession(sessionID string, userID int64, username s
 
 	session, exists := ws.sessions[sessionID]
 	if !exists {
-		session = &WsSession{
-			ID:        sessionID,
-			UserID:    userID,
-			Username:  username,
-			Data:      make(map[string]any),
-			CreatedAt: time.Now(),
-			LastSeen:  time.Now(),
-		}
+		session = newSession(sessionID, userID, username, "")
 		ws.sessions[sessionID] = session
 	} else {
 		session.LastSeen = time.Now()
@@ -320,6 +516,20 @@ func (ws *WebSock) GetOrCreateSession(sessionID string, userID int64, username s
 	return session
 }
 
+// newSession creates a session owned by userID (and ownerKey when anonymous)
+func newSession(sessionID string, userID int64, username, ownerKey string) *WsSession {
+	now := time.Now()
+	return &WsSession{
+		ID:        sessionID,
+		UserID:    userID,
+		Username:  username,
+		Data:      make(map[string]any),
+		CreatedAt: now,
+		LastSeen:  now,
+		ownerKey:  ownerKey,
+	}
+}
+
 // GetSession retrieves a session by ID
 func (ws *WebSock) GetSession(sessionID string) (*WsSession, bool) {
 	ws.mu.RLock()
@@ -357,8 +567,15 @@ func (session *WsSession) Delete(key string) {
 	delete(session.Data, key)
 }
 
-// SendToSession sends a message to all clients in a session
+// SendToSession sends a message to all clients in a session.
+// With reliable delivery the message is sequenced, see SetReliableDelivery.
 func (ws *WebSock) SendToSession(msg *WsMessage) bool {
+	if ws.Schemas.Check(msg.Data) != nil {
+		return false
+	}
+	if ws.IsReliable() {
+		return ws.sendSequenced(msg.SessionID, msg.Data, "")
+	}
 	ws.mu.RLock()
 	defer ws.mu.RUnlock()
 
@@ -380,6 +597,12 @@ func (ws *WebSock) SendToSession(msg *WsMessage) bool {
 // SendToSessionExcept sends a message to all clients in a session EXCEPT the specified client
 // Useful for broadcasting updates without echoing back to the sender
 func (ws *WebSock) SendToSessionExcept(msg *WsMessage, excludeClientID string) bool {
+	if ws.Schemas.Check(msg.Data) != nil {
+		return false
+	}
+	if ws.IsReliable() {
+		return ws.sendSequenced(msg.SessionID, msg.Data, excludeClientID)
+	}
 	ws.mu.RLock()
 	defer ws.mu.RUnlock()
 
@@ -445,15 +668,20 @@ func (c *WsClient) readPump() {
 		c.Conn.Close()
 	}()
 
-	c.Conn.SetReadLimit(4096)
 	c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-	c.Conn.SetPongHandler(func(string) error {
+	c.Conn.SetPongHandler(func(appData string) error {
 		c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		c.recordPong(appData)
 		return nil
 	})
 
 	for {
-		_, message, err := c.Conn.ReadMessage()
+		message, err := c.readMessage(c.WebSock.MaxMessageSize)
+		if err == errMessageTooLarge {
+			// Tell the client why instead of just dropping the connection
+			c.CloseWithReason(websocket.CloseMessageTooBig, "message too large")
+			break
+		}
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				// Log unexpected errors if needed
@@ -463,6 +691,10 @@ func (c *WsClient) readPump() {
 
 		c.WebSock.incrementMessagesReceived()
 
+		if c.WebSock.handleTopicMessage(c, message) || c.WebSock.handleReliableMessage(c, message) {
+			continue
+		}
+
 		if c.WebSock.onMessage != nil {
 			msg := &WsMessage{
 				Client:    c,
@@ -476,6 +708,85 @@ func (c *WsClient) readPump() {
 	}
 }
 
+// readMessage reads the next data message, failing with errMessageTooLarge once
+// more than limit bytes arrive. The limit is enforced here rather than with
+// Conn.SetReadLimit so the close frame can carry a reason.
+func (c *WsClient) readMessage(limit int64) ([]byte, error) {
+	_, r, err := c.Conn.NextReader()
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	message, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(message)) > limit {
+		return nil, errMessageTooLarge
+	}
+	return message, nil
+}
+
+// CloseWithReason sends a close frame with the given code and reason, then closes the connection
+func (c *WsClient) CloseWithReason(code int, reason string) error {
+	err := c.Conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(10*time.Second))
+	c.Conn.Close()
+	return err
+}
+
+// Latency returns the round-trip time measured by the last ping/pong (0 until the first pong)
+func (c *WsClient) Latency() time.Duration {
+	c.latencyMu.RLock()
+	defer c.latencyMu.RUnlock()
+	return c.latency
+}
+
+// LastPong returns when the client last answered a ping
+func (c *WsClient) LastPong() time.Time {
+	c.latencyMu.RLock()
+	defer c.latencyMu.RUnlock()
+	return c.lastPong
+}
+
+// Ping sends a ping immediately instead of waiting for the next keepalive tick
+func (c *WsClient) Ping() error {
+	return c.Conn.WriteControl(websocket.PingMessage, c.pingPayload(), time.Now().Add(10*time.Second))
+}
+
+// pingPayload numbers a new ping and remembers when it was sent; the send
+// time stays on the server so clients cannot report their own latency
+func (c *WsClient) pingPayload() []byte {
+	c.latencyMu.Lock()
+	defer c.latencyMu.Unlock()
+	c.pingSeq++
+	c.pingSent = time.Now()
+	return []byte(strconv.FormatUint(c.pingSeq, 10))
+}
+
+// recordPong updates the latency from a pong echoing the last pingPayload
+func (c *WsClient) recordPong(appData string) {
+	now := time.Now()
+	seq, err := strconv.ParseUint(appData, 10, 64)
+
+	c.latencyMu.Lock()
+	c.lastPong = now
+	if err != nil || seq != c.pingSeq || c.pingSent.IsZero() {
+		// Unsolicited, stale or repeated pong, or a client that does not echo the payload
+		c.latencyMu.Unlock()
+		return
+	}
+	latency := now.Sub(c.pingSent)
+	c.latency = latency
+	c.pingSent = time.Time{}
+	c.latencyMu.Unlock()
+
+	if c.WebSock.onLatencyUpdate != nil {
+		c.WebSock.onLatencyUpdate(c, latency)
+	}
+}
+
 // writePump pumps messages from the server to the WebSocket connection
 func (c *WsClient) writePump() {
 	ticker := time.NewTicker(30 * time.Second)
@@ -497,12 +808,12 @@ func (c *WsClient) writePump() {
 			if err != nil {
 				return
 			}
-			w.Write(message)
+			c.writeFragmented(w, message)
 
 			n := len(c.Send)
 			for i := 0; i < n; i++ {
 				w.Write([]byte{'\n'})
-				w.Write(<-c.Send)
+				c.writeFragmented(w, <-c.Send)
 			}
 
 			if err := w.Close(); err != nil {
@@ -510,13 +821,32 @@ func (c *WsClient) writePump() {
 			}
 		case <-ticker.C:
 			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			// The pong echoes the ping's sequence number for RTT measurement
+			if err := c.Conn.WriteMessage(websocket.PingMessage, c.pingPayload()); err != nil {
 				return
 			}
 		}
 	}
 }
 
+// writeFragmented writes message in FragmentSize pieces. gorilla/websocket
+// sends a frame whenever its write buffer fills but writes large slices as a
+// single frame, so each piece has to fit the buffer.
+func (c *WsClient) writeFragmented(w io.Writer, message []byte) error {
+	size := c.fragmentSize
+	if size <= 0 {
+		size = DefaultFragmentSize
+	}
+	for len(message) > 0 {
+		chunk := message[:min(size, len(message))]
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+		message = message[len(chunk):]
+	}
+	return nil
+}
+
 // GenerateConnectionID creates a unique connection ID
 func GenerateConnectionID() string {
 	return fmt.Sprintf("%s-%s", time.Now().Format("20060102150405"), RandStringBytes(8))
@@ -535,9 +865,7 @@ func RandStringBytes(n int) string {
 // RegisterClientRoutes registers all client-side routes (worker, manager scripts)
 func (ws *WebSock) RegisterClientRoutes(connectRoute string, getUserInfo func(r *http.Request) (username string, userID int64)) {
 	pathPrefix := ws.PathBase
-	fmt.Printf("[WebSock] RegisterClientRoutes - pathPrefix: '%s'\n", pathPrefix)
-	fmt.Printf("[WebSock] Registering routes:\n")
-	fmt.Printf("  - Connect: %s\n", pathPrefix+connectRoute)
+	comm.Debugf("websock", "RegisterClientRoutes - pathPrefix: '%s', connect: %s", pathPrefix, pathPrefix+connectRoute)
 
 	// Register WebSocket connection route
 	ws.Routes.HandlePathFn(pathPrefix+connectRoute, func(w http.ResponseWriter, r *http.Request) {
@@ -553,4 +881,12 @@ func (ws *WebSock) RegisterClientRoutes(connectRoute string, getUserInfo func(r
 		ws.HandleConnection(w, r, username, userID, connID)
 	})
 
+	// Event schemas for clients and tooling (404 until SetSchemas)
+	ws.Routes.GETPathFn(pathPrefix+"/schemas", func(w http.ResponseWriter, r *http.Request) {
+		if ws.Schemas == nil {
+			http.NotFound(w, r)
+			return
+		}
+		ws.Schemas.Handler()(w, r)
+	})
 }
@@ -0,0 +1,66 @@
+package websock
+
+// SessionStrategy decides whether connections presenting the same sessionid
+// share a WsSession (and each other's SendToSession messages)
+type SessionStrategy int
+
+const (
+	// SessionShared joins every connection with the same sessionid into one
+	// WsSession, e.g. several tabs of one browser (default)
+	SessionShared SessionStrategy = iota
+	// SessionIsolated gives each connection its own WsSession under an ID the
+	// server generates, so a client cannot join (or, by reusing a connid,
+	// release) another connection's session. The session is removed when the
+	// connection ends.
+	SessionIsolated
+)
+
+// String returns the strategy name
+func (s SessionStrategy) String() string {
+	if s == SessionIsolated {
+		return "isolated"
+	}
+	return "shared"
+}
+
+// SetSessionStrategy sets how sessions are shared; applies to connections established afterwards
+func (ws *WebSock) SetSessionStrategy(strategy SessionStrategy) *WebSock {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.sessionStrategy = strategy
+	return ws
+}
+
+// GetSessionStrategy returns the current session strategy
+func (ws *WebSock) GetSessionStrategy() SessionStrategy {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	return ws.sessionStrategy
+}
+
+// sessionIDFor returns the session a new connection joins given the sessionid
+// it requested. Isolated sessions get a fresh server-generated suffix rather
+// than the client-supplied connid, so no other connection can share the ID.
+func sessionIDFor(strategy SessionStrategy, requested string) string {
+	if strategy != SessionIsolated {
+		if requested == "" {
+			return GenerateConnectionID()
+		}
+		return requested
+	}
+	if requested == "" {
+		return GenerateConnectionID()
+	}
+	return requested + "/" + GenerateConnectionID()
+}
+
+// releaseSession drops the session of an isolated connection once it is gone;
+// shared sessions outlive their connections so clients can reconnect to them
+func (ws *WebSock) releaseSession(client *WsClient) {
+	if !client.isolated {
+		return
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	delete(ws.sessions, client.SessionID)
+}
@@ -0,0 +1,213 @@
+package websock
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// Topic protocol message types. Clients send
+//
+//	{"type":"subscribe","topic":"prices"}
+//	{"type":"unsubscribe","topic":"prices"}
+//
+// and receive {"type":"subscribed"|"unsubscribed","topic":...} in reply, or
+// {"type":"error","topic":...,"error":...} when the subscription is refused.
+// Publish delivers {"type":"publish","topic":...,"data":...} to subscribers.
+const (
+	MsgSubscribe    = "subscribe"
+	MsgUnsubscribe  = "unsubscribe"
+	MsgSubscribed   = "subscribed"
+	MsgUnsubscribed = "unsubscribed"
+	MsgPublish      = "publish"
+	MsgTopicError   = "error"
+)
+
+// MaxTopicLength bounds topic names accepted from clients
+const MaxTopicLength = 128
+
+// topicMessage is the envelope of protocol messages in both directions
+type topicMessage struct {
+	Type  string          `json:"type"`
+	Topic string          `json:"topic"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// SetTopicAuthorizer decides whether a client may subscribe to a topic
+// (all subscriptions are allowed while nil)
+func (ws *WebSock) SetTopicAuthorizer(authorize func(client *WsClient, topic string) bool) *WebSock {
+	ws.topicsMu.Lock()
+	ws.authorizeTopic = authorize
+	ws.topicsMu.Unlock()
+	return ws
+}
+
+// Subscribe adds client to topic; it reports false if the client was already subscribed
+func (ws *WebSock) Subscribe(client *WsClient, topic string) bool {
+	ws.topicsMu.Lock()
+	defer ws.topicsMu.Unlock()
+
+	if ws.topics == nil {
+		ws.topics = make(map[string]map[*WsClient]bool)
+	}
+	members, ok := ws.topics[topic]
+	if !ok {
+		members = make(map[*WsClient]bool)
+		ws.topics[topic] = members
+	}
+	if members[client] {
+		return false
+	}
+	members[client] = true
+	if client.topics == nil {
+		client.topics = make(map[string]bool)
+	}
+	client.topics[topic] = true
+	return true
+}
+
+// Unsubscribe removes client from topic; it reports false if the client was not subscribed
+func (ws *WebSock) Unsubscribe(client *WsClient, topic string) bool {
+	ws.topicsMu.Lock()
+	defer ws.topicsMu.Unlock()
+	return ws.unsubscribeLocked(client, topic)
+}
+
+func (ws *WebSock) unsubscribeLocked(client *WsClient, topic string) bool {
+	members, ok := ws.topics[topic]
+	if !ok || !members[client] {
+		return false
+	}
+	delete(members, client)
+	if len(members) == 0 {
+		delete(ws.topics, topic)
+	}
+	delete(client.topics, topic)
+	return true
+}
+
+// leaveAllTopics drops every subscription of a disconnected client
+func (ws *WebSock) leaveAllTopics(client *WsClient) {
+	ws.topicsMu.Lock()
+	defer ws.topicsMu.Unlock()
+	for topic := range client.topics {
+		ws.unsubscribeLocked(client, topic)
+	}
+}
+
+// Publish sends data to every subscriber of topic wrapped in a publish
+// envelope and returns the number of clients it was queued for. []byte and
+// json.RawMessage holding JSON are embedded as-is, anything else is marshaled.
+func (ws *WebSock) Publish(topic string, data any) (int, error) {
+	payload, err := encodePayload(data)
+	if err != nil {
+		return 0, err
+	}
+
+	message, err := json.Marshal(topicMessage{Type: MsgPublish, Topic: topic, Data: payload})
+	if err != nil {
+		return 0, err
+	}
+	if err := ws.Schemas.Check(message); err != nil {
+		return 0, err
+	}
+
+	ws.topicsMu.RLock()
+	members := make([]*WsClient, 0, len(ws.topics[topic]))
+	for client := range ws.topics[topic] {
+		members = append(members, client)
+	}
+	ws.topicsMu.RUnlock()
+
+	sent := 0
+	for _, client := range members {
+		if ws.SendToClient(client.ID, message) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// encodePayload turns data into the "data" field of a protocol envelope.
+// []byte and json.RawMessage holding JSON are embedded as-is, other bytes
+// become a JSON string and anything else is marshaled.
+func encodePayload(data any) (json.RawMessage, error) {
+	switch v := data.(type) {
+	case json.RawMessage:
+		return v, nil
+	case []byte:
+		if json.Valid(v) {
+			return v, nil
+		}
+		return json.Marshal(string(v))
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// TopicSubscribers returns how many clients are subscribed to topic
+func (ws *WebSock) TopicSubscribers(topic string) int {
+	ws.topicsMu.RLock()
+	defer ws.topicsMu.RUnlock()
+	return len(ws.topics[topic])
+}
+
+// Topics returns the subscriber count of every topic
+func (ws *WebSock) Topics() map[string]int {
+	ws.topicsMu.RLock()
+	defer ws.topicsMu.RUnlock()
+	counts := make(map[string]int, len(ws.topics))
+	for topic, members := range ws.topics {
+		counts[topic] = len(members)
+	}
+	return counts
+}
+
+// Topics returns the topics the client is subscribed to
+func (c *WsClient) Topics() []string {
+	c.WebSock.topicsMu.RLock()
+	topics := make([]string, 0, len(c.topics))
+	for topic := range c.topics {
+		topics = append(topics, topic)
+	}
+	c.WebSock.topicsMu.RUnlock()
+	sort.Strings(topics)
+	return topics
+}
+
+// handleTopicMessage answers subscribe/unsubscribe requests and reports
+// whether message was one (protocol messages are not passed to OnMessage)
+func (ws *WebSock) handleTopicMessage(client *WsClient, message []byte) bool {
+	trimmed := bytes.TrimSpace(message)
+	if len(trimmed) == 0 || trimmed[0] != '{' || !bytes.Contains(trimmed, []byte("subscribe")) {
+		return false
+	}
+	var req topicMessage
+	if json.Unmarshal(trimmed, &req) != nil || (req.Type != MsgSubscribe && req.Type != MsgUnsubscribe) {
+		return false
+	}
+
+	reply := topicMessage{Topic: req.Topic}
+	switch {
+	case req.Topic == "" || len(req.Topic) > MaxTopicLength:
+		reply.Type, reply.Error = MsgTopicError, "invalid topic"
+	case req.Type == MsgUnsubscribe:
+		ws.Unsubscribe(client, req.Topic)
+		reply.Type = MsgUnsubscribed
+	default:
+		ws.topicsMu.RLock()
+		authorize := ws.authorizeTopic
+		ws.topicsMu.RUnlock()
+		if authorize != nil && !authorize(client, req.Topic) {
+			reply.Type, reply.Error = MsgTopicError, "forbidden"
+			break
+		}
+		ws.Subscribe(client, req.Topic)
+		reply.Type = MsgSubscribed
+	}
+
+	data, _ := json.Marshal(reply)
+	ws.SendToClient(client.ID, data)
+	return true
+}
@@ -0,0 +1,106 @@
+package webstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// PlaylistItem describes one playable file in a directory playlist
+type PlaylistItem struct {
+	Name        string  `json:"name"`
+	Path        string  `json:"path"`
+	URL         string  `json:"url"`
+	Size        int64   `json:"size"`
+	ContentType string  `json:"contentType"`
+	Duration    float64 `json:"duration,omitempty"` // Seconds, omitted when unknown
+}
+
+// BuildPlaylist lists the allowed media files in dir, ordered by name.
+// Item URLs are urlPrefix joined with the file path. Durations come from
+// DurationFor when set, since WebStream does not parse media containers.
+func (ws *WebStream) BuildPlaylist(dir string, urlPrefix string) ([]PlaylistItem, error) {
+	dir = strings.TrimPrefix(path.Clean("/"+dir), "/")
+	entries, err := ws.FsAdapter.ListDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return strings.ToLower(entries[i].Name) < strings.ToLower(entries[j].Name)
+	})
+
+	items := make([]PlaylistItem, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir || !ws.AllowedExtensions[strings.ToLower(path.Ext(entry.Name))] {
+			continue
+		}
+		filePath := path.Join(dir, entry.Name)
+		info, err := ws.getMediaInfo(filePath)
+		if err != nil {
+			continue
+		}
+
+		item := PlaylistItem{
+			Name:        entry.Name,
+			Path:        filePath,
+			URL:         strings.TrimSuffix(urlPrefix, "/") + "/" + escapePath(filePath),
+			Size:        info.Size,
+			ContentType: info.ContentType,
+		}
+		if ws.DurationFor != nil {
+			item.Duration = ws.DurationFor(filePath).Seconds()
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// ServePlaylist writes the playlist for dir as JSON, or as extended M3U when
+// ?format=m3u is given
+func (ws *WebStream) ServePlaylist(w http.ResponseWriter, r *http.Request, dir string, urlPrefix string) {
+	dir = strings.TrimPrefix(path.Clean("/"+dir), "/")
+	if !ws.FsAdapter.IsDir(dir) {
+		http.Error(w, "Directory not found", http.StatusNotFound)
+		return
+	}
+
+	items, err := ws.BuildPlaylist(dir, urlPrefix)
+	if err != nil {
+		http.Error(w, "Cannot list directory", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.URL.Query().Get("format") == "m3u" {
+		w.Header().Set("Content-Type", "audio/x-mpegurl; charset=utf-8")
+		fmt.Fprint(w, "#EXTM3U\n")
+		for _, item := range items {
+			duration := -1 // unknown, per the extended M3U convention
+			if item.Duration > 0 {
+				duration = int(item.Duration + 0.5)
+			}
+			fmt.Fprintf(w, "#EXTINF:%d,%s\n%s\n", duration, item.Name, item.URL)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"directory": dir,
+		"items":     items,
+	})
+}
+
+// escapePath URL-escapes each segment of p
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -19,6 +19,7 @@ type MediaInfo struct {
 	ModTime     time.Time
 	ContentType string
 	Extension   string
+	ETag        string
 }
 
 // RangeSpec represents a byte range
@@ -45,6 +46,15 @@ type WebStream struct {
 	EnableCaching     bool
 	CacheDuration     time.Duration
 	AllowedExtensions map[string]bool
+	// ContentTypeFor optionally decides the Content-Type of a media path (built-in table when nil or "")
+	ContentTypeFor func(path string) string
+	// DurationFor optionally reports the playback duration of a media path for playlists
+	DurationFor func(path string) time.Duration
+	// PrivateCaching optionally reports paths that must only be cached by the
+	// browser, e.g. members-only media (Cache-Control private instead of public)
+	PrivateCaching func(path string) bool
+	// VariantOrder ranks variant extensions for SelectVariant (DefaultVariantOrder when nil)
+	VariantOrder []string
 }
 
 // NewWebStream creates a new WebStream instance
@@ -95,7 +105,7 @@ func NewWebStreamFromConfig(fsAdapter comm.IFsAdapter, config StreamConfig) *Web
 
 // OnRequest handles an incoming HTTP request using the registered routes
 func (ws *WebStream) OnRequest(w http.ResponseWriter, r *http.Request) {
-	fmt.Printf("[WebStream] OnRequest: %s %s\n", r.Method, r.URL.Path)
+	comm.Debugf("webstream", "OnRequest: %s %s", r.Method, r.URL.Path)
 	ws.Mux.ServeHTTP(w, r)
 }
 
@@ -115,6 +125,12 @@ func (ws *WebStream) AddAllowedExtension(ext string) {
 
 // ServeMedia serves a media file with range request support
 func (ws *WebStream) ServeMedia(w http.ResponseWriter, r *http.Request, filePath string) {
+	// Pick among foo.webm, foo.mp4, ... when asked for "foo" or given a ?codec= hint
+	filePath, negotiated := ws.SelectVariant(r, filePath)
+	if negotiated {
+		w.Header().Add("Vary", "Accept")
+	}
+
 	// Clean the file path
 	cleanPath := filepath.Clean(filePath)
 
@@ -143,6 +159,14 @@ func (ws *WebStream) ServeMedia(w http.ResponseWriter, r *http.Request, filePath
 		return
 	}
 
+	// Set common headers
+	ws.setMediaHeaders(w, info)
+
+	// Answer conditional requests from Stat metadata without opening the file
+	if ws.EnableCaching && comm.ServeNotModified(w, r, info.ETag, info.ModTime) {
+		return
+	}
+
 	// Open the file
 	file, err := ws.FsAdapter.Open(cleanPath)
 	if err != nil {
@@ -151,9 +175,6 @@ func (ws *WebStream) ServeMedia(w http.ResponseWriter, r *http.Request, filePath
 	}
 	defer file.Close()
 
-	// Set common headers
-	ws.setMediaHeaders(w, info)
-
 	// Handle range requests
 	if r.Header.Get("Range") != "" {
 		ws.serveRangeRequest(w, r, file, info)
@@ -171,6 +192,11 @@ func (ws *WebStream) getMediaInfo(path string) (*MediaInfo, error) {
 
 	ext := strings.ToLower(filepath.Ext(path))
 	contentType := ws.getContentType(ext)
+	if ws.ContentTypeFor != nil {
+		if resolved := ws.ContentTypeFor(path); resolved != "" {
+			contentType = resolved
+		}
+	}
 
 	return &MediaInfo{
 		Path:        path,
@@ -178,6 +204,7 @@ func (ws *WebStream) getMediaInfo(path string) (*MediaInfo, error) {
 		ModTime:     fileInfo.ModTime,
 		ContentType: contentType,
 		Extension:   ext,
+		ETag:        comm.StatETag(fileInfo),
 	}, nil
 }
 
@@ -191,9 +218,15 @@ func (ws *WebStream) setMediaHeaders(w http.ResponseWriter, info *MediaInfo) {
 
 	// Set caching headers
 	if ws.EnableCaching {
-		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(ws.CacheDuration.Seconds())))
+		visibility := "public"
+		if ws.PrivateCaching != nil && ws.PrivateCaching(info.Path) {
+			visibility = "private"
+		}
+		w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", visibility, int(ws.CacheDuration.Seconds())))
 		w.Header().Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
-		w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime.Unix(), info.Size))
+		if info.ETag != "" {
+			w.Header().Set("ETag", info.ETag)
+		}
 	} else {
 		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
 	}
@@ -0,0 +1,212 @@
+package webstream
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultVariantOrder ranks variant extensions when the client accepts several
+// equally well: the most widely playable containers come first
+var DefaultVariantOrder = []string{".mp4", ".webm", ".m4v", ".mov", ".mkv", ".ogg", ".avi", ".m4a", ".mp3", ".aac", ".flac", ".wav"}
+
+// codecContainers maps ?codec= hints to the containers that usually carry the codec
+var codecContainers = map[string][]string{
+	"h264":   {".mp4", ".m4v", ".mov", ".mkv"},
+	"avc":    {".mp4", ".m4v", ".mov", ".mkv"},
+	"h265":   {".mp4", ".mov", ".mkv"},
+	"hevc":   {".mp4", ".mov", ".mkv"},
+	"av1":    {".webm", ".mp4", ".mkv"},
+	"vp8":    {".webm", ".mkv"},
+	"vp9":    {".webm", ".mkv"},
+	"opus":   {".webm", ".ogg", ".mkv"},
+	"vorbis": {".ogg", ".webm", ".mkv"},
+	"theora": {".ogg"},
+	"aac":    {".m4a", ".aac", ".mp4"},
+	"mp3":    {".mp3"},
+	"flac":   {".flac"},
+}
+
+// Variant is one file of a media title available in several formats
+type Variant struct {
+	Path        string `json:"path"`
+	URL         string `json:"url,omitempty"`
+	Extension   string `json:"extension"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+}
+
+// Variants lists the allowed media files sharing the name of filePath without
+// its extension, e.g. foo.webm and foo.mp4 for "foo" or "foo.mp4", ordered
+// by VariantOrder
+func (ws *WebStream) Variants(filePath string) ([]Variant, error) {
+	filePath = strings.TrimPrefix(path.Clean("/"+filePath), "/")
+	dir, name := path.Split(filePath)
+	dir = strings.TrimSuffix(dir, "/")
+	stem := strings.TrimSuffix(name, path.Ext(name))
+	if !ws.AllowedExtensions[strings.ToLower(path.Ext(name))] {
+		// "foo.bar" without a media extension names the stem itself
+		stem = name
+	}
+
+	entries, err := ws.FsAdapter.ListDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	variants := []Variant{}
+	for _, entry := range entries {
+		ext := strings.ToLower(path.Ext(entry.Name))
+		if entry.IsDir || !ws.AllowedExtensions[ext] || strings.TrimSuffix(entry.Name, path.Ext(entry.Name)) != stem {
+			continue
+		}
+		info, err := ws.getMediaInfo(path.Join(dir, entry.Name))
+		if err != nil {
+			continue
+		}
+		variants = append(variants, Variant{
+			Path:        info.Path,
+			Extension:   ext,
+			ContentType: info.ContentType,
+			Size:        info.Size,
+		})
+	}
+
+	slices.SortStableFunc(variants, func(a, b Variant) int {
+		if ra, rb := ws.variantRank(a.Extension), ws.variantRank(b.Extension); ra != rb {
+			return ra - rb
+		}
+		return strings.Compare(a.Extension, b.Extension)
+	})
+	return variants, nil
+}
+
+// variantRank returns the position of ext in VariantOrder (unknown ones last)
+func (ws *WebStream) variantRank(ext string) int {
+	order := ws.VariantOrder
+	if order == nil {
+		order = DefaultVariantOrder
+	}
+	if i := slices.Index(order, ext); i >= 0 {
+		return i
+	}
+	return len(order)
+}
+
+// SelectVariant picks the variant of filePath to serve for r. Selection applies
+// when filePath has no media extension or r carries a ?codec= hint (a codec
+// such as "vp9" or a container such as "webm"); the hint wins over the Accept
+// header, which wins over VariantOrder. negotiated reports whether the choice
+// depended on Accept, so responses must vary on it.
+func (ws *WebStream) SelectVariant(r *http.Request, filePath string) (selected string, negotiated bool) {
+	hint := strings.ToLower(strings.TrimPrefix(r.URL.Query().Get("codec"), "."))
+	if hint == "" && ws.AllowedExtensions[strings.ToLower(path.Ext(filePath))] {
+		return filePath, false
+	}
+	variants, err := ws.Variants(filePath)
+	if err != nil || len(variants) == 0 {
+		return filePath, false
+	}
+
+	if hint != "" {
+		containers, ok := codecContainers[hint]
+		if !ok {
+			containers = []string{"." + hint}
+		}
+		for _, ext := range containers {
+			for _, v := range variants {
+				if v.Extension == ext {
+					return v.Path, false
+				}
+			}
+		}
+	}
+
+	accept := parseAccept(r.Header.Get("Accept"))
+	if len(accept) == 0 {
+		return variants[0].Path, true
+	}
+	best, bestQ := "", 0.0
+	for _, v := range variants {
+		if q := acceptQuality(accept, v.ContentType); q > bestQ {
+			best, bestQ = v.Path, q
+		}
+	}
+	if best == "" {
+		// Nothing acceptable; serve the preferred variant rather than a 406
+		best = variants[0].Path
+	}
+	return best, true
+}
+
+// ServeVariants writes the variants of filePath as JSON with URLs under urlPrefix
+func (ws *WebStream) ServeVariants(w http.ResponseWriter, r *http.Request, filePath string, urlPrefix string) {
+	variants, err := ws.Variants(filePath)
+	if err != nil || len(variants) == 0 {
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	}
+	for i := range variants {
+		variants[i].URL = strings.TrimSuffix(urlPrefix, "/") + "/" + escapePath(variants[i].Path)
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"path":     strings.TrimPrefix(path.Clean("/"+filePath), "/"),
+		"variants": variants,
+	})
+}
+
+// acceptRange is one media range of an Accept header
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept parses an Accept header into its media ranges
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for part := range strings.SplitSeq(header, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaType == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+// acceptQuality returns the q value the most specific matching range gives contentType
+func acceptQuality(ranges []acceptRange, contentType string) float64 {
+	contentType = strings.ToLower(contentType)
+	major, _, _ := strings.Cut(contentType, "/")
+	q, specificity := 0.0, -1
+	for _, ar := range ranges {
+		s := -1
+		switch ar.mediaType {
+		case contentType:
+			s = 2
+		case major + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = ar.q, s
+		}
+	}
+	return q
+}
@@ -0,0 +1,74 @@
+package websway
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ImmutableCacheControl is sent for fingerprinted assets: their content never
+// changes under the same name, so browsers may keep them for a year without revalidating
+const ImmutableCacheControl = "public, max-age=31536000, immutable"
+
+// SetImmutableAssets enables ImmutableCacheControl for fingerprinted assets
+// (see IsFingerprinted), independent of CacheMaxAge
+func (wt *WebSway) SetImmutableAssets(enabled bool) *WebSway {
+	wt.ImmutableAssets = enabled
+	return wt
+}
+
+// SetImmutablePattern enables immutable caching for request paths matching
+// expr instead of the built-in fingerprint detection, e.g. `\.[0-9a-f]{8}\.(js|css)$`
+func (wt *WebSway) SetImmutablePattern(expr string) error {
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+	wt.ImmutablePattern = pattern
+	wt.ImmutableAssets = true
+	return nil
+}
+
+// isImmutable reports whether requestPath gets ImmutableCacheControl
+func (wt *WebSway) isImmutable(requestPath string) bool {
+	if !wt.ImmutableAssets {
+		return false
+	}
+	if wt.ImmutablePattern != nil {
+		return wt.ImmutablePattern.MatchString(requestPath)
+	}
+	return IsFingerprinted(requestPath)
+}
+
+// IsFingerprinted reports whether the file name carries a content hash as
+// bundlers write it: "app.abc123.js", "index-BXq3k9aZ.js" or "chunk.1f2e3d4c.min.css".
+// The hash is the segment before the extension(s), 6 to 64 letters, digits or
+// underscores including at least one digit, so names like "app.module.js" do not count.
+func IsFingerprinted(p string) bool {
+	name := path.Base(p)
+	ext := path.Ext(name)
+	if ext == "" {
+		return false
+	}
+	stem := strings.TrimSuffix(strings.TrimSuffix(name, ext), ".min")
+	sep := strings.LastIndexAny(stem, ".-")
+	return sep > 0 && isHashSegment(stem[sep+1:])
+}
+
+// isHashSegment reports whether s looks like a content hash
+func isHashSegment(s string) bool {
+	if len(s) < 6 || len(s) > 64 {
+		return false
+	}
+	digit := false
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digit = true
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_':
+		default:
+			return false
+		}
+	}
+	return digit
+}
@@ -0,0 +1,120 @@
+package websway
+
+import (
+	"html"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// DefaultIncludeDir is the shared directory searched for includes after the app directory
+const DefaultIncludeDir = "_includes"
+
+// maxIncludeDepth bounds nested includes, which also stops include cycles
+const maxIncludeDepth = 8
+
+// includeDirective matches <!--#include virtual="header" -->, <!--#include file="nav.html" -->
+// and <!--#echo var="user" -->
+var includeDirective = regexp.MustCompile(`<!--#(include|echo)\s+(virtual|file|var)="([^"]*)"\s*-->`)
+
+// SetIncludes enables server-side includes in HTML pages:
+//
+//	<!--#include virtual="header" -->   <app>/header.html, else <IncludeDir>/header.html
+//	<!--#include file="parts/nav.html" --> relative to the including file
+//	<!--#echo var="user" -->            HTML-escaped value from IncludeVars
+//
+// Includes may nest. Processed pages get an ETag from their final content.
+func (wt *WebSway) SetIncludes(enabled bool) *WebSway {
+	wt.Includes = enabled
+	return wt
+}
+
+// SetIncludeVars sets the variables available to <!--#echo -->, e.g. user and
+// session info; the request carries the session when the handler resolved one
+func (wt *WebSway) SetIncludeVars(fn func(r *http.Request) map[string]string) *WebSway {
+	wt.IncludeVars = fn
+	return wt
+}
+
+// processesIncludes reports whether storagePath is run through processIncludes
+func (wt *WebSway) processesIncludes(storagePath string) bool {
+	ext := strings.ToLower(path.Ext(storagePath))
+	return wt.Includes && (ext == ".html" || ext == ".htm")
+}
+
+// processIncludes resolves the include and echo directives of a page
+func (wt *WebSway) processIncludes(r *http.Request, storagePath string, data []byte) []byte {
+	var vars map[string]string
+	if wt.IncludeVars != nil {
+		vars = wt.IncludeVars(r)
+	}
+	return wt.expandIncludes(storagePath, data, vars, 0)
+}
+
+func (wt *WebSway) expandIncludes(storagePath string, data []byte, vars map[string]string, depth int) []byte {
+	return includeDirective.ReplaceAllFunc(data, func(match []byte) []byte {
+		parts := includeDirective.FindSubmatch(match)
+		command, attr, value := string(parts[1]), string(parts[2]), string(parts[3])
+
+		if command == "echo" {
+			if attr != "var" {
+				return nil
+			}
+			v, ok := vars[value]
+			if !ok {
+				v = "(none)"
+			}
+			return []byte(html.EscapeString(v))
+		}
+
+		if attr == "var" {
+			return nil
+		}
+		if depth >= maxIncludeDepth {
+			return []byte("<!-- include " + html.EscapeString(value) + ": nested too deep -->")
+		}
+		included, found := wt.resolveInclude(storagePath, attr, value)
+		if !found {
+			return []byte("<!-- include " + html.EscapeString(value) + " not found -->")
+		}
+		content, err := wt.FsProvider.ReadFile(included)
+		if err != nil {
+			return []byte("<!-- include " + html.EscapeString(value) + " not readable -->")
+		}
+		return wt.expandIncludes(included, content, vars, depth+1)
+	})
+}
+
+// resolveInclude finds the storage path of an include; names without an
+// extension refer to .html files
+func (wt *WebSway) resolveInclude(storagePath, attr, name string) (string, bool) {
+	storagePath = strings.ReplaceAll(storagePath, "\\", "/")
+	if path.Ext(name) == "" {
+		name += ".html"
+	}
+
+	var candidates []string
+	if attr == "file" {
+		candidates = append(candidates, path.Join(path.Dir(storagePath), name))
+	} else {
+		// Cleaning against "/" keeps virtual names inside the searched directory
+		name = strings.TrimPrefix(path.Clean("/"+name), "/")
+		app, _, _ := strings.Cut(storagePath, "/")
+		includeDir := wt.IncludeDir
+		if includeDir == "" {
+			includeDir = DefaultIncludeDir
+		}
+		candidates = append(candidates, path.Join(app, name), path.Join(includeDir, name))
+	}
+
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, "..") || path.IsAbs(candidate) {
+			continue
+		}
+		if info, err := wt.FsProvider.Stat(candidate); err == nil && !info.IsDir {
+			return candidate, true
+		}
+	}
+	return "", false
+}
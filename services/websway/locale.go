@@ -0,0 +1,120 @@
+package websway
+
+import (
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Limits on the Accept-Language header, each looked up tag costs up to two Stat calls
+const (
+	maxLanguageRanges = 16 // Ranges of a header that are parsed
+	maxLocaleTags     = 6  // Tags looked up per page
+)
+
+// SetLocales enables Accept-Language negotiation for HTML pages. For
+// index/about.html the localized bundles index/about.de.html and
+// index/de/about.html are looked up (lowercase tags such as "de" or "pt-br");
+// pages without a match fall back to the default bundle, which is in
+// defaultLocale. supported limits the locales looked up (any when empty).
+func (wt *WebSway) SetLocales(defaultLocale string, supported ...string) *WebSway {
+	wt.Localize = true
+	wt.DefaultLocale = strings.ToLower(defaultLocale)
+	wt.SupportedLocales = nil
+	for _, locale := range supported {
+		wt.SupportedLocales = append(wt.SupportedLocales, strings.ToLower(locale))
+	}
+	return wt
+}
+
+// localize returns the storage path of the best localized bundle of an HTML
+// page for r and its language ("" when unknown), and sets Vary and Content-Language
+func (wt *WebSway) localize(w http.ResponseWriter, r *http.Request, storagePath string) string {
+	ext := strings.ToLower(path.Ext(storagePath))
+	if !wt.Localize || (ext != ".html" && ext != ".htm") {
+		return storagePath
+	}
+	w.Header().Add("Vary", "Accept-Language")
+
+	for _, tag := range wt.preferredLocales(r.Header.Get("Accept-Language")) {
+		if tag == wt.DefaultLocale {
+			break
+		}
+		if localized, ok := wt.localizedBundle(storagePath, tag); ok {
+			w.Header().Set("Content-Language", tag)
+			return localized
+		}
+	}
+	if wt.DefaultLocale != "" {
+		w.Header().Set("Content-Language", wt.DefaultLocale)
+	}
+	return storagePath
+}
+
+// localizedBundle looks up the bundle of storagePath in locale tag
+func (wt *WebSway) localizedBundle(storagePath, tag string) (string, bool) {
+	storagePath = strings.ReplaceAll(storagePath, "\\", "/")
+	dir, file := path.Split(storagePath)
+	ext := path.Ext(file)
+	candidates := []string{
+		path.Join(dir, strings.TrimSuffix(file, ext)+"."+tag+ext),
+		path.Join(dir, tag, file),
+	}
+	for _, candidate := range candidates {
+		if info, err := wt.FsProvider.Stat(candidate); err == nil && !info.IsDir {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// preferredLocales lists the locales of an Accept-Language header by
+// preference, each followed by its primary language ("de-ch" then "de"),
+// restricted to SupportedLocales when set and capped at maxLocaleTags
+func (wt *WebSway) preferredLocales(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var ranges []weighted
+	for part := range strings.SplitSeq(header, ",") {
+		if len(ranges) == maxLanguageRanges {
+			break
+		}
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" || strings.ContainsAny(tag, "/\\.") {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key == "q" {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	var tags []string
+	add := func(tag string) {
+		if len(tags) < maxLocaleTags && !slices.Contains(tags, tag) && (len(wt.SupportedLocales) == 0 || slices.Contains(wt.SupportedLocales, tag)) {
+			tags = append(tags, tag)
+		}
+	}
+	for _, lr := range ranges {
+		add(lr.tag)
+		if primary, _, found := strings.Cut(lr.tag, "-"); found {
+			add(primary)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,112 @@
+package websway
+
+import (
+	"net/http"
+	"strings"
+)
+
+// PreloadAsset is a critical asset announced with Link: rel=preload
+type PreloadAsset struct {
+	URL         string // Asset URL (absolute path, or relative to the HTML page)
+	As          string // script, style, font, image, fetch ...
+	Type        string // Optional MIME type, e.g. "font/woff2"
+	CrossOrigin string // Optional crossorigin attribute ("anonymous" is required for fonts)
+	Module      bool   // Use rel=modulepreload (ES module scripts)
+}
+
+// Preload returns a preload asset for url, inferring As from the extension
+func Preload(url string) PreloadAsset {
+	asset := PreloadAsset{URL: url}
+	path := url
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	switch {
+	case strings.HasSuffix(path, ".js"), strings.HasSuffix(path, ".mjs"):
+		asset.As = "script"
+	case strings.HasSuffix(path, ".css"):
+		asset.As = "style"
+	case strings.HasSuffix(path, ".woff2"):
+		asset.As, asset.Type, asset.CrossOrigin = "font", "font/woff2", "anonymous"
+	case strings.HasSuffix(path, ".woff"):
+		asset.As, asset.Type, asset.CrossOrigin = "font", "font/woff", "anonymous"
+	case strings.HasSuffix(path, ".png"), strings.HasSuffix(path, ".jpg"), strings.HasSuffix(path, ".jpeg"),
+		strings.HasSuffix(path, ".webp"), strings.HasSuffix(path, ".svg"), strings.HasSuffix(path, ".avif"):
+		asset.As = "image"
+	case strings.HasSuffix(path, ".json"):
+		asset.As, asset.CrossOrigin = "fetch", "anonymous"
+	}
+	return asset
+}
+
+// Prefetch returns a preload asset for an endpoint the page fetches right
+// after loading, such as the JSON an SPA requests on boot
+func Prefetch(url string) PreloadAsset {
+	return PreloadAsset{URL: url, As: "fetch", CrossOrigin: "anonymous"}
+}
+
+// LinkValue formats the asset as a Link header value
+func (pa PreloadAsset) LinkValue() string {
+	rel := "preload"
+	if pa.Module {
+		rel = "modulepreload"
+	}
+	var b strings.Builder
+	b.WriteString("<" + pa.URL + ">; rel=" + rel)
+	if pa.As != "" && !pa.Module {
+		b.WriteString("; as=" + pa.As)
+	}
+	if pa.Type != "" {
+		b.WriteString(`; type="` + pa.Type + `"`)
+	}
+	if pa.CrossOrigin != "" {
+		b.WriteString("; crossorigin=" + pa.CrossOrigin)
+	}
+	return b.String()
+}
+
+// AddPreload announces assets for the HTML pages of entry (the app directory,
+// e.g. "index" or "home") via Link: rel=preload headers
+func (wt *WebSway) AddPreload(entry string, assets ...PreloadAsset) *WebSway {
+	if wt.Preloads == nil {
+		wt.Preloads = make(map[string][]PreloadAsset)
+	}
+	wt.Preloads[entry] = append(wt.Preloads[entry], assets...)
+	return wt
+}
+
+// AddPrefetch announces endpoints the HTML pages of entry fetch on boot, so
+// the API calls start in parallel with the app's scripts instead of after them
+func (wt *WebSway) AddPrefetch(entry string, urls ...string) *WebSway {
+	for _, url := range urls {
+		wt.AddPreload(entry, Prefetch(url))
+	}
+	return wt
+}
+
+// SetEarlyHints enables sending 103 Early Hints with the preload links, so
+// browsers start fetching assets while the page itself is being prepared
+func (wt *WebSway) SetEarlyHints(enabled bool) *WebSway {
+	wt.EarlyHints = enabled
+	return wt
+}
+
+// applyPreloads adds the preload links for an HTML storage path and, when
+// enabled, flushes them as 103 Early Hints
+func (wt *WebSway) applyPreloads(w http.ResponseWriter, r *http.Request, storagePath string) {
+	if len(wt.Preloads) == 0 || !strings.HasSuffix(storagePath, ".html") {
+		return
+	}
+	entry, _, _ := strings.Cut(storagePath, "/")
+	assets := wt.Preloads[entry]
+	if len(assets) == 0 {
+		return
+	}
+	for _, asset := range assets {
+		w.Header().Add("Link", asset.LinkValue())
+	}
+	// 103 is only understood by HTTP/1.1+ clients and never sent for HEAD
+	if wt.EarlyHints && r.ProtoAtLeast(1, 1) && r.Method == http.MethodGet {
+		w.WriteHeader(http.StatusEarlyHints)
+	}
+}
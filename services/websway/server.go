@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
@@ -26,6 +27,26 @@ type WebSway struct {
 	CacheMaxAge       time.Duration
 	VirtualDirSegment string // Virtual directory segment (default: "p")
 	DefaultRoute      string // Default route for root path
+	// ContentTypeFor optionally decides the Content-Type of a storage path (detected when nil or "")
+	ContentTypeFor func(path string) string
+
+	// Preloads lists critical assets per entry point (app directory), see AddPreload
+	Preloads   map[string][]PreloadAsset
+	EarlyHints bool // Send preload links as 103 Early Hints before the page
+
+	// Fingerprinted assets are cached as immutable while enabled, see immutable.go
+	ImmutableAssets  bool
+	ImmutablePattern *regexp.Regexp // Overrides IsFingerprinted when set
+
+	// Server-side includes in HTML pages, see includes.go
+	Includes    bool
+	IncludeDir  string // Shared include directory (DefaultIncludeDir when empty)
+	IncludeVars func(r *http.Request) map[string]string
+
+	// Accept-Language negotiation of HTML pages, see locale.go
+	Localize         bool
+	DefaultLocale    string   // Language of the default bundle, sent as Content-Language
+	SupportedLocales []string // Locales looked up (any when empty)
 }
 
 // NewWebSway creates a new WebSway instance with proper routing capabilities
@@ -196,6 +217,10 @@ func (wt *WebSway) ApplyCacheHeaders(w http.ResponseWriter, requestPath string)
 		return
 	}
 	if comm.Mime.IsStaticExtension(ext) {
+		if wt.isImmutable(requestPath) {
+			w.Header().Set("Cache-Control", ImmutableCacheControl)
+			return
+		}
 		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(wt.CacheMaxAge.Seconds())))
 		return
 	}
@@ -207,15 +232,19 @@ func (wt *WebSway) ApplyCacheHeaders(w http.ResponseWriter, requestPath string)
 func (wt *WebSway) ServeFile(w http.ResponseWriter, r *http.Request) {
 	// Read file from filesystem provider
 
-	println("url", r.URL.Path)
+	comm.Debugf("websway", "ServeFile: %s", r.URL.Path)
 	storagePath, err := wt.ExtractStoragePath(r.URL.Path, "/", wt.PathBase)
 	if err != nil {
 		wt.NotFound(w, r)
 		return
 	}
 
-	data, err := wt.FsProvider.ReadFile(storagePath)
-	if err != nil {
+	// Serve the bundle in the client's language where one exists
+	storagePath = wt.localize(w, r, storagePath)
+
+	// Stat first so conditional requests are answered without reading the file
+	info, err := wt.FsProvider.Stat(storagePath)
+	if err != nil || info.IsDir {
 		wt.NotFound(w, r)
 		return
 	}
@@ -226,15 +255,43 @@ func (wt *WebSway) ServeFile(w http.ResponseWriter, r *http.Request) {
 	// Apply caching
 	wt.ApplyCacheHeaders(w, r.URL.Path)
 
-	// Set MIME type based on extension
-	ext := filepath.Ext(storagePath)
-	mimeType := comm.Mime.GetType(ext)
-	if mimeType == "" {
-		mimeType = "application/octet-stream"
+	// Pages with includes change with their parts, so only their content makes a validator
+	includes := wt.processesIncludes(storagePath)
+	etag, modTime := comm.StatETag(info), info.ModTime
+	if includes {
+		etag, modTime = "", time.Time{}
+	}
+	if !includes && comm.ServeNotModified(w, r, etag, modTime) {
+		return
+	}
+
+	// Announce critical assets of HTML entry points before reading the page
+	wt.applyPreloads(w, r, storagePath)
+
+	data, err := wt.FsProvider.ReadFile(storagePath)
+	if err != nil {
+		wt.NotFound(w, r)
+		return
+	}
+	if includes {
+		data = wt.processIncludes(r, storagePath, data)
+	}
+
+	// Content-Type, ETag, conditional and range handling
+	opts := &comm.ServeOptions{ETag: etag}
+	if wt.ContentTypeFor != nil {
+		opts.ContentType = wt.ContentTypeFor(storagePath)
+	}
+	comm.ServeBytes(w, r, storagePath, modTime, data, opts)
+}
+
+// modTime returns the modification time of a stored file, or zero if unknown
+func (wt *WebSway) modTime(storagePath string) time.Time {
+	info, err := wt.FsProvider.Stat(storagePath)
+	if err != nil {
+		return time.Time{}
 	}
-	w.Header().Set("Content-Type", mimeType)
-	w.WriteHeader(http.StatusOK)
-	w.Write(data)
+	return info.ModTime
 }
 
 func (wt *WebSway) ServeWebManifest(storagePath, prefix string, w http.ResponseWriter, r *http.Request) {
@@ -259,14 +316,13 @@ func (wt *WebSway) ServeServiceWorker(path, scope string, w http.ResponseWriter,
 	// Apply security headers
 	wt.ApplySecurityHeaders(w)
 	// Service Workers must have specific headers
-	w.Header().Set("Content-Type", "application/javascript")
 	w.Header().Set("Service-Worker-Allowed", scope)
 
 	// Apply caching
 	wt.ApplyCacheHeaders(w, path)
 
-	// Write response
-	w.WriteHeader(http.StatusOK)
-	w.Write(data)
+	comm.ServeBytes(w, r, path, wt.modTime(path), data, &comm.ServeOptions{
+		ContentType: "application/javascript",
+	})
 	return true
 }
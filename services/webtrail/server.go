@@ -2,8 +2,10 @@ package webtrail
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/go-xlite/wbx/comm"
+	"github.com/go-xlite/wbx/comm/routes"
 )
 
 type WebTrail struct {
@@ -11,6 +13,7 @@ type WebTrail struct {
 	// Note: The base path is NOT used in actual routing, only for helper methods
 	PathBase string // Optional base path for convenience (e.g., "/api" for documentation)
 	NotFound http.HandlerFunc
+	mounts   map[string]*WebTrail
 }
 
 // NewWebTrail creates a new WebTrail instance with proper routing capabilities
@@ -18,13 +21,79 @@ func NewWebTrail() *WebTrail {
 	wt := &WebTrail{
 		ServerCore: comm.NewServerCore(),
 		PathBase:   "",
+		mounts:     make(map[string]*WebTrail),
 	}
 	wt.NotFound = http.NotFound
 	return wt
 }
 
 func (wt *WebTrail) OnRequest(w http.ResponseWriter, r *http.Request) {
-	wt.Mux.ServeHTTP(w, r)
+	wt.Routes.ServeHTTP(w, r)
+}
+
+// Use adds middleware that runs for every route of this trail,
+// including the routes of trails mounted into it
+func (wt *WebTrail) Use(middleware ...func(http.Handler) http.Handler) *WebTrail {
+	for _, mw := range middleware {
+		wt.Mux.Use(mw)
+	}
+	return wt
+}
+
+// Mount attaches a child WebTrail under prefix. The prefix is stripped before the
+// child sees the request (/admin/users -> /users) and this trail's middleware runs
+// before the child's own. Mounted trails can be nested to any depth.
+func (wt *WebTrail) Mount(prefix string, child *WebTrail) *WebTrail {
+	prefix = "/" + strings.Trim(prefix, "/")
+	child.setPathBase(wt.MakePath(prefix))
+	if wt.mounts == nil {
+		wt.mounts = make(map[string]*WebTrail)
+	}
+	wt.mounts[prefix] = child
+
+	wt.Routes.ForwardPathFn(prefix, child.OnRequest)
+	wt.Routes.ForwardPathPrefixFn(prefix, child.OnRequest)
+	return wt
+}
+
+// setPathBase updates the PathBase of this trail and of every trail nested in it
+func (wt *WebTrail) setPathBase(pathBase string) {
+	wt.PathBase = pathBase
+	for prefix, child := range wt.mounts {
+		child.setPathBase(wt.MakePath(prefix))
+	}
+}
+
+// GetMounts returns the trails mounted directly into this trail, keyed by prefix
+func (wt *WebTrail) GetMounts() map[string]*WebTrail {
+	return wt.mounts
+}
+
+// GetRouteDocs returns the documentation of this trail's routes and of all mounted trails,
+// with paths expanded by PathBase
+func (wt *WebTrail) GetRouteDocs() []routes.RouteDoc {
+	docs := []routes.RouteDoc{}
+	for _, doc := range wt.Routes.GetRouteDocs() {
+		// Mount points are documented through the child's own routes
+		if _, isMount := wt.mounts["/"+strings.Trim(doc.Path, "/")]; isMount {
+			continue
+		}
+		doc.Path = wt.MakePath(doc.Path)
+		docs = append(docs, doc)
+	}
+	for _, child := range wt.mounts {
+		docs = append(docs, child.GetRouteDocs()...)
+	}
+	return docs
+}
+
+// EnableDocs registers a self-hosted documentation page (e.g. "/_routes") listing
+// every route of this trail and its mounted trails
+func (wt *WebTrail) EnableDocs(path string) {
+	wt.Routes.Describe(path, "Route documentation", "docs")
+	wt.Routes.GETPathFn(path, func(w http.ResponseWriter, r *http.Request) {
+		routes.ServeDocs(w, r, "API Routes", wt.GetRouteDocs())
+	})
 }
 
 // MakePath creates a full path by prepending the PathBase (if set)
@@ -0,0 +1,88 @@
+package helpers
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteAttachment sends reader as a file download named filename.
+// size is the content length, or -1 when unknown. When reader is an io.ReadSeeker
+// (or an io.ReaderAt with a known size) Range, If-Range and HEAD are handled by
+// http.ServeContent; other readers are streamed whole with Accept-Ranges: none.
+// A Content-Type set by the caller is kept, otherwise it is derived from the extension.
+func (h *XHelpers) WriteAttachment(w http.ResponseWriter, r *http.Request, filename string, reader io.Reader, size int64) {
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", Mime.GetType(filepath.Ext(filename)))
+	}
+	header.Set("Content-Disposition", ContentDisposition("attachment", filename))
+	header.Set("X-Content-Type-Options", "nosniff")
+
+	var seeker io.ReadSeeker
+	switch rd := reader.(type) {
+	case io.ReadSeeker:
+		seeker = rd
+	case io.ReaderAt:
+		if size >= 0 {
+			seeker = io.NewSectionReader(rd, 0, size)
+		}
+	}
+	if seeker != nil {
+		http.ServeContent(w, r, filename, time.Time{}, seeker)
+		return
+	}
+
+	header.Set("Accept-Ranges", "none")
+	if size >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if size >= 0 {
+		io.CopyN(w, reader, size)
+		return
+	}
+	io.Copy(w, reader)
+}
+
+// ContentDisposition builds a Content-Disposition value ("attachment" or "inline")
+// with an ASCII filename fallback and an RFC 5987 filename* for non-ASCII names
+func ContentDisposition(disposition, filename string) string {
+	filename = filepath.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if filename == "." || filename == "/" {
+		return disposition
+	}
+
+	var fallback strings.Builder
+	ascii := true
+	for _, c := range filename {
+		switch {
+		case c < 0x20 || c == 0x7f:
+			// Control characters are never valid in a header value
+		case c > 0x7e:
+			ascii = false
+			fallback.WriteByte('_')
+		case c == '"' || c == '\\':
+			fallback.WriteByte('\\')
+			fallback.WriteRune(c)
+		default:
+			fallback.WriteRune(c)
+		}
+	}
+
+	value := disposition + `; filename="` + fallback.String() + `"`
+	if !ascii {
+		// PathEscape leaves a few sub-delims RFC 5987 attr-chars do not allow
+		encoded := strings.NewReplacer("'", "%27", "(", "%28", ")", "%29", "*", "%2A", ",", "%2C", ";", "%3B", "=", "%3D", "@", "%40", ":", "%3A").
+			Replace(url.PathEscape(filename))
+		value += "; filename*=UTF-8''" + encoded
+	}
+	return value
+}
@@ -0,0 +1,59 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// maxPooledBuffer keeps unusually large responses from pinning memory in the pools
+const maxPooledBuffer = 64 << 10
+
+type jsonEncoder struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+	err error
+}
+
+var jsonEncoderPool = sync.Pool{
+	New: func() any {
+		je := &jsonEncoder{}
+		je.enc = json.NewEncoder(&je.buf)
+		return je
+	},
+}
+
+// SetJSONPretty toggles indented JSON output for WriteJSON (handy in development)
+func (h *XHelpers) SetJSONPretty(pretty bool) *XHelpers {
+	h.jsonPretty.Store(pretty)
+	return h
+}
+
+// SetEscapeHTML controls whether WriteJSON escapes <, > and & inside strings (default true)
+func (h *XHelpers) SetEscapeHTML(escape bool) *XHelpers {
+	h.jsonNoEscapeHTML.Store(!escape)
+	return h
+}
+
+// encodeJSON encodes data with the current options into a pooled buffer.
+// The result must be returned with releaseJSONEncoder.
+func (h *XHelpers) encodeJSON(data any) *jsonEncoder {
+	je := jsonEncoderPool.Get().(*jsonEncoder)
+	if h.jsonPretty.Load() {
+		je.enc.SetIndent("", "  ")
+	} else {
+		je.enc.SetIndent("", "")
+	}
+	je.enc.SetEscapeHTML(!h.jsonNoEscapeHTML.Load())
+	je.err = je.enc.Encode(data)
+	return je
+}
+
+func releaseJSONEncoder(je *jsonEncoder) {
+	if je.buf.Cap() > maxPooledBuffer {
+		return
+	}
+	je.buf.Reset()
+	je.err = nil
+	jsonEncoderPool.Put(je)
+}
@@ -1,10 +1,15 @@
 package helpers
 
 import (
+	"sync/atomic"
+
 	"github.com/go-xlite/wbx/comm"
 )
 
-type XHelpers struct{}
+type XHelpers struct {
+	jsonPretty       atomic.Bool // WriteJSON indents output
+	jsonNoEscapeHTML atomic.Bool // WriteJSON leaves <, > and & unescaped
+}
 
 var Mime = comm.Mime
 
@@ -0,0 +1,145 @@
+package helpers
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// PositionalList is the compact list format: column names once, then one
+// array of values per row in column order
+type PositionalList struct {
+	Columns []string `json:"columns"`
+	Data    [][]any  `json:"data"`
+	Total   int      `json:"total"` // Rows before any paging
+}
+
+// Column selects one positional value of a row
+type Column[T any] struct {
+	Name  string
+	Value func(row T) any
+}
+
+// Col is shorthand for a Column
+func Col[T any](name string, value func(row T) any) Column[T] {
+	return Column[T]{Name: name, Value: value}
+}
+
+// BuildPositional converts rows with explicit column selectors:
+//
+//	list := helpers.BuildPositional(users,
+//		helpers.Col("id", func(u *User) any { return u.ID }),
+//		helpers.Col("name", func(u *User) any { return u.Name }))
+func BuildPositional[T any](rows []T, columns ...Column[T]) *PositionalList {
+	list := &PositionalList{
+		Columns: make([]string, len(columns)),
+		Data:    make([][]any, 0, len(rows)),
+		Total:   len(rows),
+	}
+	for i, col := range columns {
+		list.Columns[i] = col.Name
+	}
+	for _, row := range rows {
+		values := make([]any, len(columns))
+		for i, col := range columns {
+			values[i] = col.Value(row)
+		}
+		list.Data = append(list.Data, values)
+	}
+	return list
+}
+
+// PositionalFromStructs converts a slice of structs (or struct pointers)
+// using their exported fields, named by the json tag when present. columns
+// picks and orders a subset; all fields are used when it is empty.
+func PositionalFromStructs[T any](rows []T, columns ...string) (*PositionalList, error) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("positional list: %s is not a struct", typ)
+	}
+
+	names, index := structColumns(typ)
+	if len(columns) > 0 {
+		for _, name := range columns {
+			if _, ok := index[name]; !ok {
+				return nil, fmt.Errorf("positional list: unknown column %q", name)
+			}
+		}
+		names = columns
+	}
+
+	list := &PositionalList{
+		Columns: slices.Clone(names),
+		Data:    make([][]any, 0, len(rows)),
+		Total:   len(rows),
+	}
+	for _, row := range rows {
+		v := reflect.ValueOf(row)
+		if v.Kind() == reflect.Pointer {
+			v = v.Elem()
+		}
+		values := make([]any, len(names))
+		if v.IsValid() {
+			for i, name := range names {
+				values[i] = v.FieldByIndex(index[name]).Interface()
+			}
+		}
+		list.Data = append(list.Data, values)
+	}
+	return list, nil
+}
+
+// structColumns returns the column names of typ in field order and their field indexes
+func structColumns(typ reflect.Type) ([]string, map[string][]int) {
+	var names []string
+	index := make(map[string][]int)
+	for _, field := range reflect.VisibleFields(typ) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		if _, dup := index[name]; dup {
+			continue
+		}
+		names = append(names, name)
+		index[name] = field.Index
+	}
+	return names, index
+}
+
+// Select returns a copy of pl reduced to columns, in that order
+func (pl *PositionalList) Select(columns ...string) (*PositionalList, error) {
+	positions := make([]int, len(columns))
+	for i, name := range columns {
+		positions[i] = slices.Index(pl.Columns, name)
+		if positions[i] < 0 {
+			return nil, fmt.Errorf("positional list: unknown column %q", name)
+		}
+	}
+	out := &PositionalList{
+		Columns: slices.Clone(columns),
+		Data:    make([][]any, 0, len(pl.Data)),
+		Total:   pl.Total,
+	}
+	for _, row := range pl.Data {
+		values := make([]any, len(positions))
+		for i, pos := range positions {
+			values[i] = row[pos]
+		}
+		out.Data = append(out.Data, values)
+	}
+	return out, nil
+}
@@ -0,0 +1,207 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Built-in fragments registered in Templates
+const (
+	TemplateErrorPage = "wbx/error"    // data: ErrorPageData
+	TemplateRedirect  = "wbx/redirect" // data: RedirectPageData
+)
+
+// ErrorPageData is rendered by the TemplateErrorPage fragment
+type ErrorPageData struct {
+	Status  int
+	Title   string
+	Message string
+	Code    string // Machine-readable error code shown under the message (optional)
+}
+
+// RedirectPageData is rendered by the TemplateRedirect fragment
+type RedirectPageData struct {
+	URL     string
+	Delay   int // Seconds before the browser follows URL
+	Message string
+}
+
+// TemplateRegistry caches parsed html/templates by name so handlers parse
+// their fragments once at startup instead of per request
+type TemplateRegistry struct {
+	mu        sync.RWMutex
+	templates map[string]*template.Template
+	funcs     template.FuncMap
+}
+
+// ErrorPageTemplate is the built-in TemplateErrorPage fragment, also the
+// default of proxy error pages
+var ErrorPageTemplate = template.Must(template.New(TemplateErrorPage).Parse(errorPageSource))
+
+// Templates is the shared registry used by WriteNamedTemplate
+var Templates = NewTemplateRegistry()
+
+var templateBufPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// NewTemplateRegistry creates a registry with the built-in error and redirect fragments
+func NewTemplateRegistry() *TemplateRegistry {
+	tr := &TemplateRegistry{templates: make(map[string]*template.Template)}
+	tr.Add(TemplateErrorPage, ErrorPageTemplate)
+	tr.MustRegister(TemplateRedirect, redirectPageSource)
+	return tr
+}
+
+// SetFuncs sets the functions available to templates registered afterwards
+func (tr *TemplateRegistry) SetFuncs(funcs template.FuncMap) *TemplateRegistry {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.funcs = funcs
+	return tr
+}
+
+// Register parses src and stores it under name, replacing any previous template
+func (tr *TemplateRegistry) Register(name, src string) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tmpl := template.New(name)
+	if tr.funcs != nil {
+		tmpl = tmpl.Funcs(tr.funcs)
+	}
+	tmpl, err := tmpl.Parse(src)
+	if err != nil {
+		return fmt.Errorf("template %q: %w", name, err)
+	}
+	tr.templates[name] = tmpl
+	return nil
+}
+
+// MustRegister is like Register but panics on parse errors, for package-level setup
+func (tr *TemplateRegistry) MustRegister(name, src string) *TemplateRegistry {
+	if err := tr.Register(name, src); err != nil {
+		panic(err)
+	}
+	return tr
+}
+
+// Add stores an already parsed template under name
+func (tr *TemplateRegistry) Add(name string, tmpl *template.Template) *TemplateRegistry {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.templates[name] = tmpl
+	return tr
+}
+
+// Get returns the template registered under name, or nil
+func (tr *TemplateRegistry) Get(name string) *template.Template {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	return tr.templates[name]
+}
+
+// WriteTemplate renders tmpl (or its associated template name, when not empty)
+// as an HTML response with status 200. The output is buffered, so a failing
+// template results in a 500 instead of a half-written page.
+func (h *XHelpers) WriteTemplate(w http.ResponseWriter, tmpl *template.Template, name string, data any) {
+	h.WriteTemplateStatus(w, http.StatusOK, tmpl, name, data)
+}
+
+// WriteTemplateStatus is WriteTemplate with an explicit status code
+func (h *XHelpers) WriteTemplateStatus(w http.ResponseWriter, status int, tmpl *template.Template, name string, data any) {
+	buf := templateBufPool.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			buf.Reset()
+			templateBufPool.Put(buf)
+		}
+	}()
+
+	var err error
+	if name == "" {
+		err = tmpl.Execute(buf, data)
+	} else {
+		err = tmpl.ExecuteTemplate(buf, name, data)
+	}
+	if err != nil {
+		h.WriteInternalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// WriteNamedTemplate renders a template from the shared Templates registry
+func (h *XHelpers) WriteNamedTemplate(w http.ResponseWriter, status int, name string, data any) {
+	tmpl := Templates.Get(name)
+	if tmpl == nil {
+		h.WriteInternalError(w, fmt.Errorf("template %q is not registered", name))
+		return
+	}
+	h.WriteTemplateStatus(w, status, tmpl, "", data)
+}
+
+// WriteErrorPage renders the TemplateErrorPage fragment
+func (h *XHelpers) WriteErrorPage(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Cache-Control", "no-store")
+	h.WriteNamedTemplate(w, status, TemplateErrorPage, ErrorPageData{
+		Status:  status,
+		Title:   http.StatusText(status),
+		Message: message,
+	})
+}
+
+// WriteMetaRefresh renders the TemplateRedirect fragment, sending the browser
+// to url after delay seconds (useful when a 3xx would be followed too early,
+// e.g. after showing a confirmation). Only http(s) and relative URLs are
+// followed; the refresh attribute is not sanitized by html/template.
+func (h *XHelpers) WriteMetaRefresh(w http.ResponseWriter, url string, delay int, message string) {
+	w.Header().Set("Cache-Control", "no-store")
+	h.WriteNamedTemplate(w, http.StatusOK, TemplateRedirect, RedirectPageData{
+		URL:     safeRefreshURL(url),
+		Delay:   delay,
+		Message: message,
+	})
+}
+
+// safeRefreshURL returns target, or "#" when it has a scheme other than http(s)
+// (e.g. javascript:) or a character that could end the refresh URL early
+func safeRefreshURL(target string) string {
+	u, err := url.Parse(target)
+	if err != nil || strings.ContainsAny(target, ";'\"") {
+		return "#"
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https":
+		return target
+	}
+	return "#"
+}
+
+const errorPageSource = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Status}} {{.Title}}</title>
+<style>body{font:16px/1.5 system-ui,sans-serif;color:#333;max-width:36em;margin:12vh auto;padding:0 1em}h1{font-size:1.5em}code{color:#888}</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Message}}<p>{{.Message}}</p>{{end}}
+<p><code>{{.Status}}{{if .Code}} {{.Code}}{{end}}</code></p>
+</body>
+</html>
+`
+
+const redirectPageSource = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta http-equiv="refresh" content="{{.Delay}};url={{.URL}}"><title>Redirecting</title></head>
+<body><p>{{if .Message}}{{.Message}} {{end}}<a href="{{.URL}}">Continue</a></p></body></html>
+`
@@ -0,0 +1,334 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-xlite/wbx/comm"
+)
+
+// Upload errors, mapped to status codes by WriteUploadError
+var (
+	ErrNotMultipart         = errors.New("upload: request is not multipart/form-data")
+	ErrUploadTooLarge       = errors.New("upload: too large")
+	ErrUploadTypeNotAllowed = errors.New("upload: file type not allowed")
+	ErrTooManyParts         = errors.New("upload: too many parts")
+)
+
+// UploadLimits bounds what ReceiveUploads accepts. Zero values use the defaults.
+type UploadLimits struct {
+	MaxPartSize  int64 // Largest single file (default 32MB)
+	MaxTotalSize int64 // Whole request body, 0 = only bounded by the part limits
+	MaxFieldSize int64 // Largest non-file field value (default 64KB)
+	MaxParts     int   // Files plus fields (default 32)
+	// AllowedTypes lists sniffed MIME types that are accepted; entries ending in
+	// "/" match a whole family (e.g. "image/"). Empty accepts everything.
+	AllowedTypes []string
+	// AllowedExtensions lists accepted filename extensions (".jpg"). Empty accepts everything.
+	AllowedExtensions []string
+	// Name decides the stored path (relative to the target dir) for an upload;
+	// the default keeps the sanitized client filename and adds -1, -2... on collisions
+	Name func(field, filename string) string
+	// Perm is the file mode of stored files (default 0644)
+	Perm fs.FileMode
+}
+
+// UploadedFile describes a stored upload
+type UploadedFile struct {
+	Field        string `json:"field"`
+	Filename     string `json:"filename"` // Sanitized client filename
+	Path         string `json:"path"`     // Path in the target adapter
+	Size         int64  `json:"size"`
+	ContentType  string `json:"content_type"`  // Sniffed from the content
+	DeclaredType string `json:"declared_type"` // As sent by the client
+	SHA256       string `json:"sha256"`
+}
+
+// UploadResult holds the stored files and the plain form fields of an upload
+type UploadResult struct {
+	Files  []UploadedFile      `json:"files"`
+	Fields map[string][]string `json:"fields"`
+}
+
+func (l UploadLimits) withDefaults() UploadLimits {
+	if l.MaxPartSize <= 0 {
+		l.MaxPartSize = 32 << 20
+	}
+	if l.MaxFieldSize <= 0 {
+		l.MaxFieldSize = 64 << 10
+	}
+	if l.MaxParts <= 0 {
+		l.MaxParts = 32
+	}
+	if l.Perm == 0 {
+		l.Perm = 0644
+	}
+	return l
+}
+
+// ReceiveUploads streams the files of a multipart/form-data request into dst
+// under dir, enforcing limits part by part. Adapters implementing
+// comm.IFsStreamWriter receive the data without buffering whole files; others
+// get a single WriteFile per part. If any part fails, files already stored are
+// removed (when the adapter supports it) and the error is returned.
+func (h *XHelpers) ReceiveUploads(w http.ResponseWriter, r *http.Request, dst comm.IFsAdapter, dir string, limits UploadLimits) (*UploadResult, error) {
+	limits = limits.withDefaults()
+	if dst.IsReadOnly() {
+		return nil, &fs.PathError{Op: "upload", Path: dir, Err: fs.ErrPermission}
+	}
+	if limits.MaxTotalSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, limits.MaxTotalSize)
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotMultipart, err)
+	}
+
+	result := &UploadResult{Fields: make(map[string][]string)}
+	stored, err := h.receiveParts(mr, dst, dir, limits, result)
+	if err != nil {
+		if remover, ok := dst.(comm.IFsStreamWriter); ok {
+			for _, p := range stored {
+				remover.Remove(p)
+			}
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// receiveParts reads every part into result and returns the paths stored so far
+func (h *XHelpers) receiveParts(mr *multipart.Reader, dst comm.IFsAdapter, dir string, limits UploadLimits, result *UploadResult) ([]string, error) {
+	var stored []string
+	for parts := 0; ; parts++ {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return stored, nil
+		}
+		if err != nil {
+			return stored, uploadReadError(err)
+		}
+		if parts >= limits.MaxParts {
+			part.Close()
+			return stored, ErrTooManyParts
+		}
+
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, limits.MaxFieldSize+1))
+			part.Close()
+			if err != nil {
+				return stored, uploadReadError(err)
+			}
+			if int64(len(value)) > limits.MaxFieldSize {
+				return stored, fmt.Errorf("%w: field %q", ErrUploadTooLarge, part.FormName())
+			}
+			result.Fields[part.FormName()] = append(result.Fields[part.FormName()], string(value))
+			continue
+		}
+
+		file, err := storePart(part, dst, dir, limits)
+		part.Close()
+		if err != nil {
+			return stored, err
+		}
+		stored = append(stored, file.Path)
+		result.Files = append(result.Files, *file)
+	}
+}
+
+// storePart validates and stores one file part. A failed part leaves nothing
+// stored.
+func storePart(part *multipart.Part, dst comm.IFsAdapter, dir string, limits UploadLimits) (*UploadedFile, error) {
+	filename := SanitizeFilename(part.FileName())
+	if !extensionAllowed(filename, limits.AllowedExtensions) {
+		return nil, fmt.Errorf("%w: %s", ErrUploadTypeNotAllowed, filename)
+	}
+
+	// Sniff from the first bytes rather than trusting the declared type
+	head := make([]byte, 512)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, uploadReadError(err)
+	}
+	head = head[:n]
+	sniffed := http.DetectContentType(head)
+	if !typeAllowed(sniffed, limits.AllowedTypes) {
+		return nil, fmt.Errorf("%w: %s (%s)", ErrUploadTypeNotAllowed, filename, sniffed)
+	}
+
+	file := &UploadedFile{
+		Field:        part.FormName(),
+		Filename:     filename,
+		ContentType:  sniffed,
+		DeclaredType: part.Header.Get("Content-Type"),
+	}
+
+	hash := sha256.New()
+	src := io.TeeReader(io.LimitReader(io.MultiReader(bytes.NewReader(head), part), limits.MaxPartSize+1), hash)
+
+	if sw, ok := dst.(comm.IFsStreamWriter); ok {
+		var out comm.IFsFileWriter
+		if limits.Name != nil {
+			file.Path = path.Join(dir, limits.Name(part.FormName(), filename))
+			out, err = sw.Create(file.Path, limits.Perm)
+		} else {
+			file.Path, out, err = createUnique(sw, dir, filename, limits.Perm)
+		}
+		if err != nil {
+			return nil, err
+		}
+		file.Size, err = io.Copy(out, src)
+		if err == nil && file.Size > limits.MaxPartSize {
+			err = fmt.Errorf("%w: %s", ErrUploadTooLarge, filename)
+		}
+		if err != nil {
+			out.Abort()
+			return nil, uploadReadError(err)
+		}
+		if err := out.Close(); err != nil {
+			return nil, err
+		}
+	} else {
+		if limits.Name != nil {
+			file.Path = path.Join(dir, limits.Name(part.FormName(), filename))
+		} else {
+			file.Path = path.Join(dir, uniqueName(dst, dir, filename))
+		}
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return nil, uploadReadError(err)
+		}
+		if int64(len(data)) > limits.MaxPartSize {
+			return nil, fmt.Errorf("%w: %s", ErrUploadTooLarge, filename)
+		}
+		if err := dst.WriteFile(file.Path, data, limits.Perm); err != nil {
+			return nil, err
+		}
+		file.Size = int64(len(data))
+	}
+
+	file.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return file, nil
+}
+
+// WriteUploadError answers a ReceiveUploads error: 413 for size limits, 415 for
+// rejected types, 400 for malformed requests and 500 otherwise. Only the
+// upload sentinel errors have their text sent; anything else can carry
+// storage paths, so it is reported instead.
+func (h *XHelpers) WriteUploadError(w http.ResponseWriter, err error) {
+	var status int
+	switch {
+	case errors.Is(err, ErrUploadTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrUploadTypeNotAllowed):
+		status = http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrNotMultipart), errors.Is(err, ErrTooManyParts), errors.Is(err, multipart.ErrMessageTooLarge):
+		status = http.StatusBadRequest
+	case errors.Is(err, fs.ErrPermission):
+		h.WriteJSON(w, http.StatusForbidden, map[string]string{"error": http.StatusText(http.StatusForbidden)})
+		return
+	default:
+		h.WriteInternalError(w, err)
+		return
+	}
+	h.WriteJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// SanitizeFilename reduces a client-supplied filename to a safe base name
+func SanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(c rune) rune {
+		if c < 0x20 || c == 0x7f || strings.ContainsRune(`/\:*?"<>|`, c) {
+			return '_'
+		}
+		return c
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	if name == "" {
+		return "upload"
+	}
+	return name
+}
+
+// maxUniqueAttempts bounds the -N suffixes tried for a colliding filename
+const maxUniqueAttempts = 1000
+
+// uniqueName returns filename, or filename-N.ext when it already exists in dir.
+// Only for adapters without CreateNew; the name can be taken before it is written.
+func uniqueName(dst comm.IFsAdapter, dir, filename string) string {
+	name := filename
+	for i := 1; i < maxUniqueAttempts && dst.Exists(path.Join(dir, name)); i++ {
+		name = suffixedName(filename, i)
+	}
+	return name
+}
+
+// createUnique creates dir/filename, or dir/filename-N.ext when taken, with
+// CreateNew so concurrent uploads of the same name never share a file
+func createUnique(sw comm.IFsStreamWriter, dir, filename string, perm fs.FileMode) (string, comm.IFsFileWriter, error) {
+	name := filename
+	for i := 1; ; i++ {
+		p := path.Join(dir, name)
+		out, err := sw.CreateNew(p, perm)
+		if err == nil {
+			return p, out, nil
+		}
+		if !errors.Is(err, fs.ErrExist) || i >= maxUniqueAttempts {
+			return "", nil, err
+		}
+		name = suffixedName(filename, i)
+	}
+}
+
+// suffixedName inserts -n before the extension of filename
+func suffixedName(filename string, n int) string {
+	ext := path.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + "-" + strconv.Itoa(n) + ext
+}
+
+func extensionAllowed(filename string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	ext := strings.ToLower(path.Ext(filename))
+	for _, a := range allowed {
+		if strings.ToLower(a) == ext || "."+strings.ToLower(a) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+func typeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	for _, a := range allowed {
+		if a == mediaType || (strings.HasSuffix(a, "/") && strings.HasPrefix(mediaType, a)) {
+			return true
+		}
+	}
+	return false
+}
+
+// uploadReadError maps body limit errors to ErrUploadTooLarge
+func uploadReadError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return fmt.Errorf("%w: request body exceeds %d bytes", ErrUploadTooLarge, maxErr.Limit)
+	}
+	return err
+}
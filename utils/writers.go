@@ -1,15 +1,31 @@
 package helpers
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+
+	"github.com/go-xlite/wbx/comm"
 )
 
+// WriteJSON encodes data into a pooled buffer and writes it with the given status.
+// Encoding errors are reported as 500 instead of a truncated body.
 func (h *XHelpers) WriteJSON(w http.ResponseWriter, status int, data any) {
+	je := h.encodeJSON(data)
+	defer releaseJSONEncoder(je)
+	if je.err != nil {
+		h.WriteInternalError(w, je.err)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(je.buf.Len()))
 	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
+	w.Write(je.buf.Bytes())
+}
+
+// WriteJSONStatusOK is the 200 OK fast path of WriteJSON
+func (h *XHelpers) WriteJSONStatusOK(w http.ResponseWriter, data any) {
+	h.WriteJSON(w, http.StatusOK, data)
 }
 
 func (h *XHelpers) WriteHTMLText(w http.ResponseWriter, status int, data string) {
@@ -46,9 +62,12 @@ func (h *XHelpers) WriteNotFound(w http.ResponseWriter) {
 	w.Write([]byte("404 - Not Found"))
 }
 
+// WriteInternalError answers 500 and reports err; its text stays off the
+// response, where it could leak internal details
 func (h *XHelpers) WriteInternalError(w http.ResponseWriter, err error) {
+	comm.ReportError(nil, err, nil, nil)
 	w.WriteHeader(http.StatusInternalServerError)
-	w.Write([]byte("500 - Internal Server Error: " + err.Error()))
+	w.Write([]byte("500 - Internal Server Error"))
 }
 
 func (h *XHelpers) WriteWebManifestBytes(w http.ResponseWriter, data []byte) {
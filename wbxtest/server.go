@@ -0,0 +1,103 @@
+// Package wbxtest provides helpers for integration tests of servers built on wbx:
+// a WebLite served by httptest, WebSocket and SSE test clients, and a fake
+// SessionService.
+package wbxtest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-xlite/wbx/weblite"
+)
+
+// Server is a WebLite served on an ephemeral local port
+type Server struct {
+	URL     string // Base URL, e.g. http://127.0.0.1:54321
+	WebLite *weblite.WebLite
+	ts      *httptest.Server
+	t       testing.TB
+}
+
+// NewServer starts a WebLite named "test" on an ephemeral port. configure (optional)
+// registers routes, middleware or a SessionManager before the server starts.
+// The server is closed automatically when the test ends.
+func NewServer(t testing.TB, configure func(wl *weblite.WebLite)) *Server {
+	t.Helper()
+	wl := weblite.NewWebLite("test")
+	if configure != nil {
+		configure(wl)
+	}
+
+	// WebLite builds its chain once and rebuilds it when middleware or the
+	// SessionManager change, so additions after NewServer are still picked up
+	ts := httptest.NewServer(wl)
+	t.Cleanup(ts.Close)
+
+	return &Server{URL: ts.URL, WebLite: wl, ts: ts, t: t}
+}
+
+// Handle registers handler for an exact path
+func (s *Server) Handle(path string, handler http.HandlerFunc) *Server {
+	s.WebLite.GetRoutes().HandlePathFn(path, handler)
+	return s
+}
+
+// HandlePrefix registers handler for a path prefix (e.g. a service's OnRequest)
+func (s *Server) HandlePrefix(prefix string, handler http.HandlerFunc) *Server {
+	s.WebLite.GetRoutes().HandlePathPrefixFn(prefix, handler)
+	return s
+}
+
+// Client returns an HTTP client for the server
+func (s *Server) Client() *http.Client {
+	return s.ts.Client()
+}
+
+// Do sends req, failing the test on transport errors
+func (s *Server) Do(req *http.Request) *http.Response {
+	s.t.Helper()
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		s.t.Fatalf("wbxtest: %s %s: %v", req.Method, req.URL, err)
+	}
+	s.t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// NewRequest builds a request for path on this server
+func (s *Server) NewRequest(method, path string, body io.Reader) *http.Request {
+	s.t.Helper()
+	req, err := http.NewRequest(method, s.URL+path, body)
+	if err != nil {
+		s.t.Fatalf("wbxtest: new request: %v", err)
+	}
+	return req
+}
+
+// Get performs a GET request for path
+func (s *Server) Get(path string) *http.Response {
+	s.t.Helper()
+	return s.Do(s.NewRequest(http.MethodGet, path, nil))
+}
+
+// Post performs a POST request for path with the given content type and body
+func (s *Server) Post(path, contentType, body string) *http.Response {
+	s.t.Helper()
+	req := s.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	return s.Do(req)
+}
+
+// ReadBody reads and closes the response body, failing the test on error
+func ReadBody(t testing.TB, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("wbxtest: read body: %v", err)
+	}
+	return string(data)
+}
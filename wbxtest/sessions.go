@@ -0,0 +1,78 @@
+package wbxtest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/go-xlite/wbx/weblite"
+)
+
+// ErrInvalidSession is returned by FakeSessions for unknown or revoked tokens
+var ErrInvalidSession = errors.New("invalid session")
+
+// FakeSessions is an in-memory weblite.SessionService for tests
+type FakeSessions struct {
+	sessions map[string]any
+	next     int
+	mu       sync.Mutex
+}
+
+var _ weblite.SessionService = (*FakeSessions)(nil)
+
+// NewFakeSessions creates an empty session store
+func NewFakeSessions() *FakeSessions {
+	return &FakeSessions{sessions: make(map[string]any)}
+}
+
+// Validate returns the data of a live session
+func (fs *FakeSessions) Validate(token string) (interface{}, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	data, ok := fs.sessions[token]
+	if !ok {
+		return nil, ErrInvalidSession
+	}
+	return data, nil
+}
+
+// Issue creates a session holding data
+func (fs *FakeSessions) Issue(data interface{}) (string, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.next++
+	token := fmt.Sprintf("test-session-%d", fs.next)
+	fs.sessions[token] = data
+	return token, nil
+}
+
+// Refresh replaces token with a new one carrying the same data
+func (fs *FakeSessions) Refresh(token string) (string, error) {
+	fs.mu.Lock()
+	data, ok := fs.sessions[token]
+	delete(fs.sessions, token)
+	fs.mu.Unlock()
+	if !ok {
+		return "", ErrInvalidSession
+	}
+	return fs.Issue(data)
+}
+
+// Revoke invalidates token
+func (fs *FakeSessions) Revoke(token string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if _, ok := fs.sessions[token]; !ok {
+		return ErrInvalidSession
+	}
+	delete(fs.sessions, token)
+	return nil
+}
+
+// Login issues a session for data and returns a cookie named cookieName carrying it,
+// ready to add to a test request
+func (fs *FakeSessions) Login(cookieName string, data any) *http.Cookie {
+	token, _ := fs.Issue(data)
+	return &http.Cookie{Name: cookieName, Value: token}
+}
@@ -0,0 +1,120 @@
+package wbxtest
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// SSEEvent is one parsed Server-Sent Events frame
+type SSEEvent struct {
+	Event string // "message" when the frame has no event field
+	Data  string // data lines joined with '\n'
+	ID    string
+	Retry int // milliseconds, 0 when absent
+}
+
+// SSEClient reads and parses an event stream in the background
+type SSEClient struct {
+	Response *http.Response
+	events   chan SSEEvent
+	cancel   context.CancelFunc
+	t        testing.TB
+}
+
+// ConnectSSE opens an event stream for path on s
+func (s *Server) ConnectSSE(path string) *SSEClient {
+	s.t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	req := s.NewRequest(http.MethodGet, path, nil).WithContext(ctx)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		cancel()
+		s.t.Fatalf("wbxtest: connect sse %s: %v", path, err)
+	}
+
+	c := &SSEClient{Response: resp, events: make(chan SSEEvent, 64), cancel: cancel, t: s.t}
+	s.t.Cleanup(c.Close)
+	go c.read()
+	return c
+}
+
+// Next returns the next event, failing the test after timeout or if the stream ended
+func (c *SSEClient) Next(timeout time.Duration) SSEEvent {
+	c.t.Helper()
+	select {
+	case ev, ok := <-c.events:
+		if !ok {
+			c.t.Fatalf("wbxtest: sse stream closed")
+		}
+		return ev
+	case <-time.After(timeout):
+		c.t.Fatalf("wbxtest: no sse event within %s", timeout)
+	}
+	return SSEEvent{}
+}
+
+// NextOfType skips events until one with the given event name arrives
+func (c *SSEClient) NextOfType(event string, timeout time.Duration) SSEEvent {
+	c.t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		ev := c.Next(time.Until(deadline))
+		if ev.Event == event {
+			return ev
+		}
+	}
+}
+
+// Close ends the stream
+func (c *SSEClient) Close() {
+	c.cancel()
+	c.Response.Body.Close()
+}
+
+// read parses frames until the stream ends
+func (c *SSEClient) read() {
+	defer close(c.events)
+	scanner := bufio.NewScanner(c.Response.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	var ev SSEEvent
+	var data []string
+	hasFields := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if hasFields {
+				if ev.Event == "" {
+					ev.Event = "message"
+				}
+				ev.Data = strings.Join(data, "\n")
+				c.events <- ev
+			}
+			ev, data, hasFields = SSEEvent{}, nil, false
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // comment
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		hasFields = true
+		switch field {
+		case "event":
+			ev.Event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			ev.ID = value
+		case "retry":
+			ev.Retry, _ = strconv.Atoi(value)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package wbxtest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WSClient is a WebSocket test client
+type WSClient struct {
+	Conn *websocket.Conn
+	t    testing.TB
+}
+
+// DialWS connects to path on s over WebSocket (header may be nil)
+func (s *Server) DialWS(path string, header http.Header) *WSClient {
+	s.t.Helper()
+	url := "ws" + strings.TrimPrefix(s.URL, "http") + path
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		s.t.Fatalf("wbxtest: dial %s: %v", url, err)
+	}
+	s.t.Cleanup(func() { conn.Close() })
+	return &WSClient{Conn: conn, t: s.t}
+}
+
+// Send writes a text message
+func (c *WSClient) Send(message string) {
+	c.t.Helper()
+	if err := c.Conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+		c.t.Fatalf("wbxtest: ws send: %v", err)
+	}
+}
+
+// Receive reads the next message, failing the test after timeout
+func (c *WSClient) Receive(timeout time.Duration) string {
+	c.t.Helper()
+	c.Conn.SetReadDeadline(time.Now().Add(timeout))
+	_, data, err := c.Conn.ReadMessage()
+	if err != nil {
+		c.t.Fatalf("wbxtest: ws receive: %v", err)
+	}
+	return string(data)
+}
+
+// ReceiveLines reads the next message and splits it on newlines, since WebSock
+// batches queued messages into one frame separated by '\n'
+func (c *WSClient) ReceiveLines(timeout time.Duration) []string {
+	c.t.Helper()
+	return strings.Split(c.Receive(timeout), "\n")
+}
+
+// Close sends a normal close frame and closes the connection
+func (c *WSClient) Close() {
+	c.Conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	c.Conn.Close()
+}
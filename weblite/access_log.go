@@ -0,0 +1,188 @@
+package weblite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-xlite/wbx/comm"
+)
+
+// AccessLogFormat selects how records are written to the access log writer
+type AccessLogFormat int
+
+const (
+	AccessLogJSON     AccessLogFormat = iota // One JSON object per line
+	AccessLogCombined                        // Apache/NCSA combined log format
+)
+
+// AccessRecord describes one served request
+type AccessRecord struct {
+	Time      time.Time     `json:"time"`
+	Method    string        `json:"method"`
+	Host      string        `json:"host"`
+	Path      string        `json:"path"`
+	Query     string        `json:"query,omitempty"`
+	Proto     string        `json:"proto"`
+	Status    int           `json:"status"`
+	Bytes     int64         `json:"bytes"`
+	Latency   time.Duration `json:"-"`
+	LatencyMS float64       `json:"latency_ms"`
+	RemoteIP  string        `json:"remote_ip"`
+	User      string        `json:"user,omitempty"`
+	Referer   string        `json:"referer,omitempty"`
+	UserAgent string        `json:"user_agent,omitempty"`
+}
+
+// AccessLog writes an AccessRecord for every request to Writer in Format
+// and/or hands it to Sink
+type AccessLog struct {
+	Writer io.Writer
+	Format AccessLogFormat
+	Sink   func(rec *AccessRecord)
+	// User names the session user of a request; by default string session data is used
+	User func(r *http.Request) string
+	// Skip excludes requests from the log, e.g. health checks
+	Skip func(r *http.Request) bool
+	mu   sync.Mutex // Serializes writes to Writer
+}
+
+type accessRecordKey struct{}
+
+// EnableAccessLog logs every request of every listener to w (may be nil when
+// only a sink is used) in format
+//
+//	wl.EnableAccessLog(os.Stdout, weblite.AccessLogCombined)
+func (wl *WebLite) EnableAccessLog(w io.Writer, format AccessLogFormat) *AccessLog {
+	al := &AccessLog{Writer: w, Format: format}
+	wl.mu.Lock()
+	wl.accessLog = al
+	wl.mu.Unlock()
+	return al
+}
+
+// SetSink hands every record to fn, e.g. to forward it to a log pipeline
+func (al *AccessLog) SetSink(fn func(rec *AccessRecord)) *AccessLog {
+	al.Sink = fn
+	return al
+}
+
+// SetUserFunc sets how the session user of a request is named
+func (al *AccessLog) SetUserFunc(fn func(r *http.Request) string) *AccessLog {
+	al.User = fn
+	return al
+}
+
+// SetSkip excludes requests matching fn from the log
+func (al *AccessLog) SetSkip(fn func(r *http.Request) bool) *AccessLog {
+	al.Skip = fn
+	return al
+}
+
+// Middleware records the request and writes it once next has returned.
+// It wraps a whole listener so rejections by limits and sessions are logged too.
+func (al *AccessLog) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if al.Skip != nil && al.Skip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := &AccessRecord{
+			Time:      time.Now(),
+			Method:    r.Method,
+			Host:      r.Host,
+			Path:      r.URL.Path,
+			Query:     r.URL.RawQuery,
+			Proto:     r.Proto,
+			RemoteIP:  comm.ClientIP(r),
+			Referer:   r.Referer(),
+			UserAgent: r.UserAgent(),
+		}
+		cw := comm.NewCaptureWriter(w)
+		defer func() {
+			rec.Status = cw.Status()
+			if rec.Status == 0 {
+				// Hijacked connections (WebSockets) answered 101 themselves;
+				// net/http sends 200 for handlers that wrote nothing
+				rec.Status = http.StatusOK
+				if cw.Hijacked() {
+					rec.Status = http.StatusSwitchingProtocols
+				}
+			}
+			rec.Bytes = cw.BytesWritten()
+			rec.Latency = time.Since(rec.Time)
+			rec.LatencyMS = float64(rec.Latency.Microseconds()) / 1000
+			al.write(rec)
+		}()
+		next.ServeHTTP(cw, r.WithContext(context.WithValue(r.Context(), accessRecordKey{}, rec)))
+	})
+}
+
+// annotate fills the fields known only past the session and proxy middleware
+func (al *AccessLog) annotate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rec, ok := r.Context().Value(accessRecordKey{}).(*AccessRecord); ok {
+			rec.RemoteIP = comm.ClientIP(r)
+			rec.User = al.user(r)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (al *AccessLog) user(r *http.Request) string {
+	if al.User != nil {
+		return al.User(r)
+	}
+	if data, ok := GetSessionContext(r.Context()); ok {
+		if name, ok := data.(string); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+func (al *AccessLog) write(rec *AccessRecord) {
+	if al.Sink != nil {
+		al.Sink(rec)
+	}
+	if al.Writer == nil {
+		return
+	}
+	var line []byte
+	if al.Format == AccessLogCombined {
+		line = []byte(rec.Combined() + "\n")
+	} else {
+		line, _ = json.Marshal(rec)
+		line = append(line, '\n')
+	}
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	al.Writer.Write(line)
+}
+
+// Combined formats rec in the Apache combined log format
+func (rec *AccessRecord) Combined() string {
+	uri := rec.Path
+	if rec.Query != "" {
+		uri += "?" + rec.Query
+	}
+	bytes := "-"
+	if rec.Bytes > 0 {
+		bytes = strconv.FormatInt(rec.Bytes, 10)
+	}
+	return fmt.Sprintf("%s - %s [%s] %q %d %s %q %q",
+		dash(rec.RemoteIP), dash(rec.User), rec.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		rec.Method+" "+uri+" "+rec.Proto, rec.Status, bytes, dash(rec.Referer), dash(rec.UserAgent))
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
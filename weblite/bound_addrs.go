@@ -0,0 +1,86 @@
+package weblite
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// boundAddr is the socket address a running server accepted connections on
+type boundAddr struct {
+	server *http.Server
+	addr   net.Addr
+}
+
+// BoundAddrs returns the addresses the running servers are actually bound to,
+// in bind order. Unlike GetAddr it reports the OS-assigned port of listeners
+// configured with port "0".
+func (wl *WebLite) BoundAddrs() []string {
+	wl.mu.RLock()
+	defer wl.mu.RUnlock()
+	addrs := make([]string, 0, len(wl.bound))
+	for _, b := range wl.bound {
+		addrs = append(addrs, b.addr.String())
+	}
+	return addrs
+}
+
+// WaitBound blocks until at least n servers are bound and returns their
+// addresses, or fails when ctx ends first. Typical use in tests:
+//
+//	go wl.Start()
+//	addrs, err := wl.WaitBound(ctx, 1)
+func (wl *WebLite) WaitBound(ctx context.Context, n int) ([]string, error) {
+	for {
+		wl.mu.Lock()
+		if len(wl.bound) >= n {
+			wl.mu.Unlock()
+			return wl.BoundAddrs(), nil
+		}
+		changed := wl.boundChangedLocked()
+		wl.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// recordBound registers the socket address of server
+func (wl *WebLite) recordBound(server *http.Server, addr net.Addr) {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	wl.bound = append(wl.bound, boundAddr{server: server, addr: addr})
+	wl.notifyBoundLocked()
+}
+
+// forgetBound removes server once it stopped serving
+func (wl *WebLite) forgetBound(server *http.Server) {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	for i, b := range wl.bound {
+		if b.server == server {
+			wl.bound = append(wl.bound[:i], wl.bound[i+1:]...)
+			break
+		}
+	}
+	wl.notifyBoundLocked()
+}
+
+// boundChangedLocked returns the channel closed on the next change; wl.mu must be held
+func (wl *WebLite) boundChangedLocked() chan struct{} {
+	if wl.boundChanged == nil {
+		wl.boundChanged = make(chan struct{})
+	}
+	return wl.boundChanged
+}
+
+// notifyBoundLocked wakes WaitBound callers; wl.mu must be held
+func (wl *WebLite) notifyBoundLocked() {
+	if wl.boundChanged != nil {
+		close(wl.boundChanged)
+		wl.boundChanged = nil
+	}
+}
@@ -1,20 +1,64 @@
 package weblite
 
 import (
+	"bufio"
 	"context"
+	"fmt"
 	"net"
+	"net/http"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
+
+	"github.com/go-xlite/wbx/comm"
 )
 
+// CloudflareTuning holds the TCP settings applied to OptimizeCloudflare listeners
+type CloudflareTuning struct {
+	MaxSegmentSize int           // TCP_MAXSEG (default 1220, 0 = kernel default)
+	KeepAlive      time.Duration // Keep-alive period for accepted connections (0 = Go default, <0 disables)
+	NoDelay        bool          // TCP_NODELAY on accepted connections (default true)
+	Backlog        int           // Listen backlog (0 = kernel default)
+}
+
+// DefaultCloudflareTuning returns the settings used when a listener does not override them.
+// TCP_MAXSEG 1220 avoids PMTU issues with Cloudflare's IPv6 tunnels.
+func DefaultCloudflareTuning() *CloudflareTuning {
+	return &CloudflareTuning{
+		MaxSegmentSize: 1220,
+		NoDelay:        true,
+	}
+}
+
+// parseCloudflareTuning reads cf_mss, cf_keepalive, cf_nodelay and cf_backlog config keys
+func parseCloudflareTuning(config map[string]string) *CloudflareTuning {
+	tuning := DefaultCloudflareTuning()
+	if v, err := strconv.Atoi(config["cf_mss"]); err == nil {
+		tuning.MaxSegmentSize = v
+	}
+	if v, err := time.ParseDuration(config["cf_keepalive"]); err == nil {
+		tuning.KeepAlive = v
+	}
+	if v := config["cf_nodelay"]; v != "" {
+		tuning.NoDelay = v != "false"
+	}
+	if v, err := strconv.Atoi(config["cf_backlog"]); err == nil {
+		tuning.Backlog = v
+	}
+	return tuning
+}
+
 // createListenerControl creates a listener control function for CloudFlare optimizations
-// Sets TCP_MAXSEG to 1220 to avoid PMTU issues with Cloudflare's IPv6 tunnels
-func createListenerControl() func(network, address string, c syscall.RawConn) error {
+func createListenerControl(tuning *CloudflareTuning) func(network, address string, c syscall.RawConn) error {
 	return func(network, address string, c syscall.RawConn) error {
+		if tuning.MaxSegmentSize <= 0 {
+			return nil
+		}
 		var sockOptErr error
 		err := c.Control(func(fd uintptr) {
-			// Set TCP_MAXSEG to 1220 to avoid PMTU issues with Cloudflare's IPv6 tunnels
 			// This is especially important for IPv6 connections through Cloudflare
-			sockOptErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_MAXSEG, 1220)
+			sockOptErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_MAXSEG, tuning.MaxSegmentSize)
 		})
 		if err != nil {
 			return err
@@ -23,10 +67,153 @@ func createListenerControl() func(network, address string, c syscall.RawConn) er
 	}
 }
 
-// CreateCloudFlareListener creates a listener with CloudFlare optimizations
+// CreateCloudFlareListener creates a listener with the default CloudFlare optimizations
 func (wl *WebLite) CreateCloudFlareListener(network, addr string) (net.Listener, error) {
+	return wl.CreateCloudFlareListenerWithTuning(network, addr, DefaultCloudflareTuning())
+}
+
+// CreateCloudFlareListenerWithTuning creates a listener applying tuning
+func (wl *WebLite) CreateCloudFlareListenerWithTuning(network, addr string, tuning *CloudflareTuning) (net.Listener, error) {
+	return wl.createCloudflareListener(network, addr, tuning, nil)
+}
+
+// createCloudflareListener applies tuning plus an extra socket control (may be nil)
+func (wl *WebLite) createCloudflareListener(network, addr string, tuning *CloudflareTuning, control func(network, address string, c syscall.RawConn) error) (net.Listener, error) {
+	if tuning == nil {
+		tuning = DefaultCloudflareTuning()
+	}
 	lc := &net.ListenConfig{
-		Control: createListenerControl(),
+		Control:   chainControl(control, createListenerControl(tuning)),
+		KeepAlive: tuning.KeepAlive,
+	}
+	ln, err := lc.Listen(context.Background(), network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calling listen(2) again on a listening socket updates its backlog
+	if tuning.Backlog > 0 {
+		if tcpLn, ok := ln.(*net.TCPListener); ok {
+			if raw, err := tcpLn.SyscallConn(); err == nil {
+				raw.Control(func(fd uintptr) {
+					if err := syscall.Listen(int(fd), tuning.Backlog); err != nil {
+						fmt.Printf("WebLite [%s] failed to set listen backlog on %s: %v\n", wl.Name, addr, err)
+					}
+				})
+			}
+		}
+	}
+
+	if !tuning.NoDelay {
+		return &noDelayListener{Listener: ln}, nil
+	}
+	return ln, nil
+}
+
+// noDelayListener disables TCP_NODELAY (enabled by Go by default) on accepted connections
+type noDelayListener struct {
+	net.Listener
+}
+
+func (l *noDelayListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetNoDelay(false)
+	}
+	return conn, err
+}
+
+// Cloudflare real-IP support
+
+// Published Cloudflare range lists, fetched by RefreshCloudflareRanges
+var cloudflareRangeURLs = []string{
+	"https://www.cloudflare.com/ips-v4",
+	"https://www.cloudflare.com/ips-v6",
+}
+
+// CloudflareTrust returns the shared Cloudflare edge range set used by
+// OptimizeCloudflare listeners and comm.ClientIP (see comm.CloudflareTrust)
+func CloudflareTrust() *comm.TrustedProxies {
+	return comm.CloudflareTrust()
+}
+
+// RefreshCloudflareRanges downloads Cloudflare's current edge ranges and
+// replaces the shared set. On failure the previous ranges stay in place.
+func RefreshCloudflareRanges(ctx context.Context) error {
+	var cidrs []string
+	client := &http.Client{Timeout: 15 * time.Second}
+	for _, url := range cloudflareRangeURLs {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("fetch %s: %w", url, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("fetch %s: %s", url, resp.Status)
+		}
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				cidrs = append(cidrs, line)
+			}
+		}
+		resp.Body.Close()
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+	if len(cidrs) == 0 {
+		return fmt.Errorf("no Cloudflare ranges received")
+	}
+	return CloudflareTrust().Replace(cidrs...)
+}
+
+// SetCloudflareRefresh sets how often the Cloudflare ranges are re-downloaded
+// while OptimizeCloudflare listeners run (default 24h, 0 disables)
+func (wl *WebLite) SetCloudflareRefresh(interval time.Duration) *WebLite {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	wl.CloudflareRefresh = interval
+	return wl
+}
+
+// startCloudflareRefresh refreshes the ranges once and then every interval until ctx ends
+func (wl *WebLite) startCloudflareRefresh(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
 	}
-	return lc.Listen(context.Background(), network, addr)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := RefreshCloudflareRanges(ctx); err != nil && ctx.Err() == nil {
+				fmt.Printf("WebLite [%s] Cloudflare range refresh failed: %v\n", wl.Name, err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// cloudflareRealIP rewrites RemoteAddr to CF-Connecting-IP when the peer is a
+// Cloudflare edge, so downstream code sees the real client address
+func cloudflareRealIP(next http.Handler) http.Handler {
+	trust := CloudflareTrust()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("CF-Connecting-IP") != "" {
+			if ip := trust.ClientIP(r); ip != comm.RemoteHost(r.RemoteAddr) {
+				_, port, _ := net.SplitHostPort(r.RemoteAddr)
+				r = r.WithContext(r.Context())
+				r.RemoteAddr = net.JoinHostPort(ip, port)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
 }
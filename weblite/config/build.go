@@ -0,0 +1,271 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-xlite/wbx/comm"
+	osfs "github.com/go-xlite/wbx/comm/adapter_fs/os_fs"
+	handlermedia "github.com/go-xlite/wbx/handlers/handler_media"
+	handlerproxy "github.com/go-xlite/wbx/handlers/handler_proxy"
+	handlersse "github.com/go-xlite/wbx/handlers/handler_sse"
+	swayhandler "github.com/go-xlite/wbx/handlers/handler_sway"
+	handlerws "github.com/go-xlite/wbx/handlers/handler_ws"
+	"github.com/go-xlite/wbx/services/webcast"
+	"github.com/go-xlite/wbx/services/webproxy"
+	"github.com/go-xlite/wbx/services/websock"
+	"github.com/go-xlite/wbx/services/webstream"
+	"github.com/go-xlite/wbx/services/websway"
+	"github.com/go-xlite/wbx/weblite"
+)
+
+// Options supplies what a config file cannot describe
+type Options struct {
+	// SessionService validates sessions; required when the file has a sessions section
+	SessionService weblite.SessionService
+	// FS holds filesystems (e.g. embedded apps) handlers refer to by name
+	FS map[string]comm.IFsAdapter
+}
+
+// Server is a WebLite built from a config file together with its parts, so
+// code can add callbacks and routes the file cannot express
+type Server struct {
+	WebLite  *weblite.WebLite
+	Sessions *weblite.SessionManager // nil without a sessions section
+	handlers map[string]any
+}
+
+// Build creates the WebLite described by f through weblite.Provider, so
+// handlers looking up the default server find it. When a handler fails the
+// server is taken out of Provider again and any server it replaced restored.
+func (f *File) Build(opts Options) (_ *Server, err error) {
+	if f.Name == "" {
+		return nil, fmt.Errorf("config: missing server name")
+	}
+
+	listeners := make([]*weblite.PortListener, 0, len(f.Listeners))
+	for i, entry := range f.Listeners {
+		config, err := listenerConfig(entry)
+		if err != nil {
+			return nil, fmt.Errorf("config: listener %d: %w", i, err)
+		}
+		if unknown := weblite.UnknownPortListenerKeys(config); len(unknown) > 0 {
+			return nil, fmt.Errorf("config: listener %d: unknown keys %v", i, unknown)
+		}
+		listener := weblite.NewPortListener(config)
+		if err := listener.Err(); err != nil {
+			return nil, fmt.Errorf("config: listener %d: %w", i, err)
+		}
+		listeners = append(listeners, listener)
+	}
+
+	var sm *weblite.SessionManager
+	if f.Sessions != nil {
+		if opts.SessionService == nil {
+			return nil, fmt.Errorf("config: sessions configured without Options.SessionService")
+		}
+		sm = f.Sessions.build(opts.SessionService)
+	}
+
+	servers := weblite.Provider.Servers
+	replaced := servers.GetByName(f.Name)
+	srv := &Server{
+		WebLite:  servers.New(f.Name),
+		Sessions: sm,
+		handlers: make(map[string]any),
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if replaced != nil {
+			servers.Items[f.Name] = replaced
+		} else {
+			delete(servers.Items, f.Name)
+		}
+	}()
+	srv.WebLite.PortListeners = append(srv.WebLite.PortListeners, listeners...)
+	if f.ShutdownTimeout > 0 {
+		srv.WebLite.SetShutdownTimeout(time.Duration(f.ShutdownTimeout))
+	}
+	if sm != nil {
+		srv.WebLite.SetSessionManager(sm)
+	}
+
+	for i := range f.Handlers {
+		h := &f.Handlers[i]
+		name := h.Name
+		if name == "" {
+			name = h.Prefix
+		}
+		if _, dup := srv.handlers[name]; dup {
+			return nil, fmt.Errorf("config: duplicate handler %q", name)
+		}
+		handler, err := srv.mount(h, opts)
+		if err != nil {
+			return nil, fmt.Errorf("config: handler %q: %w", name, err)
+		}
+		srv.handlers[name] = handler
+	}
+	return srv, nil
+}
+
+// build creates the SessionManager described by s
+func (s *Sessions) build(service weblite.SessionService) *weblite.SessionManager {
+	sm := weblite.NewSessionManager(service).
+		SetSkipPaths(s.SkipPaths...).
+		SetSkipPrefixes(s.SkipPrefixes...)
+	if s.CookieName != "" {
+		sm.CookieName = s.CookieName
+	}
+	if s.CookiePath != "" {
+		sm.CookiePath = s.CookiePath
+	}
+	sm.CookieDomain = s.CookieDomain
+	sm.Secure = !s.Insecure
+	for _, scope := range s.CookieScopes {
+		sm.AddCookieScope(scope.Prefix, scope.Name)
+	}
+	if s.TicketTTL > 0 {
+		sm.EnableConnectionTickets(time.Duration(s.TicketTTL), s.TicketPrefixes...)
+	}
+	return sm
+}
+
+// mount creates the handler h describes and registers it on the server
+func (srv *Server) mount(h *Handler, opts Options) (any, error) {
+	if h.Prefix == "" {
+		return nil, fmt.Errorf("missing prefix")
+	}
+	routes := srv.WebLite.GetRoutes()
+
+	switch h.Type {
+	case HandlerSway:
+		fsys, err := h.filesystem(opts)
+		if err != nil {
+			return nil, err
+		}
+		sway := websway.NewWebSway()
+		sway.FsProvider = fsys
+		sh := swayhandler.NewSwayHandler(sway)
+		sh.SetPathPrefix(h.Prefix)
+		if h.LoginPage != "" {
+			sh.LoginPage = h.LoginPage
+		}
+		if h.RequireSession {
+			if srv.Sessions == nil {
+				return nil, fmt.Errorf("require_session without a sessions section")
+			}
+			sh.SetSessionResolver(srv.Sessions)
+		}
+		sh.Run(srv.WebLite)
+		return sh, nil
+
+	case HandlerProxy:
+		if h.Target == "" {
+			return nil, fmt.Errorf("missing target")
+		}
+		wp, err := webproxy.NewWebProxy(h.Target)
+		if err != nil {
+			return nil, err
+		}
+		if h.MaxRequestBody > 0 {
+			wp.SetMaxRequestBody(h.MaxRequestBody)
+		}
+		wp.SetEvents(srv.WebLite.Events())
+		ph := handlerproxy.NewProxyHandler(wp)
+		ph.SetPathPrefix(h.Prefix)
+		if h.HeaderPolicy {
+			ph.SetHeaderPolicy(webproxy.DefaultHeaderPolicy())
+		}
+		if h.SafetyPolicy {
+			ph.SetSafetyPolicy(webproxy.DefaultSafetyPolicy())
+		}
+		routes.HandlePathPrefixFn(ph.PathPrefix.Get(), ph.HandleProxy())
+		return ph, nil
+
+	case HandlerStream:
+		fsys, err := h.filesystem(opts)
+		if err != nil {
+			return nil, err
+		}
+		mh := handlermedia.NewMediaHandler(webstream.NewWebStream(fsys))
+		mh.SetPathPrefix(h.Prefix)
+		if h.BufferSize > 0 {
+			mh.SetBufferSize(h.BufferSize)
+		}
+		if h.CacheFor > 0 {
+			mh.SetCaching(true, time.Duration(h.CacheFor))
+		}
+		if h.RequireSession {
+			if srv.Sessions == nil {
+				return nil, fmt.Errorf("require_session without a sessions section")
+			}
+			mh.SetSessionResolver(srv.Sessions, nil)
+		}
+		routes.ForwardPathPrefixFn(mh.PathPrefix.Get(), mh.HandleMedia())
+		return mh, nil
+
+	case HandlerSSE:
+		sh := handlersse.NewSSEHandler(webcast.NewWebCast())
+		sh.SetPathPrefix(h.Prefix)
+		sh.Mount(srv.WebLite)
+		return sh, nil
+
+	case HandlerWS:
+		name := h.Name
+		if name == "" {
+			name = h.Prefix
+		}
+		wh := handlerws.NewWsHandler(websock.NewWebSock(), name)
+		wh.SetPathPrefix(h.Prefix)
+		wh.Mount(srv.WebLite)
+		return wh, nil
+	}
+	return nil, fmt.Errorf("unknown handler type %q", h.Type)
+}
+
+// filesystem returns the filesystem a sway or stream handler serves
+func (h *Handler) filesystem(opts Options) (comm.IFsAdapter, error) {
+	switch {
+	case h.FS != "":
+		fsys, ok := opts.FS[h.FS]
+		if !ok {
+			return nil, fmt.Errorf("unknown filesystem %q", h.FS)
+		}
+		return fsys, nil
+	case h.Dir != "":
+		return osfs.NewOsFsWithBasePath(h.Dir), nil
+	}
+	return nil, fmt.Errorf("missing dir or fs")
+}
+
+// Sway returns the sway handler named name, or nil
+func (srv *Server) Sway(name string) *swayhandler.SwayHandler {
+	h, _ := srv.handlers[name].(*swayhandler.SwayHandler)
+	return h
+}
+
+// Proxy returns the proxy handler named name, or nil
+func (srv *Server) Proxy(name string) *handlerproxy.ProxyHandler {
+	h, _ := srv.handlers[name].(*handlerproxy.ProxyHandler)
+	return h
+}
+
+// Stream returns the media stream handler named name, or nil
+func (srv *Server) Stream(name string) *handlermedia.MediaHandler {
+	h, _ := srv.handlers[name].(*handlermedia.MediaHandler)
+	return h
+}
+
+// SSE returns the SSE handler named name, or nil
+func (srv *Server) SSE(name string) *handlersse.SSEHandler {
+	h, _ := srv.handlers[name].(*handlersse.SSEHandler)
+	return h
+}
+
+// WS returns the WebSocket handler named name, or nil
+func (srv *Server) WS(name string) *handlerws.WsHandler {
+	h, _ := srv.handlers[name].(*handlerws.WsHandler)
+	return h
+}
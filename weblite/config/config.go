@@ -0,0 +1,223 @@
+// Package config builds a complete WebLite server from a JSON, YAML or TOML
+// file describing its listeners, session rules and handlers:
+//
+//	name: demo
+//	listeners:
+//	  - protocol: https
+//	    ports: [8080]
+//	    addresses: ["0.0.0.0", "::"]
+//	    ssl_cert_path: certs/cert
+//	    ssl_key_path: certs/priv
+//	    idle_timeout: 2m
+//	sessions:
+//	  skip_prefixes: [/public/, /auth/]
+//	  skip_paths: [/, /login]
+//	handlers:
+//	  - type: sse
+//	    prefix: /w/xt23/sse
+//	  - type: sway
+//	    prefix: /w/xt23
+//	    dir: ./client/w
+//	    require_session: true
+//
+// Listener entries use the AddPortListener config keys; unknown keys are errors.
+// Handlers are registered in file order, which is also their routing priority.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported file formats
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// Handler types
+const (
+	HandlerSway   = "sway"
+	HandlerProxy  = "proxy"
+	HandlerStream = "stream"
+	HandlerSSE    = "sse"
+	HandlerWS     = "ws"
+)
+
+// File is the server topology described by a config file
+type File struct {
+	Name            string           `json:"name"`
+	ShutdownTimeout Duration         `json:"shutdown_timeout"`
+	Listeners       []map[string]any `json:"listeners"`
+	Sessions        *Sessions        `json:"sessions"`
+	Handlers        []Handler        `json:"handlers"`
+}
+
+// Sessions configures the SessionManager; its SessionService is supplied in code
+type Sessions struct {
+	CookieName     string        `json:"cookie_name"`
+	CookiePath     string        `json:"cookie_path"`
+	CookieDomain   string        `json:"cookie_domain"`
+	Insecure       bool          `json:"insecure"` // Send the cookie over plain HTTP too
+	SkipPaths      []string      `json:"skip_paths"`
+	SkipPrefixes   []string      `json:"skip_prefixes"`
+	CookieScopes   []CookieScope `json:"cookie_scopes"`
+	TicketTTL      Duration      `json:"ticket_ttl"` // Enables connection tickets for TicketPrefixes
+	TicketPrefixes []string      `json:"ticket_prefixes"`
+}
+
+// CookieScope gives the app mounted under Prefix its own session cookie
+type CookieScope struct {
+	Prefix string `json:"prefix"`
+	Name   string `json:"name"`
+}
+
+// Handler describes one mounted handler; fields apply to the types noted
+type Handler struct {
+	Type   string `json:"type"`   // sway, proxy, stream, sse or ws
+	Name   string `json:"name"`   // Lookup name in the built Server (default: prefix)
+	Prefix string `json:"prefix"` // Path prefix the handler is mounted at
+
+	Dir string `json:"dir"` // sway, stream: directory served from disk
+	FS  string `json:"fs"`  // sway, stream: filesystem registered in Options.FS instead of Dir
+
+	RequireSession bool   `json:"require_session"` // sway, stream: resolve sessions with the SessionManager
+	LoginPage      string `json:"login_page"`      // sway
+
+	Target         string `json:"target"`           // proxy: upstream URL
+	MaxRequestBody int64  `json:"max_request_body"` // proxy: upload cap in bytes
+	HeaderPolicy   bool   `json:"header_policy"`    // proxy: hide backend headers, add security headers
+	SafetyPolicy   bool   `json:"safety_policy"`    // proxy: SSRF protection
+
+	BufferSize int      `json:"buffer_size"` // stream
+	CacheFor   Duration `json:"cache_for"`   // stream: enables caching for this long
+}
+
+// Duration reads "30s"-style strings or a number of seconds
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case float64:
+		*d = Duration(v * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", v)
+		}
+		*d = Duration(parsed)
+	case nil:
+		*d = 0
+	default:
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	return nil
+}
+
+// Load reads a config file, picking the format from its extension
+// (.json, .yaml, .yml or .toml)
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if format == "yml" {
+		format = FormatYAML
+	}
+	file, err := Parse(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+// Parse decodes a config in format (FormatJSON, FormatYAML or FormatTOML).
+// Unknown fields are rejected so typos do not go unnoticed.
+func Parse(data []byte, format string) (*File, error) {
+	// YAML and TOML are normalized to JSON so one set of field names applies
+	switch format {
+	case FormatJSON:
+	case FormatYAML:
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		var err error
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	case FormatTOML:
+		var doc map[string]any
+		if err := toml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		var err error
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", format)
+	}
+
+	file := &File{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(file); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// listenerConfig converts a listener entry to the AddPortListener map;
+// lists are joined with commas
+func listenerConfig(entry map[string]any) (map[string]string, error) {
+	config := make(map[string]string, len(entry))
+	for key, value := range entry {
+		str, err := configString(value)
+		if err != nil {
+			return nil, fmt.Errorf("listener key %s: %w", key, err)
+		}
+		config[key] = str
+	}
+	return config, nil
+}
+
+// configString formats a decoded config value as a listener config string
+func configString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			str, err := configString(item)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = str
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+}
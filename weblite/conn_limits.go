@@ -0,0 +1,142 @@
+package weblite
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/netutil"
+)
+
+// Defaults applied when a listener does not configure the timeout; both are
+// safe for streaming responses, unlike read and write timeouts
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultIdleTimeout       = 2 * time.Minute
+)
+
+// ConnLimits bounds how long connections of a PortListener may take and how
+// many may be open at once. A zero value means unlimited.
+type ConnLimits struct {
+	ReadTimeout       time.Duration // Whole request incl. body (config key read_timeout)
+	ReadHeaderTimeout time.Duration // Request headers (config key read_header_timeout)
+	// WriteTimeout bounds writing the response (config key write_timeout).
+	// It also ends SSE streams and long downloads, so leave it unset for those.
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration // Keep-alive wait for the next request (config key idle_timeout)
+	MaxConns     int           // Open connections per bound address (config key max_conns)
+
+	err error // Invalid configuration, reported when the listener starts
+}
+
+// ParseConnLimits reads the timeout and connection limit keys of a listener
+// config. Durations use time.ParseDuration syntax ("30s", "2m"), "0" disables.
+func ParseConnLimits(config map[string]string) (*ConnLimits, error) {
+	cl := &ConnLimits{
+		ReadHeaderTimeout: DefaultReadHeaderTimeout,
+		IdleTimeout:       DefaultIdleTimeout,
+	}
+	durations := []struct {
+		key string
+		dst *time.Duration
+	}{
+		{"read_timeout", &cl.ReadTimeout},
+		{"read_header_timeout", &cl.ReadHeaderTimeout},
+		{"write_timeout", &cl.WriteTimeout},
+		{"idle_timeout", &cl.IdleTimeout},
+	}
+	for _, d := range durations {
+		value, ok := config[d.key]
+		if !ok || value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("listener config: invalid %s %q", d.key, value)
+		}
+		*d.dst = parsed
+	}
+	if value := config["max_conns"]; value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("listener config: invalid max_conns %q", value)
+		}
+		cl.MaxConns = n
+	}
+	return cl, nil
+}
+
+// configureServer copies the timeouts to server
+func (cl *ConnLimits) configureServer(server *http.Server) {
+	if cl == nil {
+		return
+	}
+	server.ReadTimeout = cl.ReadTimeout
+	server.ReadHeaderTimeout = cl.ReadHeaderTimeout
+	server.WriteTimeout = cl.WriteTimeout
+	server.IdleTimeout = cl.IdleTimeout
+}
+
+// limit caps the simultaneous connections accepted from the sockets of one
+// address; SO_REUSEPORT sockets (see SetAcceptors) share the cap
+func (cl *ConnLimits) limit(sockets []net.Listener) []net.Listener {
+	if cl == nil || cl.MaxConns <= 0 {
+		return sockets
+	}
+	if len(sockets) == 1 {
+		return []net.Listener{netutil.LimitListener(sockets[0], cl.MaxConns)}
+	}
+	sem := make(chan struct{}, cl.MaxConns)
+	limited := make([]net.Listener, len(sockets))
+	for i, ln := range sockets {
+		limited[i] = &sharedLimitListener{Listener: ln, sem: sem, done: make(chan struct{})}
+	}
+	return limited
+}
+
+// sharedLimitListener is netutil.LimitListener with a semaphore shared by
+// several sockets. A slot is taken after accepting: a socket waiting for a
+// slot must not keep one while its siblings have connections queued, so each
+// socket may hold one accepted connection until a slot frees.
+type sharedLimitListener struct {
+	net.Listener
+	sem       chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *sharedLimitListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case l.sem <- struct{}{}:
+		return &sharedLimitConn{Conn: conn, sem: l.sem}, nil
+	case <-l.done:
+		conn.Close()
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *sharedLimitListener) Close() error {
+	err := l.Listener.Close()
+	l.closeOnce.Do(func() { close(l.done) })
+	return err
+}
+
+// sharedLimitConn returns its slot once closed
+type sharedLimitConn struct {
+	net.Conn
+	sem       chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *sharedLimitConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() { <-c.sem })
+	return err
+}
@@ -0,0 +1,244 @@
+package weblite
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ConnStats counts the TCP connections of one PortListener and the outcome of
+// their TLS handshakes. It is fed by http.Server.ConnState, so HTTP/3 (QUIC)
+// connections are not included.
+type ConnStats struct {
+	open      atomic.Int64
+	accepted  atomic.Int64
+	hijacked  atomic.Int64
+	handshake atomic.Int64
+	failed    atomic.Int64
+
+	mu       sync.Mutex
+	pending  map[net.Conn]struct{} // Accepted, first request not seen yet
+	versions map[string]int64
+	ciphers  map[string]int64
+	alpn     map[string]int64
+}
+
+// ConnStatsSnapshot is a point-in-time copy of ConnStats
+type ConnStatsSnapshot struct {
+	Open             int64            `json:"open"`
+	Accepted         int64            `json:"accepted"`
+	Hijacked         int64            `json:"hijacked"` // Taken over by WebSockets and similar
+	TLSHandshakes    int64            `json:"tls_handshakes"`
+	TLSFailures      int64            `json:"tls_failures"` // Closed before the handshake completed
+	TLSVersions      map[string]int64 `json:"tls_versions,omitempty"`
+	TLSCipherSuites  map[string]int64 `json:"tls_cipher_suites,omitempty"`
+	NegotiatedProtos map[string]int64 `json:"negotiated_protocols,omitempty"` // ALPN, "none" when not negotiated
+}
+
+// ListenerStats describes one PortListener and its connections
+type ListenerStats struct {
+	Protocol  string            `json:"protocol"`
+	Ports     []string          `json:"ports"`
+	Addresses []string          `json:"addresses"`
+	Bound     []string          `json:"bound"` // Addresses currently served, see BoundAddrs
+	Conns     ConnStatsSnapshot `json:"conns"`
+}
+
+// StatsSnapshot is a point-in-time view of a WebLite server
+type StatsSnapshot struct {
+	Name      string          `json:"name"`
+	Running   bool            `json:"running"`
+	Listeners []ListenerStats `json:"listeners"`
+	Recovery  *RecoveryStats  `json:"recovery,omitempty"` // Set when EnableRecovery was called
+}
+
+// NewConnStats creates empty connection statistics
+func NewConnStats() *ConnStats {
+	return &ConnStats{
+		pending:  make(map[net.Conn]struct{}),
+		versions: make(map[string]int64),
+		ciphers:  make(map[string]int64),
+		alpn:     make(map[string]int64),
+	}
+}
+
+// track is installed as http.Server.ConnState
+func (cs *ConnStats) track(conn net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		cs.open.Add(1)
+		cs.accepted.Add(1)
+		cs.mu.Lock()
+		cs.pending[conn] = struct{}{}
+		cs.mu.Unlock()
+	case http.StateActive:
+		if cs.takePending(conn) {
+			cs.recordHandshake(conn)
+		}
+	case http.StateHijacked:
+		cs.open.Add(-1)
+		cs.hijacked.Add(1)
+		if cs.takePending(conn) {
+			cs.recordHandshake(conn)
+		}
+	case http.StateClosed:
+		cs.open.Add(-1)
+		if cs.takePending(conn) {
+			cs.recordHandshake(conn)
+		}
+	}
+}
+
+// takePending reports whether conn had not been seen past StateNew yet
+func (cs *ConnStats) takePending(conn net.Conn) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if _, ok := cs.pending[conn]; !ok {
+		return false
+	}
+	delete(cs.pending, conn)
+	return true
+}
+
+// recordHandshake counts the TLS outcome of conn once; plain connections are ignored
+func (cs *ConnStats) recordHandshake(conn net.Conn) {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return
+	}
+	state := tlsConn.ConnectionState()
+	if !state.HandshakeComplete {
+		cs.failed.Add(1)
+		return
+	}
+	cs.handshake.Add(1)
+
+	proto := state.NegotiatedProtocol
+	if proto == "" {
+		proto = "none"
+	}
+	cs.mu.Lock()
+	cs.versions[tls.VersionName(state.Version)]++
+	cs.ciphers[tls.CipherSuiteName(state.CipherSuite)]++
+	cs.alpn[proto]++
+	cs.mu.Unlock()
+}
+
+// Snapshot returns a copy of the current counters
+func (cs *ConnStats) Snapshot() ConnStatsSnapshot {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return ConnStatsSnapshot{
+		Open:             cs.open.Load(),
+		Accepted:         cs.accepted.Load(),
+		Hijacked:         cs.hijacked.Load(),
+		TLSHandshakes:    cs.handshake.Load(),
+		TLSFailures:      cs.failed.Load(),
+		TLSVersions:      copyCounts(cs.versions),
+		TLSCipherSuites:  copyCounts(cs.ciphers),
+		NegotiatedProtos: copyCounts(cs.alpn),
+	}
+}
+
+func copyCounts(m map[string]int64) map[string]int64 {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]int64, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// StatsSnapshot returns the configured listeners with their bound addresses
+// and connection statistics
+func (wl *WebLite) StatsSnapshot() StatsSnapshot {
+	wl.mu.RLock()
+	defer wl.mu.RUnlock()
+
+	snap := StatsSnapshot{Name: wl.Name, Running: wl.running}
+	if wl.recovery != nil {
+		stats := wl.recovery.GetStats()
+		snap.Recovery = &stats
+	}
+	for _, pl := range wl.PortListeners {
+		ls := ListenerStats{
+			Protocol:  pl.Protocol,
+			Ports:     slices.Clone(pl.Ports),
+			Addresses: slices.Clone(pl.Addresses),
+			Bound:     []string{},
+		}
+		for _, b := range wl.bound {
+			if wl.owners[b.server] == pl {
+				ls.Bound = append(ls.Bound, b.addr.String())
+			}
+		}
+		if pl.Conns != nil {
+			ls.Conns = pl.Conns.Snapshot()
+		}
+		snap.Listeners = append(snap.Listeners, ls)
+	}
+	return snap
+}
+
+// MetricsHandler serves the connection statistics in the Prometheus text format
+func (wl *WebLite) MetricsHandler() http.Handler {
+	scalars := []struct {
+		name, help, kind string
+		value            func(c ConnStatsSnapshot) int64
+	}{
+		{"wbx_connections_open", "Open TCP connections.", "gauge", func(c ConnStatsSnapshot) int64 { return c.Open }},
+		{"wbx_connections_accepted_total", "Accepted TCP connections.", "counter", func(c ConnStatsSnapshot) int64 { return c.Accepted }},
+		{"wbx_connections_hijacked_total", "Connections taken over by a handler.", "counter", func(c ConnStatsSnapshot) int64 { return c.Hijacked }},
+		{"wbx_tls_handshakes_total", "Completed TLS handshakes.", "counter", func(c ConnStatsSnapshot) int64 { return c.TLSHandshakes }},
+		{"wbx_tls_handshake_failures_total", "Connections closed before the TLS handshake completed.", "counter", func(c ConnStatsSnapshot) int64 { return c.TLSFailures }},
+	}
+	breakdowns := []struct {
+		name, help, label string
+		counts            func(c ConnStatsSnapshot) map[string]int64
+	}{
+		{"wbx_tls_versions_total", "Completed TLS handshakes by protocol version.", "version", func(c ConnStatsSnapshot) map[string]int64 { return c.TLSVersions }},
+		{"wbx_tls_cipher_suites_total", "Completed TLS handshakes by cipher suite.", "cipher", func(c ConnStatsSnapshot) map[string]int64 { return c.TLSCipherSuites }},
+		{"wbx_tls_alpn_total", "Completed TLS handshakes by negotiated ALPN protocol.", "protocol", func(c ConnStatsSnapshot) map[string]int64 { return c.NegotiatedProtos }},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snap := wl.StatsSnapshot()
+		labels := make([]string, len(snap.Listeners))
+		for i, ls := range snap.Listeners {
+			labels[i] = fmt.Sprintf(`server=%q,listener="%s:%s"`, snap.Name, ls.Protocol, strings.Join(ls.Ports, ","))
+		}
+
+		var b strings.Builder
+		for _, m := range scalars {
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+			for i, ls := range snap.Listeners {
+				fmt.Fprintf(&b, "%s{%s} %d\n", m.name, labels[i], m.value(ls.Conns))
+			}
+		}
+		for _, m := range breakdowns {
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", m.name, m.help, m.name)
+			for i, ls := range snap.Listeners {
+				counts := m.counts(ls.Conns)
+				keys := make([]string, 0, len(counts))
+				for k := range counts {
+					keys = append(keys, k)
+				}
+				slices.Sort(keys)
+				for _, k := range keys {
+					fmt.Fprintf(&b, "%s{%s,%s=%q} %d\n", m.name, labels[i], m.label, k, counts[k])
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte(b.String()))
+	})
+}
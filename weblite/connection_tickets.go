@@ -0,0 +1,125 @@
+package weblite
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-xlite/wbx/comm"
+)
+
+// ConnectionTickets issues short-lived, one-time tickets that stand in for the
+// session cookie/header on streaming endpoints. EventSource and browser WebSockets
+// cannot set an Authorization header, so the client fetches a ticket from an
+// authenticated endpoint and passes it as a query parameter when connecting.
+type ConnectionTickets struct {
+	TTL       time.Duration // Ticket lifetime (default 30s)
+	ParamName string        // Query parameter carrying the ticket (default "ticket")
+	Prefixes  []string      // Path prefixes where tickets are accepted
+	tickets   map[string]connectionTicket
+	mu        sync.Mutex
+}
+
+type connectionTicket struct {
+	sessionData any
+	sessionKey  string // Key of the issuing session, for revocation
+	expires     time.Time
+}
+
+// NewConnectionTickets creates a ticket store accepting tickets under the given path prefixes
+func NewConnectionTickets(ttl time.Duration, prefixes ...string) *ConnectionTickets {
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	return &ConnectionTickets{
+		TTL:       ttl,
+		ParamName: "ticket",
+		Prefixes:  prefixes,
+		tickets:   make(map[string]connectionTicket),
+	}
+}
+
+// Issue creates a ticket bound to sessionData and returns it with its expiry
+func (ct *ConnectionTickets) Issue(sessionData any) (string, time.Time, error) {
+	return ct.issue(sessionData, "")
+}
+
+func (ct *ConnectionTickets) issue(sessionData any, sessionKey string) (string, time.Time, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(buf)
+	now := time.Now()
+	expires := now.Add(ct.TTL)
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	for key, t := range ct.tickets {
+		if now.After(t.expires) {
+			delete(ct.tickets, key)
+		}
+	}
+	ct.tickets[token] = connectionTicket{sessionData: sessionData, sessionKey: sessionKey, expires: expires}
+	return token, expires, nil
+}
+
+// Redeem consumes a ticket and returns the session data it was issued for.
+// A ticket can only be redeemed once.
+func (ct *ConnectionTickets) Redeem(token string) (any, bool) {
+	t, ok := ct.redeem(token)
+	return t.sessionData, ok
+}
+
+func (ct *ConnectionTickets) redeem(token string) (connectionTicket, bool) {
+	if token == "" {
+		return connectionTicket{}, false
+	}
+	ct.mu.Lock()
+	t, ok := ct.tickets[token]
+	delete(ct.tickets, token)
+	ct.mu.Unlock()
+
+	if !ok || time.Now().After(t.expires) {
+		return connectionTicket{}, false
+	}
+	return t, true
+}
+
+// Accepts reports whether tickets are accepted for path
+func (ct *ConnectionTickets) Accepts(path string) bool {
+	for _, prefix := range ct.Prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// IssueHandler returns a handler that issues a ticket for the session in the
+// request context. Register it on a route covered by SessionManager.Middleware.
+func (ct *ConnectionTickets) IssueHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionData, ok := GetSessionContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		token, expires, err := ct.issue(sessionData, comm.SessionKey(r))
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(map[string]any{
+			"ticket":    token,
+			"param":     ct.ParamName,
+			"expiresAt": expires,
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package weblite
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CookieScope gives the app mounted under Prefix its own session cookie, so
+// apps sharing a server (e.g. /w/xt23/ and /g/xt23/) keep independent sessions
+type CookieScope struct {
+	Prefix string // Mount prefix with trailing slash, e.g. "/w/xt23/"
+	Name   string // Cookie name used under Prefix
+	Path   string // Cookie Path attribute, defaults to Prefix
+}
+
+// AddCookieScope scopes the session cookie of requests under prefix to name and
+// path prefix. An empty name derives one from CookieName and the prefix
+// ("session_w_xt23"). The longest matching prefix wins; other requests use
+// CookieName and CookiePath.
+func (sm *SessionManager) AddCookieScope(prefix, name string) *SessionManager {
+	prefix = "/" + strings.Trim(prefix, "/") + "/"
+	if name == "" {
+		name = sm.CookieName + "_" + strings.ReplaceAll(strings.Trim(prefix, "/"), "/", "_")
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.CookieScopes = append(sm.CookieScopes, &CookieScope{Prefix: prefix, Name: name, Path: prefix})
+	return sm
+}
+
+// cookieFor returns the cookie name and path used for requests to path
+func (sm *SessionManager) cookieFor(path string) (string, string) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	var best *CookieScope
+	for _, scope := range sm.CookieScopes {
+		if strings.HasPrefix(path, scope.Prefix) && (best == nil || len(scope.Prefix) > len(best.Prefix)) {
+			best = scope
+		}
+	}
+	if best == nil {
+		return sm.CookieName, sm.CookiePath
+	}
+	cookiePath := best.Path
+	if cookiePath == "" {
+		cookiePath = best.Prefix
+	}
+	return best.Name, cookiePath
+}
+
+// CookieNameFor returns the name of the session cookie that applies to r
+func (sm *SessionManager) CookieNameFor(r *http.Request) string {
+	name, _ := sm.cookieFor(r.URL.Path)
+	return name
+}
+
+// SetCookieFor sets the session cookie of the scope r belongs to
+func (sm *SessionManager) SetCookieFor(w http.ResponseWriter, r *http.Request, token string) {
+	name, path := sm.cookieFor(r.URL.Path)
+	sm.writeCookie(w, name, path, token, 0)
+}
+
+// SetCookieWithExpiryFor sets the session cookie of the scope r belongs to with an expiration time
+func (sm *SessionManager) SetCookieWithExpiryFor(w http.ResponseWriter, r *http.Request, token string, maxAge int) {
+	name, path := sm.cookieFor(r.URL.Path)
+	sm.writeCookie(w, name, path, token, maxAge)
+}
+
+// ClearCookieFor removes the session cookie of the scope r belongs to
+func (sm *SessionManager) ClearCookieFor(w http.ResponseWriter, r *http.Request) {
+	name, path := sm.cookieFor(r.URL.Path)
+	sm.writeCookie(w, name, path, "", -1)
+}
@@ -0,0 +1,130 @@
+package weblite
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+// DualStackPolicy decides how the IPv4 and IPv6 wildcard addresses ("0.0.0.0"
+// and "::") of a listener share a port and which bind failures are tolerated
+type DualStackPolicy string
+
+const (
+	// DualStackPreferV6 lets the "::" socket accept IPv4 too (the OS default);
+	// a "0.0.0.0" bind failing with EADDRINUSE is ignored once "::" is bound
+	DualStackPreferV6 DualStackPolicy = "prefer-v6"
+	// DualStackPreferV4 binds "::" as IPv6-only so IPv4 goes to the "0.0.0.0"
+	// socket; "::" may fail (e.g. IPv6 disabled) when "0.0.0.0" is bound
+	DualStackPreferV4 DualStackPolicy = "prefer-v4"
+	// DualStackRequireBoth binds "::" as IPv6-only and fails unless both families bind
+	DualStackRequireBoth DualStackPolicy = "require-both"
+	// DualStackEither accepts whichever wildcard family binds; the other may fail
+	DualStackEither DualStackPolicy = "either"
+)
+
+// ParseDualStackPolicy converts a config value, defaulting to DualStackPreferV6
+func ParseDualStackPolicy(value string) DualStackPolicy {
+	switch policy := DualStackPolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case DualStackPreferV4, DualStackRequireBoth, DualStackEither:
+		return policy
+	default:
+		return DualStackPreferV6
+	}
+}
+
+// SetDualStack sets the dual-stack bind policy
+func (pl *PortListener) SetDualStack(policy DualStackPolicy) *PortListener {
+	pl.DualStack = policy
+	return pl
+}
+
+// v6OnlyFor reports whether the socket for addr must be IPv6-only so the IPv4
+// wildcard on the same port can bind separately
+func (pl *PortListener) v6OnlyFor(addr string) bool {
+	if pl.DualStack != DualStackPreferV4 && pl.DualStack != DualStackRequireBoth {
+		return false
+	}
+	host, _, err := net.SplitHostPort(addr)
+	return err == nil && host == "::"
+}
+
+// dualStackV6First reports whether the "::" socket also serves IPv4 and so
+// must be bound before "0.0.0.0"
+func (pl *PortListener) dualStackV6First() bool {
+	if pl.DualStack == DualStackPreferV4 || pl.DualStack == DualStackRequireBoth {
+		return false
+	}
+	return slices.Contains(pl.Addresses, "::") && slices.Contains(pl.Addresses, "0.0.0.0")
+}
+
+// canIgnoreBindError reports whether err from binding addr is tolerated by the
+// policy; peerBound tells whether the other wildcard family covers the same port
+func (pl *PortListener) canIgnoreBindError(addr string, err error, peerBound bool) bool {
+	if err == nil || !peerBound {
+		return false
+	}
+	host, _, splitErr := net.SplitHostPort(addr)
+	if splitErr != nil {
+		return false
+	}
+
+	switch pl.DualStack {
+	case DualStackRequireBoth:
+		return false
+	case DualStackPreferV4:
+		return host == "::" && isFamilyUnavailable(err)
+	case DualStackEither:
+		return (host == "::" || host == "0.0.0.0") && (isAddrInUse(err) || isFamilyUnavailable(err))
+	default:
+		return host == "0.0.0.0" && isAddrInUse(err)
+	}
+}
+
+// bindNetwork picks the network for addr. IPv4 literals use "tcp4" since plain
+// "tcp" binds "0.0.0.0" as a dual-stack IPv6 socket; "tcp6" makes Go set
+// IPV6_V6ONLY, so it is only used when v6only is wanted.
+func bindNetwork(addr string, v6only bool) string {
+	if v6only {
+		return "tcp6"
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "tcp"
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && ip.Is4() {
+		return "tcp4"
+	}
+	return "tcp"
+}
+
+// peerWildcard returns the wildcard address of the other IP family for addr
+// ("" when addr is not a wildcard)
+func peerWildcard(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	switch host {
+	case "::":
+		return net.JoinHostPort("0.0.0.0", port)
+	case "0.0.0.0":
+		return net.JoinHostPort("::", port)
+	}
+	return ""
+}
+
+func isAddrInUse(err error) bool {
+	return errors.Is(err, syscall.EADDRINUSE)
+}
+
+// isFamilyUnavailable matches binds failing because an IP family is disabled
+// or the port is taken by a dual-stack socket of the other family
+func isFamilyUnavailable(err error) bool {
+	return errors.Is(err, syscall.EADDRINUSE) ||
+		errors.Is(err, syscall.EADDRNOTAVAIL) ||
+		errors.Is(err, syscall.EAFNOSUPPORT)
+}
@@ -0,0 +1,15 @@
+package weblite
+
+import "github.com/go-xlite/wbx/comm/events"
+
+// Events returns the server's event bus. Subsystems mounted on the server
+// publish session, reload, proxy health and client events to it; see package
+// events for the topics.
+func (wl *WebLite) Events() *events.Bus {
+	return wl.events
+}
+
+// publishReload announces a live listener change
+func (wl *WebLite) publishReload(action string, err error) {
+	wl.events.Publish(events.ConfigReloaded, "weblite/"+wl.Name, events.ConfigReloadedData{Action: action, Err: err})
+}
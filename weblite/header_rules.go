@@ -0,0 +1,148 @@
+package weblite
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-xlite/wbx/comm"
+)
+
+// HeaderRule adjusts the response headers of every request under Prefix.
+// Rules run after the handler has set its own headers, just before they are
+// sent: Remove first, then Set (override), then Add (append).
+type HeaderRule struct {
+	Prefix string
+	Set    map[string]string
+	Add    map[string]string
+	Remove []string
+}
+
+// SetHeader overrides header on matching responses
+func (hr *HeaderRule) SetHeader(key, value string) *HeaderRule {
+	if hr.Set == nil {
+		hr.Set = make(map[string]string)
+	}
+	hr.Set[key] = value
+	return hr
+}
+
+// AddHeader appends a header value on matching responses
+func (hr *HeaderRule) AddHeader(key, value string) *HeaderRule {
+	if hr.Add == nil {
+		hr.Add = make(map[string]string)
+	}
+	hr.Add[key] = value
+	return hr
+}
+
+// RemoveHeader strips headers from matching responses
+func (hr *HeaderRule) RemoveHeader(keys ...string) *HeaderRule {
+	hr.Remove = append(hr.Remove, keys...)
+	return hr
+}
+
+func (hr *HeaderRule) apply(h http.Header) {
+	for _, key := range hr.Remove {
+		h.Del(key)
+	}
+	for key, value := range hr.Set {
+		h.Set(key, value)
+	}
+	for key, value := range hr.Add {
+		h.Add(key, value)
+	}
+}
+
+// HeaderRule returns a new rule for prefix, applied in registration order
+// after any earlier rules matching the same request:
+//
+//	wl.HeaderRule("/static/").SetHeader("Cache-Control", "public, max-age=31536000")
+//	wl.HeaderRule("/media/").SetHeader("Cross-Origin-Resource-Policy", "cross-origin")
+func (wl *WebLite) HeaderRule(prefix string) *HeaderRule {
+	rule := &HeaderRule{Prefix: prefix}
+	wl.AddHeaderRule(rule)
+	return rule
+}
+
+// AddHeaderRule registers a header rule
+func (wl *WebLite) AddHeaderRule(rule *HeaderRule) *WebLite {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	wl.HeaderRules = append(wl.HeaderRules, rule)
+	return wl
+}
+
+// headerRulesMiddleware applies the matching header rules to each response
+func (wl *WebLite) headerRulesMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wl.mu.RLock()
+		var matched []*HeaderRule
+		for _, rule := range wl.HeaderRules {
+			if strings.HasPrefix(r.URL.Path, rule.Prefix) {
+				matched = append(matched, rule)
+			}
+		}
+		wl.mu.RUnlock()
+
+		if len(matched) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		hw := &headerRuleWriter{ResponseWriter: w, rules: matched}
+		next.ServeHTTP(comm.WrapResponseWriter(w, hw), r)
+
+		// Handlers that write nothing get an implicit 200 from net/http
+		hw.applyRules()
+	})
+}
+
+// headerRuleWriter applies rules right before the header is written
+type headerRuleWriter struct {
+	http.ResponseWriter
+	rules   []*HeaderRule
+	applied bool // Also set on hijack, when headers no longer apply
+}
+
+func (hw *headerRuleWriter) applyRules() {
+	if hw.applied {
+		return
+	}
+	hw.applied = true
+	for _, rule := range hw.rules {
+		rule.apply(hw.ResponseWriter.Header())
+	}
+}
+
+func (hw *headerRuleWriter) WriteHeader(statusCode int) {
+	// 1xx informational responses carry their own headers
+	if statusCode >= 200 || statusCode == http.StatusSwitchingProtocols {
+		hw.applyRules()
+	}
+	hw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (hw *headerRuleWriter) Write(b []byte) (int, error) {
+	hw.applyRules()
+	return hw.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher
+func (hw *headerRuleWriter) Flush() {
+	hw.applyRules()
+	if flusher, ok := hw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker
+func (hw *headerRuleWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := hw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("underlying ResponseWriter does not support Hijack")
+	}
+	hw.applied = true
+	return hj.Hijack()
+}
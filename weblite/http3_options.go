@@ -0,0 +1,86 @@
+package weblite
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DefaultAltSvcMaxAge is how long clients may remember the HTTP/3 endpoint
+// when a listener does not configure it
+const DefaultAltSvcMaxAge = 24 * time.Hour
+
+// HTTP3Options tunes the HTTP/3 server of an HTTPS listener. Zero values keep
+// the quic-go defaults. Only used when built with -tags http3.
+type HTTP3Options struct {
+	MaxIdleTimeout     time.Duration // Idle QUIC connections are closed after this (config key h3_max_idle_timeout, default 30s)
+	MaxIncomingStreams int64         // Concurrent requests per connection (config key h3_max_streams, default 100)
+	AltSvcMaxAge       time.Duration // Alt-Svc ma parameter (config key h3_alt_svc_max_age, default 24h)
+
+	// Allow0RTT accepts requests sent as TLS early data on resumed connections
+	// (config key h3_0rtt). Early data can be replayed, so unsafe methods are
+	// answered with 425 Too Early until the handshake completes and safe ones
+	// carry an Early-Data: 1 header (RFC 8470).
+	Allow0RTT bool
+
+	err error // Invalid configuration, reported when the listener starts
+}
+
+// ParseHTTP3Options reads the h3_* keys of a listener config. Durations use
+// time.ParseDuration syntax ("30s", "2m").
+func ParseHTTP3Options(config map[string]string) (*HTTP3Options, error) {
+	opts := &HTTP3Options{Allow0RTT: config["h3_0rtt"] == "true"}
+	durations := []struct {
+		key string
+		dst *time.Duration
+	}{
+		{"h3_max_idle_timeout", &opts.MaxIdleTimeout},
+		{"h3_alt_svc_max_age", &opts.AltSvcMaxAge},
+	}
+	for _, d := range durations {
+		value := config[d.key]
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("listener config: invalid %s %q", d.key, value)
+		}
+		*d.dst = parsed
+	}
+	if value := config["h3_max_streams"]; value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("listener config: invalid h3_max_streams %q", value)
+		}
+		opts.MaxIncomingStreams = n
+	}
+	return opts, nil
+}
+
+// validate reports negative settings of a typed config
+func (o *HTTP3Options) validate() error {
+	if o.MaxIdleTimeout < 0 || o.MaxIncomingStreams < 0 || o.AltSvcMaxAge < 0 {
+		return fmt.Errorf("listener config: negative HTTP/3 option")
+	}
+	return nil
+}
+
+// altSvcMaxAge returns the Alt-Svc ma parameter in seconds
+func (o *HTTP3Options) altSvcMaxAge() int {
+	if o == nil || o.AltSvcMaxAge <= 0 {
+		return int(DefaultAltSvcMaxAge.Seconds())
+	}
+	return int(o.AltSvcMaxAge.Seconds())
+}
+
+// QUICAddrs returns the UDP addresses the running HTTP/3 servers are bound to
+func (wl *WebLite) QUICAddrs() []string {
+	wl.mu.RLock()
+	defer wl.mu.RUnlock()
+	addrs := make([]string, 0, len(wl.quicServers))
+	for _, server := range wl.quicServers {
+		addrs = append(addrs, server.LocalAddr().String())
+	}
+	return addrs
+}
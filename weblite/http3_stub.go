@@ -5,18 +5,18 @@ package weblite
 
 import (
 	"crypto/tls"
+	"errors"
 	"net/http"
 )
 
 // wrapWithHTTP3AltSvc is a no-op when HTTP/3 is not compiled
-func wrapWithHTTP3AltSvc(handler http.Handler, port string) http.Handler {
+func wrapWithHTTP3AltSvc(handler http.Handler, port string, opts *HTTP3Options) http.Handler {
 	return handler
 }
 
-// startHTTP3Server is a no-op when HTTP/3 is not compiled
-func (wl *WebLite) startHTTP3Server(addr string, tlsConfig *tls.Config, handler http.Handler) error {
-	// No-op: HTTP/3 not compiled
-	return nil
+// listenHTTP3 fails when HTTP/3 is not compiled
+func (wl *WebLite) listenHTTP3(addr string, tlsConfig *tls.Config, handler http.Handler, opts *HTTP3Options) (quicServer, func() error, error) {
+	return nil, nil, errors.New("HTTP/3 support not compiled (build with -tags http3)")
 }
 
 // isHTTP3Enabled returns false when HTTP/3 is not compiled in
@@ -4,10 +4,13 @@
 package weblite
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 
+	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/http3"
 )
 
@@ -15,6 +18,7 @@ import (
 type http3AltSvcMiddleware struct {
 	handler http.Handler
 	port    string
+	maxAge  int // Seconds
 }
 
 func (m *http3AltSvcMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -22,32 +26,93 @@ func (m *http3AltSvcMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request
 	if m.port != "" {
 		// Check if header already exists to avoid duplication
 		if len(w.Header().Values("Alt-Svc")) == 0 {
-			w.Header().Add("Alt-Svc", fmt.Sprintf(`h3=":%s"; ma=86400`, m.port))
+			w.Header().Add("Alt-Svc", fmt.Sprintf(`h3=":%s"; ma=%d`, m.port, m.maxAge))
 		}
 	}
 	m.handler.ServeHTTP(w, r)
 }
 
 // wrapWithHTTP3AltSvc wraps a handler to automatically add Alt-Svc headers
-func wrapWithHTTP3AltSvc(handler http.Handler, port string) http.Handler {
+func wrapWithHTTP3AltSvc(handler http.Handler, port string, opts *HTTP3Options) http.Handler {
 	return &http3AltSvcMiddleware{
 		handler: handler,
 		port:    port,
+		maxAge:  opts.altSvcMaxAge(),
 	}
 }
 
-// startHTTP3Server starts an HTTP/3 server for the given address
-// This runs in addition to the HTTP/1.1/2.0 server
-func (wl *WebLite) startHTTP3Server(addr string, tlsConfig *tls.Config, handler http.Handler) error {
+// http3Listener is an HTTP/3 server with the UDP socket it serves
+type http3Listener struct {
+	*http3.Server
+	conn net.PacketConn
+}
+
+// LocalAddr returns the bound UDP address
+func (l *http3Listener) LocalAddr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// quicConnKey holds the *quic.Conn of an HTTP/3 request context
+type quicConnKey struct{}
+
+// rejectReplayable answers 425 Too Early to unsafe requests that arrived as
+// 0-RTT early data and marks safe ones with Early-Data: 1 (RFC 8470)
+func rejectReplayable(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _ := r.Context().Value(quicConnKey{}).(*quic.Conn)
+		if conn != nil {
+			select {
+			case <-conn.HandshakeComplete():
+			default:
+				switch r.Method {
+				case http.MethodGet, http.MethodHead, http.MethodOptions:
+					r.Header.Set("Early-Data", "1")
+				default:
+					http.Error(w, "Too Early", http.StatusTooEarly)
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// listenHTTP3 binds the UDP socket for an HTTP/3 server on addr and returns the
+// server with a function serving it. This runs in addition to the HTTP/1.1/2.0 server.
+func (wl *WebLite) listenHTTP3(addr string, tlsConfig *tls.Config, handler http.Handler, opts *HTTP3Options) (quicServer, func() error, error) {
+	if opts == nil {
+		opts = &HTTP3Options{}
+	}
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	http3Server := &http3.Server{
 		Addr:      addr,
 		TLSConfig: tlsConfig,
 		Handler:   handler,
+		QUICConfig: &quic.Config{
+			MaxIdleTimeout:     opts.MaxIdleTimeout,
+			MaxIncomingStreams: opts.MaxIncomingStreams,
+			Allow0RTT:          opts.Allow0RTT,
+		},
+	}
+	if opts.Allow0RTT {
+		http3Server.Handler = rejectReplayable(handler)
+		http3Server.ConnContext = func(ctx context.Context, c *quic.Conn) context.Context {
+			return context.WithValue(ctx, quicConnKey{}, c)
+		}
 	}
 
 	fmt.Printf("WebLite [%s] starting HTTP/3 on %s\n", wl.Name, addr)
 
-	return http3Server.ListenAndServe()
+	serve := func() error {
+		// Closing the server does not close a connection passed to Serve
+		defer conn.Close()
+		return http3Server.Serve(conn)
+	}
+	return &http3Listener{Server: http3Server, conn: conn}, serve, nil
 }
 
 // isHTTP3Enabled returns true when HTTP/3 is compiled in
@@ -0,0 +1,184 @@
+package weblite
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sync"
+
+	"github.com/go-xlite/wbx/comm"
+)
+
+// IPValidator restricts a listener to client addresses, the IP counterpart of
+// DomainValidator. Ranges are CIDRs or bare IPs; disallowed ranges take
+// precedence and an empty allowed list accepts every other address.
+type IPValidator struct {
+	AllowedIPs    []netip.Prefix
+	DisallowedIPs []netip.Prefix
+	// TrustedProxies resolves the client IP from forwarding headers, which are
+	// only honoured when the peer lies in a trusted range. nil checks the peer
+	// address (already the real client on OptimizeCloudflare listeners).
+	TrustedProxies *comm.TrustedProxies
+	mu             sync.RWMutex
+
+	err error // Invalid configuration, reported when the listener starts
+}
+
+// NewIPValidator creates a new IP validator
+func NewIPValidator() *IPValidator {
+	return &IPValidator{}
+}
+
+// parseIPRanges parses CIDRs or bare IPs
+func parseIPRanges(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := comm.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// SetAllowedIPs sets the allowed ranges
+func (iv *IPValidator) SetAllowedIPs(cidrs ...string) error {
+	prefixes, err := parseIPRanges(cidrs)
+	if err != nil {
+		return err
+	}
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+	iv.AllowedIPs = prefixes
+	return nil
+}
+
+// AddAllowedIP adds a single range to the allowed list
+func (iv *IPValidator) AddAllowedIP(cidr string) error {
+	prefix, err := comm.ParsePrefix(cidr)
+	if err != nil {
+		return err
+	}
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+	iv.AllowedIPs = append(iv.AllowedIPs, prefix)
+	return nil
+}
+
+// SetDisallowedIPs sets the blocked ranges
+func (iv *IPValidator) SetDisallowedIPs(cidrs ...string) error {
+	prefixes, err := parseIPRanges(cidrs)
+	if err != nil {
+		return err
+	}
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+	iv.DisallowedIPs = prefixes
+	return nil
+}
+
+// AddDisallowedIP adds a single range to the blocked list
+func (iv *IPValidator) AddDisallowedIP(cidr string) error {
+	prefix, err := comm.ParsePrefix(cidr)
+	if err != nil {
+		return err
+	}
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+	iv.DisallowedIPs = append(iv.DisallowedIPs, prefix)
+	return nil
+}
+
+// SetTrustedProxies sets the proxies whose forwarding headers name the client
+func (iv *IPValidator) SetTrustedProxies(tp *comm.TrustedProxies) *IPValidator {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+	iv.TrustedProxies = tp
+	return iv
+}
+
+// ClientIP returns the address r is validated by
+func (iv *IPValidator) ClientIP(r *http.Request) string {
+	iv.mu.RLock()
+	tp := iv.TrustedProxies
+	iv.mu.RUnlock()
+	if tp != nil {
+		return tp.ClientIP(r)
+	}
+	return comm.RemoteHost(r.RemoteAddr)
+}
+
+// IsAllowed checks if ip is allowed; unparsable addresses are rejected
+func (iv *IPValidator) IsAllowed(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	iv.mu.RLock()
+	defer iv.mu.RUnlock()
+
+	// Check disallowed ranges first (takes precedence)
+	for _, prefix := range iv.DisallowedIPs {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	// If no allowed ranges specified, allow all (except disallowed)
+	if len(iv.AllowedIPs) == 0 {
+		return true
+	}
+
+	for _, prefix := range iv.AllowedIPs {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware creates a middleware function for IP validation
+func (iv *IPValidator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !iv.IsAllowed(iv.ClientIP(r)) {
+			http.Error(w, "Address not allowed", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IsEnabled returns true if IP validation is enabled (has allowed or disallowed ranges configured)
+func (iv *IPValidator) IsEnabled() bool {
+	iv.mu.RLock()
+	defer iv.mu.RUnlock()
+	return len(iv.AllowedIPs) > 0 || len(iv.DisallowedIPs) > 0
+}
+
+// ParseIPValidator reads the ips_allow, ips_block and ips_trusted_proxies keys
+// (comma-separated CIDRs or IPs) of a listener config
+func ParseIPValidator(config map[string]string) (*IPValidator, error) {
+	return newIPValidator(splitList(config["ips_allow"]), splitList(config["ips_block"]), splitList(config["ips_trusted_proxies"]))
+}
+
+// newIPValidator builds a validator from allowed, blocked and trusted proxy ranges
+func newIPValidator(allowed, blocked, trusted []string) (*IPValidator, error) {
+	iv := NewIPValidator()
+	if err := iv.SetAllowedIPs(allowed...); err != nil {
+		return nil, fmt.Errorf("listener config: invalid allowed IP range: %w", err)
+	}
+	if err := iv.SetDisallowedIPs(blocked...); err != nil {
+		return nil, fmt.Errorf("listener config: invalid blocked IP range: %w", err)
+	}
+	if len(trusted) > 0 {
+		tp, err := comm.NewTrustedProxies(trusted...)
+		if err != nil {
+			return nil, fmt.Errorf("listener config: invalid trusted proxy range: %w", err)
+		}
+		iv.TrustedProxies = tp
+	}
+	return iv, nil
+}
@@ -0,0 +1,161 @@
+package weblite
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// OCSP refresh bounds: staples are renewed halfway through their validity,
+// but never sooner than ocspMinRefresh or later than ocspMaxRefresh
+const (
+	ocspMinRefresh = time.Minute
+	ocspMaxRefresh = 12 * time.Hour
+	ocspRetry      = 5 * time.Minute
+	ocspTimeout    = 15 * time.Second
+)
+
+// ocspStapler keeps an OCSP response for one certificate fresh and serves the
+// certificate with it attached
+type ocspStapler struct {
+	name   string // WebLite name for log messages
+	cert   tls.Certificate
+	leaf   *x509.Certificate
+	issuer *x509.Certificate
+	client *http.Client
+
+	mu      sync.RWMutex
+	stapled *tls.Certificate // cert with the current staple, nil until the first fetch
+	expires time.Time        // NextUpdate of the current staple
+}
+
+// newOCSPStapler prepares stapling for cert; the chain must include the issuer
+// and the leaf must name an OCSP responder
+func newOCSPStapler(name string, cert tls.Certificate) (*ocspStapler, error) {
+	if len(cert.Certificate) < 2 {
+		return nil, errors.New("certificate chain has no issuer")
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		var err error
+		if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, err
+		}
+	}
+	if len(leaf.OCSPServer) == 0 {
+		return nil, errors.New("certificate names no OCSP responder")
+	}
+	issuer, err := x509.ParseCertificate(cert.Certificate[1])
+	if err != nil {
+		return nil, err
+	}
+	return &ocspStapler{
+		name:   name,
+		cert:   cert,
+		leaf:   leaf,
+		issuer: issuer,
+		client: &http.Client{Timeout: ocspTimeout},
+	}, nil
+}
+
+// getCertificate serves the certificate with the staple while it is valid
+func (s *ocspStapler) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.stapled != nil && time.Now().Before(s.expires) {
+		return s.stapled, nil
+	}
+	return &s.cert, nil
+}
+
+// run refreshes the staple until ctx ends
+func (s *ocspStapler) run(ctx context.Context) {
+	for {
+		wait, err := s.refresh(ctx)
+		if err != nil && ctx.Err() == nil {
+			fmt.Printf("WebLite [%s] OCSP refresh for %s failed: %v\n", s.name, s.leaf.Subject.CommonName, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// refresh fetches a new staple and returns when to refresh next
+func (s *ocspStapler) refresh(ctx context.Context) (time.Duration, error) {
+	raw, resp, err := s.fetch(ctx)
+	if err != nil {
+		return ocspRetry, err
+	}
+
+	switch resp.Status {
+	case ocsp.Good:
+		stapled := s.cert
+		stapled.OCSPStaple = raw
+		s.mu.Lock()
+		s.stapled = &stapled
+		s.expires = resp.NextUpdate
+		if resp.NextUpdate.IsZero() {
+			s.expires = time.Now().Add(ocspMaxRefresh)
+		}
+		s.mu.Unlock()
+	case ocsp.Revoked:
+		// Never staple a revocation; clients without OCSP checks keep working
+		s.mu.Lock()
+		s.stapled = nil
+		s.mu.Unlock()
+		return ocspMaxRefresh, fmt.Errorf("certificate revoked at %s", resp.RevokedAt.Format(time.RFC3339))
+	default:
+		return ocspRetry, errors.New("responder reports unknown status")
+	}
+
+	wait := ocspMaxRefresh
+	if !resp.NextUpdate.IsZero() {
+		wait = resp.NextUpdate.Sub(resp.ThisUpdate) / 2
+		wait -= time.Since(resp.ThisUpdate)
+	}
+	return min(max(wait, ocspMinRefresh), ocspMaxRefresh), nil
+}
+
+// fetch asks the first responder of the leaf for its status
+func (s *ocspStapler) fetch(ctx context.Context) ([]byte, *ocsp.Response, error) {
+	reqBody, err := ocsp.CreateRequest(s.leaf, s.issuer, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.leaf.OCSPServer[0], bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("responder returned %s", res.Status)
+	}
+	raw, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := ocsp.ParseResponseForCert(raw, s.leaf, s.issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+	return raw, resp, nil
+}
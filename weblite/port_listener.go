@@ -1,9 +1,12 @@
 package weblite
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 // PortListener represents a configured port listener with protocol and SSL settings
@@ -19,6 +22,46 @@ type PortListener struct {
 	HTTPSRedirectPort  string           // For HTTP listeners: redirect to this HTTPS port
 	HTTPSRedirect      bool             // Automatically redirect HTTP to HTTPS when SSL is enabled (default: true)
 	DomainValidator    *DomainValidator // Domain validator for validation
+	MaxHeaderBytes     int              // http.Server.MaxHeaderBytes (0 = Go default of 1MB)
+	Limits             *RequestLimits   // URL length and per-header size limits
+
+	// TCP tuning for OptimizeCloudflare listeners (config keys cf_mss, cf_keepalive, cf_nodelay, cf_backlog)
+	Cloudflare *CloudflareTuning
+
+	// DualStack decides how "::" and "0.0.0.0" share a port (config key dual_stack)
+	DualStack DualStackPolicy
+
+	// Acceptors > 1 opens that many SO_REUSEPORT sockets per address so the
+	// kernel spreads incoming connections over several accept loops (config key acceptors)
+	Acceptors int
+
+	// Conns counts connections and TLS handshakes across all servers of this listener
+	Conns *ConnStats
+
+	// TLS restricts versions, cipher suites, curves and ALPN of HTTPS listeners
+	// (config keys tls_min_version, tls_max_version, tls_ciphers, tls_curves, tls_alpn)
+	TLS *TLSPolicy
+
+	// OCSPStapling fetches and refreshes OCSP staples for the certificate (config key ocsp_stapling)
+	OCSPStapling bool
+	// TicketKeyRotation replaces the session ticket key at this interval,
+	// keeping the previous keys for resumption (config key ticket_key_rotation, e.g. "6h")
+	TicketKeyRotation time.Duration
+
+	// ConnLimits sets the http.Server timeouts and caps open connections
+	// (config keys read_timeout, read_header_timeout, write_timeout, idle_timeout, max_conns)
+	ConnLimits *ConnLimits
+
+	// HTTP3 tunes the HTTP/3 server of HTTPS listeners
+	// (config keys h3_max_idle_timeout, h3_max_streams, h3_0rtt, h3_alt_svc_max_age)
+	HTTP3 *HTTP3Options
+
+	// IPValidator restricts client addresses, see DomainValidator for hosts
+	// (config keys ips_allow, ips_block, ips_trusted_proxies)
+	IPValidator *IPValidator
+
+	// ticketKeyErr records an invalid ticket_key_rotation value
+	ticketKeyErr error
 }
 
 // NewPortListener creates a new PortListener from a configuration map
@@ -32,6 +75,9 @@ func NewPortListener(config map[string]string) *PortListener {
 		SSLKeyData:         config["ssl_key_data"],
 		HTTPSRedirectPort:  config["https_redirect_port"],
 		HTTPSRedirect:      config["https_redirect"] != "false", // Default true
+		DualStack:          ParseDualStackPolicy(config["dual_stack"]),
+		Conns:              NewConnStats(),
+		OCSPStapling:       config["ocsp_stapling"] == "true",
 	}
 
 	// Parse ports
@@ -57,6 +103,49 @@ func NewPortListener(config map[string]string) *PortListener {
 		pl.Addresses = []string{"::"}
 	}
 
+	pl.Acceptors, _ = strconv.Atoi(config["acceptors"])
+
+	// Parse request size limits
+	pl.MaxHeaderBytes, _ = strconv.Atoi(config["max_header_bytes"])
+	pl.Limits = &RequestLimits{}
+	pl.Limits.MaxURLLength, _ = strconv.Atoi(config["max_url_length"])
+	pl.Limits.MaxHeaderSize, _ = strconv.Atoi(config["max_header_size"])
+
+	// TLS policy; a bad value fails the listener at start rather than weakening it
+	if policy, err := ParseTLSPolicy(config); err != nil {
+		pl.TLS = &TLSPolicy{err: err}
+	} else {
+		pl.TLS = policy
+	}
+
+	// A bad rotation interval fails the listener at start rather than silently keeping Go's default
+	if value := config["ticket_key_rotation"]; value != "" {
+		rotation, err := time.ParseDuration(value)
+		if err != nil || rotation < 0 {
+			pl.ticketKeyErr = fmt.Errorf("listener config: invalid ticket_key_rotation %q", value)
+		} else {
+			pl.TicketKeyRotation = rotation
+		}
+	}
+
+	// Timeouts and connection cap; a bad value fails the listener at start rather than lifting the limit
+	if limits, err := ParseConnLimits(config); err != nil {
+		pl.ConnLimits = &ConnLimits{err: err}
+	} else {
+		pl.ConnLimits = limits
+	}
+
+	if opts, err := ParseHTTP3Options(config); err != nil {
+		pl.HTTP3 = &HTTP3Options{err: err}
+	} else {
+		pl.HTTP3 = opts
+	}
+
+	// CloudFlare TCP tuning
+	if pl.OptimizeCloudflare {
+		pl.Cloudflare = parseCloudflareTuning(config)
+	}
+
 	// Initialize domain validator
 	pl.DomainValidator = NewDomainValidator()
 
@@ -69,6 +158,13 @@ func NewPortListener(config map[string]string) *PortListener {
 		pl.DomainValidator.SetAllowedDomains(domains...)
 	}
 
+	// Client address ranges; a bad range fails the listener at start rather than opening it up
+	if iv, err := ParseIPValidator(config); err != nil {
+		pl.IPValidator = &IPValidator{err: err}
+	} else {
+		pl.IPValidator = iv
+	}
+
 	// Parse disallowed domains into validator
 	if disallowedStr := config["domains_block"]; disallowedStr != "" {
 		domains := strings.Split(disallowedStr, ",")
@@ -81,11 +177,41 @@ func NewPortListener(config map[string]string) *PortListener {
 	return pl
 }
 
+// Err returns the first invalid setting of the listener config; such settings
+// otherwise only fail the listener when it starts
+func (pl *PortListener) Err() error {
+	if pl.TLS != nil && pl.TLS.err != nil {
+		return pl.TLS.err
+	}
+	if pl.ConnLimits != nil && pl.ConnLimits.err != nil {
+		return pl.ConnLimits.err
+	}
+	if pl.HTTP3 != nil && pl.HTTP3.err != nil {
+		return pl.HTTP3.err
+	}
+	if pl.IPValidator != nil && pl.IPValidator.err != nil {
+		return pl.IPValidator.err
+	}
+	return pl.ticketKeyErr
+}
+
 // IsHTTPS returns true if this listener is configured for HTTPS
 func (pl *PortListener) IsHTTPS() bool {
 	return pl.Protocol == "https"
 }
 
+// SetRequestLimits sets the maximum URL length and single header size (0 = unlimited)
+func (pl *PortListener) SetRequestLimits(maxURLLength, maxHeaderSize int) *PortListener {
+	pl.Limits = &RequestLimits{MaxURLLength: maxURLLength, MaxHeaderSize: maxHeaderSize}
+	return pl
+}
+
+// SetCloudflareTuning overrides the TCP settings of an OptimizeCloudflare listener
+func (pl *PortListener) SetCloudflareTuning(tuning *CloudflareTuning) *PortListener {
+	pl.Cloudflare = tuning
+	return pl
+}
+
 // HasSSLConfig returns true if SSL configuration is present
 func (pl *PortListener) HasSSLConfig() bool {
 	return (pl.SSLCertPath != "" && pl.SSLKeyPath != "") ||
@@ -0,0 +1,219 @@
+package weblite
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PortListenerConfig is the typed form of the AddPortListener config map.
+// Zero values select the same defaults as a missing map key.
+type PortListenerConfig struct {
+	Protocol  string   // "http" (default) or "https"
+	Ports     []string // Required; "0" picks a free port
+	Addresses []string // Default "::"
+
+	// Certificate files or PEM data; https needs one complete pair
+	SSLCertPath string
+	SSLKeyPath  string
+	SSLCertData string
+	SSLKeyData  string
+
+	HTTPSRedirectPort    string // For HTTP listeners: redirect to this HTTPS port
+	DisableHTTPSRedirect bool   // Turns off the HTTP to HTTPS redirect of TLS listeners
+
+	OptimizeCloudflare bool
+	Cloudflare         *CloudflareTuning // nil = DefaultCloudflareTuning
+	DualStack          DualStackPolicy   // "" = DualStackPreferV6
+	Acceptors          int
+
+	MaxHeaderBytes int // 0 = Go default of 1MB
+	MaxURLLength   int // 0 = unlimited
+	MaxHeaderSize  int // 0 = unlimited
+
+	TLS               *TLSPolicy // nil = secure defaults
+	OCSPStapling      bool
+	TicketKeyRotation time.Duration
+	ConnLimits        *ConnLimits   // nil = default header read and idle timeouts, no connection cap
+	HTTP3             *HTTP3Options // nil = quic-go defaults, no 0-RTT
+
+	AllowedDomains []string // Empty accepts all; supports wildcards
+	BlockedDomains []string
+
+	AllowedIPs     []string // CIDRs or IPs; empty accepts all
+	BlockedIPs     []string
+	TrustedProxies []string // Peers whose forwarding headers name the client, see IPValidator
+}
+
+// Validate reports every invalid setting of the config
+func (c *PortListenerConfig) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("listener config: "+format, args...))
+	}
+
+	switch strings.ToLower(c.Protocol) {
+	case "", "http":
+	case "https":
+		hasFiles := c.SSLCertPath != "" && c.SSLKeyPath != ""
+		hasData := c.SSLCertData != "" && c.SSLKeyData != ""
+		if !hasFiles && !hasData {
+			fail("https needs SSLCertPath and SSLKeyPath or SSLCertData and SSLKeyData")
+		}
+	default:
+		fail("unknown protocol %q", c.Protocol)
+	}
+
+	if len(c.Ports) == 0 {
+		fail("no ports")
+	}
+	for _, port := range c.Ports {
+		if n, err := strconv.Atoi(strings.TrimSpace(port)); err != nil || n < 0 || n > 65535 {
+			fail("invalid port %q", port)
+		}
+	}
+	if c.HTTPSRedirectPort != "" {
+		if n, err := strconv.Atoi(c.HTTPSRedirectPort); err != nil || n <= 0 || n > 65535 {
+			fail("invalid HTTPSRedirectPort %q", c.HTTPSRedirectPort)
+		}
+	}
+	for _, addr := range c.Addresses {
+		if strings.TrimSpace(addr) == "" {
+			fail("empty address")
+		}
+	}
+
+	if c.DualStack != "" && ParseDualStackPolicy(string(c.DualStack)) != c.DualStack {
+		fail("unknown dual-stack policy %q", c.DualStack)
+	}
+	if c.Acceptors < 0 {
+		fail("negative Acceptors")
+	}
+	if c.MaxHeaderBytes < 0 || c.MaxURLLength < 0 || c.MaxHeaderSize < 0 {
+		fail("negative request size limit")
+	}
+	if c.TicketKeyRotation < 0 {
+		fail("negative TicketKeyRotation")
+	}
+	if c.TLS != nil {
+		if err := c.TLS.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cl := c.ConnLimits; cl != nil {
+		if cl.ReadTimeout < 0 || cl.ReadHeaderTimeout < 0 || cl.WriteTimeout < 0 || cl.IdleTimeout < 0 || cl.MaxConns < 0 {
+			fail("negative connection limit")
+		}
+	}
+	if _, err := newIPValidator(c.AllowedIPs, c.BlockedIPs, c.TrustedProxies); err != nil {
+		errs = append(errs, err)
+	}
+	if c.HTTP3 != nil {
+		if err := c.HTTP3.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewPortListenerFromConfig validates cfg and creates the PortListener
+func NewPortListenerFromConfig(cfg PortListenerConfig) (*PortListener, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	pl := &PortListener{
+		Protocol:           strings.ToLower(cfg.Protocol),
+		OptimizeCloudflare: cfg.OptimizeCloudflare,
+		SSLCertPath:        cfg.SSLCertPath,
+		SSLKeyPath:         cfg.SSLKeyPath,
+		SSLCertData:        cfg.SSLCertData,
+		SSLKeyData:         cfg.SSLKeyData,
+		HTTPSRedirectPort:  cfg.HTTPSRedirectPort,
+		HTTPSRedirect:      !cfg.DisableHTTPSRedirect,
+		DualStack:          ParseDualStackPolicy(string(cfg.DualStack)),
+		Acceptors:          cfg.Acceptors,
+		MaxHeaderBytes:     cfg.MaxHeaderBytes,
+		Limits:             &RequestLimits{MaxURLLength: cfg.MaxURLLength, MaxHeaderSize: cfg.MaxHeaderSize},
+		Conns:              NewConnStats(),
+		TLS:                cfg.TLS,
+		OCSPStapling:       cfg.OCSPStapling,
+		TicketKeyRotation:  cfg.TicketKeyRotation,
+		ConnLimits:         cfg.ConnLimits,
+		HTTP3:              cfg.HTTP3,
+		DomainValidator:    NewDomainValidator(),
+	}
+	if pl.Protocol == "" {
+		pl.Protocol = "http"
+	}
+	for _, port := range cfg.Ports {
+		pl.Ports = append(pl.Ports, strings.TrimSpace(port))
+	}
+	for _, addr := range cfg.Addresses {
+		pl.Addresses = append(pl.Addresses, strings.Trim(strings.TrimSpace(addr), "[]"))
+	}
+	if len(pl.Addresses) == 0 {
+		pl.Addresses = []string{"::"}
+	}
+	if pl.ConnLimits == nil {
+		pl.ConnLimits, _ = ParseConnLimits(nil)
+	}
+	if pl.OptimizeCloudflare {
+		pl.Cloudflare = cfg.Cloudflare
+		if pl.Cloudflare == nil {
+			pl.Cloudflare = DefaultCloudflareTuning()
+		}
+	}
+	pl.IPValidator, _ = newIPValidator(cfg.AllowedIPs, cfg.BlockedIPs, cfg.TrustedProxies) // Validated above
+	if len(cfg.AllowedDomains) > 0 {
+		pl.DomainValidator.SetAllowedDomains(cfg.AllowedDomains...)
+	}
+	if len(cfg.BlockedDomains) > 0 {
+		pl.DomainValidator.SetDisallowedDomains(cfg.BlockedDomains...)
+	}
+	return pl, nil
+}
+
+// AddPortListenerConfig adds a port listener from a typed config, returning
+// the validation error instead of ignoring bad settings
+func (wl *WebLite) AddPortListenerConfig(cfg PortListenerConfig) error {
+	listener, err := NewPortListenerFromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	wl.PortListeners = append(wl.PortListeners, listener)
+	return nil
+}
+
+// portListenerKeys lists the keys NewPortListener reads
+var portListenerKeys = map[string]bool{
+	"protocol": true, "ports": true, "addresses": true,
+	"ssl_cert_path": true, "ssl_key_path": true, "ssl_cert_data": true, "ssl_key_data": true,
+	"https_redirect_port": true, "https_redirect": true,
+	"optimizeCloudflare": true, "cf_mss": true, "cf_keepalive": true, "cf_nodelay": true, "cf_backlog": true,
+	"dual_stack": true, "acceptors": true,
+	"max_header_bytes": true, "max_url_length": true, "max_header_size": true,
+	"tls_min_version": true, "tls_max_version": true, "tls_ciphers": true, "tls_curves": true, "tls_alpn": true,
+	"ocsp_stapling": true, "ticket_key_rotation": true,
+	"read_timeout": true, "read_header_timeout": true, "write_timeout": true, "idle_timeout": true, "max_conns": true,
+	"h3_max_idle_timeout": true, "h3_max_streams": true, "h3_0rtt": true, "h3_alt_svc_max_age": true,
+	"domains_allow": true, "domains_block": true,
+	"ips_allow": true, "ips_block": true, "ips_trusted_proxies": true,
+}
+
+// UnknownPortListenerKeys returns the keys of config that NewPortListener ignores, sorted
+func UnknownPortListenerKeys(config map[string]string) []string {
+	var unknown []string
+	for key := range config {
+		if !portListenerKeys[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
@@ -0,0 +1,130 @@
+package weblite
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	httppprof "net/http/pprof"
+	"net/netip"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-xlite/wbx/comm"
+	"github.com/go-xlite/wbx/comm/routes"
+)
+
+// Limits of a route CPU sample, see EnablePprof
+const (
+	DefaultSampleDuration = 10 * time.Second
+	MaxSampleDuration     = time.Minute
+)
+
+// ProfileRouteLabel is the pprof label carrying the route prefix of sampled requests
+const ProfileRouteLabel = "route"
+
+// EnablePprof mounts the net/http/pprof endpoints under prefix for requests
+// guard accepts; others get a 404. A nil guard admits loopback clients only,
+// judged by comm.ClientIP so requests relayed by a local reverse proxy are
+// attributed to their real client rather than the proxy.
+// Besides the standard profiles, {prefix}/sample?route=/api/&seconds=10 records
+// a CPU profile while labelling requests under route, so the handler's share
+// can be isolated with: go tool pprof -tagfocus route=/api/ cpu.pprof
+//
+//	wl.EnablePprof("/_debug/pprof", routes.ClientIn(comm.ClientIP, "10.0.0.0/8"))
+func (wl *WebLite) EnablePprof(prefix string, guard routes.RequestPredicate) *WebLite {
+	if guard == nil {
+		guard = loopbackClient
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if !guard(r) {
+			http.NotFound(w, r)
+			return
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, prefix), "/")
+		switch name {
+		case "cmdline":
+			httppprof.Cmdline(w, r)
+		case "profile":
+			httppprof.Profile(w, r)
+		case "symbol":
+			httppprof.Symbol(w, r)
+		case "trace":
+			httppprof.Trace(w, r)
+		case "sample":
+			wl.serveRouteSample(w, r)
+		default:
+			// Index serves named profiles (heap, goroutine, ...) relative to /debug/pprof/
+			r.URL.Path = "/debug/pprof/" + name
+			httppprof.Index(w, r)
+		}
+	}
+	wl.Routes.HandlePathFn(prefix, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, prefix+"/", http.StatusMovedPermanently)
+	})
+	wl.Routes.HandlePathPrefixFn(prefix+"/", handler)
+	return wl
+}
+
+// loopbackClient reports whether the client of r is on the local host
+func loopbackClient(r *http.Request) bool {
+	addr, err := netip.ParseAddr(comm.ClientIP(r))
+	return err == nil && addr.Unmap().IsLoopback()
+}
+
+// serveRouteSample records a CPU profile for ?seconds= while requests under
+// ?route= carry the ProfileRouteLabel
+func (wl *WebLite) serveRouteSample(w http.ResponseWriter, r *http.Request) {
+	route := r.URL.Query().Get("route")
+	if !strings.HasPrefix(route, "/") {
+		http.Error(w, "route must be a path prefix", http.StatusBadRequest)
+		return
+	}
+	duration := DefaultSampleDuration
+	if s := r.URL.Query().Get("seconds"); s != "" {
+		seconds, err := strconv.Atoi(s)
+		if err != nil || seconds <= 0 {
+			http.Error(w, "invalid seconds", http.StatusBadRequest)
+			return
+		}
+		duration = min(time.Duration(seconds)*time.Second, MaxSampleDuration)
+	}
+
+	// Only one CPU profile can run per process
+	if !wl.sampleRoute.CompareAndSwap(nil, &route) {
+		http.Error(w, "a route sample is already running", http.StatusConflict)
+		return
+	}
+	defer wl.sampleRoute.Store(nil)
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="cpu.pprof"`)
+	if err := pprof.StartCPUProfile(w); err != nil {
+		w.Header().Del("Content-Disposition")
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		http.Error(w, fmt.Sprintf("could not start CPU profile: %v", err), http.StatusConflict)
+		return
+	}
+	select {
+	case <-time.After(duration):
+	case <-r.Context().Done():
+	}
+	pprof.StopCPUProfile()
+}
+
+// profileLabels runs requests under the sampled route with ProfileRouteLabel set
+func (wl *WebLite) profileLabels(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := wl.sampleRoute.Load()
+		if route == nil || !strings.HasPrefix(r.URL.Path, *route) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		pprof.Do(r.Context(), pprof.Labels(ProfileRouteLabel, *route), func(ctx context.Context) {
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+}
@@ -0,0 +1,135 @@
+package weblite
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-xlite/wbx/comm"
+)
+
+// RecoveryStats counts the panics answered by a Recovery
+type RecoveryStats struct {
+	Panics    int64     `json:"panics"`
+	LastPanic time.Time `json:"last_panic"` // Zero until the first panic
+	LastPath  string    `json:"last_path,omitempty"`
+}
+
+// Recovery catches panics of every route, logs them with their stack and
+// answers with the 500 error page, or a JSON error on API routes
+type Recovery struct {
+	ErrorPage   func(status int) []byte // Page source, e.g. RootHandler.ErrorPage; plain text when nil
+	APIPrefixes []string                // Paths answered with a JSON error
+	stats       RecoveryStats
+	mu          sync.Mutex
+}
+
+// recoveryError is the data passed to error pages using {{.Status}}, {{.Title}} and {{.Message}}
+type recoveryError struct {
+	Status  int    `json:"status"`
+	Code    string `json:"error"`
+	Title   string `json:"-"`
+	Message string `json:"message"`
+}
+
+// EnableRecovery answers panicking requests with errorPage(500) (typically
+// RootHandler.ErrorPage) and requests under apiPrefixes with a JSON error:
+//
+//	wl.EnableRecovery(root.ErrorPage, "/api/")
+func (wl *WebLite) EnableRecovery(errorPage func(status int) []byte, apiPrefixes ...string) *Recovery {
+	rc := &Recovery{ErrorPage: errorPage, APIPrefixes: apiPrefixes}
+	wl.mu.Lock()
+	wl.recovery = rc
+	wl.mu.Unlock()
+	return rc
+}
+
+// GetStats returns a copy of the panic counters
+func (rc *Recovery) GetStats() RecoveryStats {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.stats
+}
+
+// Middleware recovers panics of next through comm.RecoverPanics, counting
+// them in the stats. A panic after the response started aborts only this
+// connection, as net/http would do.
+func (rc *Recovery) Middleware(next http.Handler) http.Handler {
+	counted := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec != http.ErrAbortHandler {
+					rc.record(r)
+				}
+				panic(rec)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+	return comm.RecoverPanics("weblite", counted, func(w http.ResponseWriter, r *http.Request, err error) {
+		rc.respond(w, r)
+	})
+}
+
+func (rc *Recovery) record(r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.stats.Panics++
+	rc.stats.LastPanic = time.Now()
+	rc.stats.LastPath = r.URL.Path
+}
+
+// isAPI reports whether r is answered with JSON
+func (rc *Recovery) isAPI(r *http.Request) bool {
+	for _, prefix := range rc.APIPrefixes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// respond writes the 500 answer for a recovered panic
+func (rc *Recovery) respond(w http.ResponseWriter, r *http.Request) {
+	re := recoveryError{
+		Status:  http.StatusInternalServerError,
+		Code:    "internal_error",
+		Title:   http.StatusText(http.StatusInternalServerError),
+		Message: "The server hit an unexpected error. Please try again later.",
+	}
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Del("Content-Encoding")
+	h.Set("Cache-Control", "no-store")
+	h.Set("X-Content-Type-Options", "nosniff")
+
+	if rc.isAPI(r) {
+		h.Set("Content-Type", "application/json")
+		w.WriteHeader(re.Status)
+		json.NewEncoder(w).Encode(re)
+		return
+	}
+
+	var page []byte
+	if rc.ErrorPage != nil {
+		page = rc.ErrorPage(re.Status)
+	}
+	if len(page) == 0 {
+		http.Error(w, re.Title, re.Status)
+		return
+	}
+	// Pages shared with proxies may use the error fields
+	if bytes.Contains(page, []byte("{{")) {
+		var body bytes.Buffer
+		if tmpl, err := template.New("error").Parse(string(page)); err == nil && tmpl.Execute(&body, re) == nil {
+			page = body.Bytes()
+		}
+	}
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(re.Status)
+	w.Write(page)
+}
@@ -0,0 +1,279 @@
+package weblite
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// listenerStartupWait is how long live listener changes wait for bind failures
+const listenerStartupWait = 250 * time.Millisecond
+
+// DefaultDrainTimeout bounds how long a removed listener may finish in-flight requests
+const DefaultDrainTimeout = 5 * time.Second
+
+// AddListener adds a port listener; on a running server it is bound immediately.
+// Bind failures that occur within a short startup window are returned.
+func (wl *WebLite) AddListener(config map[string]string) (*PortListener, error) {
+	listener := NewPortListener(config)
+	if err := wl.ApplyListener(listener); err != nil {
+		return nil, err
+	}
+	return listener, nil
+}
+
+// ApplyListener registers listener and, on a running server, starts it
+func (wl *WebLite) ApplyListener(listener *PortListener) error {
+	wl.mu.Lock()
+	wl.PortListeners = append(wl.PortListeners, listener)
+	running := wl.running
+	wl.mu.Unlock()
+
+	if !running {
+		return nil
+	}
+	if err := wl.bindLive(listener); err != nil {
+		wl.mu.Lock()
+		wl.removePortListener(listener)
+		wl.mu.Unlock()
+		return err
+	}
+	wl.publishReload("listener.added", nil)
+	return nil
+}
+
+// RemoveListener unregisters listener and drains its servers: they stop
+// accepting connections and in-flight requests get up to DefaultDrainTimeout
+func (wl *WebLite) RemoveListener(listener *PortListener) error {
+	wl.mu.Lock()
+	found := wl.removePortListener(listener)
+	wl.mu.Unlock()
+
+	if !found {
+		return fmt.Errorf("listener not registered on server %s", wl.Name)
+	}
+	err := wl.drainListener(listener, DefaultDrainTimeout)
+	wl.publishReload("listener.removed", err)
+	return err
+}
+
+// ReplaceListener swaps old for a listener built from config: the new one is
+// bound first and old is drained afterwards. When both share an address the
+// old listener is drained before binding, which briefly refuses connections.
+func (wl *WebLite) ReplaceListener(old *PortListener, config map[string]string) (*PortListener, error) {
+	listener := NewPortListener(config)
+
+	wl.mu.RLock()
+	running := wl.running
+	wl.mu.RUnlock()
+
+	if running {
+		// Hold the server open while old and new listeners hand over
+		wl.active.Add(1)
+		defer wl.active.Done()
+
+		if sharesBindAddress(old, listener) {
+			if err := wl.drainListener(old, DefaultDrainTimeout); err != nil {
+				fmt.Printf("WebLite [%s] drain error: %v\n", wl.Name, err)
+			}
+			if err := wl.bindLive(listener); err != nil {
+				// Put the old listener back so the server keeps serving
+				if rebindErr := wl.bindLive(old); rebindErr != nil {
+					fmt.Printf("WebLite [%s] failed to restore listener: %v\n", wl.Name, rebindErr)
+				}
+				return nil, err
+			}
+		} else {
+			if err := wl.bindLive(listener); err != nil {
+				return nil, err
+			}
+			if err := wl.drainListener(old, DefaultDrainTimeout); err != nil {
+				fmt.Printf("WebLite [%s] drain error: %v\n", wl.Name, err)
+			}
+		}
+	}
+
+	wl.mu.Lock()
+	wl.removePortListener(old)
+	wl.PortListeners = append(wl.PortListeners, listener)
+	wl.mu.Unlock()
+	if running {
+		wl.publishReload("listener.replaced", nil)
+	}
+	return listener, nil
+}
+
+// Restart drains and re-binds every listener of a running server, picking up
+// changed ports, TLS material and middleware without a process restart.
+// Start keeps blocking across the restart.
+func (wl *WebLite) Restart() error {
+	wl.mu.RLock()
+	running := wl.running
+	listeners := make([]*PortListener, len(wl.PortListeners))
+	copy(listeners, wl.PortListeners)
+	wl.mu.RUnlock()
+
+	if !running {
+		return fmt.Errorf("server %s is not running", wl.Name)
+	}
+
+	fmt.Printf("WebLite [%s] restarting...\n", wl.Name)
+
+	// Hold the server open while listeners are cycled
+	wl.active.Add(1)
+	defer wl.active.Done()
+
+	for _, listener := range listeners {
+		if err := wl.drainListener(listener, DefaultDrainTimeout); err != nil {
+			fmt.Printf("WebLite [%s] drain error: %v\n", wl.Name, err)
+		}
+	}
+
+	var errs []string
+	for _, listener := range listeners {
+		if err := wl.bindLive(listener); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		err := fmt.Errorf("errors restarting server %s: %s", wl.Name, strings.Join(errs, "; "))
+		wl.publishReload("restart", err)
+		return err
+	}
+
+	fmt.Printf("WebLite [%s] restarted\n", wl.Name)
+	wl.publishReload("restart", nil)
+	return nil
+}
+
+// bindLive starts listener on a running server and waits briefly for bind failures
+func (wl *WebLite) bindLive(listener *PortListener) error {
+	failures := make(chan error, len(listener.Ports)*len(listener.Addresses))
+	wl.launchListener(listener, func(addr string, err error) {
+		// The peer family is still binding concurrently, so trust the configuration
+		peer := peerWildcard(addr)
+		if err != nil && listener.canIgnoreBindError(addr, err, peer != "" && listener.bindsAddress(peer)) {
+			fmt.Printf("WebLite [%s] bind on %s failed (%v), but %s is configured - allowed by dual-stack policy %s\n", wl.Name, addr, err, peer, listener.DualStack)
+			return
+		}
+		if err != nil {
+			fmt.Printf("WebLite [%s] listener %s stopped: %v\n", wl.Name, addr, err)
+			failures <- err
+		}
+	})
+
+	select {
+	case err := <-failures:
+		wl.drainListener(listener, 0)
+		return err
+	case <-time.After(listenerStartupWait):
+		return nil
+	}
+}
+
+// drainListener gracefully shuts down the servers started for listener
+func (wl *WebLite) drainListener(listener *PortListener, timeout time.Duration) error {
+	wl.mu.Lock()
+	var servers []*http.Server
+	remaining := wl.servers[:0]
+	for _, server := range wl.servers {
+		if wl.owners[server] == listener {
+			servers = append(servers, server)
+			delete(wl.owners, server)
+		} else {
+			remaining = append(remaining, server)
+		}
+	}
+	wl.servers = remaining
+
+	var quic []quicServer
+	for _, server := range wl.quicServers {
+		if wl.owners[server] == listener {
+			quic = append(quic, server)
+		}
+	}
+	wl.mu.Unlock()
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var errs []string
+	for _, server := range servers {
+		var err error
+		if timeout > 0 {
+			err = server.Shutdown(ctx)
+		} else {
+			err = server.Close()
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	for _, server := range quic {
+		var err error
+		if timeout > 0 {
+			err = server.Shutdown(ctx)
+		} else {
+			err = server.Close()
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("errors draining listener: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// removePortListener removes listener from PortListeners; wl.mu must be held
+func (wl *WebLite) removePortListener(listener *PortListener) bool {
+	for i, pl := range wl.PortListeners {
+		if pl == listener {
+			wl.PortListeners = append(wl.PortListeners[:i], wl.PortListeners[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// sharesBindAddress reports whether a and b would bind a common port/address
+func sharesBindAddress(a, b *PortListener) bool {
+	for _, pa := range a.Ports {
+		for _, pb := range b.Ports {
+			// Port "0" always gets a fresh port from the OS
+			if pa != pb || pa == "0" {
+				continue
+			}
+			for _, aa := range a.Addresses {
+				for _, ab := range b.Addresses {
+					if aa == ab || isWildcard(aa) || isWildcard(ab) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// bindsAddress reports whether listener is configured to bind addr (host:port)
+func (pl *PortListener) bindsAddress(addr string) bool {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(pl.Addresses, host) && slices.Contains(pl.Ports, port)
+}
+
+func isWildcard(addr string) bool {
+	return addr == "" || addr == "::" || addr == "0.0.0.0"
+}
@@ -0,0 +1,56 @@
+package weblite
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// RequestLimits rejects requests whose URL or individual headers are too long.
+// It complements http.Server.MaxHeaderBytes (which bounds the whole header block
+// and fails with a bare 431) with per-item limits and JSON error bodies.
+type RequestLimits struct {
+	MaxURLLength  int // Maximum request URI length in bytes (0 = unlimited), 414 when exceeded
+	MaxHeaderSize int // Maximum size of a single header line, name + value (0 = unlimited), 431 when exceeded
+}
+
+// Enabled reports whether any limit is configured
+func (rl *RequestLimits) Enabled() bool {
+	return rl != nil && (rl.MaxURLLength > 0 || rl.MaxHeaderSize > 0)
+}
+
+// Middleware enforces the limits before calling next
+func (rl *RequestLimits) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rl.MaxURLLength > 0 && len(r.RequestURI) > rl.MaxURLLength {
+			writeLimitError(w, http.StatusRequestURITooLong, "uri_too_long",
+				fmt.Sprintf("request URI is %d bytes, limit is %d", len(r.RequestURI), rl.MaxURLLength), rl.MaxURLLength)
+			return
+		}
+
+		if rl.MaxHeaderSize > 0 {
+			for name, values := range r.Header {
+				for _, value := range values {
+					if size := len(name) + len(value); size > rl.MaxHeaderSize {
+						writeLimitError(w, http.StatusRequestHeaderFieldsTooLarge, "header_too_large",
+							fmt.Sprintf("header %s is %d bytes, limit is %d", name, size, rl.MaxHeaderSize), rl.MaxHeaderSize)
+						return
+					}
+				}
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeLimitError(w http.ResponseWriter, status int, code, message string, limit int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Connection", "close")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error":   code,
+		"message": message,
+		"limit":   limit,
+	})
+}
@@ -0,0 +1,95 @@
+package weblite
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// SetAcceptors opens n SO_REUSEPORT sockets per address (n <= 1 disables)
+func (pl *PortListener) SetAcceptors(n int) *PortListener {
+	pl.Acceptors = n
+	return pl
+}
+
+// bindAcceptors opens listener.Acceptors sockets sharing addr via SO_REUSEPORT,
+// each served by its own accept loop (see serveSockets). With port "0" the
+// first socket picks the port and the others join it.
+func (wl *WebLite) bindAcceptors(listener *PortListener, addr string) ([]net.Listener, error) {
+	first, err := wl.bindSocket(listener, addr, reusePortControl)
+	if err != nil {
+		return nil, err
+	}
+	addr = first.Addr().String()
+
+	lns := []net.Listener{first}
+	for len(lns) < listener.Acceptors {
+		ln, err := wl.bindSocket(listener, addr, reusePortControl)
+		if err != nil {
+			closeSockets(lns)
+			return nil, fmt.Errorf("acceptor %d of %d: %w", len(lns)+1, listener.Acceptors, err)
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
+
+// reusePortControl sets SO_REUSEPORT before bind
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// chainControl runs several ListenConfig.Control functions in order, skipping nil ones
+func chainControl(controls ...func(network, address string, c syscall.RawConn) error) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		for _, control := range controls {
+			if control == nil {
+				continue
+			}
+			if err := control(network, address, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// serveSockets runs serve on every socket of one address, so each
+// SO_REUSEPORT socket has its own accept loop. When one loop fails the
+// sockets are closed so the others end too; the first error is returned once
+// all have exited.
+func serveSockets(sockets []net.Listener, serve func(ln net.Listener) error) error {
+	if len(sockets) == 1 {
+		return serve(sockets[0])
+	}
+	errs := make(chan error, len(sockets))
+	for _, ln := range sockets {
+		go func() {
+			errs <- serve(ln)
+		}()
+	}
+	var first error
+	for range sockets {
+		if err := <-errs; first == nil {
+			first = err
+			closeSockets(sockets)
+		}
+	}
+	return first
+}
+
+// closeSockets closes every socket of one address
+func closeSockets(sockets []net.Listener) {
+	for _, ln := range sockets {
+		ln.Close()
+	}
+}
@@ -0,0 +1,23 @@
+package weblite
+
+// RobotsNoIndex keeps pages out of search results and stops crawlers from
+// following their links
+const RobotsNoIndex = "noindex, nofollow"
+
+// SetRobots sends X-Robots-Tag with directives (e.g. "noindex, noarchive") on
+// every response under prefix. Unlike robots.txt the header binds crawlers
+// that fetched the page anyway, e.g. through an external link.
+func (wl *WebLite) SetRobots(prefix, directives string) *HeaderRule {
+	return wl.HeaderRule(prefix).SetHeader("X-Robots-Tag", directives)
+}
+
+// NoIndex keeps the responses under prefixes out of search engines, e.g. login
+// apps, admin surfaces, or "/" on a staging server
+//
+//	wl.NoIndex("/g/", "/admin/")
+func (wl *WebLite) NoIndex(prefixes ...string) *WebLite {
+	for _, prefix := range prefixes {
+		wl.SetRobots(prefix, RobotsNoIndex)
+	}
+	return wl
+}
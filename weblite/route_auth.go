@@ -0,0 +1,42 @@
+package weblite
+
+import (
+	"net/http"
+
+	"github.com/go-xlite/wbx/comm/routes"
+)
+
+// RouteAuthLookup reports the auth requirement declared by the route serving a
+// request, see routes.Route.Public and routes.Route.RequireSession
+type RouteAuthLookup interface {
+	RouteAuth(r *http.Request) (string, bool)
+}
+
+// RouteAuth returns the auth requirement declared by the route serving r,
+// looking in the route tree of its host first
+func (wl *WebLite) RouteAuth(r *http.Request) (string, bool) {
+	if vh, _ := wl.matchHost(r); vh != nil {
+		return vh.routes.RouteAuth(r)
+	}
+	return wl.Routes.RouteAuth(r)
+}
+
+// MiddlewareFor returns session middleware that consults the auth declared on
+// the routes of lookup: public routes are let through, session routes are
+// enforced even under skipped paths, and undeclared routes use the skip lists.
+func (sm *SessionManager) MiddlewareFor(lookup RouteAuthLookup) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		enforce := sm.Middleware(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if auth, ok := lookup.RouteAuth(r); ok {
+				if auth == routes.AuthPublic {
+					next.ServeHTTP(w, r)
+				} else {
+					sm.enforce(w, r, next)
+				}
+				return
+			}
+			enforce.ServeHTTP(w, r)
+		})
+	}
+}
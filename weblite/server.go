@@ -6,11 +6,17 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/go-xlite/wbx/comm"
+	"github.com/go-xlite/wbx/comm/events"
 	"github.com/go-xlite/wbx/comm/routes"
+	"github.com/go-xlite/wbx/middleware"
 	"github.com/gorilla/mux"
 )
 
@@ -25,10 +31,52 @@ type WebLite struct {
 	// Port listeners configuration
 	PortListeners []*PortListener
 
+	// Middlewares wrap Routes on every listener (first added = outermost)
+	Middlewares []func(http.Handler) http.Handler
+
+	// HeaderRules adjust response headers per path prefix, see HeaderRule
+	HeaderRules []*HeaderRule
+
+	tenants *Tenants     // See EnableTenants
+	vhosts  virtualHosts // Per-host route trees, see Host
+
 	// Server management
-	servers []*http.Server
-	running bool
-	mu      sync.RWMutex
+	servers     []*http.Server
+	quicServers []quicServer          // HTTP/3 servers running next to HTTPS listeners
+	owners      map[any]*PortListener // Server -> listener it was started for
+	active      sync.WaitGroup        // Running listener goroutines
+	running     bool
+	mu          sync.RWMutex
+
+	// CloudflareRefresh is how often Cloudflare edge ranges are re-downloaded
+	// while OptimizeCloudflare listeners run (0 disables)
+	CloudflareRefresh time.Duration
+
+	// Actual socket addresses of running servers, see BoundAddrs
+	bound        []boundAddr
+	boundChanged chan struct{} // Closed and replaced whenever bound changes
+
+	// Route prefix of the running CPU sample, see EnablePprof
+	sampleRoute atomic.Pointer[string]
+
+	// ShutdownTimeout bounds how long Stop waits for in-flight requests
+	ShutdownTimeout time.Duration
+	// DrainPeriod is how long responses close their keep-alive connections
+	// during Stop before requests still arriving on them get 503, capped at
+	// half of the shutdown timeout (0 = half, negative disables)
+	DrainPeriod   time.Duration
+	drainDeadline atomic.Int64          // Unix nanos while Stop drains, see drainMiddleware
+	drainers      []Drainer             // See AddDrainer and StopAndDrain
+	conns         map[net.Conn]struct{} // Open, non-hijacked HTTP connections
+	connsMu       sync.Mutex
+
+	tlsUpkeep tlsUpkeep  // Per-listener OCSP staples and ticket keys, see maintainTLS
+	recovery  *Recovery  // Answers panicking requests, see EnableRecovery
+	accessLog *AccessLog // See EnableAccessLog
+
+	events *events.Bus // Cross-subsystem events, see Events
+
+	chain atomic.Pointer[cachedChain] // Handler chain of ServeHTTP, see sessionChain
 }
 
 // NewWebLite creates a new WebLite instance with default configuration
@@ -37,7 +85,12 @@ func NewWebLite(name string) *WebLite {
 		Name:          name,
 		mux:           mux.NewRouter(),
 		servers:       make([]*http.Server, 0),
+		owners:        make(map[any]*PortListener),
 		PortListeners: make([]*PortListener, 0),
+
+		CloudflareRefresh: 24 * time.Hour,
+		ShutdownTimeout:   DefaultShutdownTimeout,
+		events:            events.NewBus(),
 	}
 	wl.Routes = routes.NewRoutes(wl.mux)
 	return wl
@@ -50,6 +103,17 @@ func (wl *WebLite) SetSessionManager(sm *SessionManager) *WebLite {
 	wl.mu.Lock()
 	defer wl.mu.Unlock()
 	wl.SessionManager = sm
+	if sm != nil && sm.Events == nil {
+		sm.Events = wl.events
+	}
+	return wl
+}
+
+// Use adds middleware applied around the routes on every listener started afterwards
+func (wl *WebLite) Use(middleware ...func(http.Handler) http.Handler) *WebLite {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	wl.Middlewares = append(wl.Middlewares, middleware...)
 	return wl
 }
 
@@ -58,6 +122,11 @@ func (wl *WebLite) AddPortListener(config map[string]string) *WebLite {
 	wl.mu.Lock()
 	defer wl.mu.Unlock()
 
+	// Typos would otherwise be silently ignored, see AddPortListenerConfig
+	for _, key := range UnknownPortListenerKeys(config) {
+		fmt.Printf("WebLite [%s] ignoring unknown listener config key %q\n", wl.Name, key)
+	}
+
 	listener := NewPortListener(config)
 	wl.PortListeners = append(wl.PortListeners, listener)
 
@@ -100,56 +169,49 @@ func (wl *WebLite) Start() error {
 }
 
 // startWithPortListeners starts servers based on PortListener configurations
+// and blocks until every listener, including ones added while running, has exited
 func (wl *WebLite) startWithPortListeners() error {
 	type bindResult struct {
-		addr string
-		err  error
-	}
-
-	// Calculate total number of bind addresses across all listeners
-	wl.mu.RLock()
-	totalBinds := 0
-	for _, listener := range wl.PortListeners {
-		totalBinds += len(listener.Ports) * len(listener.Addresses)
+		listener *PortListener
+		addr     string
+		err      error
 	}
-	wl.mu.RUnlock()
 
-	resultChan := make(chan bindResult, totalBinds)
-	var wg sync.WaitGroup
+	var resultsMu sync.Mutex
+	var results []bindResult
 
 	// Start servers for each port listener
 	wl.mu.RLock()
 	listeners := make([]*PortListener, len(wl.PortListeners))
 	copy(listeners, wl.PortListeners)
+	refresh := wl.CloudflareRefresh
 	wl.mu.RUnlock()
 
+	// Keep Cloudflare edge ranges current while CF listeners trust CF-Connecting-IP
 	for _, listener := range listeners {
-		for _, port := range listener.Ports {
-			for _, addr := range listener.Addresses {
-				wg.Add(1)
-				go func(pl *PortListener, p string, a string) {
-					defer wg.Done()
-					err := wl.startListenerServer(pl, a, p)
-					if err != nil && err != http.ErrServerClosed {
-						bindAddr := net.JoinHostPort(a, p)
-						resultChan <- bindResult{addr: bindAddr, err: err}
-					} else if err == nil {
-						bindAddr := net.JoinHostPort(a, p)
-						resultChan <- bindResult{addr: bindAddr, err: nil}
-					}
-				}(listener, port, addr)
-			}
+		if listener.OptimizeCloudflare {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			wl.startCloudflareRefresh(ctx, refresh)
+			break
 		}
 	}
 
+	for _, listener := range listeners {
+		wl.launchListener(listener, func(addr string, err error) {
+			resultsMu.Lock()
+			results = append(results, bindResult{listener: listener, addr: addr, err: err})
+			resultsMu.Unlock()
+		})
+	}
+
 	// Wait for all servers to complete
-	wg.Wait()
-	close(resultChan)
+	wl.active.Wait()
 
 	// Collect results
 	var successAddrs []string
 	var errors []bindResult
-	for result := range resultChan {
+	for _, result := range results {
 		if result.err == nil {
 			successAddrs = append(successAddrs, result.addr)
 		} else {
@@ -157,41 +219,162 @@ func (wl *WebLite) startWithPortListeners() error {
 		}
 	}
 
-	// Check for ignorable errors (IPv4/IPv6 dual-stack)
+	// Check for bind failures tolerated by the listener's dual-stack policy
 	for _, errResult := range errors {
-		canIgnore := false
-
-		if strings.Contains(errResult.err.Error(), "address already in use") ||
-			strings.Contains(errResult.err.Error(), "bind: address already in use") {
-			isIPv4Wildcard := strings.HasPrefix(errResult.addr, "0.0.0.0")
-			hasIPv6Success := false
-			for _, successAddr := range successAddrs {
-				if successAddr == "::" || strings.HasPrefix(successAddr, "::") {
-					hasIPv6Success = true
-					break
-				}
-			}
-
-			if isIPv4Wildcard && hasIPv6Success {
-				canIgnore = true
-				fmt.Printf("WebLite [%s] IPv4 bind on %s failed (address in use), but IPv6 is bound - assuming dual-stack mode\n", wl.Name, errResult.addr)
-			}
-		}
-
-		if !canIgnore {
+		peer := peerWildcard(errResult.addr)
+		peerBound := peer != "" && slices.Contains(successAddrs, peer)
+		if !errResult.listener.canIgnoreBindError(errResult.addr, errResult.err, peerBound) {
 			return errResult.err
 		}
+		fmt.Printf("WebLite [%s] bind on %s failed (%v), but %s is bound - allowed by dual-stack policy %s\n", wl.Name, errResult.addr, errResult.err, peer, errResult.listener.DualStack)
 	}
 
 	return nil
 }
 
-// startListenerServer starts a server for a specific PortListener configuration
-func (wl *WebLite) startListenerServer(listener *PortListener, bindAddr, port string) error {
+// launchListener starts a server for every port/address pair of listener.
+// report receives each bind address with its exit error (nil on graceful shutdown).
+func (wl *WebLite) launchListener(listener *PortListener, report func(addr string, err error)) {
+	for _, port := range listener.Ports {
+		// When "::" also serves IPv4, "0.0.0.0" waits for its bind attempt so
+		// the outcome does not depend on goroutine scheduling. With port "0"
+		// every address waits for the first and binds the port the OS assigned
+		// it, so the listener (and its HTTP/3 endpoint) has one port.
+		v6First := listener.dualStackV6First()
+		sharePort := port == "0" && len(listener.Addresses) > 1
+		leader := ""
+		switch {
+		case v6First:
+			leader = "::"
+		case sharePort:
+			leader = listener.Addresses[0]
+		}
+		leaderPort := port
+		leaderAttempted := make(chan struct{})
+		markAttempted := sync.OnceFunc(func() { close(leaderAttempted) })
+
+		for _, addr := range listener.Addresses {
+			wl.active.Add(1)
+			go func(p string, a string) {
+				defer wl.active.Done()
+				bound := func(string) {}
+				switch {
+				case a == leader:
+					bound = func(boundPort string) {
+						if sharePort && boundPort != "" {
+							leaderPort, p = boundPort, boundPort
+						}
+						markAttempted()
+					}
+					defer markAttempted()
+				case sharePort || (v6First && a == "0.0.0.0"):
+					<-leaderAttempted
+					p = leaderPort
+				}
+				err := wl.startListenerServer(listener, a, p, bound)
+				if err == http.ErrServerClosed {
+					err = nil
+				}
+				report(net.JoinHostPort(a, p), err)
+			}(port, addr)
+		}
+	}
+}
+
+// Handler returns the routes wrapped with the listener-independent middleware
+// (Use middlewares, header rules and error reporting). Listeners add domain validation,
+// sessions, redirects and limits on top of it.
+func (wl *WebLite) Handler() http.Handler {
+	handler := http.Handler(http.HandlerFunc(wl.serveRoutes))
+
+	// Complete access log records with the session user and real client IP
+	// as the routes see them
+	if wl.accessLog != nil {
+		handler = wl.accessLog.annotate(handler)
+	}
+
+	for i := len(wl.Middlewares) - 1; i >= 0; i-- {
+		handler = wl.Middlewares[i](handler)
+	}
+
+	// Header rules see the final headers of handlers and middlewares
+	handler = wl.headerRulesMiddleware(handler)
+
+	// Answer panics with the error page; inside error reporting, which then
+	// skips the 500 already reported with the stack
+	if wl.recovery != nil {
+		handler = wl.recovery.Middleware(handler)
+	}
+
+	// Label requests of a running route sample, see EnablePprof
+	handler = wl.profileLabels(handler)
+
+	// Report panics and 5xx responses when a global error reporter is installed
+	if comm.GetErrorReporter() != nil {
+		handler = middleware.ReportErrors(handler)
+	}
+	return handler
+}
+
+// ServeHTTP serves r through Handler and the SessionManager, as a listener
+// does without its listener options (e.g. for httptest or a tenant)
+func (wl *WebLite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	wl.mu.RLock()
+	sm := wl.SessionManager
+	wl.mu.RUnlock()
+	wl.sessionChain(sm).ServeHTTP(w, r)
+}
+
+// chainKey is the configuration a chain built by sessionChain depends on
+type chainKey struct {
+	middlewares int
+	first       *func(http.Handler) http.Handler // Backing array of Middlewares
+	accessLog   *AccessLog
+	recovery    *Recovery
+	reporting   bool
+	sessions    *SessionManager
+}
+
+type cachedChain struct {
+	key     chainKey
+	handler http.Handler
+}
+
+// sessionChain returns Handler wrapped in sm, building it only once per
+// configuration rather than per request
+func (wl *WebLite) sessionChain(sm *SessionManager) http.Handler {
+	wl.mu.RLock()
+	defer wl.mu.RUnlock()
+	key := chainKey{
+		middlewares: len(wl.Middlewares),
+		accessLog:   wl.accessLog,
+		recovery:    wl.recovery,
+		reporting:   comm.GetErrorReporter() != nil,
+		sessions:    sm,
+	}
+	if len(wl.Middlewares) > 0 {
+		key.first = &wl.Middlewares[0]
+	}
+	if cached := wl.chain.Load(); cached != nil && cached.key == key {
+		return cached.handler
+	}
+
+	handler := wl.Handler()
+	if sm != nil {
+		handler = sm.MiddlewareFor(wl)(handler)
+	}
+	wl.chain.Store(&cachedChain{key: key, handler: handler})
+	return handler
+}
+
+// startListenerServer starts a server for a specific PortListener configuration.
+// attempted is called once the TCP bind has been tried, with the bound port
+// ("" when the bind failed).
+func (wl *WebLite) startListenerServer(listener *PortListener, bindAddr, port string, attempted func(boundPort string)) error {
 	addr := net.JoinHostPort(bindAddr, port)
 
 	// Wrap handler with domain validation if needed
-	handler := http.Handler(wl.mux)
+	handler := wl.Handler()
 
 	// Apply domain validation through DomainValidator
 	if listener.DomainValidator != nil && listener.DomainValidator.IsEnabled() {
@@ -200,12 +383,43 @@ func (wl *WebLite) startListenerServer(listener *PortListener, bindAddr, port st
 
 	// Apply session management if configured
 	if wl.SessionManager != nil {
-		handler = wl.SessionManager.Middleware(handler)
+		handler = wl.SessionManager.MiddlewareFor(wl)(handler)
+	}
+
+	// Requests for tenant hosts bypass the parent routes and sessions
+	if wl.tenants != nil {
+		handler = wl.tenants.hostMiddleware(handler)
 	}
 
 	isHTTPS := listener.IsHTTPS()
 	hasSSL := listener.HasSSLConfig()
 
+	// Bind before building the port-dependent parts so port "0" reports the
+	// OS-assigned port in logs, Alt-Svc, redirects and BoundAddrs
+	sockets, err := wl.bindTCP(listener, addr)
+	if err != nil {
+		attempted("")
+		return err
+	}
+	_, boundPort, _ := net.SplitHostPort(sockets[0].Addr().String())
+	attempted(boundPort)
+	defer closeSockets(sockets)
+	if listener.ConnLimits != nil && listener.ConnLimits.err != nil {
+		return listener.ConnLimits.err
+	}
+	sockets = listener.ConnLimits.limit(sockets)
+	if listener.HTTP3 != nil && listener.HTTP3.err != nil {
+		return listener.HTTP3.err
+	}
+	if listener.IPValidator != nil && listener.IPValidator.err != nil {
+		return listener.IPValidator.err
+	}
+	if listener.ticketKeyErr != nil {
+		return listener.ticketKeyErr
+	}
+	addr = sockets[0].Addr().String()
+	_, port, _ = net.SplitHostPort(addr)
+
 	// Wrap with HTTPS redirect if needed
 	if isHTTPS && hasSSL && listener.HTTPSRedirect {
 		handler = wrapWithHTTPSRedirect(handler)
@@ -213,18 +427,56 @@ func (wl *WebLite) startListenerServer(listener *PortListener, bindAddr, port st
 
 	// Wrap with HTTP/3 Alt-Svc if enabled
 	if wl.isHTTP3Enabled() && isHTTPS && hasSSL {
-		handler = wrapWithHTTP3AltSvc(handler, port)
+		handler = wrapWithHTTP3AltSvc(handler, port, listener.HTTP3)
+	}
+
+	// Reject client addresses outside the listener's IP ranges, for tenant
+	// hosts too; ranges may be added while running, so it is always installed
+	if listener.IPValidator != nil {
+		handler = listener.IPValidator.Middleware(handler)
+	}
+
+	// Behind Cloudflare, expose the real client IP to everything downstream
+	if listener.OptimizeCloudflare {
+		handler = cloudflareRealIP(handler)
+	}
+
+	// Reject oversized URLs and headers before anything else runs
+	if listener.Limits.Enabled() {
+		handler = listener.Limits.Middleware(handler)
+	}
+
+	// Close connections after each response while Stop drains
+	handler = wl.drainMiddleware(handler)
+
+	// Log every request, including the ones rejected above
+	if wl.accessLog != nil {
+		handler = wl.accessLog.Middleware(handler)
 	}
 
 	server := &http.Server{
-		Addr:    addr,
-		Handler: handler,
+		Addr:           addr,
+		Handler:        handler,
+		MaxHeaderBytes: listener.MaxHeaderBytes,
+	}
+	server.ConnState = wl.trackConn
+	if listener.Conns != nil {
+		server.ConnState = func(conn net.Conn, state http.ConnState) {
+			wl.trackConn(conn, state)
+			listener.Conns.track(conn, state)
+		}
 	}
+	listener.TLS.configureServer(server)
+	listener.ConnLimits.configureServer(server)
 
 	wl.mu.Lock()
 	wl.servers = append(wl.servers, server)
+	wl.owners[server] = listener
 	wl.mu.Unlock()
 
+	wl.recordBound(server, sockets[0].Addr())
+	defer wl.forgetBound(server)
+
 	// Build log message
 	logMsg := fmt.Sprintf("WebLite [%s] starting %s server on %s", wl.Name, strings.ToUpper(listener.Protocol), addr)
 	if listener.OptimizeCloudflare {
@@ -237,23 +489,23 @@ func (wl *WebLite) startListenerServer(listener *PortListener, bindAddr, port st
 
 	// Handle CloudFlare optimization
 	if listener.OptimizeCloudflare {
-		ln, err := wl.CreateCloudFlareListener("tcp", addr)
-		if err != nil {
-			return fmt.Errorf("failed to create CloudFlare listener: %w", err)
-		}
-		defer ln.Close()
-
 		if isHTTPS && hasSSL {
 			tlsConfig, err := wl.createTLSConfigFromListener(listener)
 			if err != nil {
 				return err
 			}
+			stopTLS, err := wl.maintainTLS(listener, tlsConfig)
+			if err != nil {
+				return err
+			}
+			defer stopTLS()
 			server.TLSConfig = tlsConfig
-			tlsListener := tls.NewListener(ln, server.TLSConfig)
-			return server.Serve(tlsListener)
+			return serveSockets(sockets, func(ln net.Listener) error {
+				return server.Serve(tls.NewListener(ln, server.TLSConfig))
+			})
 		}
 
-		return server.Serve(ln)
+		return serveSockets(sockets, server.Serve)
 	}
 
 	// Standard listener (no CloudFlare optimizations)
@@ -262,67 +514,52 @@ func (wl *WebLite) startListenerServer(listener *PortListener, bindAddr, port st
 		if err != nil {
 			return err
 		}
+		stopTLS, err := wl.maintainTLS(listener, tlsConfig)
+		if err != nil {
+			return err
+		}
+		defer stopTLS()
 
 		// Handle mixed protocol if HTTPS redirect is enabled
 		if listener.HTTPSRedirect {
-			ln, err := net.Listen("tcp", addr)
-			if err != nil {
-				return fmt.Errorf("failed to create listener: %w", err)
-			}
-			defer ln.Close()
-
-			mixedLn := &mixedProtocolListener{
-				Listener:  ln,
-				tlsConfig: tlsConfig,
-				httpsPort: port,
+			serveMixed := func(ln net.Listener) error {
+				mixedLn := &mixedProtocolListener{
+					Listener:  ln,
+					tlsConfig: tlsConfig,
+					httpsPort: port,
+				}
+				return server.Serve(tls.NewListener(mixedLn, tlsConfig))
 			}
 
-			tlsLn := tls.NewListener(mixedLn, tlsConfig)
-
 			// Start HTTP/3 if enabled
 			if wl.isHTTP3Enabled() {
-				go func() {
-					if err := wl.startHTTP3Server(addr, tlsConfig, handler); err != nil {
-						fmt.Printf("HTTP/3 server error: %v\n", err)
-					}
-				}()
+				return wl.serveWithHTTP3(listener, server, addr, tlsConfig, handler, func() error {
+					return serveSockets(sockets, serveMixed)
+				})
 			}
 
-			return server.Serve(tlsLn)
+			return serveSockets(sockets, serveMixed)
+		}
+
+		// createTLSConfigFromListener loaded the certificate from data or files
+		server.TLSConfig = tlsConfig
+		serveTLS := func(ln net.Listener) error {
+			return server.ServeTLS(ln, "", "")
 		}
 
 		// Start HTTP/3 if enabled
 		if wl.isHTTP3Enabled() {
-			errChan := make(chan error, 2)
-
-			go func() {
-				if err := wl.startHTTP3Server(addr, tlsConfig, handler); err != nil {
-					errChan <- fmt.Errorf("HTTP/3 server error: %w", err)
-				}
-			}()
-
-			go func() {
-				var err error
-				if listener.SSLCertData != "" && listener.SSLKeyData != "" {
-					server.TLSConfig = tlsConfig
-					err = server.ListenAndServeTLS("", "")
-				} else {
-					err = server.ListenAndServeTLS(listener.SSLCertPath, listener.SSLKeyPath)
-				}
+			return wl.serveWithHTTP3(listener, server, addr, tlsConfig, handler, func() error {
+				err := serveSockets(sockets, serveTLS)
 				if err != nil && err != http.ErrServerClosed {
-					errChan <- fmt.Errorf("HTTP/1.1/2.0 server error: %w", err)
+					return fmt.Errorf("HTTP/1.1/2.0 server error: %w", err)
 				}
-			}()
-
-			return <-errChan
+				return err
+			})
 		}
 
 		// Regular HTTPS without HTTP/3
-		if listener.SSLCertData != "" && listener.SSLKeyData != "" {
-			server.TLSConfig = tlsConfig
-			return server.ListenAndServeTLS("", "")
-		}
-		return server.ListenAndServeTLS(listener.SSLCertPath, listener.SSLKeyPath)
+		return serveSockets(sockets, serveTLS)
 	}
 
 	// Regular HTTP server
@@ -353,7 +590,93 @@ func (wl *WebLite) startListenerServer(listener *PortListener, bindAddr, port st
 		fmt.Printf("WebLite [%s] HTTP redirect to HTTPS port %s\n", wl.Name, listener.HTTPSRedirectPort)
 	}
 
-	return server.ListenAndServe()
+	return serveSockets(sockets, server.Serve)
+}
+
+// bindTCP opens the TCP socket for one address of listener, or a group of
+// SO_REUSEPORT sockets when listener.Acceptors > 1
+func (wl *WebLite) bindTCP(listener *PortListener, addr string) ([]net.Listener, error) {
+	if listener.Acceptors > 1 {
+		return wl.bindAcceptors(listener, addr)
+	}
+	ln, err := wl.bindSocket(listener, addr, nil)
+	if err != nil {
+		return nil, err
+	}
+	return []net.Listener{ln}, nil
+}
+
+// bindSocket opens one TCP socket for addr; control (may be nil) runs before bind
+func (wl *WebLite) bindSocket(listener *PortListener, addr string, control func(network, address string, c syscall.RawConn) error) (net.Listener, error) {
+	network := bindNetwork(addr, listener.v6OnlyFor(addr))
+	if listener.OptimizeCloudflare {
+		ln, err := wl.createCloudflareListener(network, addr, listener.Cloudflare, control)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CloudFlare listener: %w", err)
+		}
+		return ln, nil
+	}
+	lc := &net.ListenConfig{Control: control}
+	ln, err := lc.Listen(context.Background(), network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create listener: %w", err)
+	}
+	return ln, nil
+}
+
+// quicServer is the part of an HTTP/3 server WebLite needs for shutdown
+type quicServer interface {
+	Shutdown(ctx context.Context) error
+	Close() error
+	LocalAddr() net.Addr // Bound UDP address, see QUICAddrs
+}
+
+// serveWithHTTP3 runs serve (the TCP server) alongside an HTTP/3 server on addr.
+// The UDP socket is bound up front so bind failures reach the bind report.
+// When either side fails the other is closed, so no server outlives its sibling,
+// and the call only returns once both have exited.
+func (wl *WebLite) serveWithHTTP3(listener *PortListener, server *http.Server, addr string, tlsConfig *tls.Config, handler http.Handler, serve func() error) error {
+	h3, serveH3, err := wl.listenHTTP3(addr, tlsConfig, handler, listener.HTTP3)
+	if err != nil {
+		return fmt.Errorf("HTTP/3 server error: %w", err)
+	}
+
+	wl.mu.Lock()
+	wl.quicServers = append(wl.quicServers, h3)
+	wl.owners[h3] = listener
+	wl.mu.Unlock()
+
+	errChan := make(chan error, 2)
+	go func() {
+		err := serveH3()
+		if err != nil && err != http.ErrServerClosed {
+			err = fmt.Errorf("HTTP/3 server error: %w", err)
+		}
+		errChan <- err
+	}()
+	go func() {
+		errChan <- serve()
+	}()
+
+	err = <-errChan
+	if err != nil && err != http.ErrServerClosed {
+		server.Close()
+		h3.Close()
+	}
+	if second := <-errChan; (err == nil || err == http.ErrServerClosed) && second != nil {
+		err = second
+	}
+
+	wl.mu.Lock()
+	for i, s := range wl.quicServers {
+		if s == h3 {
+			wl.quicServers = append(wl.quicServers[:i], wl.quicServers[i+1:]...)
+			break
+		}
+	}
+	delete(wl.owners, h3)
+	wl.mu.Unlock()
+	return err
 }
 
 // createTLSConfigFromListener creates a TLS config from a PortListener
@@ -364,10 +687,7 @@ func (wl *WebLite) createTLSConfigFromListener(listener *PortListener) (*tls.Con
 		if err != nil {
 			return nil, fmt.Errorf("failed to load TLS certificate from data: %w", err)
 		}
-		return &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			MinVersion:   tls.VersionTLS12,
-		}, nil
+		return listenerTLSConfig(listener, cert)
 	}
 
 	if listener.SSLCertPath != "" && listener.SSLKeyPath != "" {
@@ -376,49 +696,38 @@ func (wl *WebLite) createTLSConfigFromListener(listener *PortListener) (*tls.Con
 		if err != nil {
 			return nil, fmt.Errorf("failed to load TLS certificate from files: %w", err)
 		}
-		return &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			MinVersion:   tls.VersionTLS12,
-		}, nil
+		return listenerTLSConfig(listener, cert)
 	}
 
 	return nil, fmt.Errorf("no SSL configuration provided")
 }
 
-// Stop gracefully stops all server instances
-func (wl *WebLite) Stop() error {
-	wl.mu.Lock()
-	if !wl.running {
-		wl.mu.Unlock()
-		return fmt.Errorf("server %s is not running", wl.Name)
+// listenerTLSConfig builds the TLS config for cert under the listener's TLSPolicy
+func listenerTLSConfig(listener *PortListener, cert tls.Certificate) (*tls.Config, error) {
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if err := listener.TLS.apply(cfg); err != nil {
+		return nil, err
 	}
-	wl.mu.Unlock()
-
-	fmt.Printf("WebLite [%s] stopping...\n", wl.Name)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	var errors []error
-	wl.mu.Lock()
-	servers := wl.servers
-	wl.mu.Unlock()
+	return cfg, nil
+}
 
-	for _, server := range servers {
-		if err := server.Shutdown(ctx); err != nil {
-			errors = append(errors, err)
-		}
+// Stop gracefully stops all server instances within ShutdownTimeout: the
+// listeners close right away, registered drainers close their streams and
+// in-flight requests may finish; connections left at the timeout are closed
+func (wl *WebLite) Stop() error {
+	wl.mu.RLock()
+	timeout := wl.ShutdownTimeout
+	wl.mu.RUnlock()
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	wl.mu.Lock()
-	wl.servers = make([]*http.Server, 0)
-	wl.running = false
-	wl.mu.Unlock()
-
-	if len(errors) > 0 {
-		return fmt.Errorf("errors stopping server: %v", errors)
+	fmt.Printf("WebLite [%s] stopping...\n", wl.Name)
+	if _, err := wl.shutdown(ctx); err != nil {
+		return err
 	}
-
 	fmt.Printf("WebLite [%s] stopped\n", wl.Name)
 	return nil
 }
@@ -440,8 +749,14 @@ func (wl *WebLite) Close() error {
 			errors = append(errors, err)
 		}
 	}
+	for _, server := range wl.quicServers {
+		if err := server.Close(); err != nil {
+			errors = append(errors, err)
+		}
+	}
 
 	wl.servers = make([]*http.Server, 0)
+	wl.owners = make(map[any]*PortListener)
 	wl.running = false
 
 	if len(errors) > 0 {
@@ -451,7 +766,7 @@ func (wl *WebLite) Close() error {
 	return nil
 }
 
-// GetAddr returns the addresses the server is bound to
+// GetAddr returns the configured listen addresses (port "0" stays "0"; see BoundAddrs)
 func (wl *WebLite) GetAddr() []string {
 	wl.mu.RLock()
 	defer wl.mu.RUnlock()
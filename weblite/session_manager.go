@@ -4,6 +4,11 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/go-xlite/wbx/comm"
+	"github.com/go-xlite/wbx/comm/audit"
+	"github.com/go-xlite/wbx/comm/events"
 )
 
 // SessionService interface for your external session validation/issuing service
@@ -27,9 +32,19 @@ type SessionManager struct {
 	Secure       bool // HTTPS only
 	HttpOnly     bool
 	SameSite     http.SameSite
-	SkipPaths    []string // Exact paths to skip
-	SkipPrefixes []string // Path prefixes to skip
+	SkipPaths    []string           // Exact paths to skip
+	SkipPrefixes []string           // Path prefixes to skip
+	Tickets      *ConnectionTickets // Optional one-time tickets for streaming endpoints
 	mu           sync.RWMutex
+
+	// Notified on revocation so live WS/SSE connections of the session are closed
+	revokeListeners []comm.SessionRevocationListener
+
+	// CookieScopes give mounted apps their own session cookie, see AddCookieScope
+	CookieScopes []*CookieScope
+
+	// Events receives SessionRevoked; SetSessionManager defaults it to the server's bus
+	Events events.Publisher
 }
 
 // NewSessionManager creates a new session manager
@@ -78,6 +93,14 @@ func (sm *SessionManager) AddSkipPrefix(prefix ...string) *SessionManager {
 	return sm
 }
 
+// EnableConnectionTickets lets WS/SSE endpoints under prefixes authenticate with a
+// one-time ticket query parameter instead of the session cookie.
+// Serve the returned store's IssueHandler on an authenticated route.
+func (sm *SessionManager) EnableConnectionTickets(ttl time.Duration, prefixes ...string) *ConnectionTickets {
+	sm.Tickets = NewConnectionTickets(ttl, prefixes...)
+	return sm.Tickets
+}
+
 // ShouldSkip checks if a path should skip session validation
 func (sm *SessionManager) ShouldSkip(path string) bool {
 	sm.mu.RLock()
@@ -110,70 +133,147 @@ func (sm *SessionManager) Middleware(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			return
 		}
+		sm.enforce(w, r, next)
+	})
+}
 
-		// Try to get session cookie
-		cookie, err := r.Cookie(sm.CookieName)
-		if err != nil {
-			// No session cookie - return unauthorized
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+// enforce serves r with its session in context, or answers 401
+func (sm *SessionManager) enforce(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	// Streaming endpoints may present a connection ticket instead of the cookie
+	if sm.Tickets != nil && sm.Tickets.Accepts(r.URL.Path) {
+		if token := r.URL.Query().Get(sm.Tickets.ParamName); token != "" {
+			ticket, ok := sm.Tickets.redeem(token)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			ctx := SetSessionContext(r.Context(), ticket.sessionData)
+			ctx = comm.WithSessionKey(ctx, ticket.sessionKey)
+			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
+	}
 
-		// Validate session with your service
-		sessionData, err := sm.Service.Validate(cookie.Value)
-		if err != nil {
-			// Invalid session - clear cookie and return unauthorized
-			sm.ClearCookie(w)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
+	// Validate the session cookie with your service
+	sessionData, token, err := sm.validateCookie(r)
+	if err != nil {
+		// Invalid session - clear cookie; a missing one needs no clearing
+		if token != "" {
+			sm.ClearCookieFor(w, r)
 		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
 
-		// Store session data in request context for handlers to use
-		ctx := SetSessionContext(r.Context(), sessionData)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	// Store session data in request context for handlers to use
+	ctx := SetSessionContext(r.Context(), sessionData)
+	ctx = comm.WithSessionKey(ctx, comm.SessionKeyFor(token))
+	next.ServeHTTP(w, r.WithContext(ctx))
 }
 
 // SetCookie sets the session cookie
 func (sm *SessionManager) SetCookie(w http.ResponseWriter, token string) {
-	cookie := &http.Cookie{
-		Name:     sm.CookieName,
-		Value:    token,
-		Path:     sm.CookiePath,
-		Domain:   sm.CookieDomain,
-		Secure:   sm.Secure,
-		HttpOnly: sm.HttpOnly,
-		SameSite: sm.SameSite,
-		MaxAge:   0, // Session cookie (expires when browser closes)
-	}
-	http.SetCookie(w, cookie)
+	sm.writeCookie(w, sm.CookieName, sm.CookiePath, token, 0) // Session cookie (expires when browser closes)
 }
 
 // SetCookieWithExpiry sets the session cookie with an expiration time
 func (sm *SessionManager) SetCookieWithExpiry(w http.ResponseWriter, token string, maxAge int) {
+	sm.writeCookie(w, sm.CookieName, sm.CookiePath, token, maxAge)
+}
+
+// ClearCookie removes the session cookie
+func (sm *SessionManager) ClearCookie(w http.ResponseWriter) {
+	sm.writeCookie(w, sm.CookieName, sm.CookiePath, "", -1)
+}
+
+// writeCookie sets a session cookie with the manager's attributes
+func (sm *SessionManager) writeCookie(w http.ResponseWriter, name, path, token string, maxAge int) {
 	cookie := &http.Cookie{
-		Name:     sm.CookieName,
+		Name:     name,
 		Value:    token,
-		Path:     sm.CookiePath,
+		Path:     path,
 		Domain:   sm.CookieDomain,
 		Secure:   sm.Secure,
 		HttpOnly: sm.HttpOnly,
-		SameSite: sm.SameSite,
 		MaxAge:   maxAge,
 	}
+	if maxAge >= 0 {
+		cookie.SameSite = sm.SameSite
+	}
 	http.SetCookie(w, cookie)
 }
 
-// ClearCookie removes the session cookie
-func (sm *SessionManager) ClearCookie(w http.ResponseWriter) {
-	cookie := &http.Cookie{
-		Name:     sm.CookieName,
-		Value:    "",
-		Path:     sm.CookiePath,
-		Domain:   sm.CookieDomain,
-		MaxAge:   -1,
-		Secure:   sm.Secure,
-		HttpOnly: sm.HttpOnly,
+// Revoke invalidates the session presented by r, clears its cookie and
+// records a session.revoke audit event
+func (sm *SessionManager) Revoke(w http.ResponseWriter, r *http.Request, actor string) error {
+	return sm.revoke(w, r, audit.TypeSessionRevoke, actor)
+}
+
+// Logout is Revoke for a user signing out: it records a single auth.logout
+// audit event instead, and only clears the cookie when there is no Service
+func (sm *SessionManager) Logout(w http.ResponseWriter, r *http.Request, actor string) error {
+	return sm.revoke(w, r, audit.TypeLogout, actor)
+}
+
+// revoke invalidates the session of r and records one eventType audit event
+func (sm *SessionManager) revoke(w http.ResponseWriter, r *http.Request, eventType, actor string) error {
+	defer sm.ClearCookieFor(w, r)
+
+	cookie, err := r.Cookie(sm.CookieNameFor(r))
+	if err != nil || cookie.Value == "" || sm.Service == nil {
+		if eventType == audit.TypeLogout {
+			audit.EmitRequest(r, eventType, audit.OutcomeSuccess, actor, nil)
+		}
+		return nil
 	}
-	http.SetCookie(w, cookie)
+	err = sm.Service.Revoke(cookie.Value)
+	outcome := audit.OutcomeSuccess
+	if err != nil {
+		outcome = audit.OutcomeFailure
+	}
+	audit.EmitRequest(r, eventType, outcome, actor, nil)
+	if err == nil {
+		sm.NotifyRevoked(cookie.Value)
+	}
+	return err
+}
+
+// OnRevoke registers listeners (WebSock, WebCast) that close the live
+// connections of a session when it is revoked
+func (sm *SessionManager) OnRevoke(listeners ...comm.SessionRevocationListener) *SessionManager {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.revokeListeners = append(sm.revokeListeners, listeners...)
+	return sm
+}
+
+// NotifyRevoked closes the connections opened with token on every listener and
+// returns how many were closed. Revoke calls it; call it directly when a session
+// is revoked elsewhere (e.g. by an admin in the session service).
+func (sm *SessionManager) NotifyRevoked(token string) int {
+	return sm.NotifyRevokedKeys(comm.SessionKeyFor(token))
+}
+
+// NotifyRevokedKeys is NotifyRevoked for sessions known by their
+// comm.SessionKeyFor keys, e.g. the ones a session service revoked in bulk
+func (sm *SessionManager) NotifyRevokedKeys(sessionKeys ...string) int {
+	sm.mu.RLock()
+	listeners := sm.revokeListeners
+	sm.mu.RUnlock()
+
+	total := 0
+	for _, key := range sessionKeys {
+		if key == "" {
+			continue
+		}
+		closed := 0
+		for _, listener := range listeners {
+			closed += listener.SessionRevoked(key)
+		}
+		if sm.Events != nil {
+			sm.Events.Publish(events.SessionRevoked, "sessions", events.SessionRevokedData{SessionKey: key, Closed: closed})
+		}
+		total += closed
+	}
+	return total
 }
@@ -0,0 +1,36 @@
+package weblite
+
+import (
+	"net/http"
+
+	"github.com/go-xlite/wbx/comm"
+)
+
+// SessionManager resolves sessions for handlers that enforce authentication
+// themselves (api, sway, media), sharing its cookie and service configuration
+var _ comm.SessionResolver = (*SessionManager)(nil)
+
+// ResolveSession returns the session of r. Data already placed in the context
+// by Middleware is reused, otherwise the session cookie is validated.
+// Connection tickets are single-use and only redeemed by Middleware.
+func (sm *SessionManager) ResolveSession(r *http.Request) (any, bool) {
+	if data, ok := GetSessionContext(r.Context()); ok {
+		return data, true
+	}
+	data, _, err := sm.validateCookie(r)
+	return data, err == nil
+}
+
+// validateCookie validates the session cookie of r with the service and
+// returns the session data and token
+func (sm *SessionManager) validateCookie(r *http.Request) (any, string, error) {
+	cookie, err := r.Cookie(sm.CookieNameFor(r))
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := sm.Service.Validate(cookie.Value)
+	if err != nil {
+		return nil, cookie.Value, err
+	}
+	return data, cookie.Value, nil
+}
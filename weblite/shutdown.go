@@ -0,0 +1,242 @@
+package weblite
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultShutdownTimeout bounds Stop when no timeout was set
+const DefaultShutdownTimeout = 5 * time.Second
+
+// Drainer closes long-lived connections ahead of a shutdown, spread over
+// window, and reports how many are still open. WebSock, WebCast and their
+// handlers implement it.
+type Drainer interface {
+	Drain(window time.Duration) int
+	GetClientCount() int
+}
+
+// hijackingDrainer is implemented by drainers whose connections were hijacked
+// from the HTTP server (WebSockets), so they are not among ActiveConnections
+// and Close does not end them
+type hijackingDrainer interface {
+	HijacksConnections() bool
+}
+
+// hijacks reports whether d's connections live outside the HTTP server
+func hijacks(d Drainer) bool {
+	h, ok := d.(hijackingDrainer)
+	return ok && h.HijacksConnections()
+}
+
+// SetShutdownTimeout sets how long Stop waits for in-flight requests
+func (wl *WebLite) SetShutdownTimeout(d time.Duration) *WebLite {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	wl.ShutdownTimeout = d
+	return wl
+}
+
+// AddDrainer registers servers whose streams StopAndDrain closes before waiting
+func (wl *WebLite) AddDrainer(drainers ...Drainer) *WebLite {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	wl.drainers = append(wl.drainers, drainers...)
+	return wl
+}
+
+// ActiveConnections returns the number of open HTTP connections across all
+// listeners, excluding connections hijacked by WebSockets
+func (wl *WebLite) ActiveConnections() int {
+	wl.connsMu.Lock()
+	defer wl.connsMu.Unlock()
+	return len(wl.conns)
+}
+
+// SetDrainPeriod sets how long responses close their keep-alive connections
+// during Stop before later requests get 503, capped at half of the shutdown
+// timeout (0 = half, negative disables)
+func (wl *WebLite) SetDrainPeriod(d time.Duration) *WebLite {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	wl.DrainPeriod = d
+	return wl
+}
+
+// beginDrain turns keep-alives off on servers, closing idle connections and
+// making every response carry Connection: close, and starts the drain period
+// after which requests still arriving on open connections get 503. It does not
+// wait; Shutdown, which runs alongside, waits for the connections to go.
+func (wl *WebLite) beginDrain(ctx context.Context, servers []*http.Server) {
+	for _, server := range servers {
+		server.SetKeepAlivesEnabled(false)
+	}
+
+	wl.mu.RLock()
+	period := wl.DrainPeriod
+	wl.mu.RUnlock()
+	if period < 0 {
+		return
+	}
+	if deadline, ok := ctx.Deadline(); ok && (period == 0 || period > time.Until(deadline)/2) {
+		period = time.Until(deadline) / 2
+	}
+	if period > 0 {
+		wl.drainDeadline.Store(time.Now().Add(period).UnixNano())
+	}
+}
+
+// drainMiddleware closes connections after each response while Stop drains and
+// turns requests away with 503 once the drain deadline has passed
+func (wl *WebLite) drainMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if deadline := wl.drainDeadline.Load(); deadline != 0 {
+			if r.ProtoMajor < 3 { // HTTP/2 turns it into GOAWAY, HTTP/3 forbids it
+				w.Header().Set("Connection", "close")
+			}
+			if time.Now().UnixNano() >= deadline {
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// drainPollInterval is how often waitDrained checks the drainers' client counts
+const drainPollInterval = 50 * time.Millisecond
+
+// waitDrained waits until the drainers have no clients left or ctx ends
+func waitDrained(ctx context.Context, drainers []Drainer) {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		open := 0
+		for _, drainer := range drainers {
+			open += drainer.GetClientCount()
+		}
+		if open == 0 {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// trackConn is installed as http.Server.ConnState for every listener
+func (wl *WebLite) trackConn(conn net.Conn, state http.ConnState) {
+	wl.connsMu.Lock()
+	defer wl.connsMu.Unlock()
+	switch state {
+	case http.StateNew:
+		if wl.conns == nil {
+			wl.conns = make(map[net.Conn]struct{})
+		}
+		wl.conns[conn] = struct{}{}
+	case http.StateHijacked, http.StateClosed:
+		delete(wl.conns, conn)
+	}
+}
+
+// StopAndDrain stops accepting connections, asks the registered drainers to
+// close their WebSockets and SSE streams (spread over half the time left
+// until ctx's deadline) and waits for the streams to end and in-flight
+// requests to finish. When ctx ends first the remaining connections,
+// WebSockets included, are closed; their number is returned.
+// Stop is StopAndDrain bounded by ShutdownTimeout.
+func (wl *WebLite) StopAndDrain(ctx context.Context) (int, error) {
+	fmt.Printf("WebLite [%s] draining...\n", wl.Name)
+	forced, err := wl.shutdown(ctx)
+	if err != nil {
+		return forced, err
+	}
+	fmt.Printf("WebLite [%s] drained (%d connections force-closed)\n", wl.Name, forced)
+	return forced, nil
+}
+
+// shutdown is the drain and shutdown sequence shared by Stop and StopAndDrain
+func (wl *WebLite) shutdown(ctx context.Context) (int, error) {
+	wl.mu.Lock()
+	if !wl.running {
+		wl.mu.Unlock()
+		return 0, fmt.Errorf("server %s is not running", wl.Name)
+	}
+	servers := wl.servers
+	quicServers := append([]quicServer(nil), wl.quicServers...)
+	drainers := append([]Drainer(nil), wl.drainers...)
+	wl.mu.Unlock()
+
+	// Responses close their connections from now on
+	wl.beginDrain(ctx, servers)
+	defer wl.drainDeadline.Store(0)
+
+	// Shutdown closes the listeners right away, then waits for idle connections
+	done := make(chan error, len(servers)+len(quicServers))
+	for _, server := range servers {
+		go func() { done <- server.Shutdown(ctx) }()
+	}
+	for _, server := range quicServers {
+		go func() { done <- server.Shutdown(ctx) }()
+	}
+
+	// Drainers spread their closes over the same window side by side, then
+	// the streams are given until ctx ends to finish
+	var window time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		window = time.Until(deadline) / 2
+	}
+	var wg sync.WaitGroup
+	for _, drainer := range drainers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			drainer.Drain(window)
+		}()
+	}
+	wg.Wait()
+	waitDrained(ctx, drainers)
+
+	var errors []error
+	for range len(servers) + len(quicServers) {
+		if err := <-done; err != nil && err != context.Canceled && err != context.DeadlineExceeded {
+			errors = append(errors, err)
+		}
+	}
+
+	forced := 0
+	if ctx.Err() != nil {
+		forced = wl.ActiveConnections()
+		for _, drainer := range drainers {
+			if hijacks(drainer) {
+				// Close does not reach hijacked connections; close them here
+				forced += drainer.GetClientCount()
+				drainer.Drain(0)
+			}
+		}
+		for _, server := range servers {
+			server.Close()
+		}
+		for _, server := range quicServers {
+			server.Close()
+		}
+	}
+
+	wl.mu.Lock()
+	wl.servers = make([]*http.Server, 0)
+	wl.owners = make(map[any]*PortListener)
+	wl.running = false
+	wl.mu.Unlock()
+
+	if len(errors) > 0 {
+		return forced, fmt.Errorf("errors stopping server: %v", errors)
+	}
+	return forced, nil
+}
@@ -0,0 +1,234 @@
+package weblite
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-xlite/wbx/comm"
+)
+
+// DefaultTenantBase is the path under which tenants are mounted (/t/{tenant}/...)
+const DefaultTenantBase = "/t"
+
+type tenantContextKey struct{}
+
+// Tenant is an isolated set of handlers mounted under {base}/{id}/ and/or
+// served for dedicated hosts. Server is a routing-only WebLite: register
+// handlers on it exactly as on a top-level server, using tenant-relative paths.
+type Tenant struct {
+	ID       string
+	Prefix   string   // Path prefix the tenant is mounted at, e.g. "/t/acme"
+	Hosts    []string // Hosts served directly by this tenant (no path prefix)
+	Server   *WebLite
+	Sessions *SessionManager // Per-tenant sessions (nil falls back to the parent's)
+	stats    tenantCounters
+}
+
+// TenantStats holds per-tenant traffic counters
+type TenantStats struct {
+	ID          string    `json:"id"`
+	Requests    int64     `json:"requests"`
+	Errors      int64     `json:"errors"` // 5xx responses
+	BytesOut    int64     `json:"bytesOut"`
+	Active      int64     `json:"active"`
+	LastRequest time.Time `json:"lastRequest"`
+}
+
+type tenantCounters struct {
+	requests, errors, bytesOut, active atomic.Int64
+	lastRequest                        atomic.Int64 // unix nano
+}
+
+// Tenants resolves requests to tenants from the path or host
+type Tenants struct {
+	Base  string
+	wl    *WebLite
+	items map[string]*Tenant
+	hosts map[string]*Tenant
+	mu    sync.RWMutex
+}
+
+// EnableTenants mounts tenant dispatch under base (DefaultTenantBase when empty).
+// The parent's SessionManager skips the tenant area; tenants use their own
+// SessionManager or fall back to the parent's.
+func (wl *WebLite) EnableTenants(base string) *Tenants {
+	if base == "" {
+		base = DefaultTenantBase
+	}
+	base = "/" + strings.Trim(base, "/")
+
+	ts := &Tenants{
+		Base:  base,
+		wl:    wl,
+		items: make(map[string]*Tenant),
+		hosts: make(map[string]*Tenant),
+	}
+
+	wl.mu.Lock()
+	wl.tenants = ts
+	sm := wl.SessionManager
+	wl.mu.Unlock()
+
+	if sm != nil {
+		sm.AddSkipPrefix(base + "/")
+	}
+	wl.Routes.HandlePathPrefixH(base+"/", http.HandlerFunc(ts.servePath))
+	return ts
+}
+
+// Add creates (or returns) the tenant id, optionally served for hosts
+func (ts *Tenants) Add(id string, hosts ...string) *Tenant {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	tenant, ok := ts.items[id]
+	if !ok {
+		tenant = &Tenant{
+			ID:     id,
+			Prefix: ts.Base + "/" + id,
+			Server: NewWebLite(ts.wl.Name + "/" + id),
+		}
+		ts.items[id] = tenant
+	}
+	for _, host := range hosts {
+		host = strings.ToLower(host)
+		tenant.Hosts = append(tenant.Hosts, host)
+		ts.hosts[host] = tenant
+	}
+	return tenant
+}
+
+// Get returns the tenant with id, or nil
+func (ts *Tenants) Get(id string) *Tenant {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return ts.items[id]
+}
+
+// Remove unmounts the tenant with id
+func (ts *Tenants) Remove(id string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if tenant, ok := ts.items[id]; ok {
+		for _, host := range tenant.Hosts {
+			delete(ts.hosts, host)
+		}
+		delete(ts.items, id)
+	}
+}
+
+// Stats returns the counters of every tenant
+func (ts *Tenants) Stats() []TenantStats {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	list := make([]TenantStats, 0, len(ts.items))
+	for _, tenant := range ts.items {
+		list = append(list, tenant.Stats())
+	}
+	return list
+}
+
+// forHost returns the tenant serving host, or nil
+func (ts *Tenants) forHost(host string) *Tenant {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return ts.hosts[strings.ToLower(host)]
+}
+
+// servePath dispatches {base}/{id}/rest to the tenant with the prefix stripped
+func (ts *Tenants) servePath(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, ts.Base+"/")
+	id, rest, _ := strings.Cut(rest, "/")
+	tenant := ts.Get(id)
+	if tenant == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	r2 := r.Clone(r.Context())
+	r2.URL.Path = "/" + rest
+	r2.URL.RawPath = ""
+	tenant.serve(w, r2, ts.wl)
+}
+
+// hostMiddleware sends requests for tenant hosts straight to their tenant
+func (ts *Tenants) hostMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tenant := ts.forHost(r.Host); tenant != nil {
+			tenant.serve(w, r, ts.wl)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serve runs the tenant's handler chain with the tenant in context
+func (t *Tenant) serve(w http.ResponseWriter, r *http.Request, parent *WebLite) {
+	t.stats.requests.Add(1)
+	t.stats.active.Add(1)
+	t.stats.lastRequest.Store(time.Now().UnixNano())
+	defer t.stats.active.Add(-1)
+
+	sm := t.Sessions
+	if sm == nil {
+		parent.mu.RLock()
+		sm = parent.SessionManager
+		parent.mu.RUnlock()
+	}
+	handler := t.Server.sessionChain(sm)
+
+	cw := comm.NewCaptureWriter(w)
+	handler.ServeHTTP(cw, r.WithContext(context.WithValue(r.Context(), tenantContextKey{}, t)))
+
+	t.stats.bytesOut.Add(cw.BytesWritten())
+	if cw.Status() >= http.StatusInternalServerError {
+		t.stats.errors.Add(1)
+	}
+}
+
+// SetSessionManager scopes sm to this tenant: its cookie is restricted to the
+// tenant prefix and, when still named "session", renamed to "session_{id}"
+func (t *Tenant) SetSessionManager(sm *SessionManager) *Tenant {
+	if len(t.Hosts) == 0 {
+		sm.CookiePath = t.Prefix + "/"
+	}
+	if sm.CookieName == "session" {
+		sm.CookieName = "session_" + t.ID
+	}
+	t.Sessions = sm
+	return t
+}
+
+// Path returns the absolute URL path of a tenant-relative path
+func (t *Tenant) Path(p string) string {
+	return t.Prefix + "/" + strings.TrimPrefix(p, "/")
+}
+
+// Stats returns the tenant's traffic counters
+func (t *Tenant) Stats() TenantStats {
+	stats := TenantStats{
+		ID:       t.ID,
+		Requests: t.stats.requests.Load(),
+		Errors:   t.stats.errors.Load(),
+		BytesOut: t.stats.bytesOut.Load(),
+		Active:   t.stats.active.Load(),
+	}
+	if last := t.stats.lastRequest.Load(); last > 0 {
+		stats.LastRequest = time.Unix(0, last)
+	}
+	return stats
+}
+
+// TenantFromContext returns the tenant serving the request, if any
+func TenantFromContext(ctx context.Context) (*Tenant, bool) {
+	tenant, ok := ctx.Value(tenantContextKey{}).(*Tenant)
+	return tenant, ok
+}
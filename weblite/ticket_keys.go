@@ -0,0 +1,158 @@
+package weblite
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ticketKeysKept is how many session ticket keys stay valid for decryption,
+// so a ticket survives up to that many rotations
+const ticketKeysKept = 3
+
+// ticketKeyRing encrypts session tickets with the newest key and accepts
+// tickets from the previous ones. The keys live on a private tls.Config so
+// rotation also reaches the copies net/http and quic-go make of the listener config.
+type ticketKeyRing struct {
+	keys  [][32]byte
+	store *tls.Config
+}
+
+// newTicketKeyRing creates a ring with one fresh key
+func newTicketKeyRing() (*ticketKeyRing, error) {
+	ring := &ticketKeyRing{store: &tls.Config{}}
+	return ring, ring.rotate()
+}
+
+// rotate makes a new key current and drops the oldest beyond ticketKeysKept
+func (r *ticketKeyRing) rotate() error {
+	var key [32]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return err
+	}
+	r.keys = append([][32]byte{key}, r.keys...)
+	if len(r.keys) > ticketKeysKept {
+		r.keys = r.keys[:ticketKeysKept]
+	}
+	r.store.SetSessionTicketKeys(r.keys)
+	return nil
+}
+
+// install routes ticket encryption of cfg through the ring
+func (r *ticketKeyRing) install(cfg *tls.Config) {
+	cfg.WrapSession = r.store.EncryptTicket
+	cfg.UnwrapSession = r.store.DecryptTicket
+}
+
+// SetOCSPStapling enables fetching and refreshing OCSP staples for the listener certificate
+func (pl *PortListener) SetOCSPStapling(enabled bool) *PortListener {
+	pl.OCSPStapling = enabled
+	return pl
+}
+
+// SetTicketKeyRotation sets how often session ticket keys are replaced (0 = Go's daily rotation)
+func (pl *PortListener) SetTicketKeyRotation(interval time.Duration) *PortListener {
+	pl.TicketKeyRotation = interval
+	pl.ticketKeyErr = nil
+	return pl
+}
+
+// listenerTLS is the OCSP stapling and ticket key rotation shared by every
+// socket of a listener, so a dual-stack listener runs one fetcher and resumes
+// sessions across both addresses
+type listenerTLS struct {
+	ring    *ticketKeyRing
+	stapler *ocspStapler
+	users   int
+	stop    context.CancelFunc
+}
+
+// tlsUpkeep holds the running listenerTLS of each listener, see maintainTLS
+type tlsUpkeep struct {
+	mu    sync.Mutex
+	items map[*PortListener]*listenerTLS
+}
+
+// maintainTLS applies the OCSP stapling and ticket key rotation of listener
+// to cfg, starting them for the first socket of the listener. The returned
+// function releases them; the background work stops with the last socket.
+func (wl *WebLite) maintainTLS(listener *PortListener, cfg *tls.Config) (func(), error) {
+	wl.tlsUpkeep.mu.Lock()
+	defer wl.tlsUpkeep.mu.Unlock()
+
+	state := wl.tlsUpkeep.items[listener]
+	if state == nil {
+		var err error
+		if state, err = wl.startTLSUpkeep(listener, cfg); err != nil {
+			return nil, err
+		}
+		if wl.tlsUpkeep.items == nil {
+			wl.tlsUpkeep.items = make(map[*PortListener]*listenerTLS)
+		}
+		wl.tlsUpkeep.items[listener] = state
+	}
+	state.users++
+
+	if state.ring != nil {
+		state.ring.install(cfg)
+	}
+	if state.stapler != nil {
+		cfg.Certificates = nil
+		cfg.GetCertificate = state.stapler.getCertificate
+	}
+
+	return func() {
+		wl.tlsUpkeep.mu.Lock()
+		defer wl.tlsUpkeep.mu.Unlock()
+		if state.users--; state.users == 0 {
+			state.stop()
+			delete(wl.tlsUpkeep.items, listener)
+		}
+	}, nil
+}
+
+// startTLSUpkeep creates the ticket key ring and OCSP stapler of listener,
+// the stapler for the certificate of cfg, and starts refreshing them
+func (wl *WebLite) startTLSUpkeep(listener *PortListener, cfg *tls.Config) (*listenerTLS, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	state := &listenerTLS{stop: cancel}
+
+	if listener.TicketKeyRotation > 0 {
+		ring, err := newTicketKeyRing()
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("session ticket keys: %w", err)
+		}
+		state.ring = ring
+		go func() {
+			ticker := time.NewTicker(listener.TicketKeyRotation)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := ring.rotate(); err != nil {
+						fmt.Printf("WebLite [%s] session ticket key rotation failed: %v\n", wl.Name, err)
+					}
+				}
+			}
+		}()
+	}
+
+	if listener.OCSPStapling && len(cfg.Certificates) == 1 {
+		stapler, err := newOCSPStapler(wl.Name, cfg.Certificates[0])
+		if err != nil {
+			// Serving without a staple is still valid TLS
+			fmt.Printf("WebLite [%s] OCSP stapling disabled: %v\n", wl.Name, err)
+		} else {
+			state.stapler = stapler
+			go stapler.run(ctx)
+		}
+	}
+
+	return state, nil
+}
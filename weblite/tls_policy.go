@@ -0,0 +1,182 @@
+package weblite
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// TLSPolicy restricts the TLS parameters an HTTPS listener negotiates.
+// Zero fields keep the secure defaults: TLS 1.2 minimum, Go's cipher suite
+// and curve selection, and the standard h2/http/1.1 ALPN list.
+type TLSPolicy struct {
+	MinVersion uint16 // Default tls.VersionTLS12
+	MaxVersion uint16 // 0 = newest supported; HTTP/3 needs TLS 1.3
+	// CipherSuites limits the TLS 1.2 suites; TLS 1.3 suites are not configurable in Go
+	CipherSuites     []uint16
+	CurvePreferences []tls.CurveID
+	// NextProtos is the ALPN list; leaving out "h2" disables HTTP/2 on the listener
+	NextProtos []string
+
+	err error // First config parse error, reported when the listener starts
+}
+
+// ParseTLSPolicy reads the tls_min_version, tls_max_version ("1.2" or "1.3"),
+// tls_ciphers, tls_curves and tls_alpn (comma separated) config keys.
+// Cipher suite names are the Go/IANA names; insecure suites are rejected.
+// It returns nil when none of the keys are set.
+func ParseTLSPolicy(config map[string]string) (*TLSPolicy, error) {
+	if config["tls_min_version"] == "" && config["tls_max_version"] == "" && config["tls_ciphers"] == "" &&
+		config["tls_curves"] == "" && config["tls_alpn"] == "" {
+		return nil, nil
+	}
+
+	policy := &TLSPolicy{}
+	var err error
+	if policy.MinVersion, err = parseTLSVersion(config["tls_min_version"]); err != nil {
+		return nil, err
+	}
+	if policy.MaxVersion, err = parseTLSVersion(config["tls_max_version"]); err != nil {
+		return nil, err
+	}
+	for _, name := range splitList(config["tls_ciphers"]) {
+		id, ok := secureCipherSuite(name)
+		if !ok {
+			return nil, fmt.Errorf("tls_ciphers: unknown or insecure cipher suite %q", name)
+		}
+		policy.CipherSuites = append(policy.CipherSuites, id)
+	}
+	for _, name := range splitList(config["tls_curves"]) {
+		id, ok := curveByName(name)
+		if !ok {
+			return nil, fmt.Errorf("tls_curves: unknown curve %q", name)
+		}
+		policy.CurvePreferences = append(policy.CurvePreferences, id)
+	}
+	policy.NextProtos = splitList(config["tls_alpn"])
+	return policy, policy.validate()
+}
+
+// SetTLSPolicy sets the TLS policy (nil restores the defaults)
+func (pl *PortListener) SetTLSPolicy(policy *TLSPolicy) *PortListener {
+	pl.TLS = policy
+	return pl
+}
+
+// validate checks the combination of settings
+func (p *TLSPolicy) validate() error {
+	if p.MinVersion != 0 && p.MinVersion < tls.VersionTLS12 {
+		return fmt.Errorf("tls policy: minimum version %s is below TLS 1.2", tls.VersionName(p.MinVersion))
+	}
+	if p.MinVersion != 0 && p.MaxVersion != 0 && p.MinVersion > p.MaxVersion {
+		return fmt.Errorf("tls policy: minimum version %s is above maximum %s", tls.VersionName(p.MinVersion), tls.VersionName(p.MaxVersion))
+	}
+	if p.MaxVersion != 0 && p.MaxVersion < tls.VersionTLS13 && len(p.CipherSuites) > 0 {
+		for _, id := range p.CipherSuites {
+			if !slices.Contains(tls12Suites(), id) {
+				return fmt.Errorf("tls policy: cipher suite %s is TLS 1.3 only", tls.CipherSuiteName(id))
+			}
+		}
+	}
+	// net/http refuses to serve HTTP/2 without one of these suites
+	if len(p.CipherSuites) > 0 && (len(p.NextProtos) == 0 || slices.Contains(p.NextProtos, "h2")) &&
+		!slices.Contains(p.CipherSuites, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256) &&
+		!slices.Contains(p.CipherSuites, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256) {
+		return fmt.Errorf("tls policy: HTTP/2 requires TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 or TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256; add one or leave h2 out of the ALPN list")
+	}
+	return nil
+}
+
+// apply sets the policy on cfg; a nil policy only enforces the TLS 1.2 minimum
+func (p *TLSPolicy) apply(cfg *tls.Config) error {
+	cfg.MinVersion = tls.VersionTLS12
+	if p == nil {
+		return nil
+	}
+	if p.err != nil {
+		return p.err
+	}
+	if err := p.validate(); err != nil {
+		return err
+	}
+	if p.MinVersion != 0 {
+		cfg.MinVersion = p.MinVersion
+	}
+	cfg.MaxVersion = p.MaxVersion
+	cfg.CipherSuites = slices.Clone(p.CipherSuites)
+	cfg.CurvePreferences = slices.Clone(p.CurvePreferences)
+	if len(p.NextProtos) > 0 {
+		cfg.NextProtos = slices.Clone(p.NextProtos)
+	}
+	return nil
+}
+
+// configureServer disables HTTP/2 when the ALPN list leaves it out; otherwise
+// net/http would add "h2" back to the TLS config
+func (p *TLSPolicy) configureServer(server *http.Server) {
+	if p != nil && len(p.NextProtos) > 0 && !slices.Contains(p.NextProtos, "h2") {
+		server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+}
+
+func parseTLSVersion(value string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "tls") {
+	case "":
+		return 0, nil
+	case "1.2", "12":
+		return tls.VersionTLS12, nil
+	case "1.3", "13":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q (use 1.2 or 1.3)", value)
+	}
+}
+
+// secureCipherSuite looks name up among the suites Go considers secure
+func secureCipherSuite(name string) (uint16, bool) {
+	for _, suite := range tls.CipherSuites() {
+		if strings.EqualFold(suite.Name, name) {
+			return suite.ID, true
+		}
+	}
+	return 0, false
+}
+
+// tls12Suites lists the secure suites usable with TLS 1.2
+func tls12Suites() []uint16 {
+	var ids []uint16
+	for _, suite := range tls.CipherSuites() {
+		if slices.Contains(suite.SupportedVersions, tls.VersionTLS12) {
+			ids = append(ids, suite.ID)
+		}
+	}
+	return ids
+}
+
+func curveByName(name string) (tls.CurveID, bool) {
+	switch strings.ToUpper(strings.ReplaceAll(name, "-", "")) {
+	case "X25519":
+		return tls.X25519, true
+	case "X25519MLKEM768":
+		return tls.X25519MLKEM768, true
+	case "P256", "SECP256R1":
+		return tls.CurveP256, true
+	case "P384", "SECP384R1":
+		return tls.CurveP384, true
+	case "P521", "SECP521R1":
+		return tls.CurveP521, true
+	}
+	return 0, false
+}
+
+func splitList(value string) []string {
+	var out []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,124 @@
+package weblite
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-xlite/wbx/comm/routes"
+	"github.com/gorilla/mux"
+)
+
+type hostVarsKey struct{}
+
+// virtualHost is a separate route tree served for requests matching a host pattern
+type virtualHost struct {
+	pattern string
+	match   *mux.Route // Host matcher only, never serves
+	routes  *routes.Routes
+}
+
+// virtualHosts dispatches requests to the route tree of their host, in the
+// order hosts were added, falling back to the server's own routes
+type virtualHosts struct {
+	items []*virtualHost
+	mu    sync.RWMutex
+}
+
+// Host returns the route tree served for requests to host, creating it on
+// first use. Patterns use gorilla/mux Host syntax ("api.example.com",
+// "{tenant}.example.com"); host variables are merged into mux.Vars. Requests for
+// other hosts keep using Routes. The listener's DomainValidator still applies,
+// so allow the host there when it restricts domains.
+func (wl *WebLite) Host(host string) *routes.Routes {
+	pattern := strings.ToLower(host)
+
+	wl.vhosts.mu.Lock()
+	defer wl.vhosts.mu.Unlock()
+	for _, vh := range wl.vhosts.items {
+		if vh.pattern == pattern {
+			return vh.routes
+		}
+	}
+
+	router := mux.NewRouter()
+	router.Use(mergeHostVars)
+	hostRoutes := routes.NewRoutes(router)
+	hostRoutes.MethodOverride = wl.Routes.MethodOverride
+	hostRoutes.AutoHead = wl.Routes.AutoHead
+
+	wl.vhosts.items = append(wl.vhosts.items, &virtualHost{
+		pattern: pattern,
+		match:   mux.NewRouter().Host(pattern),
+		routes:  hostRoutes,
+	})
+	return hostRoutes
+}
+
+// Hosts returns the host patterns with their own route tree
+func (wl *WebLite) Hosts() []string {
+	wl.vhosts.mu.RLock()
+	defer wl.vhosts.mu.RUnlock()
+	patterns := make([]string, len(wl.vhosts.items))
+	for i, vh := range wl.vhosts.items {
+		patterns[i] = vh.pattern
+	}
+	return patterns
+}
+
+// serveRoutes serves r from the route tree of its host, or from Routes
+func (wl *WebLite) serveRoutes(w http.ResponseWriter, r *http.Request) {
+	if vh, vars := wl.matchHost(r); vh != nil {
+		if len(vars) > 0 {
+			r = r.WithContext(context.WithValue(r.Context(), hostVarsKey{}, vars))
+		}
+		vh.routes.ServeHTTP(w, r)
+		return
+	}
+	wl.Routes.ServeHTTP(w, r)
+}
+
+// matchHost returns the virtual host serving r and its host variables, or nil
+func (wl *WebLite) matchHost(r *http.Request) (*virtualHost, map[string]string) {
+	wl.vhosts.mu.RLock()
+	items := wl.vhosts.items
+	wl.vhosts.mu.RUnlock()
+
+	// Host names are case-insensitive, gorilla/mux matching is not
+	probe := r
+	if len(items) > 0 && (strings.ToLower(r.Host) != r.Host || strings.ToLower(r.URL.Host) != r.URL.Host) {
+		lowered, url := *r, *r.URL
+		lowered.Host = strings.ToLower(r.Host)
+		url.Host = strings.ToLower(r.URL.Host)
+		lowered.URL = &url
+		probe = &lowered
+	}
+
+	for _, vh := range items {
+		var match mux.RouteMatch
+		if vh.match.Match(probe, &match) {
+			return vh, match.Vars
+		}
+	}
+	return nil, nil
+}
+
+// mergeHostVars adds the host pattern variables to the route variables
+func mergeHostVars(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hostVars, ok := r.Context().Value(hostVarsKey{}).(map[string]string)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		vars := make(map[string]string, len(hostVars))
+		for k, v := range hostVars {
+			vars[k] = v
+		}
+		for k, v := range mux.Vars(r) {
+			vars[k] = v
+		}
+		next.ServeHTTP(w, mux.SetURLVars(r, vars))
+	})
+}